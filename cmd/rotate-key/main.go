@@ -0,0 +1,98 @@
+// rotate-key 更换主密钥并用新密钥重新加密全部存储源的敏感配置
+// 使用密钥文件时直接替换文件；主密钥来自环境变量时输出新密钥，由管理员更新环境变量
+// 运行前先停止服务，服务进程仍持有旧密钥
+//
+//	go run ./cmd/rotate-key [-new-key <base64>]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/wentf9/MyGoFileHub/config"
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	_ "github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/local"
+	_ "github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/smb"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/secrets"
+)
+
+func main() {
+	if err := rotateKey(os.Args[1:]); err != nil {
+		log.Fatalf("Key rotation failed: %v", err)
+	}
+}
+
+func rotateKey(args []string) error {
+	fs := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	newKey := fs.String("new-key", "", "新的主密钥 (base64 编码的 32 字节)，省略时随机生成")
+	fs.Parse(args)
+
+	fileService, err := openFileService()
+	if err != nil {
+		return err
+	}
+
+	var key []byte
+	if *newKey != "" {
+		key, err = secrets.ParseKey(*newKey)
+	} else {
+		key, err = secrets.GenerateKey()
+	}
+	if err != nil {
+		return err
+	}
+	next, err := secrets.New(key)
+	if err != nil {
+		return err
+	}
+
+	keyFile := config.AppConfig.MasterKeyFile
+	fromEnv := config.AppConfig.MasterKey != ""
+	// 先把新密钥写到旁边，替换密钥文件前崩溃时新密钥不会丢失
+	pending := keyFile + ".new"
+	if !fromEnv {
+		if err := secrets.WriteKeyFile(pending, key); err != nil {
+			return err
+		}
+	}
+	// 数据库在一个事务中更新，失败时仍全部使用原密钥
+	n, err := fileService.RotateSecrets(context.Background(), next)
+	if err != nil {
+		if !fromEnv {
+			os.Remove(pending)
+		}
+		return err
+	}
+	fmt.Printf("Re-encrypted secrets of %d storage sources\n", n)
+	if fromEnv {
+		fmt.Printf("Update the environment before restarting:\nMY_GO_FILE_HUB_MASTER_KEY=%s\n", secrets.EncodeKey(key))
+		return nil
+	}
+	if err := os.Rename(pending, keyFile); err != nil {
+		return fmt.Errorf("new key is in %s but could not replace %s: %w", pending, keyFile, err)
+	}
+	fmt.Printf("New master key written to %s\n", keyFile)
+	return nil
+}
+
+// openFileService 打开服务使用的数据库与当前主密钥
+func openFileService() (*application.FileService, error) {
+	db, err := persistence.InitDB(config.AppConfig.DataDir + "/gofile.db")
+	if err != nil {
+		return nil, err
+	}
+	masterKey, err := secrets.LoadKey(config.AppConfig.MasterKey, config.AppConfig.MasterKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	cipher, err := secrets.New(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	permService := application.NewPermissionService(persistence.NewPermissionRepository(db), persistence.NewUserRepository(db))
+	return application.NewFileService(persistence.NewSourceRepository(db), persistence.NewSearchIndexRepository(db), persistence.NewTrashRepository(db), permService, cipher), nil
+}
@@ -8,10 +8,17 @@ import (
 )
 
 type Config struct {
-	ServerPort string `env:"MY_GO_FILE_HUB_SERVER_PORT" envDefault:"3939"`
-	Listen     string `env:"MY_GO_FILE_HUB_LISTEN" envDefault:"localhost"`
-	DataDir    string `env:"MY_GO_FILE_HUB_DATA_DIR" envDefault:"./data"`
-	LanOnly    string `env:"MY_GO_FILE_HUB_LAN_ONLYDB_USER" envDefault:"false"`
+	ServerPort    string `env:"MY_GO_FILE_HUB_SERVER_PORT" envDefault:"3939"`
+	Listen        string `env:"MY_GO_FILE_HUB_LISTEN" envDefault:"localhost"`
+	DataDir       string `env:"MY_GO_FILE_HUB_DATA_DIR" envDefault:"./data"`
+	LanOnly       string `env:"MY_GO_FILE_HUB_LAN_ONLYDB_USER" envDefault:"false"`
+	OpTimeout     int    `env:"MY_GO_FILE_HUB_OP_TIMEOUT" envDefault:"30"`   // 存储操作默认超时(秒)，0 表示不限制
+	TrashDays     int    `env:"MY_GO_FILE_HUB_TRASH_DAYS" envDefault:"30"`   // 回收站默认保留天数，0 表示不自动清理
+	TrashSource   string `env:"MY_GO_FILE_HUB_TRASH_SOURCE"`                 // 统一存放回收站内容的存储源 Key，为空时各存储源使用自己的隐藏目录
+	VersionKeep   int    `env:"MY_GO_FILE_HUB_VERSION_KEEP" envDefault:"20"` // 每个文件默认最多保留的历史版本数，0 表示不限制
+	VersionDays   int    `env:"MY_GO_FILE_HUB_VERSION_DAYS" envDefault:"90"` // 历史版本默认保留天数，0 表示不限制
+	MasterKey     string `env:"MY_GO_FILE_HUB_MASTER_KEY"`                   // 加密存储源密码等敏感配置的主密钥 (base64 编码的 32 字节)，为空时使用密钥文件
+	MasterKeyFile string `env:"MY_GO_FILE_HUB_MASTER_KEY_FILE"`              // 主密钥文件，为空时为 DataDir/master.key，不存在时自动生成
 }
 
 var AppConfig Config
@@ -23,6 +30,9 @@ func init() {
 	if AppConfig.LanOnly != "true" && AppConfig.LanOnly != "false" {
 		panic("Invalid value for MY_GO_FILE_HUB_LAN_ONLY, must be 'true' or 'false'")
 	}
+	if AppConfig.MasterKeyFile == "" {
+		AppConfig.MasterKeyFile = AppConfig.DataDir + "/master.key"
+	}
 	if AppConfig.Listen == "localhost" {
 		AppConfig.Listen = "127.0.0.1"
 	}
@@ -0,0 +1,253 @@
+package application
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	pathpkg "path"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// 打包下载支持的格式
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+// ErrArchiveFormat 不支持的打包格式
+var ErrArchiveFormat = errors.New("unsupported archive format")
+
+// ArchiveEntry 打包前确认过的一个选中项
+type ArchiveEntry struct {
+	Path string
+	Info vfs.FileInfo
+}
+
+// archiveWriter 打包时逐个写入条目，zip 与 tar.gz 各有一个实现
+type archiveWriter interface {
+	addDir(name string, info vfs.FileInfo) error
+	addFile(ctx context.Context, name string, info vfs.FileInfo, r io.Reader) error
+	Close() error
+}
+
+// ArchiveContentType 返回格式对应的 MIME 类型，格式不支持时返回 ErrArchiveFormat
+func ArchiveContentType(format string) (string, error) {
+	switch format {
+	case ArchiveZip:
+		return "application/zip", nil
+	case ArchiveTarGz:
+		return "application/gzip", nil
+	}
+	return "", ErrArchiveFormat
+}
+
+// PrepareArchive 检查选中的路径，在开始输出之前发现不存在或无权限的路径
+// 这样 Handler 还能返回 JSON 错误，而不是一个损坏的压缩包
+func (s *FileService) PrepareArchive(ctx context.Context, sourceKey string, paths []string) ([]ArchiveEntry, error) {
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ArchiveEntry, 0, len(paths))
+	for _, p := range paths {
+		p = pathpkg.Clean(cleanPath(p))
+		info, err := driver.Stat(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, ArchiveEntry{Path: p, Info: info})
+	}
+	return entries, nil
+}
+
+// WriteArchive 将选中的文件和目录流式打包写入 w，不使用临时文件
+// 用户没有读权限的条目直接跳过；ctx 取消 (客户端断开) 时立即停止
+// zip 使用数据描述符流式写入，条目超过 4GB 时 archive/zip 会自动写入 zip64 字段
+func (s *FileService) WriteArchive(ctx context.Context, sourceKey string, entries []ArchiveEntry, format string, w io.Writer) error {
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+
+	var aw archiveWriter
+	switch format {
+	case ArchiveZip:
+		aw = &zipArchive{zw: zip.NewWriter(w)}
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(w)
+		aw = &tarArchive{gz: gz, tw: tar.NewWriter(gz)}
+	default:
+		return ErrArchiveFormat
+	}
+
+	used := make(map[string]bool)
+	for _, entry := range entries {
+		// 选中根目录时直接展开其内容，其余选中项以自身名称作为顶层条目
+		name := pathpkg.Base(entry.Path)
+		if entry.Path == "/" {
+			name = ""
+		} else {
+			name = uniqueEntryName(used, name)
+		}
+		if err := s.archiveEntry(ctx, driver, aw, entry.Path, name, entry.Info); err != nil {
+			return err
+		}
+	}
+	return aw.Close()
+}
+
+// archiveEntry 递归写入一个条目
+func (s *FileService) archiveEntry(ctx context.Context, driver vfs.StorageDriver, aw archiveWriter, path, name string, info vfs.FileInfo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !info.IsDir {
+		rc, err := driver.Open(ctx, path)
+		if err != nil {
+			if skippable(err) {
+				return nil
+			}
+			return err
+		}
+		defer rc.Close()
+		return aw.addFile(ctx, name, info, rc)
+	}
+
+	children, err := driver.List(ctx, path)
+	if err != nil {
+		if skippable(err) {
+			return nil
+		}
+		return err
+	}
+	if name != "" {
+		if err := aw.addDir(name, info); err != nil {
+			return err
+		}
+	}
+	for _, child := range children {
+		if err := s.archiveEntry(ctx, driver, aw, pathpkg.Join(path, child.Name), pathpkg.Join(name, child.Name), child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skippable 无权限或遍历过程中被删除的条目不影响整体打包
+func skippable(err error) bool {
+	return errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist)
+}
+
+// uniqueEntryName 多个选中项同名时 (来自不同目录) 追加序号，避免解压时互相覆盖
+func uniqueEntryName(used map[string]bool, name string) string {
+	candidate := name
+	base, ext := splitExt(name)
+	for i := 1; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchive) addDir(name string, info vfs.FileInfo) error {
+	_, err := a.zw.CreateHeader(&zip.FileHeader{
+		Name:     name + "/",
+		Method:   zip.Store,
+		Modified: info.ModTime,
+	})
+	return err
+}
+
+func (a *zipArchive) addFile(ctx context.Context, name string, info vfs.FileInfo, r io.Reader) error {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: info.ModTime,
+	}
+	if isCompressed(name) {
+		header.Method = zip.Store
+	}
+	fw, err := a.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = vfs.CopyContext(ctx, fw, r)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
+
+type tarArchive struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (a *tarArchive) addDir(name string, info vfs.FileInfo) error {
+	return a.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name + "/",
+		Mode:     0755,
+		ModTime:  info.ModTime,
+		Format:   tar.FormatPAX,
+	})
+}
+
+func (a *tarArchive) addFile(ctx context.Context, name string, info vfs.FileInfo, r io.Reader) error {
+	// tar 头部必须写明大小，打包期间文件变化时截断或补零，保证归档结构完整
+	if err := a.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     info.Size,
+		Mode:     0644,
+		ModTime:  info.ModTime,
+		Format:   tar.FormatPAX,
+	}); err != nil {
+		return err
+	}
+	n, err := vfs.CopyContext(ctx, a.tw, io.LimitReader(r, info.Size))
+	if err != nil {
+		return err
+	}
+	if n < info.Size {
+		_, err = io.CopyN(a.tw, zeroReader{}, info.Size-n)
+	}
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// isCompressed 已压缩的格式直接存储，避免浪费 CPU
+func isCompressed(name string) bool {
+	switch strings.ToLower(pathpkg.Ext(name)) {
+	case ".zip", ".gz", ".tgz", ".bz2", ".xz", ".7z", ".rar", ".zst",
+		".jpg", ".jpeg", ".png", ".gif", ".webp",
+		".mp3", ".aac", ".flac", ".ogg", ".mp4", ".mkv", ".mov", ".avi", ".webm":
+		return true
+	}
+	return false
+}
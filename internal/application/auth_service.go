@@ -20,10 +20,25 @@ var userCache = sync.Map{} // 用于存储用户的 JWT Token
 
 type AuthService struct {
 	userRepo repository.UserRepository
+	permRepo repository.PermissionRepository
+
+	hookMu      sync.RWMutex
+	deleteHooks []UserDeleteHook
 }
 
-func NewAuthService(repo repository.UserRepository) *AuthService {
-	return &AuthService{userRepo: repo}
+// UserDeleteHook 删除用户前调用，清理以用户名关联的记录 (分享、回收站等)，避免同名的新用户继承
+// actor 为执行删除的管理员，返回错误时取消删除
+type UserDeleteHook func(ctx context.Context, username, actor string) error
+
+func NewAuthService(repo repository.UserRepository, permRepo repository.PermissionRepository) *AuthService {
+	return &AuthService{userRepo: repo, permRepo: permRepo}
+}
+
+// OnDelete 注册删除用户前的清理回调
+func (s *AuthService) OnDelete(h UserDeleteHook) {
+	s.hookMu.Lock()
+	defer s.hookMu.Unlock()
+	s.deleteHooks = append(s.deleteHooks, h)
 }
 
 // Login 验证并返回 Token
@@ -39,35 +54,34 @@ func (s *AuthService) LoginJwt(ctx context.Context, username, password string) (
 	if err != nil {
 		return "", errors.New("invalid username or password")
 	}
-	// 生成 JWT Token
+	// 密码正确后才提示停用，不泄露账号是否存在
+	if !user.IsActive {
+		return "", ErrUserDisabled
+	}
+	return issueToken(user)
+}
+
+// issueToken 为用户签发 Token，ver 记录签发时的 TokenVersion
+func issueToken(user *model.User) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,
+		"username": user.Username,
+		"ver":      user.TokenVersion,
 		"exp":      time.Now().Add(time.Hour * 24).Unix(), // 24小时过期
 	})
+	return token.SignedString(JWTSecret)
+}
 
-	tokenString, err := token.SignedString(JWTSecret)
-	if err != nil {
-		return "", err
-	}
-
-	return tokenString, nil
+// TokenRevoked 判断 Token 是否签发于用户最近一次修改密码之前 (ver 与当前 TokenVersion 不一致)
+// 没有 ver 的旧 Token 视为 0
+func TokenRevoked(user *model.User, claims jwt.MapClaims) bool {
+	ver, _ := claims["ver"].(float64)
+	return int(ver) != user.TokenVersion
 }
 
 func (s *AuthService) LoginBasic(ctx context.Context, username, password string) (*model.User, error) {
-	var user *model.User
-	var err error
-	if vaule, ok := userCache.Load(username); ok {
-		user = vaule.(*model.User)
-	} else {
-		// 查询用户
-		user, err = s.userRepo.FindByUsername(ctx, username)
-		if err != nil {
-			return nil, errors.New("invalid username or password") // 模糊报错，防止枚举攻击
-		}
-		value, loaded := userCache.LoadOrStore(username, user)
-		if loaded {
-			user = value.(*model.User)
-		}
+	user, err := cachedUser(ctx, s.userRepo, username)
+	if err != nil {
+		return nil, errors.New("invalid username or password") // 模糊报错，防止枚举攻击
 	}
 
 	// 验证密码
@@ -75,9 +89,34 @@ func (s *AuthService) LoginBasic(ctx context.Context, username, password string)
 	if err != nil {
 		return nil, errors.New("invalid username or password")
 	}
+	if !user.IsActive {
+		return nil, ErrUserDisabled
+	}
 	return user, nil
 }
 
+// GetUser 按用户名获取用户 (优先读缓存)
+func (s *AuthService) GetUser(ctx context.Context, username string) (*model.User, error) {
+	user, err := cachedUser(ctx, s.userRepo, username)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// cachedUser 按用户名读取用户 (优先读缓存)，读取期间用户被修改时不写入缓存
+func cachedUser(ctx context.Context, repo repository.UserRepository, username string) (*model.User, error) {
+	if value, ok := userCache.Load(username); ok {
+		return value.(*model.User), nil
+	}
+	gen := userGeneration(username)
+	user, err := repo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return cacheStore(&userCache, username, username, gen, user).(*model.User), nil
+}
+
 // Register 注册新用户 (用于初始化管理员)
 func (s *AuthService) Register(ctx context.Context, username, password string, role string) error {
 	// 1. 哈希密码
@@ -95,23 +134,3 @@ func (s *AuthService) Register(ctx context.Context, username, password string, r
 
 	return s.userRepo.Save(ctx, user)
 }
-
-func (s *AuthService) UpdateUser(ctx context.Context, username, password string, role string) error {
-	// 1. 查询用户
-	user, err := s.userRepo.FindByUsername(ctx, username)
-	if err != nil {
-		return errors.New("user not found")
-	}
-
-	// 2. 哈希新密码
-	hashedPwd, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-
-	// 3. 更新用户信息
-	user.PasswordHash = string(hashedPwd)
-	user.Role = role
-
-	return s.userRepo.Save(ctx, user)
-}
@@ -0,0 +1,192 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+)
+
+func TestLoginChecksIsActive(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "bob", RoleUser)
+	disabled := env.addUser(t, "carol", RoleUser)
+	disabled.IsActive = false
+	if err := env.db.Save(disabled).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name               string
+		username, password string
+		err                error // nil 表示登录成功
+		anyErr             bool  // 期望模糊的认证失败错误
+	}{
+		{"active", "bob", "password123", nil, false},
+		{"disabled", "carol", "password123", ErrUserDisabled, false},
+		// 密码错误时不提示账号已停用
+		{"disabled wrong password", "carol", "wrong", nil, true},
+		{"wrong password", "bob", "wrong", nil, true},
+		{"unknown user", "dave", "password123", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(login string, err error) {
+				t.Helper()
+				switch {
+				case tt.anyErr:
+					if err == nil || errors.Is(err, ErrUserDisabled) {
+						t.Fatalf("%s = %v, want generic authentication error", login, err)
+					}
+				case !errors.Is(err, tt.err):
+					t.Fatalf("%s = %v, want %v", login, err, tt.err)
+				}
+			}
+			_, err := env.auth.LoginJwt(env.ctx, tt.username, tt.password)
+			check("LoginJwt", err)
+			_, err = env.auth.LoginBasic(env.ctx, tt.username, tt.password)
+			check("LoginBasic", err)
+		})
+	}
+}
+
+// 停用立即生效，不受用户缓存影响
+func TestSetActiveTakesEffect(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "bob", RoleUser)
+	if _, err := env.auth.LoginBasic(env.ctx, "bob", "password123"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.auth.SetActive(env.ctx, "admin", "bob", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.auth.LoginBasic(env.ctx, "bob", "password123"); !errors.Is(err, ErrUserDisabled) {
+		t.Fatalf("LoginBasic after deactivation = %v, want ErrUserDisabled", err)
+	}
+	if env.files.permService.CheckPermission(env.ctx, "bob", env.source.ID, "/", "read") {
+		t.Fatalf("deactivated user still has permissions")
+	}
+
+	if _, err := env.auth.SetActive(env.ctx, "admin", "bob", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.auth.LoginBasic(env.ctx, "bob", "password123"); err != nil {
+		t.Fatalf("LoginBasic after reactivation = %v", err)
+	}
+}
+
+func TestSetActiveKeepsAdmin(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.auth.SetActive(env.ctx, "admin", "admin", false); !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("deactivating yourself = %v, want ErrInvalidOperation", err)
+	}
+	env.addUser(t, "root", RoleAdmin)
+	if _, err := env.auth.SetActive(env.ctx, "root", "admin", false); err != nil {
+		t.Fatal(err)
+	}
+	// 最后一个启用的管理员不能被停用
+	if _, err := env.auth.SetActive(env.ctx, "admin", "root", false); !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("deactivating the last admin = %v, want ErrInvalidOperation", err)
+	}
+}
+
+// 删除用户后，同名的新用户不会继承旧用户的分享、文件收集请求、通知与回收站条目
+func TestDeleteUserCleansUpRecords(t *testing.T) {
+	env := newTestEnv(t)
+	shares := NewShareService(env.files, persistence.NewShareRepository(env.db))
+	notify := NewNotificationService(persistence.NewNotificationRepository(env.db))
+	requests := NewFileRequestService(env.files, persistence.NewFileRequestRepository(env.db), notify)
+	env.auth.OnDelete(shares.OnUserDeleted)
+	env.auth.OnDelete(requests.OnUserDeleted)
+	env.auth.OnDelete(notify.OnUserDeleted)
+	env.auth.OnDelete(env.files.OnUserDeleted)
+
+	env.addUser(t, "bob", RoleUser)
+	records := []any{
+		&model.Share{Token: "bob-share", Username: "bob", SourceID: env.source.ID, SourceKey: env.sourceKey, Path: "/a"},
+		&model.FileRequest{Token: "bob-request", Username: "bob", SourceID: env.source.ID, SourceKey: env.sourceKey, Path: "/r", Title: "r"},
+		&model.Notification{Username: "bob", Type: "test", Title: "hello"},
+		&model.TrashItem{SourceID: env.source.ID, SourceKey: env.sourceKey, Path: "/b", Name: "b", TrashSourceKey: env.sourceKey, TrashPath: "/x", DeletedBy: "bob", DeletedAt: time.Now()},
+	}
+	for _, r := range records {
+		if err := env.db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := env.auth.DeleteUser(env.ctx, "admin", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	env.addUser(t, "bob", RoleUser)
+
+	if list, err := shares.List(env.ctx, "bob", false); err != nil || len(list) != 0 {
+		t.Fatalf("shares of the new bob = %d, %v; want none", len(list), err)
+	}
+	if list, err := requests.List(env.ctx, "bob", false); err != nil || len(list) != 0 {
+		t.Fatalf("file requests of the new bob = %d, %v; want none", len(list), err)
+	}
+	if list, err := notify.List(env.ctx, "bob", false); err != nil || len(list) != 0 {
+		t.Fatalf("notifications of the new bob = %d, %v; want none", len(list), err)
+	}
+	if list, err := env.files.ListTrash(env.ctx, "bob", "", false); err != nil || len(list) != 0 {
+		t.Fatalf("trash of the new bob = %d, %v; want none", len(list), err)
+	}
+	// 回收站条目转给执行删除的管理员，仍然可以恢复
+	if list, err := env.files.ListTrash(env.ctx, "admin", "", false); err != nil || len(list) != 1 {
+		t.Fatalf("trash of admin = %d, %v; want the item deleted by the old bob", len(list), err)
+	}
+}
+
+// pausingUserRepo 在 FindByUsername 读到数据后暂停，模拟读取与修改并发
+type pausingUserRepo struct {
+	repository.UserRepository
+	read   chan struct{} // 读到数据后关闭
+	resume chan struct{} // 关闭后返回读到的数据
+}
+
+func (r *pausingUserRepo) FindByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := r.UserRepository.FindByUsername(ctx, username)
+	close(r.read)
+	<-r.resume
+	return user, err
+}
+
+// 缓存未命中时读到的旧数据，如果读取期间用户被修改，不能写回缓存
+func TestUserCacheIgnoresStaleRead(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "bob", RoleUser)
+	userRepo := persistence.NewUserRepository(env.db)
+	paused := &pausingUserRepo{UserRepository: userRepo, read: make(chan struct{}), resume: make(chan struct{})}
+	reader := NewAuthService(paused, persistence.NewPermissionRepository(env.db))
+
+	done := make(chan *model.User)
+	go func() {
+		user, err := reader.GetUser(env.ctx, "bob")
+		if err != nil {
+			t.Error(err)
+		}
+		done <- user
+	}()
+	<-paused.read
+	// 读取返回之前停用 bob
+	if _, err := env.auth.SetActive(env.ctx, "admin", "bob", false); err != nil {
+		t.Fatal(err)
+	}
+	close(paused.resume)
+	if stale := <-done; stale == nil || !stale.IsActive {
+		t.Fatalf("expected the in-flight read to return the old user")
+	}
+
+	user, err := env.auth.GetUser(env.ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.IsActive {
+		t.Fatal("stale user was written back to the cache")
+	}
+}
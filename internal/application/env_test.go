@@ -0,0 +1,81 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/secrets"
+
+	_ "github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/local"
+
+	"gorm.io/gorm"
+)
+
+// testEnv 使用临时 SQLite 数据库与本地目录的测试环境
+type testEnv struct {
+	db        *gorm.DB
+	files     *FileService
+	auth      *AuthService
+	source    *model.StorageSource
+	root      string          // 存储源的本地根目录
+	sourceKey string          // 驱动缓存按 Key 全局共享，每个测试使用不同的 Key
+	ctx       context.Context // 以管理员身份访问
+}
+
+var testSourceSeq atomic.Int64
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := persistence.InitDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cipher, err := secrets.New(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userRepo := persistence.NewUserRepository(db)
+	permRepo := persistence.NewPermissionRepository(db)
+	files := NewFileService(persistence.NewSourceRepository(db), persistence.NewSearchIndexRepository(db), persistence.NewTrashRepository(db), NewPermissionService(permRepo, userRepo), cipher)
+	env := &testEnv{
+		db:        db,
+		files:     files,
+		auth:      NewAuthService(userRepo, permRepo),
+		root:      filepath.Join(dir, "files"),
+		sourceKey: fmt.Sprintf("test%d", testSourceSeq.Add(1)),
+	}
+
+	env.source = &model.StorageSource{Key: env.sourceKey, Name: "test", Type: "local", Config: model.JSONMap{"root_path": env.root}}
+	if err := files.sourceRepo.Save(context.Background(), env.source); err != nil {
+		t.Fatal(err)
+	}
+	env.addUser(t, "admin", RoleAdmin)
+	env.ctx = context.WithValue(context.Background(), "username", "admin")
+	t.Cleanup(func() {
+		evictDriver(env.sourceKey)
+		invalidateUser("admin")
+	})
+	return env
+}
+
+// addUser 创建启用状态的用户，密码为 password123
+func (e *testEnv) addUser(t *testing.T, username, role string) *model.User {
+	t.Helper()
+	hashed, err := hashPassword("password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &model.User{Username: username, PasswordHash: hashed, Role: role, IsActive: true}
+	if err := e.db.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	invalidateUser(username)
+	t.Cleanup(func() { invalidateUser(username) })
+	return user
+}
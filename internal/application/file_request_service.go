@@ -0,0 +1,375 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	pathpkg "path"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// 文件收集请求相关错误
+var (
+	// ErrFileRequestNotFound 请求不存在或已关闭，归类为 vfs.ErrNotFound
+	ErrFileRequestNotFound = fmt.Errorf("file request %w", vfs.ErrNotFound)
+	ErrFileRequestExpired  = errors.New("file request has expired")
+	ErrFileTypeNotAllowed  = errors.New("file type not allowed")
+	ErrInvalidFileRequest  = errors.New("invalid file request")
+)
+
+// maxFileNameBytes 大多数文件系统的文件名长度上限
+const maxFileNameBytes = 255
+
+// maxUploaderName 上传者姓名的最大长度 (字符)
+const maxUploaderName = 64
+
+// windowsReserved Windows (SMB) 上不能作为文件名的设备名
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// FileRequestService 文件收集请求
+// 上传以创建者的身份经过 SecureDriver，只写入请求的专属子目录，上传者看不到目录内容
+type FileRequestService struct {
+	files  *FileService
+	repo   repository.FileRequestRepository
+	notify *NotificationService
+}
+
+// FileRequestOptions 创建文件收集请求的参数
+type FileRequestOptions struct {
+	SourceKey    string
+	Path         string // 在该目录下以 Title 建立专属子目录
+	Title        string
+	Description  string
+	MaxFileSize  int64
+	AllowedTypes []string // 扩展名，如 "pdf"、".zip"
+	ExpiresAt    *time.Time
+}
+
+// FileRequestInfo 上传者看到的请求信息，不包含存储源与路径
+type FileRequestInfo struct {
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Owner        string     `json:"owner"`
+	MaxFileSize  int64      `json:"max_file_size"`
+	AllowedTypes []string   `json:"allowed_types"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+// Uploader 上传者自愿填写的信息
+type Uploader struct {
+	Name  string
+	Email string
+	IP    string
+}
+
+func NewFileRequestService(files *FileService, repo repository.FileRequestRepository, notify *NotificationService) *FileRequestService {
+	s := &FileRequestService{files: files, repo: repo, notify: notify}
+	files.OnChange(s.onChange)
+	return s
+}
+
+// Create 在 Path 下建立以 Title 命名的专属子目录 (重名时自动加序号)，创建者需要对 Path 有写权限
+func (s *FileRequestService) Create(ctx context.Context, username string, opts FileRequestOptions) (*model.FileRequest, error) {
+	// 标题中的斜杠不是路径，整体作为一个目录名
+	folder, ok := SanitizeFileName(strings.NewReplacer("/", "_", "\\", "_").Replace(opts.Title))
+	if !ok {
+		return nil, fmt.Errorf("%w: title is required", ErrInvalidFileRequest)
+	}
+	if opts.MaxFileSize < 0 {
+		return nil, fmt.Errorf("%w: max_file_size must not be negative", ErrInvalidFileRequest)
+	}
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidFileRequest)
+	}
+	types, err := normalizeTypes(opts.AllowedTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.files.sourceRepo.FindByKey(ctx, opts.SourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, opts.SourceKey)
+	}
+	parent := pathpkg.Clean("/" + opts.Path)
+	driver, err := s.files.GetDriver(ctx, opts.SourceKey)
+	if err != nil {
+		return nil, err
+	}
+	info, err := driver.Stat(ctx, parent)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir {
+		return nil, fmt.Errorf("%w: %s is not a directory", ErrInvalidFileRequest, parent)
+	}
+	dir := pathpkg.Join(parent, folder)
+	if _, err := driver.Stat(ctx, dir); err == nil {
+		if dir, err = s.files.availableName(ctx, driver, dir); err != nil {
+			return nil, err
+		}
+	}
+	// Mkdir 经过 SecureDriver，顺带完成写权限检查
+	if err := driver.Mkdir(ctx, dir, 0755); err != nil {
+		return nil, err
+	}
+
+	token, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	req := &model.FileRequest{
+		Token:        token,
+		Username:     username,
+		SourceID:     source.ID,
+		SourceKey:    source.Key,
+		Path:         dir,
+		Title:        strings.TrimSpace(opts.Title),
+		Description:  opts.Description,
+		MaxFileSize:  opts.MaxFileSize,
+		AllowedTypes: strings.Join(types, ","),
+		ExpiresAt:    opts.ExpiresAt,
+	}
+	if err := s.repo.Save(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// List 列出用户创建的请求，管理员 all 为 true 时列出全部
+func (s *FileRequestService) List(ctx context.Context, username string, all bool) ([]*model.FileRequest, error) {
+	if all && !s.files.permService.IsAdmin(ctx, username) {
+		return nil, vfs.NewError("file-request", "/", vfs.ErrPermissionDenied)
+	}
+	if all {
+		username = ""
+	}
+	return s.repo.FindByUser(ctx, username)
+}
+
+// Close 关闭请求，上传地址立即失效，已收到的文件与记录保留
+func (s *FileRequestService) Close(ctx context.Context, username string, id uint) error {
+	if _, err := s.owned(ctx, username, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// Uploads 返回请求收到的文件
+func (s *FileRequestService) Uploads(ctx context.Context, username string, id uint) ([]*model.FileRequestUpload, error) {
+	if _, err := s.owned(ctx, username, id); err != nil {
+		return nil, err
+	}
+	return s.repo.FindUploads(ctx, id)
+}
+
+// owned 其他用户的请求同样返回不存在
+func (s *FileRequestService) owned(ctx context.Context, username string, id uint) (*model.FileRequest, error) {
+	req, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrFileRequestNotFound
+	}
+	if req.Username != username && !s.files.permService.IsAdmin(ctx, username) {
+		return nil, ErrFileRequestNotFound
+	}
+	return req, nil
+}
+
+// Open 按令牌查找可用的请求
+func (s *FileRequestService) Open(ctx context.Context, token string) (*model.FileRequest, error) {
+	req, err := s.repo.FindByToken(ctx, token)
+	if err != nil || !s.files.sourceIs(ctx, req.SourceKey, req.SourceID) {
+		return nil, ErrFileRequestNotFound
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrFileRequestExpired
+	}
+	return req, nil
+}
+
+// PublicInfo 上传者看到的请求信息
+func (s *FileRequestService) PublicInfo(req *model.FileRequest) *FileRequestInfo {
+	return &FileRequestInfo{
+		Title:        req.Title,
+		Description:  req.Description,
+		Owner:        req.Username,
+		MaxFileSize:  req.MaxFileSize,
+		AllowedTypes: allowedTypes(req),
+		ExpiresAt:    req.ExpiresAt,
+	}
+}
+
+// ValidateUploader 检查上传者填写的姓名与邮箱，均可为空
+func (s *FileRequestService) ValidateUploader(u *Uploader) error {
+	u.Name = strings.TrimSpace(u.Name)
+	u.Email = strings.TrimSpace(u.Email)
+	if utf8.RuneCountInString(u.Name) > maxUploaderName || strings.ContainsFunc(u.Name, isControl) {
+		return fmt.Errorf("%w: invalid uploader name", ErrInvalidFileRequest)
+	}
+	if u.Email != "" {
+		addr, err := mail.ParseAddress(u.Email)
+		if err != nil || addr.Address != u.Email || len(u.Email) > 255 {
+			return fmt.Errorf("%w: invalid uploader email", ErrInvalidFileRequest)
+		}
+	}
+	return nil
+}
+
+// Upload 将一个文件写入请求的专属子目录
+// 文件名经过清理，扩展名与大小超出限制时拒绝；同名文件自动重命名，不会覆盖已收到的文件
+func (s *FileRequestService) Upload(ctx context.Context, req *model.FileRequest, name string, r io.Reader, size int64) (UploadResult, error) {
+	clean, ok := SanitizeFileName(name)
+	if !ok {
+		return UploadResult{Name: name}, fmt.Errorf("%w: invalid file name", ErrInvalidFileRequest)
+	}
+	if types := allowedTypes(req); len(types) > 0 {
+		ext := strings.ToLower(strings.TrimPrefix(pathpkg.Ext(clean), "."))
+		if !slices.Contains(types, ext) {
+			return UploadResult{Name: clean}, fmt.Errorf("%w: %s", ErrFileTypeNotAllowed, clean)
+		}
+	}
+	if req.MaxFileSize > 0 {
+		if size > req.MaxFileSize {
+			return UploadResult{Name: clean}, ErrFileTooLarge
+		}
+		r = &limitedReader{r: r, remaining: req.MaxFileSize}
+	}
+	return s.files.Upload(ctx, req.SourceKey, pathpkg.Join(req.Path, clean), r, size, ConflictRename)
+}
+
+// Complete 记录一次提交中成功写入的文件，并通知创建者
+func (s *FileRequestService) Complete(ctx context.Context, req *model.FileRequest, uploader Uploader, results []UploadResult) {
+	var uploads []*model.FileRequestUpload
+	var names []string
+	for _, r := range results {
+		if r.Status == "failed" {
+			continue
+		}
+		uploads = append(uploads, &model.FileRequestUpload{
+			RequestID:     req.ID,
+			Name:          r.Name,
+			Path:          r.Path,
+			Size:          r.Size,
+			UploaderName:  uploader.Name,
+			UploaderEmail: uploader.Email,
+			IP:            uploader.IP,
+		})
+		names = append(names, r.Name)
+	}
+	if len(uploads) == 0 {
+		return
+	}
+	if err := s.repo.AddUploads(ctx, uploads); err != nil {
+		fmt.Printf("[FileRequest] Failed to record uploads for request %d: %v\n", req.ID, err)
+	}
+
+	from := uploader.Name
+	switch {
+	case from != "" && uploader.Email != "":
+		from += " <" + uploader.Email + ">"
+	case from == "" && uploader.Email != "":
+		from = uploader.Email
+	case from == "":
+		from = "匿名用户 (" + uploader.IP + ")"
+	}
+	title := fmt.Sprintf("「%s」收到 %d 个文件", req.Title, len(uploads))
+	message := fmt.Sprintf("%s 上传到 %s:%s\n%s", from, req.SourceKey, req.Path, strings.Join(names, "\n"))
+	s.notify.Notify(ctx, req.Username, model.NotifyFileRequest, title, message)
+}
+
+// onChange 专属子目录被移动后，请求随之指向新路径
+func (s *FileRequestService) onChange(ctx context.Context, sourceKey string, ev vfs.ChangeEvent) {
+	if ev.Op != vfs.ChangeRename {
+		return
+	}
+	source, err := s.files.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return
+	}
+	if err := s.repo.Rename(ctx, source.ID, ev.Path, ev.Dest); err != nil {
+		fmt.Printf("[FileRequest] Failed to update requests for %s:%s: %v\n", sourceKey, ev.Path, err)
+	}
+}
+
+// OnUserDeleted 删除用户的全部文件收集请求，已收到的文件保留在存储源中 (UserDeleteHook)
+func (s *FileRequestService) OnUserDeleted(ctx context.Context, username, actor string) error {
+	return s.repo.DeleteByUser(ctx, username)
+}
+
+// SanitizeFileName 将外部提供的文件名清理为可以安全写入任意后端的名字
+// 去掉路径部分与控制字符，替换 Windows 不允许的字符与设备名，去掉首部的点 (隐藏文件) 与尾部的点和空格，
+// 超长时在保留扩展名的前提下截断；清理后为空时返回 false
+func SanitizeFileName(name string) (string, bool) {
+	name = pathpkg.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.ToValidUTF8(name, "_")
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case isControl(r):
+			return -1
+		case strings.ContainsRune(`<>:"|?*`, r):
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimRight(name, ". "), ". ")
+	if name == "" {
+		return "", false
+	}
+	if stem, _, _ := strings.Cut(name, "."); windowsReserved[strings.ToUpper(strings.TrimSpace(stem))] {
+		name = "_" + name
+	}
+	if len(name) > maxFileNameBytes {
+		base, ext := splitExt(name)
+		if len(ext) > maxFileNameBytes/4 {
+			base, ext = name, ""
+		}
+		base = base[:maxFileNameBytes-len(ext)]
+		// 不在多字节字符中间截断
+		for !utf8.ValidString(base) {
+			base = base[:len(base)-1]
+		}
+		name = base + ext
+	}
+	return name, true
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+// normalizeTypes 统一为不带点的小写扩展名
+func normalizeTypes(types []string) ([]string, error) {
+	var out []string
+	for _, t := range types {
+		t = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(t), "."))
+		if t == "" {
+			continue
+		}
+		if strings.ContainsAny(t, "/\\,. ") {
+			return nil, fmt.Errorf("%w: invalid file type %q", ErrInvalidFileRequest, t)
+		}
+		if !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func allowedTypes(req *model.FileRequest) []string {
+	if req.AllowedTypes == "" {
+		return []string{}
+	}
+	return strings.Split(req.AllowedTypes, ",")
+}
@@ -5,26 +5,105 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"log"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/wentf9/MyGoFileHub/config"
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
 	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
 	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
 	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/secrets"
 )
 
 type FileService struct {
 	sourceRepo  repository.SourceRepository
-	permService *PermissionService // 注入权限服务
+	indexRepo   repository.SearchIndexRepository
+	trashRepo   repository.TrashRepository
+	permService *PermissionService             // 注入权限服务
+	cipher      atomic.Pointer[secrets.Cipher] // 加解密存储源配置中的敏感项，更换主密钥时整体替换
+
+	listenerMu sync.RWMutex
+	listeners  []ChangeListener
+	overwrites []OverwriteHook
 }
 
+// ChangeListener 订阅存储源内容变更 (全文索引等)
+type ChangeListener func(ctx context.Context, sourceKey string, ev vfs.ChangeEvent)
+
+// OverwriteHook 已有文件被覆盖前调用 (文件版本等)，driver 可以读取旧内容，返回错误时中止写入
+type OverwriteHook func(ctx context.Context, sourceKey string, driver vfs.StorageDriver, path string, info vfs.FileInfo) error
+
 // NewFileService 注入 Repository
-func NewFileService(repo repository.SourceRepository, perm *PermissionService) *FileService {
-	return &FileService{sourceRepo: repo, permService: perm}
+func NewFileService(repo repository.SourceRepository, indexRepo repository.SearchIndexRepository, trashRepo repository.TrashRepository, perm *PermissionService, cipher *secrets.Cipher) *FileService {
+	s := &FileService{sourceRepo: repo, indexRepo: indexRepo, trashRepo: trashRepo, permService: perm}
+	s.cipher.Store(cipher)
+	go s.trashCleanupLoop()
+	return s
+}
+
+// OnChange 注册变更订阅，对之后所有写操作生效 (包括 REST、tus 与 WebDAV)
+func (s *FileService) OnChange(l ChangeListener) {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	s.listeners = append(s.listeners, l)
+}
+
+// OnOverwrite 注册覆盖前回调，对之后所有写操作生效 (包括 REST、tus 与 WebDAV)
+func (s *FileService) OnOverwrite(h OverwriteHook) {
+	s.listenerMu.Lock()
+	defer s.listenerMu.Unlock()
+	s.overwrites = append(s.overwrites, h)
 }
 
-var dirverCache = sync.Map{} // map[uint64]vfs.StorageDriver
+// snapshotter 生成某个存储源的覆盖前回调，交给 SnapshotDriver
+func (s *FileService) snapshotter(sourceKey string) vfs.SnapshotFunc {
+	return func(ctx context.Context, base vfs.StorageDriver, path string, info vfs.FileInfo) error {
+		s.listenerMu.RLock()
+		defer s.listenerMu.RUnlock()
+		for _, h := range s.overwrites {
+			if err := h(ctx, sourceKey, base, path, info); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// notifier 生成某个存储源的变更回调，交给 WatchDriver
+func (s *FileService) notifier(sourceKey string) vfs.ChangeListener {
+	return func(ctx context.Context, ev vfs.ChangeEvent) {
+		s.listenerMu.RLock()
+		defer s.listenerMu.RUnlock()
+		for _, l := range s.listeners {
+			l(ctx, sourceKey, ev)
+		}
+	}
+}
+
+var dirverCache = sync.Map{} // map[string]*cachedDriver
 var dirverMu sync.Mutex
 
+// cachedDriver 缓存的驱动实例，refs 位于装饰器链最内层
+// 驱逐时 Close 沿装饰器链传到 refs，等仍在使用它的请求结束后才真正断开连接
+type cachedDriver struct {
+	driver vfs.StorageDriver
+	refs   *vfs.RefCountDriver
+}
+
+// lease 返回驱动，并在 ctx 结束前保持它可用
+func (c *cachedDriver) lease(ctx context.Context) vfs.StorageDriver {
+	c.refs.Hold(ctx)
+	return c.driver
+}
+
+// ErrSourceNotFound 存储源不存在，归类为 vfs.ErrNotFound
+var ErrSourceNotFound = fmt.Errorf("storage source %w", vfs.ErrNotFound)
+
 // ListFiles 列出文件
 // sourceKey: 数据库中存储源的Key
 func (s *FileService) ListFiles(ctx context.Context, sourceKey string, path string) ([]vfs.FileInfo, error) {
@@ -58,12 +137,48 @@ func (s *FileService) GetFileStream(ctx context.Context, sourceKey string, path
 	return driver.Open(ctx, path)
 }
 
+// Delete 删除文件或目录，存储源启用回收站时移入回收站，否则永久删除
 func (s *FileService) Delete(ctx context.Context, sourceKey string, path string) error {
 	driver, err := s.GetDriver(ctx, sourceKey)
 	if err != nil {
 		return err
 	}
-	return driver.Delete(ctx, path)
+	source, err := s.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	if source.TrashDays < 0 {
+		return driver.Delete(ctx, path)
+	}
+	_, err = s.moveToTrash(ctx, source, driver, path)
+	return err
+}
+
+// SetModTime 修改文件的最后修改时间
+func (s *FileService) SetModTime(ctx context.Context, sourceKey string, path string, mtime time.Time) error {
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	return driver.SetModTime(ctx, path, mtime)
+}
+
+// SetMode 修改文件权限位
+func (s *FileService) SetMode(ctx context.Context, sourceKey string, path string, mode fs.FileMode) error {
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	return driver.SetMode(ctx, path, mode)
+}
+
+// Truncate 截断或扩展文件
+func (s *FileService) Truncate(ctx context.Context, sourceKey string, path string, size int64) error {
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	return driver.Truncate(ctx, path, size)
 }
 
 func (s *FileService) GetAllSource(ctx context.Context) ([]vfs.FileInfo, error) {
@@ -83,23 +198,52 @@ func (s *FileService) GetAllSource(ctx context.Context) ([]vfs.FileInfo, error)
 	return infos, nil
 }
 
+// sourceTimeout 存储源的操作超时：源自身配置优先，否则使用全局默认值
+func sourceTimeout(source *model.StorageSource) time.Duration {
+	switch {
+	case source.Timeout > 0:
+		return time.Duration(source.Timeout) * time.Second
+	case source.Timeout < 0:
+		return 0
+	default:
+		return time.Duration(config.AppConfig.OpTimeout) * time.Second
+	}
+}
+
 func (s *FileService) GetDriver(ctx context.Context, sourceKey string) (vfs.StorageDriver, error) {
 	// 1. 先从缓存获取
 	dirver, ok := dirverCache.Load(sourceKey)
 	if ok {
-		if value, valid := dirver.(vfs.StorageDriver); valid {
-			return value, nil
-		} else {
+		value, valid := dirver.(*cachedDriver)
+		if !valid {
 			return nil, fmt.Errorf("invalid driver type in cache")
 		}
+		// 定期健康检查，失败时驱逐并重建 (例如 NAS 重启后 SMB 会话失效)
+		rec := healthOf(sourceKey)
+		if !rec.due(time.Now()) {
+			return value.lease(ctx), nil
+		}
+		// 驱动为所有用户共享，检查结果不应受发起请求的客户端断开影响，超时仍由 pingTimeout 控制
+		err := pingDriver(context.WithoutCancel(ctx), value.driver)
+		rec.record(err)
+		if err == nil || errors.Is(err, context.Canceled) {
+			return value.lease(ctx), nil
+		}
+		fmt.Printf("[Health] Source %s unhealthy, rebuilding driver: %v\n", sourceKey, err)
+		dirverMu.Lock()
+		// 只驱逐我们检查过的那个实例，避免误删其他协程刚重建的驱动
+		if current, ok := dirverCache.Load(sourceKey); ok && current == dirver {
+			evictDriver(sourceKey)
+		}
+		dirverMu.Unlock()
 	}
 	dirverMu.Lock()
 	defer dirverMu.Unlock()
 	// 2. 再次检查缓存，防止并发重复创建
 	dirver, ok = dirverCache.Load(sourceKey)
 	if ok {
-		if value, valid := dirver.(vfs.StorageDriver); valid {
-			return value, nil
+		if value, valid := dirver.(*cachedDriver); valid {
+			return value.lease(ctx), nil
 		} else {
 			return nil, fmt.Errorf("invalid driver type in cache")
 		}
@@ -107,13 +251,25 @@ func (s *FileService) GetDriver(ctx context.Context, sourceKey string) (vfs.Stor
 	// 3. 从数据库查询配置
 	source, err := s.sourceRepo.FindByKey(ctx, sourceKey)
 	if err != nil {
-		return nil, fmt.Errorf("storage source not found: %v", err)
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
 	}
-	// 4. 通过工厂创建驱动
+	// 4. 通过工厂创建驱动，解密敏感配置后按驱动的 Schema 校验
 	driver, err := drivers.CreateInstance(source.Type)
 	if err != nil {
 		return nil, err
 	}
+	config, err := s.openConfig(source.Type, source.Config)
+	if err == nil {
+		// 驱动升级后删除或改名的配置项不影响已有存储源，只记录日志
+		if unknown := drivers.UnknownFields(source.Type, config); len(unknown) > 0 {
+			log.Printf("storage source %s: ignoring unknown %s config fields: %s", sourceKey, source.Type, strings.Join(unknown, ", "))
+		}
+		config, err = drivers.Normalize(source.Type, config)
+	}
+	if err != nil {
+		healthOf(sourceKey).record(err)
+		return nil, fmt.Errorf("invalid driver config: %w", &vfs.PathError{Op: "init", Path: sourceKey, Kind: vfs.ErrUnavailable, Err: err})
+	}
 	// ---------------------------------------------------------
 	// 5. 获取当前用户并包裹 SecureDriver
 	// ---------------------------------------------------------
@@ -124,17 +280,33 @@ func (s *FileService) GetDriver(ctx context.Context, sourceKey string) (vfs.Stor
 		if username == nil {
 			// 如果没有登录(或者内部调用)，视情况处理。
 			// 这里假设必须登录，否则返回 error 或者是仅限 Admin 的 Context
-			return false, errors.New("unauthorized: user context missing")
+			return false, fmt.Errorf("%w: user context missing", vfs.ErrPermissionDenied)
 		}
 		// 调用 PermissionService 进行真正的数据库校验
 		return s.permService.CheckPermission(c, username.(string), source.ID, path, action), nil
 	}
+	// 统计使用者，驱逐后等进行中的请求结束再关闭连接
+	refs := vfs.NewRefCountDriver(driver)
+	// 非流式操作加上超时，NAS 挂死时不会无限阻塞请求
+	driver = vfs.NewTimeoutDriver(refs, sourceTimeout(source))
+	// 回收站与版本目录对用户、搜索与索引都不可见
+	driver = vfs.NewHiddenDriver(driver, isReservedPath)
+	// 覆盖已有文件前保存旧版本
+	driver = vfs.NewSnapshotDriver(driver, s.snapshotter(sourceKey))
+	// 写操作成功后通知订阅者
+	driver = vfs.NewWatchDriver(driver, s.notifier(sourceKey))
 	secureDriver := vfs.NewSecureDriver(driver, checker)
-	// 6. 初始化驱动 (传入从数据库取出的 Config JSONMap)
-	// source.Config 本身就是 map[string]interface{}，可以直接传
-	if err := secureDriver.Init(ctx, source.Config); err != nil {
-		return nil, fmt.Errorf("failed to init driver: %v", err)
+	// 6. 初始化驱动 (传入规范化后的配置：类型已转换，缺省项已填入默认值)
+	// 驱动为所有用户共享，不能因为触发加载的客户端断开而失败，超时由 driverInitTimeout 控制
+	initCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), driverInitTimeout)
+	defer cancel()
+	if err := secureDriver.Init(initCtx, config); err != nil {
+		healthOf(sourceKey).record(err)
+		// 驱动无法初始化 (NAS 离线、凭据错误等) 对调用方而言都是存储源不可用
+		return nil, fmt.Errorf("failed to init driver: %w", &vfs.PathError{Op: "init", Path: sourceKey, Kind: vfs.ErrUnavailable, Err: err})
 	}
-	dirverCache.Store(sourceKey, secureDriver)
-	return secureDriver, nil
+	healthOf(sourceKey).record(nil)
+	cached := &cachedDriver{driver: secureDriver, refs: refs}
+	dirverCache.Store(sourceKey, cached)
+	return cached.lease(ctx), nil
 }
@@ -0,0 +1,55 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/local"
+)
+
+// ctxLocalDriver 与本地驱动相同，但 Init 像建立网络连接一样响应 ctx 取消
+type ctxLocalDriver struct {
+	vfs.StorageDriver
+}
+
+// ctxLocalInit Init 开始时调用，模拟初始化期间客户端断开
+var ctxLocalInit func()
+
+func (d *ctxLocalDriver) Init(ctx context.Context, config map[string]any) error {
+	if ctxLocalInit != nil {
+		ctxLocalInit()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return d.StorageDriver.Init(ctx, config)
+}
+
+func init() {
+	drivers.Register("ctxlocal", func() vfs.StorageDriver {
+		return &ctxLocalDriver{StorageDriver: local.NewLocalDriver()}
+	}, drivers.Schema{Fields: []drivers.Field{{Name: "root_path", Type: drivers.FieldString, Required: true}}})
+}
+
+// 驱动为所有用户共享，触发加载的请求在初始化期间断开时仍然正常初始化
+func TestGetDriverIgnoresRequestCancel(t *testing.T) {
+	env := newTestEnv(t)
+	source := &model.StorageSource{Key: env.sourceKey + "c", Name: "ctx", Type: "ctxlocal", Config: model.JSONMap{"root_path": env.root}}
+	if err := env.files.sourceRepo.Save(env.ctx, source); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { reloadSource(source.Key) })
+
+	ctx, cancel := context.WithCancel(env.ctx)
+	ctxLocalInit = cancel
+	t.Cleanup(func() { ctxLocalInit = nil })
+	if _, err := env.files.GetDriver(ctx, source.Key); err != nil {
+		t.Fatalf("GetDriver with a canceled request = %v", err)
+	}
+	if _, err := env.files.ListFiles(env.ctx, source.Key, "/"); err != nil {
+		t.Fatalf("ListFiles after loading = %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	pathpkg "path"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// 文件系统操作类型
+const (
+	OpMkdir  = "mkdir"
+	OpRename = "rename"
+	OpMove   = "move"
+	OpCopy   = "copy"
+	OpDelete = "delete"
+)
+
+// ErrInvalidOperation 操作参数不合法 (缺少路径、对根目录操作、复制到自身子目录等)
+var ErrInvalidOperation = errors.New("invalid operation")
+
+// FsOperation 单个文件系统操作，既用于单独的接口，也作为批量操作的元素
+type FsOperation struct {
+	Op         string `json:"op"`
+	SourceKey  string `json:"source_key"`
+	Path       string `json:"path"`
+	Dest       string `json:"dest,omitempty"`        // move/copy 的目标完整路径；rename 的新名称
+	DestSource string `json:"dest_source,omitempty"` // move/copy 跨存储源时的目标源，默认与 source_key 相同
+	Overwrite  bool   `json:"overwrite,omitempty"`   // 目标已存在时是否覆盖
+}
+
+// FsResult 单个操作的执行结果
+type FsResult struct {
+	Op     string `json:"op"`
+	Path   string `json:"path"`
+	Dest   string `json:"dest,omitempty"`
+	Status string `json:"status"` // ok, failed, skipped
+	Error  string `json:"error,omitempty"`
+}
+
+// Execute 执行单个操作，所有读写都经过 SecureDriver 的权限检查
+func (s *FileService) Execute(ctx context.Context, op FsOperation) error {
+	switch op.Op {
+	case OpMkdir:
+		return s.Mkdir(ctx, op.SourceKey, op.Path)
+	case OpRename:
+		return s.Rename(ctx, op.SourceKey, op.Path, op.Dest, op.Overwrite)
+	case OpMove:
+		return s.Move(ctx, op.SourceKey, op.Path, op.destSource(), op.Dest, op.Overwrite)
+	case OpCopy:
+		return s.Copy(ctx, op.SourceKey, op.Path, op.destSource(), op.Dest, op.Overwrite)
+	case OpDelete:
+		if isRoot(op.Path) {
+			return fmt.Errorf("%w: cannot delete root", ErrInvalidOperation)
+		}
+		return s.Delete(ctx, op.SourceKey, op.Path)
+	}
+	return fmt.Errorf("%w: unknown op %q", ErrInvalidOperation, op.Op)
+}
+
+// ExecuteBatch 依次执行多个操作，单个失败不影响后续操作
+// progress 在每个操作完成后调用 (可为 nil)；ctx 取消后剩余操作标记为 skipped
+func (s *FileService) ExecuteBatch(ctx context.Context, ops []FsOperation, progress func(i int, r FsResult)) []FsResult {
+	results := make([]FsResult, len(ops))
+	for i, op := range ops {
+		r := FsResult{Op: op.Op, Path: op.Path, Dest: op.Dest, Status: "ok"}
+		if err := ctx.Err(); err != nil {
+			r.Status, r.Error = "skipped", err.Error()
+		} else if err := s.Execute(ctx, op); err != nil {
+			r.Status, r.Error = "failed", err.Error()
+		}
+		results[i] = r
+		if progress != nil {
+			progress(i, r)
+		}
+	}
+	return results
+}
+
+func (op FsOperation) destSource() string {
+	if op.DestSource != "" {
+		return op.DestSource
+	}
+	return op.SourceKey
+}
+
+// Mkdir 创建目录 (包括缺失的父目录)，目录已存在时返回 os.ErrExist
+func (s *FileService) Mkdir(ctx context.Context, sourceKey string, path string) error {
+	if isRoot(path) {
+		return fmt.Errorf("%w: path is required", ErrInvalidOperation)
+	}
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	if _, err := driver.Stat(ctx, path); err == nil {
+		return vfs.NewError("mkdir", path, vfs.ErrAlreadyExists)
+	}
+	return driver.Mkdir(ctx, path, 0755)
+}
+
+// Rename 在同一目录下重命名，newName 不能包含路径分隔符
+func (s *FileService) Rename(ctx context.Context, sourceKey string, path string, newName string, overwrite bool) error {
+	if newName == "" || newName == "." || newName == ".." || strings.ContainsAny(newName, "/\\") {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidOperation, newName)
+	}
+	return s.Move(ctx, sourceKey, path, sourceKey, pathpkg.Join(pathpkg.Dir(cleanPath(path)), newName), overwrite)
+}
+
+// Move 移动到目标完整路径；跨存储源时先复制再删除源文件
+// overwrite 时被覆盖的目标移入回收站，移动失败时目标保持不变
+func (s *FileService) Move(ctx context.Context, sourceKey string, path string, destSource string, dest string, overwrite bool) error {
+	if destSource != sourceKey {
+		if err := s.Copy(ctx, sourceKey, path, destSource, dest, overwrite); err != nil {
+			return err
+		}
+		// 内容已复制到目标，源文件直接删除而不是移入回收站
+		driver, err := s.GetDriver(ctx, sourceKey)
+		if err != nil {
+			return err
+		}
+		return driver.Delete(ctx, path)
+	}
+
+	src, dst, err := checkTransfer(path, dest, true)
+	if err != nil {
+		return err
+	}
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	if _, err := driver.Stat(ctx, src); err != nil {
+		return err
+	}
+	exists, err := destExists(ctx, driver, dst, overwrite)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return driver.Rename(ctx, src, dst)
+	}
+	// 先移到目标旁边的临时名，再由 replacePath 把旧目标移入回收站并换上新内容
+	tmp := tempSibling(dst, "move")
+	if err := driver.Rename(ctx, src, tmp); err != nil {
+		return err
+	}
+	if err := s.replace(ctx, sourceKey, driver, tmp, dst); err != nil {
+		if undoErr := driver.Rename(context.WithoutCancel(ctx), tmp, src); undoErr != nil {
+			fmt.Printf("[FS] Failed to move %s:%s back from %s: %v\n", sourceKey, src, tmp, undoErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Copy 递归复制文件或目录，支持跨存储源
+// overwrite 时先复制到临时名，成功后才把旧目标移入回收站
+func (s *FileService) Copy(ctx context.Context, sourceKey string, path string, destSource string, dest string, overwrite bool) error {
+	src, dst, err := checkTransfer(path, dest, destSource == sourceKey)
+	if err != nil {
+		return err
+	}
+	srcDriver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	dstDriver, err := s.GetDriver(ctx, destSource)
+	if err != nil {
+		return err
+	}
+	info, err := srcDriver.Stat(ctx, src)
+	if err != nil {
+		return err
+	}
+	exists, err := destExists(ctx, dstDriver, dst, overwrite)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return copyTree(ctx, srcDriver, src, dstDriver, dst, info)
+	}
+	// 复制到临时名，全部成功后才替换旧目标；复制失败时旧目标保持不变
+	tmp := tempSibling(dst, "copy")
+	err = copyTree(ctx, srcDriver, src, dstDriver, tmp, info)
+	if err == nil {
+		err = s.replace(ctx, destSource, dstDriver, tmp, dst)
+	}
+	if err != nil {
+		// 清理复制了一半的内容
+		dstDriver.Delete(context.WithoutCancel(ctx), tmp)
+		return err
+	}
+	return nil
+}
+
+// Replace 用 tmp 替换已存在的 dst，旧的 dst 移入回收站 (未启用回收站时删除)
+// 供先写入临时文件再替换的写入方式使用 (如 WebDAV PUT)
+func (s *FileService) Replace(ctx context.Context, sourceKey string, tmp, dst string) error {
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	return s.replace(ctx, sourceKey, driver, tmp, dst)
+}
+
+// replace 用 tmp 替换已存在的 dst，旧的 dst 移入回收站 (未启用回收站时删除)
+func (s *FileService) replace(ctx context.Context, sourceKey string, driver vfs.StorageDriver, tmp, dst string) error {
+	source, err := s.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	return s.replacePath(ctx, source, driver, tmp, dst)
+}
+
+func copyTree(ctx context.Context, srcDriver vfs.StorageDriver, src string, dstDriver vfs.StorageDriver, dst string, info vfs.FileInfo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !info.IsDir {
+		rc, err := srcDriver.Open(ctx, src)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := dstDriver.Create(ctx, dst, rc, info.Size); err != nil {
+			return err
+		}
+		// 保留修改时间，失败不影响复制结果
+		dstDriver.SetModTime(ctx, dst, info.ModTime)
+		return nil
+	}
+
+	if err := dstDriver.Mkdir(ctx, dst, 0755); err != nil {
+		return err
+	}
+	children, err := srcDriver.List(ctx, src)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := copyTree(ctx, srcDriver, pathpkg.Join(src, child.Name), dstDriver, pathpkg.Join(dst, child.Name), child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTransfer 规范化 move/copy 的源与目标路径
+// sameSource 为 true 时禁止把目录复制到自身内部，否则会无限递归
+func checkTransfer(path, dest string, sameSource bool) (string, string, error) {
+	if isRoot(path) || isRoot(dest) {
+		return "", "", fmt.Errorf("%w: source and destination must not be root", ErrInvalidOperation)
+	}
+	src := pathpkg.Clean(cleanPath(path))
+	dst := pathpkg.Clean(cleanPath(dest))
+	if sameSource && (dst == src || strings.HasPrefix(dst, src+"/")) {
+		return "", "", fmt.Errorf("%w: destination is inside source", ErrInvalidOperation)
+	}
+	return src, dst, nil
+}
+
+// destExists 判断目标是否已存在，已存在且不允许覆盖时返回 ErrAlreadyExists
+func destExists(ctx context.Context, driver vfs.StorageDriver, dst string, overwrite bool) (bool, error) {
+	if _, err := driver.Stat(ctx, dst); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !overwrite {
+		return false, vfs.NewError("write", dst, vfs.ErrAlreadyExists)
+	}
+	return true, nil
+}
+
+func isRoot(p string) bool {
+	return pathpkg.Clean(cleanPath(p)) == "/"
+}
@@ -0,0 +1,119 @@
+package application
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles 在存储源根目录下创建文件，键为相对路径
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertNoTemp 检查目录中没有遗留替换用的临时文件
+func assertNoTemp(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") && "/"+e.Name() != TrashDir {
+			t.Errorf("leftover temporary entry %s", e.Name())
+		}
+	}
+}
+
+func TestMoveOverwriteMovesOldToTrash(t *testing.T) {
+	env := newTestEnv(t)
+	writeFiles(t, env.root, map[string]string{"a.txt": "new", "b.txt": "old"})
+
+	if err := env.files.Move(env.ctx, env.sourceKey, "/a.txt", env.sourceKey, "/b.txt", true); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(env.root, "b.txt")); string(got) != "new" {
+		t.Fatalf("b.txt = %q, want new", got)
+	}
+	if _, err := os.Stat(filepath.Join(env.root, "a.txt")); !os.IsNotExist(err) {
+		t.Fatalf("a.txt still exists: %v", err)
+	}
+	items, err := env.files.ListTrash(env.ctx, "admin", env.sourceKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Path != "/b.txt" {
+		t.Fatalf("trash = %+v, want the old /b.txt", items)
+	}
+	assertNoTemp(t, env.root)
+}
+
+func TestCopyOverwriteMovesOldToTrash(t *testing.T) {
+	env := newTestEnv(t)
+	writeFiles(t, env.root, map[string]string{"src/a.txt": "new", "dst/keep.txt": "old"})
+
+	if err := env.files.Copy(env.ctx, env.sourceKey, "/src", env.sourceKey, "/dst", true); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(env.root, "dst", "a.txt")); string(got) != "new" {
+		t.Fatalf("dst/a.txt = %q, want new", got)
+	}
+	if _, err := os.Stat(filepath.Join(env.root, "dst", "keep.txt")); !os.IsNotExist(err) {
+		t.Fatalf("old dst/keep.txt still in place: %v", err)
+	}
+	items, err := env.files.ListTrash(env.ctx, "admin", env.sourceKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Path != "/dst" {
+		t.Fatalf("trash = %+v, want the old /dst", items)
+	}
+	assertNoTemp(t, env.root)
+}
+
+// 复制中途失败时，已存在的目标必须保持原样，临时内容被清理
+func TestCopyOverwriteFailureKeepsDest(t *testing.T) {
+	env := newTestEnv(t)
+	writeFiles(t, env.root, map[string]string{"src/a.txt": "new", "dst/keep.txt": "old"})
+	// 指向不存在文件的符号链接在复制时无法打开
+	if err := os.Symlink(filepath.Join(env.root, "missing"), filepath.Join(env.root, "src", "z-broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.files.Copy(env.ctx, env.sourceKey, "/src", env.sourceKey, "/dst", true); err == nil {
+		t.Fatal("Copy succeeded, want an error")
+	}
+	if got, err := os.ReadFile(filepath.Join(env.root, "dst", "keep.txt")); err != nil || string(got) != "old" {
+		t.Fatalf("dst/keep.txt = %q, %v; want the original content", got, err)
+	}
+	items, err := env.files.ListTrash(env.ctx, "admin", env.sourceKey, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("trash = %+v, want empty", items)
+	}
+	assertNoTemp(t, env.root)
+}
+
+func TestMoveWithoutOverwrite(t *testing.T) {
+	env := newTestEnv(t)
+	writeFiles(t, env.root, map[string]string{"a.txt": "new", "b.txt": "old"})
+
+	if err := env.files.Move(env.ctx, env.sourceKey, "/a.txt", env.sourceKey, "/b.txt", false); err == nil {
+		t.Fatal("Move onto an existing file succeeded without overwrite")
+	}
+	if got, _ := os.ReadFile(filepath.Join(env.root, "b.txt")); string(got) != "old" {
+		t.Fatalf("b.txt = %q, want old", got)
+	}
+}
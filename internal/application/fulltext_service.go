@@ -0,0 +1,310 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/extract"
+)
+
+const (
+	// fullTextCrawlInterval 定期全量扫描，补上绕过本服务直接修改 NAS 的文件
+	fullTextCrawlInterval = 6 * time.Hour
+	// fullTextCrawlDelay 启动后首次扫描的延迟，避免与启动过程争抢资源
+	fullTextCrawlDelay = time.Minute
+	// fullTextQueueSize 待处理的变更通知上限，队列满时丢弃 (由下次扫描补上)
+	fullTextQueueSize = 1024
+
+	DefaultFullTextLimit = 20
+	MaxFullTextLimit     = 100
+)
+
+// crawling 正在扫描的存储源
+var crawling = sync.Map{} // map[uint]struct{}
+
+// FullTextService 全文索引：定期扫描存储源并根据写操作增量更新，
+// 提取纯文本、Markdown、HTML、PDF 与 OOXML 文档的内容
+type FullTextService struct {
+	files *FileService
+	repo  repository.FullTextRepository
+	queue chan fullTextTask
+}
+
+type fullTextTask struct {
+	sourceKey string
+	ev        vfs.ChangeEvent
+}
+
+// FullTextResult 一条全文搜索结果
+type FullTextResult struct {
+	Source  string    `json:"source"`
+	Path    string    `json:"path"`
+	Name    string    `json:"name"`
+	Title   string    `json:"title,omitempty"`
+	Author  string    `json:"author,omitempty"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Score   float64   `json:"score"`
+	Snippet string    `json:"snippet"`
+}
+
+func NewFullTextService(files *FileService, repo repository.FullTextRepository) *FullTextService {
+	s := &FullTextService{
+		files: files,
+		repo:  repo,
+		queue: make(chan fullTextTask, fullTextQueueSize),
+	}
+	files.OnChange(s.enqueue)
+	go s.worker()
+	go s.crawlLoop()
+	return s
+}
+
+// Search 按相关度返回当前用户有权读取的文档，total 为可读命中总数
+func (s *FullTextService) Search(ctx context.Context, query string, sourceKeys []string, limit, offset int) ([]FullTextResult, int, error) {
+	username, _ := ctx.Value("username").(string)
+	if username == "" {
+		return nil, 0, fmt.Errorf("%w: user context missing", vfs.ErrPermissionDenied)
+	}
+	if strings.TrimSpace(query) == "" {
+		return nil, 0, fmt.Errorf("%w: empty query", ErrInvalidQuery)
+	}
+	if limit <= 0 {
+		limit = DefaultFullTextLimit
+	}
+	limit = min(limit, MaxFullTextLimit)
+	offset = max(offset, 0)
+
+	sources, err := s.files.searchableSources(ctx, username, sourceKeys, "/")
+	if err != nil {
+		return nil, 0, err
+	}
+	// 权限过滤与分页在查询中完成，不读取整个命中集合
+	scopes := make([]repository.ReadScope, 0, len(sources))
+	keys := make(map[uint]string, len(sources))
+	for _, source := range sources {
+		if source.DisableIndex {
+			continue
+		}
+		scopes = append(scopes, s.files.permService.ReadScope(ctx, username, source.ID))
+		keys[source.ID] = source.Key
+	}
+	page, total, err := s.repo.Search(ctx, query, scopes, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]FullTextResult, len(page))
+	for i, hit := range page {
+		snippet, _ := s.repo.Snippet(ctx, hit.Doc.ID, query)
+		results[i] = FullTextResult{
+			Source:  keys[hit.Doc.SourceID],
+			Path:    hit.Doc.Path,
+			Name:    hit.Doc.Name,
+			Title:   hit.Doc.Title,
+			Author:  hit.Doc.Author,
+			Size:    hit.Doc.Size,
+			ModTime: hit.Doc.ModTime,
+			Score:   hit.Score,
+			Snippet: snippet,
+		}
+	}
+	return results, total, nil
+}
+
+// Reindex 在后台扫描存储源，已在扫描中时直接返回
+func (s *FullTextService) Reindex(ctx context.Context, sourceKey string) error {
+	source, err := s.files.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	if source.DisableIndex {
+		return fmt.Errorf("%w: indexing is disabled for source %s", ErrInvalidOperation, sourceKey)
+	}
+	go s.crawl(context.Background(), source)
+	return nil
+}
+
+func (s *FullTextService) crawlLoop() {
+	time.Sleep(fullTextCrawlDelay)
+	for {
+		sources, err := s.files.sourceRepo.FindAll(context.Background())
+		if err != nil {
+			fmt.Printf("[FullText] Failed to load sources: %v\n", err)
+		}
+		for _, source := range sources {
+			s.crawl(context.Background(), source)
+		}
+		time.Sleep(fullTextCrawlInterval)
+	}
+}
+
+// crawl 扫描整个存储源：新增或修改过 (大小、修改时间不同) 的文件重新提取，已不存在的文件移出索引
+// 关闭了索引的存储源不扫描，并清除之前建立的索引
+func (s *FullTextService) crawl(ctx context.Context, source *model.StorageSource) {
+	if source.DisableIndex {
+		if err := s.repo.Delete(ctx, source.ID, "/"); err != nil {
+			fmt.Printf("[FullText] Failed to clear index of source %s: %v\n", source.Key, err)
+		}
+		return
+	}
+	if _, running := crawling.LoadOrStore(source.ID, struct{}{}); running {
+		return
+	}
+	defer crawling.Delete(source.ID)
+
+	start := time.Now()
+	driver, err := s.files.GetDriver(ctx, source.Key)
+	if err != nil {
+		fmt.Printf("[FullText] Failed to crawl source %s: %v\n", source.Key, err)
+		return
+	}
+	driver = baseDriver(driver)
+	docs, err := s.repo.FindBySource(ctx, source.ID)
+	if err != nil {
+		fmt.Printf("[FullText] Failed to crawl source %s: %v\n", source.Key, err)
+		return
+	}
+	existing := make(map[string]*model.TextDocument, len(docs))
+	for _, doc := range docs {
+		existing[doc.Path] = doc
+	}
+
+	indexed := 0
+	seen := make(map[string]bool, len(docs))
+	err = walkTree(ctx, driver, "/", func(p string, info vfs.FileInfo) (bool, error) {
+		if info.IsDir || !extract.Supported(info.Name) {
+			return true, nil
+		}
+		seen[p] = true
+		if doc, ok := existing[p]; ok && doc.Size == info.Size && doc.ModTime.Equal(info.ModTime) {
+			return true, nil
+		}
+		if err := s.indexFile(ctx, driver, source, p, info); err != nil {
+			fmt.Printf("[FullText] Failed to index %s:%s: %v\n", source.Key, p, err)
+		}
+		indexed++
+		return true, nil
+	})
+	// 扫描不完整时不清理，避免把暂时无法访问的文件移出索引
+	if err != nil {
+		fmt.Printf("[FullText] Failed to crawl source %s: %v\n", source.Key, err)
+		return
+	}
+	removed := 0
+	for p := range existing {
+		if !seen[p] {
+			if err := s.repo.Delete(ctx, source.ID, p); err == nil {
+				removed++
+			}
+		}
+	}
+	fmt.Printf("[FullText] Crawled source %s in %s: %d indexed, %d removed\n",
+		source.Key, time.Since(start).Round(time.Millisecond), indexed, removed)
+}
+
+// indexFile 提取并写入一个文件；提取失败时仍然记录文件 (可按文件名搜到)，并保存失败原因
+func (s *FullTextService) indexFile(ctx context.Context, driver vfs.StorageDriver, source *model.StorageSource, path string, info vfs.FileInfo) error {
+	doc := &model.TextDocument{
+		SourceID:  source.ID,
+		Path:      path,
+		Name:      info.Name,
+		Size:      info.Size,
+		ModTime:   info.ModTime,
+		IndexedAt: time.Now(),
+	}
+	var text string
+	extracted, err := s.extract(ctx, driver, path, info)
+	if err != nil {
+		doc.Error = err.Error()
+		if len(doc.Error) > 255 {
+			doc.Error = doc.Error[:255]
+		}
+	} else {
+		doc.Title, doc.Author, text = extracted.Title, extracted.Author, extracted.Text
+	}
+	return s.repo.Upsert(ctx, doc, text)
+}
+
+func (s *FullTextService) extract(ctx context.Context, driver vfs.StorageDriver, path string, info vfs.FileInfo) (*extract.Document, error) {
+	if info.Size > extract.MaxFileSize {
+		return nil, extract.ErrTooLarge
+	}
+	rc, err := driver.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return extract.Extract(info.Name, rc, info.Size)
+}
+
+// enqueue 变更通知在写操作的协程中调用，只入队不阻塞
+func (s *FullTextService) enqueue(ctx context.Context, sourceKey string, ev vfs.ChangeEvent) {
+	select {
+	case s.queue <- fullTextTask{sourceKey: sourceKey, ev: ev}:
+	default:
+		fmt.Printf("[FullText] Queue full, dropping %s event for %s:%s\n", ev.Op, sourceKey, ev.Path)
+	}
+}
+
+// worker 逐个处理变更，同一时间只提取一个文件
+func (s *FullTextService) worker() {
+	for task := range s.queue {
+		if err := s.apply(context.Background(), task); err != nil {
+			fmt.Printf("[FullText] Failed to update index for %s:%s: %v\n", task.sourceKey, task.ev.Path, err)
+		}
+	}
+}
+
+func (s *FullTextService) apply(ctx context.Context, task fullTextTask) error {
+	source, err := s.files.sourceRepo.FindByKey(ctx, task.sourceKey)
+	if err != nil {
+		return err
+	}
+	if source.DisableIndex {
+		return nil
+	}
+	switch task.ev.Op {
+	case vfs.ChangeWrite:
+		return s.indexPath(ctx, source, task.ev.Path)
+	case vfs.ChangeDelete:
+		return s.repo.Delete(ctx, source.ID, task.ev.Path)
+	case vfs.ChangeRename:
+		if err := s.repo.Rename(ctx, source.ID, task.ev.Path, task.ev.Dest); err != nil {
+			return err
+		}
+		// 文件名参与检索，重命名后重新索引；目录只需更新路径
+		return s.indexPath(ctx, source, task.ev.Dest)
+	}
+	return nil
+}
+
+// indexPath 重新索引单个文件，文件已不存在或不再是支持的类型时移出索引
+func (s *FullTextService) indexPath(ctx context.Context, source *model.StorageSource, path string) error {
+	driver, err := s.files.GetDriver(ctx, source.Key)
+	if err != nil {
+		return err
+	}
+	driver = baseDriver(driver)
+	info, err := driver.Stat(ctx, path)
+	if errors.Is(err, vfs.ErrNotFound) {
+		return s.repo.Delete(ctx, source.ID, path)
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir {
+		return nil
+	}
+	if !extract.Supported(info.Name) {
+		return s.repo.Delete(ctx, source.ID, path)
+	}
+	return s.indexFile(ctx, driver, source, path, info)
+}
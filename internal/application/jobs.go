@@ -0,0 +1,154 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// 后台任务状态
+const (
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobCancelled = "cancelled"
+)
+
+const (
+	// jobRetention 任务结束后保留多久以便客户端查询结果
+	jobRetention = time.Hour
+	// jobCleanupInterval 清理过期任务的间隔
+	jobCleanupInterval = 10 * time.Minute
+)
+
+// ErrJobNotFound 任务不存在、已过期或不属于当前用户
+var ErrJobNotFound = errors.New("job not found")
+
+// Job 后台执行的批量文件操作
+type Job struct {
+	ID         string     `json:"id"`
+	Username   string     `json:"-"`
+	Status     string     `json:"status"`
+	Total      int        `json:"total"`
+	Completed  int        `json:"completed"`
+	Failed     int        `json:"failed"`
+	Results    []FsResult `json:"results"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// 全局任务表: key 为任务 ID，value 为 *Job (进程内有效，重启后丢失)
+var jobs sync.Map
+
+// jobCleanup 创建第一个任务时启动定期清理，之后不再创建任务也会按时释放已结束的任务
+var jobCleanup sync.Once
+
+// StartBatchJob 在后台执行批量操作并立即返回任务
+// 任务沿用请求 ctx 中的用户信息做权限检查，但不随请求结束而取消
+func (s *FileService) StartBatchJob(ctx context.Context, username string, ops []FsOperation) (*Job, error) {
+	jobCleanup.Do(func() { go jobCleanupLoop() })
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	job := &Job{
+		ID:        id,
+		Username:  username,
+		Status:    JobRunning,
+		Total:     len(ops),
+		Results:   make([]FsResult, len(ops)),
+		CreatedAt: time.Now(),
+		cancel:    cancel,
+	}
+	for i, op := range ops {
+		job.Results[i] = FsResult{Op: op.Op, Path: op.Path, Dest: op.Dest, Status: "pending"}
+	}
+	jobs.Store(id, job)
+
+	go func() {
+		defer cancel()
+		s.ExecuteBatch(jobCtx, ops, func(i int, r FsResult) {
+			job.mu.Lock()
+			job.Results[i] = r
+			job.Completed++
+			if r.Status != "ok" {
+				job.Failed++
+			}
+			job.mu.Unlock()
+		})
+		job.mu.Lock()
+		now := time.Now()
+		job.FinishedAt = &now
+		if jobCtx.Err() != nil {
+			job.Status = JobCancelled
+		} else {
+			job.Status = JobDone
+		}
+		job.mu.Unlock()
+	}()
+	return job.Snapshot(), nil
+}
+
+// GetJob 查询任务进度，只能查看自己创建的任务
+func (s *FileService) GetJob(username, id string) (*Job, error) {
+	v, ok := jobs.Load(id)
+	if !ok || v.(*Job).Username != username {
+		return nil, ErrJobNotFound
+	}
+	return v.(*Job).Snapshot(), nil
+}
+
+// CancelJob 取消任务，正在执行的操作完成后停止，剩余操作标记为 skipped
+func (s *FileService) CancelJob(username, id string) (*Job, error) {
+	v, ok := jobs.Load(id)
+	if !ok || v.(*Job).Username != username {
+		return nil, ErrJobNotFound
+	}
+	job := v.(*Job)
+	job.cancel()
+	return job.Snapshot(), nil
+}
+
+// Snapshot 复制一份当前状态，避免序列化时与后台 goroutine 竞争
+func (j *Job) Snapshot() *Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &Job{
+		ID:         j.ID,
+		Username:   j.Username,
+		Status:     j.Status,
+		Total:      j.Total,
+		Completed:  j.Completed,
+		Failed:     j.Failed,
+		Results:    append([]FsResult(nil), j.Results...),
+		CreatedAt:  j.CreatedAt,
+		FinishedAt: j.FinishedAt,
+	}
+}
+
+// jobCleanupLoop 定期清理过期任务
+func jobCleanupLoop() {
+	ticker := time.NewTicker(jobCleanupInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		expireJobs(time.Now())
+	}
+}
+
+// expireJobs 清理结束超过 jobRetention 的任务
+func expireJobs(now time.Time) {
+	jobs.Range(func(key, value any) bool {
+		job := value.(*Job)
+		job.mu.Lock()
+		expired := job.FinishedAt != nil && now.Sub(*job.FinishedAt) > jobRetention
+		job.mu.Unlock()
+		if expired {
+			jobs.Delete(key)
+		}
+		return true
+	})
+}
@@ -0,0 +1,35 @@
+package application
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// 已结束的任务超过保留时长后被清理，不依赖之后再创建任务
+func TestExpireJobs(t *testing.T) {
+	env := newTestEnv(t)
+	job, err := env.files.StartBatchJob(env.ctx, "admin", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for job.FinishedAt == nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		if job, err = env.files.GetJob("admin", job.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.FinishedAt == nil {
+		t.Fatal("empty job did not finish")
+	}
+
+	expireJobs(job.FinishedAt.Add(jobRetention / 2))
+	if _, err := env.files.GetJob("admin", job.ID); err != nil {
+		t.Fatalf("job removed before retention: %v", err)
+	}
+	expireJobs(job.FinishedAt.Add(jobRetention + time.Second))
+	if _, err := env.files.GetJob("admin", job.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("GetJob after retention = %v, want ErrJobNotFound", err)
+	}
+}
@@ -0,0 +1,145 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	pathpkg "path"
+	"sync"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/media"
+)
+
+// mediaWorkers 列目录时同时提取元数据的文件数
+const mediaWorkers = 4
+
+// MediaService 图片 EXIF、音频标签与视频容器信息
+// 解析器通过 OpenFile + Seek 只读取需要的字节范围，结果缓存在数据库中，文件大小或修改时间变化后重新提取
+type MediaService struct {
+	files *FileService
+	repo  repository.MediaInfoRepository
+}
+
+// MediaFileInfo 附带媒体元数据的文件信息，JSON 中与 vfs.FileInfo 的字段平铺
+type MediaFileInfo struct {
+	vfs.FileInfo
+	Media *media.Metadata `json:"Media,omitempty"`
+}
+
+func NewMediaService(files *FileService, repo repository.MediaInfoRepository) *MediaService {
+	s := &MediaService{files: files, repo: repo}
+	files.OnChange(s.onChange)
+	return s
+}
+
+// Metadata 返回文件的媒体元数据；目录、不支持的类型与无法解析的文件返回 nil
+func (s *MediaService) Metadata(ctx context.Context, sourceKey, path string, info vfs.FileInfo) (*media.Metadata, error) {
+	if info.IsDir || !media.Supported(info.Name) {
+		return nil, nil
+	}
+	source, err := s.files.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	if cached, err := s.repo.FindByPath(ctx, source.ID, path); err == nil &&
+		cached.Size == info.Size && cached.ModTime.Equal(info.ModTime) {
+		if cached.Error != "" {
+			return nil, nil
+		}
+		var md media.Metadata
+		if err := json.Unmarshal([]byte(cached.Data), &md); err == nil {
+			return &md, nil
+		}
+	}
+
+	md, err := s.extract(ctx, sourceKey, path, info)
+	record := &model.MediaInfo{
+		SourceID:    source.ID,
+		Path:        path,
+		Size:        info.Size,
+		ModTime:     info.ModTime,
+		ExtractedAt: time.Now(),
+	}
+	switch {
+	case err == nil:
+		data, err := json.Marshal(md)
+		if err != nil {
+			return nil, err
+		}
+		record.Data = string(data)
+	case errors.Is(err, media.ErrMalformed):
+		// 文件本身损坏，记录下来避免每次都重新读取；网络错误、无权限等不缓存
+		record.Error = err.Error()
+		if len(record.Error) > 255 {
+			record.Error = record.Error[:255]
+		}
+	default:
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, record); err != nil {
+		fmt.Printf("[Media] Failed to cache metadata for %s:%s: %v\n", sourceKey, path, err)
+	}
+	return md, nil
+}
+
+// extract 经过 SecureDriver 打开文件，顺带完成读权限检查
+func (s *MediaService) extract(ctx context.Context, sourceKey, path string, info vfs.FileInfo) (*media.Metadata, error) {
+	driver, err := s.files.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return nil, err
+	}
+	f, err := driver.OpenFile(ctx, path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return media.Extract(info.Name, media.NewReader(f, info.Size), info.Size)
+}
+
+// Annotate 为目录列表中的文件附加媒体元数据，单个文件提取失败时不附加
+func (s *MediaService) Annotate(ctx context.Context, sourceKey, dir string, files []vfs.FileInfo) []MediaFileInfo {
+	out := make([]MediaFileInfo, len(files))
+	sem := make(chan struct{}, mediaWorkers)
+	var wg sync.WaitGroup
+	for i, info := range files {
+		out[i].FileInfo = info
+		if info.IsDir || !media.Supported(info.Name) {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			path := pathpkg.Join(dir, info.Name)
+			md, err := s.Metadata(ctx, sourceKey, path, info)
+			if err != nil {
+				fmt.Printf("[Media] Failed to extract metadata for %s:%s: %v\n", sourceKey, path, err)
+				return
+			}
+			out[i].Media = md
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// onChange 写入后大小或修改时间变化，缓存自然失效；删除与重命名时清理旧路径
+func (s *MediaService) onChange(ctx context.Context, sourceKey string, ev vfs.ChangeEvent) {
+	if ev.Op == vfs.ChangeWrite {
+		return
+	}
+	source, err := s.files.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return
+	}
+	if err := s.repo.Delete(ctx, source.ID, ev.Path); err != nil {
+		fmt.Printf("[Media] Failed to drop cached metadata for %s:%s: %v\n", sourceKey, ev.Path, err)
+	}
+}
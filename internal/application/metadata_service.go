@@ -0,0 +1,132 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pathpkg "path"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// MetadataService 文件自定义元数据 (标签、项目号、审核状态等)
+// 优先使用驱动的原生能力，驱动不支持时回退到数据库
+type MetadataService struct {
+	fileService *FileService
+	metaRepo    repository.MetadataRepository
+}
+
+func NewMetadataService(fileService *FileService, repo repository.MetadataRepository) *MetadataService {
+	return &MetadataService{fileService: fileService, metaRepo: repo}
+}
+
+// ForSource 返回指定存储源的元数据访问器 (已带权限检查)
+// WebDAV 的 Dead Properties 也通过它读写
+func (s *MetadataService) ForSource(ctx context.Context, sourceKey string) (vfs.MetadataDriver, error) {
+	driver, err := s.fileService.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.fileService.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	return &fallbackMetadata{driver: driver, sourceID: source.ID, repo: s.metaRepo}, nil
+}
+
+func (s *MetadataService) GetMetadata(ctx context.Context, sourceKey string, path string) (map[string]string, error) {
+	md, err := s.ForSource(ctx, sourceKey)
+	if err != nil {
+		return nil, err
+	}
+	return md.GetMetadata(ctx, path)
+}
+
+// SetMetadata 批量设置元数据
+func (s *MetadataService) SetMetadata(ctx context.Context, sourceKey string, path string, meta map[string]string) error {
+	md, err := s.ForSource(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	for key, value := range meta {
+		if err := md.SetMetadata(ctx, path, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MetadataService) DeleteMetadata(ctx context.Context, sourceKey string, path string, keys []string) error {
+	md, err := s.ForSource(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := md.DeleteMetadata(ctx, path, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fallbackMetadata 先尝试驱动原生元数据，收到 ErrMetadataNotSupported 时改用数据库
+// 注意：SecureDriver 在返回 ErrMetadataNotSupported 之前已经完成了权限检查
+type fallbackMetadata struct {
+	driver   vfs.StorageDriver
+	sourceID uint
+	repo     repository.MetadataRepository
+}
+
+func (m *fallbackMetadata) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	if md, ok := m.driver.(vfs.MetadataDriver); ok {
+		meta, err := md.GetMetadata(ctx, path)
+		if !errors.Is(err, vfs.ErrMetadataNotSupported) {
+			return meta, err
+		}
+	}
+	records, err := m.repo.FindByPath(ctx, m.sourceID, metaPath(path))
+	if err != nil {
+		return nil, err
+	}
+	meta := make(map[string]string, len(records))
+	for _, r := range records {
+		meta[r.Key] = r.Value
+	}
+	return meta, nil
+}
+
+func (m *fallbackMetadata) SetMetadata(ctx context.Context, path string, key, value string) error {
+	if md, ok := m.driver.(vfs.MetadataDriver); ok {
+		err := md.SetMetadata(ctx, path, key, value)
+		if !errors.Is(err, vfs.ErrMetadataNotSupported) {
+			return err
+		}
+	}
+	// 数据库回退时确认文件存在，避免给不存在的路径打标签
+	if _, err := m.driver.Stat(ctx, path); err != nil {
+		return err
+	}
+	return m.repo.Upsert(ctx, &model.FileMetadata{
+		SourceID: m.sourceID,
+		Path:     metaPath(path),
+		Key:      key,
+		Value:    value,
+	})
+}
+
+func (m *fallbackMetadata) DeleteMetadata(ctx context.Context, path string, key string) error {
+	if md, ok := m.driver.(vfs.MetadataDriver); ok {
+		err := md.DeleteMetadata(ctx, path, key)
+		if !errors.Is(err, vfs.ErrMetadataNotSupported) {
+			return err
+		}
+	}
+	return m.repo.Delete(ctx, m.sourceID, metaPath(path), key)
+}
+
+// metaPath 统一数据库中的路径格式，保证 "/a/b" 与 "a/b/" 指向同一条记录
+func metaPath(p string) string {
+	return pathpkg.Clean(cleanPath(p))
+}
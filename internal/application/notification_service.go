@@ -0,0 +1,44 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+)
+
+// notificationLimit 单次最多返回的通知条数
+const notificationLimit = 200
+
+// NotificationService 站内通知
+type NotificationService struct {
+	repo repository.NotificationRepository
+}
+
+func NewNotificationService(repo repository.NotificationRepository) *NotificationService {
+	return &NotificationService{repo: repo}
+}
+
+// Notify 给用户发送一条通知，失败只打印不影响调用方
+func (s *NotificationService) Notify(ctx context.Context, username, kind, title, message string) {
+	n := &model.Notification{Username: username, Type: kind, Title: title, Message: message}
+	if err := s.repo.Save(ctx, n); err != nil {
+		fmt.Printf("[Notify] Failed to notify %s: %v\n", username, err)
+	}
+}
+
+// List 按时间倒序返回用户最近的通知
+func (s *NotificationService) List(ctx context.Context, username string, unreadOnly bool) ([]*model.Notification, error) {
+	return s.repo.FindByUser(ctx, username, unreadOnly, notificationLimit)
+}
+
+// MarkRead 将通知标记为已读，ids 为空时标记全部
+func (s *NotificationService) MarkRead(ctx context.Context, username string, ids []uint) error {
+	return s.repo.MarkRead(ctx, username, ids)
+}
+
+// OnUserDeleted 删除用户的全部通知 (UserDeleteHook)
+func (s *NotificationService) OnUserDeleted(ctx context.Context, username, actor string) error {
+	return s.repo.DeleteByUser(ctx, username)
+}
@@ -24,46 +24,16 @@ func NewPermissionService(pRepo repository.PermissionRepository, uRepo repositor
 // CheckPermission 检查细粒度权限
 // action: "read" 或 "write"
 func (s *PermissionService) CheckPermission(ctx context.Context, username string, sourceID uint, path string, action string) bool {
-	// 1. 获取用户信息
-	var user *model.User
-	var err error
-	if vaule, ok := userCache.Load(username); ok {
-		user = vaule.(*model.User)
-	} else {
-		// 查询用户
-		user, err = s.userRepo.FindByUsername(ctx, username)
-		if err != nil {
-			return false
-		}
-		value, loaded := userCache.LoadOrStore(username, user)
-		if loaded {
-			user = value.(*model.User)
-		}
+	user, perms := s.loadRules(ctx, username, sourceID)
+	if user == nil {
+		return false
 	}
-
-	// 2. 超级管理员拥有所有权限
+	// 超级管理员拥有所有权限
 	if user.Role == "admin" {
 		return true
 	}
 
-	// 3. 获取该用户在该源下的所有权限规则
-
-	var perms []*model.UserPermission
-	if vaule, ok := permissionCache.Load(username + "_" + strconv.FormatUint(uint64(sourceID), 10)); ok {
-		perms = vaule.([]*model.UserPermission)
-	} else {
-		perms, err = s.permRepo.FindByUserAndSource(ctx, user.ID, sourceID)
-		if err != nil || len(perms) == 0 {
-			permissionCache.Store(username+"_"+strconv.FormatUint(uint64(sourceID), 10), []*model.UserPermission{})
-			return false
-		}
-		value, loaded := permissionCache.LoadOrStore(username+"_"+strconv.FormatUint(uint64(sourceID), 10), perms)
-		if loaded {
-			perms = value.([]*model.UserPermission)
-		}
-	}
-
-	// 4. 最长前缀匹配算法
+	// 最长前缀匹配算法
 	var bestMatch *model.UserPermission
 	maxLen := -1
 
@@ -83,18 +53,91 @@ func (s *PermissionService) CheckPermission(ctx context.Context, username string
 		}
 	}
 
-	// 5. 如果没有匹配的规则，默认拒绝
+	// 如果没有匹配的规则，默认拒绝
 	if bestMatch == nil {
 		return false
 	}
 
-	// 6. 根据动作检查字段
+	// 根据动作检查字段
 	if action == "write" {
 		return bestMatch.AllowWrite
 	}
 	return bestMatch.AllowRead
 }
 
+// CanReadUnder 判断 dir 本身或其下任意子路径是否可读
+// 用于遍历时剪枝：父目录无权限但子目录单独授权时，仍需进入父目录
+func (s *PermissionService) CanReadUnder(ctx context.Context, username string, sourceID uint, dir string) bool {
+	if s.CheckPermission(ctx, username, sourceID, dir, "read") {
+		return true
+	}
+	_, perms := s.loadRules(ctx, username, sourceID)
+	prefix := strings.TrimSuffix(cleanPath(dir), "/") + "/"
+	for _, p := range perms {
+		if p.AllowRead && strings.HasPrefix(cleanPath(p.PathPrefix), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadScope 用户在该源下的可读范围，判断规则与 CheckPermission 一致，供查询下推到数据库
+func (s *PermissionService) ReadScope(ctx context.Context, username string, sourceID uint) repository.ReadScope {
+	scope := repository.ReadScope{SourceID: sourceID}
+	user, perms := s.loadRules(ctx, username, sourceID)
+	if user == nil {
+		return scope
+	}
+	if user.Role == "admin" {
+		scope.All = true
+		return scope
+	}
+	for _, p := range perms {
+		scope.Rules = append(scope.Rules, repository.PathRule{Prefix: cleanPath(p.PathPrefix), Allow: p.AllowRead})
+	}
+	return scope
+}
+
+// loadRules 获取用户及其在该源下的全部权限规则 (优先读缓存)，用户不存在时返回 nil
+func (s *PermissionService) loadRules(ctx context.Context, username string, sourceID uint) (*model.User, []*model.UserPermission) {
+	// 1. 获取用户信息
+	user := s.loadUser(ctx, username)
+	if user == nil {
+		return nil, nil
+	}
+	if user.Role == "admin" {
+		return user, nil
+	}
+
+	// 2. 获取该用户在该源下的所有权限规则
+	key := username + "_" + strconv.FormatUint(uint64(sourceID), 10)
+	if vaule, ok := permissionCache.Load(key); ok {
+		return user, vaule.([]*model.UserPermission)
+	}
+	gen := userGeneration(username)
+	perms, err := s.permRepo.FindByUserAndSource(ctx, user.ID, sourceID)
+	if err != nil || len(perms) == 0 {
+		perms = []*model.UserPermission{}
+	}
+	return user, cacheStore(&permissionCache, key, username, gen, perms).([]*model.UserPermission)
+}
+
+// IsAdmin 判断用户是否为管理员
+func (s *PermissionService) IsAdmin(ctx context.Context, username string) bool {
+	user := s.loadUser(ctx, username)
+	return user != nil && user.Role == "admin"
+}
+
+// loadUser 获取用户信息 (优先读缓存)，用户不存在或已停用时返回 nil
+// 停用用户没有任何权限，他创建的分享链接与文件收集请求随之失效
+func (s *PermissionService) loadUser(ctx context.Context, username string) *model.User {
+	user, err := cachedUser(ctx, s.userRepo, username)
+	if err != nil || !user.IsActive {
+		return nil
+	}
+	return user
+}
+
 // cleanPath 简单的路径标准化
 func cleanPath(p string) string {
 	if !strings.HasPrefix(p, "/") {
@@ -0,0 +1,88 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/extract"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/render"
+)
+
+// ErrNotText 文件不是文本，无法预览
+var ErrNotText = errors.New("file is not a text file")
+
+const (
+	DefaultPreviewBytes = 256 << 10
+	MaxPreviewBytes     = 2 << 20
+)
+
+// 预览类型
+const (
+	PreviewMarkdown = "markdown"
+	PreviewCode     = "code"
+	PreviewText     = "text"
+)
+
+// Preview 文本文件预览：Content 为转码后的 UTF-8 原文，HTML 为净化后可直接展示的内容
+type Preview struct {
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Encoding  string `json:"encoding"`
+	Truncated bool   `json:"truncated"` // 只读取了开头 maxBytes 字节
+	Kind      string `json:"kind"`
+	Language  string `json:"language,omitempty"`
+	Content   string `json:"content"`
+	HTML      string `json:"html"`
+}
+
+// Preview 读取文本文件开头的 maxBytes 字节，识别编码并转为 UTF-8，
+// Markdown 渲染为 HTML，已知语言的源码按语法高亮
+func (s *FileService) Preview(ctx context.Context, sourceKey string, path string, maxBytes int64) (*Preview, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultPreviewBytes
+	}
+	maxBytes = min(maxBytes, MaxPreviewBytes)
+
+	info, err := s.Stat(ctx, sourceKey, path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir {
+		return nil, fmt.Errorf("%w: %s is a directory", ErrNotText, path)
+	}
+	stream, err := s.GetFileStream(ctx, sourceKey, path)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	data, err := io.ReadAll(io.LimitReader(stream, maxBytes))
+	if err != nil {
+		return nil, err
+	}
+	if extract.IsBinary(data) {
+		return nil, fmt.Errorf("%w: %s", ErrNotText, path)
+	}
+
+	text, encoding := extract.DecodeTextEncoding(data)
+	p := &Preview{
+		Name:      info.Name,
+		Size:      info.Size,
+		Encoding:  encoding,
+		Truncated: info.Size > int64(len(data)),
+		Content:   text,
+	}
+	if render.IsMarkdown(info.Name) {
+		p.Kind = PreviewMarkdown
+		p.HTML, err = render.Markdown(text)
+		return p, err
+	}
+	lexer := render.Language(info.Name)
+	p.Kind = PreviewText
+	if lexer != nil {
+		p.Kind, p.Language = PreviewCode, lexer.Config().Name
+	}
+	p.HTML, err = render.Highlight(lexer, text)
+	return p, err
+}
@@ -0,0 +1,392 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pathpkg "path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// 搜索结果的类型过滤
+const (
+	SearchTypeFile = "file"
+	SearchTypeDir  = "dir"
+)
+
+const (
+	DefaultSearchLimit = 1000
+	MaxSearchLimit     = 10000
+	// searchIndexTTL 索引超过该时长视为过期：本次搜索回退为实时遍历，同时在后台重建
+	searchIndexTTL = time.Hour
+	// indexFlushSize 建立索引时每累积多少条写一次数据库
+	indexFlushSize = 500
+)
+
+// ErrInvalidQuery 搜索条件不合法 (glob / 正则语法错误等)
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// errSearchLimit 结果数达到上限，用于提前结束遍历
+var errSearchLimit = errors.New("search limit reached")
+
+// indexing 正在重建索引的存储源，避免重复重建
+var indexing = sync.Map{} // map[uint]struct{}
+
+// SearchQuery 搜索条件，零值字段表示不限制
+type SearchQuery struct {
+	Sources  []string  // 为空时搜索全部有权限的存储源
+	Path     string    // 起始目录，默认为根目录
+	Name     string    // 文件名 glob，不区分大小写；不含通配符时按包含匹配
+	Regex    string    // 文件名正则
+	MinSize  int64     // 字节，指定大小范围时只匹配文件
+	MaxSize  int64     // 字节
+	After    time.Time // 修改时间下限
+	Before   time.Time // 修改时间上限
+	Type     string    // SearchTypeFile / SearchTypeDir
+	Limit    int       // 最多返回的条数，默认 DefaultSearchLimit
+	UseIndex bool      // 优先使用持久化索引，避免重复扫描 SMB 共享
+}
+
+// SearchResult 一条搜索结果
+type SearchResult struct {
+	Source  string    `json:"source"`
+	Path    string    `json:"path"`
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	IsDir   bool      `json:"is_dir"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// SearchFailure 某个存储源搜索失败 (离线、超时等)，不影响其他存储源
+type SearchFailure struct {
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+// SearchSummary 搜索结束后的统计
+type SearchSummary struct {
+	Count     int             `json:"count"`
+	Truncated bool            `json:"truncated"` // 达到 Limit 后提前结束
+	Failed    []SearchFailure `json:"failed,omitempty"`
+}
+
+// searcher 一次搜索的状态
+type searcher struct {
+	query    *SearchQuery
+	glob     string
+	re       *regexp.Regexp
+	username string
+	emit     func(SearchResult) error
+	summary  SearchSummary
+}
+
+// Search 在一个或全部有权限的存储源中搜索，每找到一条结果就调用 emit
+// emit 返回错误或 ctx 被取消 (客户端断开) 时立即停止遍历
+// 指定的存储源不存在或无权限时在开始搜索前返回错误
+func (s *FileService) Search(ctx context.Context, q SearchQuery, emit func(SearchResult) error) (*SearchSummary, error) {
+	sr, err := newSearcher(ctx, &q, emit)
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := s.searchableSources(ctx, sr.username, q.Sources, q.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		err := s.searchSource(ctx, sr, source)
+		if errors.Is(err, errSearchLimit) {
+			sr.summary.Truncated = true
+			break
+		}
+		if ctx.Err() != nil {
+			return &sr.summary, ctx.Err()
+		}
+		if err != nil {
+			sr.summary.Failed = append(sr.summary.Failed, SearchFailure{Source: source.Key, Error: err.Error()})
+		}
+	}
+	return &sr.summary, nil
+}
+
+// searchableSources 返回要搜索的存储源：未指定时为 dir 下有可读内容的全部存储源，
+// 指定的存储源不存在或无权限时返回错误
+func (s *FileService) searchableSources(ctx context.Context, username string, keys []string, dir string) ([]*model.StorageSource, error) {
+	var sources []*model.StorageSource
+	if len(keys) == 0 {
+		all, err := s.sourceRepo.FindAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range all {
+			if s.permService.CanReadUnder(ctx, username, source.ID, dir) {
+				sources = append(sources, source)
+			}
+		}
+		return sources, nil
+	}
+	for _, key := range keys {
+		source, err := s.sourceRepo.FindByKey(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, key)
+		}
+		if !s.permService.CanReadUnder(ctx, username, source.ID, dir) {
+			return nil, vfs.NewError("search", dir, vfs.ErrPermissionDenied)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func newSearcher(ctx context.Context, q *SearchQuery, emit func(SearchResult) error) (*searcher, error) {
+	username, _ := ctx.Value("username").(string)
+	if username == "" {
+		return nil, fmt.Errorf("%w: user context missing", vfs.ErrPermissionDenied)
+	}
+	q.Path = pathpkg.Clean("/" + q.Path)
+	if q.Limit <= 0 {
+		q.Limit = DefaultSearchLimit
+	}
+	q.Limit = min(q.Limit, MaxSearchLimit)
+	if q.Type != "" && q.Type != SearchTypeFile && q.Type != SearchTypeDir {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidQuery, q.Type)
+	}
+
+	sr := &searcher{query: q, username: username, emit: emit}
+	if q.Name != "" {
+		sr.glob = strings.ToLower(q.Name)
+		if !strings.ContainsAny(sr.glob, "*?[") {
+			sr.glob = "*" + sr.glob + "*"
+		}
+		if _, err := pathpkg.Match(sr.glob, ""); err != nil {
+			return nil, fmt.Errorf("%w: bad name pattern", ErrInvalidQuery)
+		}
+	}
+	if q.Regex != "" {
+		re, err := regexp.Compile(q.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidQuery, err)
+		}
+		sr.re = re
+	}
+	return sr, nil
+}
+
+// match 判断条目是否满足全部过滤条件
+func (sr *searcher) match(info vfs.FileInfo) bool {
+	q := sr.query
+	switch {
+	case q.Type == SearchTypeFile && info.IsDir, q.Type == SearchTypeDir && !info.IsDir:
+		return false
+	// 目录的大小没有意义，指定大小范围时只匹配文件
+	case (q.MinSize > 0 || q.MaxSize > 0) && info.IsDir:
+		return false
+	case q.MinSize > 0 && info.Size < q.MinSize, q.MaxSize > 0 && info.Size > q.MaxSize:
+		return false
+	case !q.After.IsZero() && info.ModTime.Before(q.After), !q.Before.IsZero() && info.ModTime.After(q.Before):
+		return false
+	}
+	if sr.glob != "" {
+		if ok, _ := pathpkg.Match(sr.glob, strings.ToLower(info.Name)); !ok {
+			return false
+		}
+	}
+	return sr.re == nil || sr.re.MatchString(info.Name)
+}
+
+func (sr *searcher) add(source, path string, info vfs.FileInfo) error {
+	if sr.summary.Count >= sr.query.Limit {
+		return errSearchLimit
+	}
+	sr.summary.Count++
+	return sr.emit(SearchResult{
+		Source:  source,
+		Path:    path,
+		Name:    info.Name,
+		Size:    info.Size,
+		IsDir:   info.IsDir,
+		ModTime: info.ModTime,
+	})
+}
+
+// searchSource 搜索单个存储源：索引可用时查询索引，否则 (或存储源关闭了索引) 实时遍历
+// 遍历使用未包装权限检查的底层驱动，逐条判断读权限，
+// 这样父目录无权限而子目录单独授权时也能搜到子目录中的文件
+func (s *FileService) searchSource(ctx context.Context, sr *searcher, source *model.StorageSource) error {
+	canRead := func(p string) bool {
+		return s.permService.CheckPermission(ctx, sr.username, source.ID, p, "read")
+	}
+
+	if sr.query.UseIndex && !source.DisableIndex {
+		state, err := s.indexRepo.FindState(ctx, source.ID)
+		if err == nil && time.Since(state.IndexedAt) < searchIndexTTL {
+			return s.searchIndex(ctx, sr, source, state, canRead)
+		}
+		s.startIndexBuild(source)
+	}
+
+	driver, err := s.GetDriver(ctx, source.Key)
+	if err != nil {
+		return err
+	}
+	return walkTree(ctx, baseDriver(driver), sr.query.Path, func(p string, info vfs.FileInfo) (bool, error) {
+		if !canRead(p) {
+			return info.IsDir && s.permService.CanReadUnder(ctx, sr.username, source.ID, p), nil
+		}
+		if sr.match(info) {
+			return true, sr.add(source.Key, p, info)
+		}
+		return true, nil
+	})
+}
+
+// searchIndex 路径、大小、时间、文件名与权限过滤都下推到查询中，只读取需要的条目；
+// 逐条检查仍然保留，以 Go 中的判断为准
+func (s *FileService) searchIndex(ctx context.Context, sr *searcher, source *model.StorageSource, state *model.SearchIndexState, canRead func(string) bool) error {
+	q := sr.query
+	scope := s.permService.ReadScope(ctx, sr.username, source.ID)
+	filter := repository.SearchFilter{
+		PathPrefix: q.Path,
+		MinSize:    q.MinSize,
+		MaxSize:    q.MaxSize,
+		After:      q.After,
+		Before:     q.Before,
+		Name:       sr.glob,
+		Scope:      &scope,
+	}
+	if q.Type != "" || q.MinSize > 0 || q.MaxSize > 0 {
+		isDir := q.Type == SearchTypeDir
+		filter.IsDir = &isDir
+	}
+	// 正则无法下推，此时不限制读取的条目数；多读一条用于判断是否截断
+	if sr.re == nil {
+		filter.Limit = q.Limit - sr.summary.Count + 1
+	}
+	return s.indexRepo.Query(ctx, state, filter, func(e *model.SearchEntry) error {
+		info := vfs.FileInfo{Name: e.Name, Size: e.Size, IsDir: e.IsDir, ModTime: e.ModTime}
+		if !sr.match(info) || !canRead(e.Path) {
+			return nil
+		}
+		return sr.add(source.Key, e.Path, info)
+	})
+}
+
+// RebuildSearchIndex 在后台重建存储源的搜索索引，已在重建中时直接返回
+func (s *FileService) RebuildSearchIndex(ctx context.Context, sourceKey string) error {
+	source, err := s.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	if source.DisableIndex {
+		return fmt.Errorf("%w: indexing is disabled for source %s", ErrInvalidOperation, sourceKey)
+	}
+	s.startIndexBuild(source)
+	return nil
+}
+
+func (s *FileService) startIndexBuild(source *model.StorageSource) {
+	if _, running := indexing.LoadOrStore(source.ID, struct{}{}); running {
+		return
+	}
+	go func() {
+		defer indexing.Delete(source.ID)
+		start := time.Now()
+		entries, err := s.buildIndex(context.Background(), source)
+		if err != nil {
+			fmt.Printf("[Search] Failed to index source %s: %v\n", source.Key, err)
+			return
+		}
+		fmt.Printf("[Search] Indexed %d entries of source %s in %s\n", entries, source.Key, time.Since(start).Round(time.Millisecond))
+	}()
+}
+
+// buildIndex 遍历整个存储源写入新版本的索引，全部写完后再切换
+func (s *FileService) buildIndex(ctx context.Context, source *model.StorageSource) (int64, error) {
+	driver, err := s.GetDriver(ctx, source.Key)
+	if err != nil {
+		return 0, err
+	}
+	generation := time.Now().UnixNano()
+	var total int64
+	batch := make([]*model.SearchEntry, 0, indexFlushSize)
+	flush := func() error {
+		err := s.indexRepo.InsertEntries(ctx, batch)
+		batch = batch[:0]
+		return err
+	}
+	err = walkTree(ctx, baseDriver(driver), "/", func(p string, info vfs.FileInfo) (bool, error) {
+		batch = append(batch, &model.SearchEntry{
+			SourceID:   source.ID,
+			Generation: generation,
+			Path:       p,
+			Name:       info.Name,
+			IsDir:      info.IsDir,
+			Size:       info.Size,
+			ModTime:    info.ModTime,
+		})
+		total++
+		if len(batch) >= indexFlushSize {
+			return true, flush()
+		}
+		return true, nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return total, s.indexRepo.Commit(ctx, &model.SearchIndexState{
+		SourceID:   source.ID,
+		Generation: generation,
+		Entries:    total,
+		IndexedAt:  time.Now(),
+	})
+}
+
+// walkTree 深度优先遍历 root 下的全部条目，visit 返回 false 时不进入该目录
+// 子目录无权限或遍历过程中被删除时跳过，root 本身无法列出时返回错误
+func walkTree(ctx context.Context, driver vfs.StorageDriver, root string, visit func(p string, info vfs.FileInfo) (bool, error)) error {
+	stack := []string{root}
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		dir := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		children, err := driver.List(ctx, dir)
+		if err != nil {
+			if dir != root && skippable(err) {
+				continue
+			}
+			return err
+		}
+		for _, child := range children {
+			p := pathpkg.Join(dir, child.Name)
+			descend, err := visit(p, child)
+			if err != nil {
+				return err
+			}
+			if child.IsDir && descend {
+				stack = append(stack, p)
+			}
+		}
+	}
+	return nil
+}
+
+// baseDriver 去掉 SecureDriver 包装，由调用方自行判断权限
+func baseDriver(driver vfs.StorageDriver) vfs.StorageDriver {
+	if sd, ok := driver.(*vfs.SecureDriver); ok {
+		return sd.Base()
+	}
+	return driver
+}
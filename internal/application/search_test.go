@@ -0,0 +1,161 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+)
+
+// searchFiles 每个文件都包含 needle，用于全文搜索
+var searchFiles = []string{"/a/1.txt", "/a/b/2.txt", "/a/b/c/3.txt", "/ab/4.txt", "/x/5.txt", "/文档/6.txt", "/y/7.txt"}
+
+// newSearchEnv 创建 searchFiles 与一个按路径授权的普通用户，返回该用户的 ctx
+func newSearchEnv(t *testing.T) (*testEnv, context.Context) {
+	t.Helper()
+	env := newTestEnv(t)
+	for _, p := range searchFiles {
+		file := filepath.Join(env.root, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("needle in "+p), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := env.addUser(t, "searcher", RoleUser)
+	rules := []model.UserPermission{
+		{PathPrefix: "/a", AllowRead: true},
+		{PathPrefix: "/a/b", AllowRead: false},
+		{PathPrefix: "/a/b/c", AllowRead: true},
+		{PathPrefix: "/x", AllowRead: false}, // 长度相同时靠前的规则优先
+		{PathPrefix: "/x", AllowRead: true},
+		{PathPrefix: "文档", AllowRead: true},
+	}
+	for _, rule := range rules {
+		rule.UserID, rule.SourceID = user.ID, env.source.ID
+		if err := env.db.Create(&rule).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return env, context.WithValue(context.Background(), "username", "searcher")
+}
+
+// readableFiles 按 CheckPermission 逐个判断可读的 searchFiles
+func readableFiles(env *testEnv, ctx context.Context) []string {
+	var paths []string
+	for _, p := range searchFiles {
+		if env.files.permService.CheckPermission(ctx, "searcher", env.source.ID, p, "read") {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// 下推到索引查询的权限条件与 CheckPermission 一致
+func TestIndexSearchReadScope(t *testing.T) {
+	env, ctx := newSearchEnv(t)
+	if _, err := env.files.buildIndex(env.ctx, env.source); err != nil {
+		t.Fatal(err)
+	}
+	want := readableFiles(env, ctx)
+	if !slices.Equal(want, []string{"/a/1.txt", "/a/b/c/3.txt", "/ab/4.txt", "/文档/6.txt"}) {
+		t.Fatalf("unexpected readable files %v", want)
+	}
+
+	search := func(q SearchQuery) ([]string, *SearchSummary) {
+		t.Helper()
+		var paths []string
+		q.Sources, q.Type = []string{env.sourceKey}, SearchTypeFile
+		summary, err := env.files.Search(ctx, q, func(r SearchResult) error {
+			paths = append(paths, r.Path)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(paths)
+		return paths, summary
+	}
+	for _, useIndex := range []bool{true, false} {
+		if got, _ := search(SearchQuery{UseIndex: useIndex}); !slices.Equal(got, want) {
+			t.Fatalf("search (index=%v) = %v, want %v", useIndex, got, want)
+		}
+	}
+	if got, _ := search(SearchQuery{UseIndex: true, Name: "3.TXT"}); !slices.Equal(got, []string{"/a/b/c/3.txt"}) {
+		t.Fatalf("name search = %v", got)
+	}
+	got, summary := search(SearchQuery{UseIndex: true, Limit: 2})
+	if len(got) != 2 || !summary.Truncated {
+		t.Fatalf("limited search = %v, truncated=%v", got, summary.Truncated)
+	}
+}
+
+// 全文搜索在查询中按权限过滤并分页，total 为可读命中总数
+func TestFullTextSearchPaging(t *testing.T) {
+	env, ctx := newSearchEnv(t)
+	fulltext := NewFullTextService(env.files, persistence.NewFullTextRepository(env.db))
+	fulltext.crawl(env.ctx, env.source)
+	want := readableFiles(env, ctx)
+
+	var got []string
+	for offset := 0; offset < len(want)+2; offset += 2 {
+		results, total, err := fulltext.Search(ctx, "needle", nil, 2, offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != len(want) {
+			t.Fatalf("total = %d, want %d", total, len(want))
+		}
+		for _, r := range results {
+			got = append(got, r.Path)
+		}
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Fatalf("paged results = %v, want %v", got, want)
+	}
+}
+
+// 关闭索引的存储源不建立索引，已有的索引被清除，搜索时实时遍历
+func TestDisableIndex(t *testing.T) {
+	env, ctx := newSearchEnv(t)
+	repo := persistence.NewFullTextRepository(env.db)
+	fulltext := NewFullTextService(env.files, repo)
+	fulltext.crawl(env.ctx, env.source)
+
+	env.source.DisableIndex = true
+	if err := env.files.sourceRepo.Save(env.ctx, env.source); err != nil {
+		t.Fatal(err)
+	}
+	fulltext.crawl(env.ctx, env.source)
+	if docs, err := repo.FindBySource(env.ctx, env.source.ID); err != nil || len(docs) != 0 {
+		t.Fatalf("documents after disabling = %d, %v", len(docs), err)
+	}
+	if _, total, err := fulltext.Search(ctx, "needle", nil, 10, 0); err != nil || total != 0 {
+		t.Fatalf("fulltext search = %d, %v", total, err)
+	}
+	if err := fulltext.Reindex(env.ctx, env.sourceKey); !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("Reindex = %v, want ErrInvalidOperation", err)
+	}
+	if err := env.files.RebuildSearchIndex(env.ctx, env.sourceKey); !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("RebuildSearchIndex = %v, want ErrInvalidOperation", err)
+	}
+
+	var count int
+	q := SearchQuery{Sources: []string{env.sourceKey}, Type: SearchTypeFile, UseIndex: true}
+	if _, err := env.files.Search(ctx, q, func(SearchResult) error { count++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if want := len(readableFiles(env, ctx)); count != want {
+		t.Fatalf("search without index = %d results, want %d", count, want)
+	}
+	if _, err := env.files.indexRepo.FindState(env.ctx, env.source.ID); err == nil {
+		t.Fatal("search built an index for a source with indexing disabled")
+	}
+}
@@ -0,0 +1,332 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pathpkg "path"
+	"strings"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 分享链接相关错误
+var (
+	// ErrShareNotFound 分享不存在或已撤销，归类为 vfs.ErrNotFound
+	ErrShareNotFound  = fmt.Errorf("share link %w", vfs.ErrNotFound)
+	ErrShareExpired   = errors.New("share link has expired")
+	ErrShareExhausted = errors.New("share link download limit reached")
+	ErrSharePassword  = errors.New("share link password required or incorrect")
+	ErrShareMode      = errors.New("operation not allowed for this share link")
+	ErrInvalidShare   = errors.New("invalid share options")
+)
+
+// shareAccessTTL 输入密码后签发的访问令牌有效期
+const shareAccessTTL = time.Hour
+
+// shareLogLimit 单次最多返回的访问日志条数
+const shareLogLimit = 1000
+
+// ShareService 公开分享链接
+// 访问者以创建者的身份读写，路径限制在分享的文件或目录之内
+type ShareService struct {
+	files *FileService
+	repo  repository.ShareRepository
+}
+
+// ShareOptions 创建分享的参数
+type ShareOptions struct {
+	SourceKey    string
+	Path         string
+	Mode         string // read (默认) 或 upload
+	Password     string // 为空表示不设密码
+	ExpiresAt    *time.Time
+	MaxDownloads int // 0 表示不限制
+}
+
+// ShareInfo 创建者看到的分享信息
+type ShareInfo struct {
+	*model.Share
+	HasPassword bool `json:"has_password"`
+	Expired     bool `json:"expired"`
+}
+
+// SharePublicInfo 访问者看到的分享信息，不包含存储源与完整路径
+type SharePublicInfo struct {
+	Name               string     `json:"name"`
+	IsDir              bool       `json:"is_dir"`
+	Mode               string     `json:"mode"`
+	PasswordRequired   bool       `json:"password_required"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+	RemainingDownloads *int       `json:"remaining_downloads,omitempty"`
+}
+
+func NewShareService(files *FileService, repo repository.ShareRepository) *ShareService {
+	s := &ShareService{files: files, repo: repo}
+	files.OnChange(s.onChange)
+	return s
+}
+
+// Create 为文件或目录创建分享链接
+// 创建者需要对该路径有读权限；只传模式只能分享目录，且需要写权限
+func (s *ShareService) Create(ctx context.Context, username string, opts ShareOptions) (*ShareInfo, error) {
+	if opts.Mode == "" {
+		opts.Mode = model.ShareModeRead
+	}
+	if opts.Mode != model.ShareModeRead && opts.Mode != model.ShareModeUpload {
+		return nil, fmt.Errorf("%w: unknown mode %q", ErrInvalidShare, opts.Mode)
+	}
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidShare)
+	}
+	if opts.MaxDownloads < 0 {
+		return nil, fmt.Errorf("%w: max_downloads must not be negative", ErrInvalidShare)
+	}
+	path := pathpkg.Clean("/" + opts.Path)
+
+	source, err := s.files.sourceRepo.FindByKey(ctx, opts.SourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, opts.SourceKey)
+	}
+	// 经过 SecureDriver 的 Stat 顺带完成读权限检查
+	info, err := s.files.Stat(ctx, opts.SourceKey, path)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Mode == model.ShareModeUpload {
+		if !info.IsDir {
+			return nil, fmt.Errorf("%w: upload links can only point to a directory", ErrInvalidShare)
+		}
+		if !s.files.permService.CheckPermission(ctx, username, source.ID, path, "write") {
+			return nil, vfs.NewError("share", path, vfs.ErrPermissionDenied)
+		}
+	}
+
+	token, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	share := &model.Share{
+		Token:        token,
+		Username:     username,
+		SourceID:     source.ID,
+		SourceKey:    source.Key,
+		Path:         path,
+		IsDir:        info.IsDir,
+		Mode:         opts.Mode,
+		ExpiresAt:    opts.ExpiresAt,
+		MaxDownloads: opts.MaxDownloads,
+	}
+	if opts.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		share.PasswordHash = string(hash)
+	}
+	if err := s.repo.Save(ctx, share); err != nil {
+		return nil, err
+	}
+	return shareInfo(share), nil
+}
+
+// List 列出用户创建的分享，管理员 all 为 true 时列出全部
+func (s *ShareService) List(ctx context.Context, username string, all bool) ([]*ShareInfo, error) {
+	if all && !s.files.permService.IsAdmin(ctx, username) {
+		return nil, vfs.NewError("share", "/", vfs.ErrPermissionDenied)
+	}
+	if all {
+		username = ""
+	}
+	shares, err := s.repo.FindByUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*ShareInfo, len(shares))
+	for i, share := range shares {
+		infos[i] = shareInfo(share)
+	}
+	return infos, nil
+}
+
+// Revoke 撤销分享，只有创建者与管理员可以撤销
+func (s *ShareService) Revoke(ctx context.Context, username string, id uint) error {
+	if _, err := s.owned(ctx, username, id); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+// AccessLog 返回分享最近的访问日志
+func (s *ShareService) AccessLog(ctx context.Context, username string, id uint) ([]*model.ShareAccess, error) {
+	if _, err := s.owned(ctx, username, id); err != nil {
+		return nil, err
+	}
+	return s.repo.FindAccess(ctx, id, shareLogLimit)
+}
+
+// owned 其他用户的分享同样返回不存在，不暴露分享是否存在
+func (s *ShareService) owned(ctx context.Context, username string, id uint) (*model.Share, error) {
+	share, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrShareNotFound
+	}
+	if share.Username != username && !s.files.permService.IsAdmin(ctx, username) {
+		return nil, ErrShareNotFound
+	}
+	return share, nil
+}
+
+// Open 按令牌查找可用的分享：已过期返回 ErrShareExpired，只读分享下载次数用尽返回 ErrShareExhausted
+func (s *ShareService) Open(ctx context.Context, token string) (*model.Share, error) {
+	share, err := s.repo.FindByToken(ctx, token)
+	if err != nil || !s.files.sourceIs(ctx, share.SourceKey, share.SourceID) {
+		return nil, ErrShareNotFound
+	}
+	if share.ExpiresAt != nil && !share.ExpiresAt.After(time.Now()) {
+		return nil, ErrShareExpired
+	}
+	if share.Mode == model.ShareModeRead && share.MaxDownloads > 0 && share.Downloads >= share.MaxDownloads {
+		return nil, ErrShareExhausted
+	}
+	return share, nil
+}
+
+// PublicInfo 访问者看到的分享信息
+func (s *ShareService) PublicInfo(share *model.Share) *SharePublicInfo {
+	info := &SharePublicInfo{
+		Name:             pathpkg.Base(share.Path),
+		IsDir:            share.IsDir,
+		Mode:             share.Mode,
+		PasswordRequired: share.HasPassword(),
+		ExpiresAt:        share.ExpiresAt,
+	}
+	if share.MaxDownloads > 0 {
+		remaining := max(share.MaxDownloads-share.Downloads, 0)
+		info.RemainingDownloads = &remaining
+	}
+	return info
+}
+
+// Unlock 校验分享密码，签发限定于该分享的访问令牌
+func (s *ShareService) Unlock(share *model.Share, password string) (string, time.Time, error) {
+	if !share.HasPassword() {
+		return "", time.Time{}, fmt.Errorf("%w: share link has no password", ErrInvalidShare)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(password)); err != nil {
+		return "", time.Time{}, ErrSharePassword
+	}
+	expiresAt := time.Now().Add(shareAccessTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"share": share.Token,
+		"exp":   expiresAt.Unix(),
+	})
+	signed, err := token.SignedString(JWTSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// Authorize 检查访问令牌，没有密码的分享无需令牌
+func (s *ShareService) Authorize(share *model.Share, accessToken string) error {
+	if !share.HasPassword() {
+		return nil
+	}
+	if accessToken == "" {
+		return ErrSharePassword
+	}
+	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return JWTSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return ErrSharePassword
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || claims["share"] != share.Token {
+		return ErrSharePassword
+	}
+	return nil
+}
+
+// Resolve 将分享内的相对路径转换为存储源中的路径，"../" 无法越出分享根目录
+// 文件分享只能访问其本身
+func (s *ShareService) Resolve(share *model.Share, rel string) (string, error) {
+	rel = pathpkg.Clean("/" + rel)
+	if !share.IsDir && rel != "/" {
+		return "", vfs.NewError("share", rel, vfs.ErrNotFound)
+	}
+	return pathpkg.Join(share.Path, rel), nil
+}
+
+// RelPath 将存储源中的路径转换回分享内的相对路径，用于日志与响应，避免暴露完整路径
+func (s *ShareService) RelPath(share *model.Share, path string) string {
+	if !share.IsDir {
+		return "/" + pathpkg.Base(share.Path)
+	}
+	return pathpkg.Clean("/" + strings.TrimPrefix(path, share.Path))
+}
+
+// AddDownload 预占一次下载，只读分享的下载次数用尽时返回 ErrShareExhausted
+// 判断与计数在同一条 UPDATE 中完成，并发请求不会超出上限；下载没有完成时用 RefundDownload 退还
+func (s *ShareService) AddDownload(ctx context.Context, share *model.Share) error {
+	ok, err := s.repo.AddDownload(ctx, share.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrShareExhausted
+	}
+	return nil
+}
+
+// RefundDownload 退还 AddDownload 预占的下载次数
+func (s *ShareService) RefundDownload(ctx context.Context, share *model.Share) error {
+	return s.repo.RefundDownload(ctx, share.ID)
+}
+
+// OnUserDeleted 删除用户的全部分享 (UserDeleteHook)
+func (s *ShareService) OnUserDeleted(ctx context.Context, username, actor string) error {
+	return s.repo.DeleteByUser(ctx, username)
+}
+
+// LogAccess 记录访问日志，失败只打印不影响请求
+func (s *ShareService) LogAccess(ctx context.Context, access *model.ShareAccess) {
+	if len(access.UserAgent) > 255 {
+		access.UserAgent = access.UserAgent[:255]
+	}
+	if err := s.repo.LogAccess(ctx, access); err != nil {
+		fmt.Printf("[Share] Failed to log access to share %d: %v\n", access.ShareID, err)
+	}
+}
+
+// onChange 文件或目录被移动后，分享随之指向新路径
+func (s *ShareService) onChange(ctx context.Context, sourceKey string, ev vfs.ChangeEvent) {
+	if ev.Op != vfs.ChangeRename {
+		return
+	}
+	source, err := s.files.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return
+	}
+	if err := s.repo.Rename(ctx, source.ID, ev.Path, ev.Dest); err != nil {
+		fmt.Printf("[Share] Failed to update shares for %s:%s: %v\n", sourceKey, ev.Path, err)
+	}
+}
+
+func shareInfo(share *model.Share) *ShareInfo {
+	return &ShareInfo{
+		Share:       share,
+		HasPassword: share.HasPassword(),
+		Expired:     share.ExpiresAt != nil && !share.ExpiresAt.After(time.Now()),
+	}
+}
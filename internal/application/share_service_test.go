@@ -0,0 +1,171 @@
+package application
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+)
+
+func newTestShares(t *testing.T) (*testEnv, *ShareService) {
+	t.Helper()
+	env := newTestEnv(t)
+	if err := os.MkdirAll(filepath.Join(env.root, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(env.root, "docs", "a.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return env, NewShareService(env.files, persistence.NewShareRepository(env.db))
+}
+
+func TestShareDownloadLimit(t *testing.T) {
+	env, shares := newTestShares(t)
+	info, err := shares.Create(env.ctx, "admin", ShareOptions{SourceKey: env.sourceKey, Path: "/docs/a.txt", MaxDownloads: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 2 {
+		share, err := shares.Open(env.ctx, info.Token)
+		if err != nil {
+			t.Fatalf("download %d: Open = %v", i, err)
+		}
+		if err := shares.AddDownload(env.ctx, share); err != nil {
+			t.Fatalf("download %d: AddDownload = %v", i, err)
+		}
+	}
+	if _, err := shares.Open(env.ctx, info.Token); !errors.Is(err, ErrShareExhausted) {
+		t.Fatalf("Open after limit = %v, want ErrShareExhausted", err)
+	}
+	// 已经通过 Open 的并发请求也不能超出上限
+	if err := shares.AddDownload(env.ctx, info.Share); !errors.Is(err, ErrShareExhausted) {
+		t.Fatalf("AddDownload after limit = %v, want ErrShareExhausted", err)
+	}
+}
+
+// 并发下载在发送前预占次数，成功预占的请求数不会超过上限；未完成的下载退还后可以再次下载
+func TestShareDownloadReserveConcurrent(t *testing.T) {
+	env, shares := newTestShares(t)
+	info, err := shares.Create(env.ctx, "admin", ShareOptions{SourceKey: env.sourceKey, Path: "/docs/a.txt", MaxDownloads: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	share, err := shares.Open(env.ctx, info.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch err := shares.AddDownload(env.ctx, share); {
+			case err == nil:
+				reserved.Add(1)
+			case !errors.Is(err, ErrShareExhausted):
+				t.Errorf("AddDownload = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := reserved.Load(); n != 3 {
+		t.Fatalf("reserved %d downloads, want 3", n)
+	}
+
+	if err := shares.RefundDownload(env.ctx, share); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := shares.Open(env.ctx, info.Token); err != nil {
+		t.Fatalf("Open after refund = %v", err)
+	}
+	if err := shares.AddDownload(env.ctx, share); err != nil {
+		t.Fatalf("AddDownload after refund = %v", err)
+	}
+	if err := shares.AddDownload(env.ctx, share); !errors.Is(err, ErrShareExhausted) {
+		t.Fatalf("AddDownload beyond limit = %v, want ErrShareExhausted", err)
+	}
+}
+
+func TestShareOpen(t *testing.T) {
+	env, shares := newTestShares(t)
+	upload, err := shares.Create(env.ctx, "admin", ShareOptions{SourceKey: env.sourceKey, Path: "/docs", Mode: model.ShareModeUpload, MaxDownloads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := time.Now().Add(time.Hour)
+	expired, err := shares.Create(env.ctx, "admin", ShareOptions{SourceKey: env.sourceKey, Path: "/docs", ExpiresAt: &expiresAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	if err := env.db.Model(&model.Share{}).Where("id = ?", expired.ID).Update("expires_at", past).Error; err != nil {
+		t.Fatal(err)
+	}
+	exhausted, err := shares.Create(env.ctx, "admin", ShareOptions{SourceKey: env.sourceKey, Path: "/docs", MaxDownloads: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.db.Model(&model.Share{}).Where("id = ?", exhausted.ID).Update("downloads", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		err   error
+	}{
+		// 只传分享不计下载次数
+		{"upload mode ignores limit", upload.Token, nil},
+		{"expired", expired.Token, ErrShareExpired},
+		{"exhausted", exhausted.Token, ErrShareExhausted},
+		{"unknown token", "missing", vfs.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := shares.Open(env.ctx, tt.token); !errors.Is(err, tt.err) {
+				t.Fatalf("Open = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestShareResolve(t *testing.T) {
+	shares := &ShareService{}
+	dir := &model.Share{Path: "/docs", IsDir: true}
+	file := &model.Share{Path: "/docs/a.txt"}
+	tests := []struct {
+		name  string
+		share *model.Share
+		rel   string
+		want  string
+		err   error
+	}{
+		{"dir root", dir, "", "/docs", nil},
+		{"dir child", dir, "sub/b.txt", "/docs/sub/b.txt", nil},
+		{"dir escape", dir, "../etc/passwd", "/docs/etc/passwd", nil},
+		{"dir absolute escape", dir, "/../../secret", "/docs/secret", nil},
+		{"dir dot segments", dir, "sub/../../a.txt", "/docs/a.txt", nil},
+		{"file root", file, "/", "/docs/a.txt", nil},
+		{"file empty", file, "", "/docs/a.txt", nil},
+		{"file escape", file, "..", "/docs/a.txt", nil},
+		{"file child", file, "b.txt", "", vfs.ErrNotFound},
+		{"file sibling", file, "../b.txt", "", vfs.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shares.Resolve(tt.share, tt.rel)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Fatalf("Resolve(%q) = %q, %v; want %q, %v", tt.rel, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
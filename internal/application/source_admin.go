@@ -0,0 +1,206 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers"
+)
+
+// sourceTestTimeout 测试连接的超时时间，包括 Init 与一次 Ping
+const sourceTestTimeout = 15 * time.Second
+
+// sourceKeyPattern 存储源 Key 会出现在 URL 路径中 (/files/:source_key、/webdav/:source_key)
+var sourceKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// validateSourceConfig 按驱动的 Schema 检查配置，不建立连接；返回交给 Init 的明文规范化配置
+// 保存的仍是管理员填写的原始配置 (敏感项加密)，之后修改的驱动默认值对未填写的项生效
+func (s *FileService) validateSourceConfig(source *model.StorageSource) (map[string]any, error) {
+	if source.Config == nil {
+		source.Config = model.JSONMap{}
+	}
+	opened, err := s.openConfig(source.Type, source.Config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+	}
+	// 保存时未声明的配置项视为拼写错误；加载已保存的配置时只记录日志 (见 GetDriver)
+	if unknown := drivers.UnknownFields(source.Type, opened); len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s config has unknown fields: %s", ErrInvalidOperation, source.Type, strings.Join(unknown, ", "))
+	}
+	config, err := drivers.Normalize(source.Type, opened)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+	}
+	return config, nil
+}
+
+// validateSource 检查保存前的存储源
+func (s *FileService) validateSource(source *model.StorageSource) error {
+	if !sourceKeyPattern.MatchString(source.Key) {
+		return fmt.Errorf("%w: source key must be 1-32 letters, digits, '-' or '_'", ErrInvalidOperation)
+	}
+	source.Name = strings.TrimSpace(source.Name)
+	if source.Name == "" || len(source.Name) > 64 {
+		return fmt.Errorf("%w: source name must be 1-64 bytes", ErrInvalidOperation)
+	}
+	_, err := s.validateSourceConfig(source)
+	return err
+}
+
+// reloadSource 驱逐并关闭缓存的驱动，下一次访问按新配置重建
+func reloadSource(sourceKey string) {
+	dirverMu.Lock()
+	evictDriver(sourceKey)
+	dirverMu.Unlock()
+	sourceHealth.Delete(sourceKey)
+}
+
+// ListSources 返回全部存储源及其配置 (仅管理员)，敏感配置项替换为 RedactedSecret
+func (s *FileService) ListSources(ctx context.Context) ([]*model.StorageSource, error) {
+	sources, err := s.sourceRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, source := range sources {
+		sources[i] = redactSource(source)
+	}
+	return sources, nil
+}
+
+// CreateSource 校验并保存新的存储源，不检查能否连接 (见 TestSource)；返回隐去敏感配置的副本
+func (s *FileService) CreateSource(ctx context.Context, source *model.StorageSource) (*model.StorageSource, error) {
+	if _, err := s.sourceRepo.FindByKey(ctx, source.Key); err == nil {
+		return nil, fmt.Errorf("%w: storage source %s", vfs.ErrAlreadyExists, source.Key)
+	}
+	config, err := s.sealConfig(source.Type, source.Config, nil)
+	if err != nil {
+		return nil, err
+	}
+	source.Config = config
+	if err := s.validateSource(source); err != nil {
+		return nil, err
+	}
+	source.ID = 0
+	if err := s.sourceRepo.Save(ctx, source); err != nil {
+		return nil, err
+	}
+	return redactSource(source), nil
+}
+
+// UpdateSource 用 source 替换 sourceKey 的全部配置，Key 不能修改 (回收站、分享等记录引用了它)
+// 敏感配置项为 RedactedSecret 时保留原值 (连接目标不变时，见 sealConfig)；返回隐去敏感配置的副本
+func (s *FileService) UpdateSource(ctx context.Context, sourceKey string, source *model.StorageSource) (*model.StorageSource, error) {
+	existing, err := s.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	if source.Key != "" && source.Key != sourceKey {
+		return nil, fmt.Errorf("%w: source key cannot be changed", ErrInvalidOperation)
+	}
+	source.Key = sourceKey
+	config, err := s.sealConfig(source.Type, source.Config, existing)
+	if err != nil {
+		return nil, err
+	}
+	source.Config = config
+	if err := s.validateSource(source); err != nil {
+		return nil, err
+	}
+	source.ID = existing.ID
+	source.CreatedAt = existing.CreatedAt
+	if err := s.sourceRepo.Save(ctx, source); err != nil {
+		return nil, err
+	}
+	reloadSource(sourceKey)
+	return redactSource(source), nil
+}
+
+// DeleteSource 删除存储源配置及引用它的分享、回收站、历史版本等记录，存储后端中的文件不受影响
+func (s *FileService) DeleteSource(ctx context.Context, sourceKey string) error {
+	source, err := s.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	if err := s.sourceRepo.Delete(ctx, source.ID); err != nil {
+		return err
+	}
+	reloadSource(sourceKey)
+	return nil
+}
+
+// sourceIs 判断 sourceKey 当前是否仍是 id 对应的存储源
+// 分享等记录按 Key 访问文件，存储源删除后同名重建时不能沿用旧记录
+func (s *FileService) sourceIs(ctx context.Context, sourceKey string, id uint) bool {
+	source, err := s.sourceRepo.FindByKey(ctx, sourceKey)
+	return err == nil && source.ID == id
+}
+
+// DriverSchemas 返回全部驱动的配置说明，供前端生成存储源表单
+func (s *FileService) DriverSchemas() []drivers.Schema {
+	return drivers.Schemas()
+}
+
+// TestSource 用一个临时驱动执行 Init 与 Ping，返回耗时；不影响缓存中的驱动
+// source.Key 为已有存储源时，取值为 RedactedSecret 的敏感配置项使用已保存的值 (编辑表单中测试)，
+// 但驱动类型或连接目标改变时需要重新填写 (见 sealConfig)
+// 连接失败归类为 vfs.ErrUnavailable，配置不合法归类为 ErrInvalidOperation
+func (s *FileService) TestSource(ctx context.Context, source *model.StorageSource) (time.Duration, error) {
+	var existing *model.StorageSource
+	if source.Key != "" {
+		if saved, err := s.sourceRepo.FindByKey(ctx, source.Key); err == nil {
+			existing = saved
+		}
+	}
+	sealed, err := s.sealConfig(source.Type, source.Config, existing)
+	if err != nil {
+		return 0, err
+	}
+	source.Config = sealed
+	config, err := s.validateSourceConfig(source)
+	if err != nil {
+		return 0, err
+	}
+	driver, err := drivers.CreateInstance(source.Type)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, sourceTestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	// 部分驱动的 Init 不一定响应 ctx，超时后在后台等它结束再关闭
+	done := make(chan error, 1)
+	go func() { done <- driver.Init(ctx, config) }()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		go func() {
+			<-done
+			driver.Close()
+		}()
+		return 0, fmt.Errorf("%w: connection test did not finish within %s", vfs.ErrTimeout, sourceTestTimeout)
+	}
+	defer driver.Close()
+	if err == nil {
+		err = pingDriver(ctx, driver)
+	}
+	if err != nil {
+		// 返回底层原因 (凭据错误、主机不可达等)，便于管理员排查
+		return 0, fmt.Errorf("%w: %v", vfs.ErrUnavailable, err)
+	}
+	return time.Since(start), nil
+}
+
+// TestSavedSource 测试已保存的存储源
+func (s *FileService) TestSavedSource(ctx context.Context, sourceKey string) (time.Duration, error) {
+	source, err := s.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	return s.TestSource(ctx, source)
+}
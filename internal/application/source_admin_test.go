@@ -0,0 +1,32 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+)
+
+// 未声明的配置项在保存时拒绝；已保存的存储源 (例如驱动升级后删除了某个配置项) 仍可加载
+func TestUnknownConfigFields(t *testing.T) {
+	env := newTestEnv(t)
+	config := model.JSONMap{"root_path": env.root, "root_pth": env.root}
+
+	create := &model.StorageSource{Key: env.sourceKey + "b", Name: "typo", Type: "local", Config: config}
+	if _, err := env.files.CreateSource(env.ctx, create); !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("CreateSource = %v, want ErrInvalidOperation", err)
+	}
+	update := &model.StorageSource{Name: "test", Type: "local", Config: config}
+	if _, err := env.files.UpdateSource(env.ctx, env.sourceKey, update); !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("UpdateSource = %v, want ErrInvalidOperation", err)
+	}
+
+	env.source.Config = config
+	if err := env.files.sourceRepo.Save(env.ctx, env.source); err != nil {
+		t.Fatal(err)
+	}
+	reloadSource(env.sourceKey)
+	if _, err := env.files.GetDriver(env.ctx, env.sourceKey); err != nil {
+		t.Fatalf("GetDriver with an unknown stored field: %v", err)
+	}
+}
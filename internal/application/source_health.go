@@ -0,0 +1,155 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+const (
+	// healthCheckInterval 缓存中的驱动每隔多久在请求路径上 Ping 一次
+	healthCheckInterval = 30 * time.Second
+	// pingTimeout 单次 Ping 的超时时间，避免 NAS 挂死时拖住请求
+	pingTimeout = 5 * time.Second
+	// driverInitTimeout 加载驱动时 Init 的超时时间 (SMB 需要建立 pool_min 个连接)
+	driverInitTimeout = 30 * time.Second
+)
+
+// 健康状态取值
+const (
+	HealthUnknown   = "unknown" // 驱动尚未加载
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// SourceHealth 存储源健康状态 (供管理员查看)
+type SourceHealth struct {
+	Key       string    `json:"key"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Status    string    `json:"status"`
+	LastCheck time.Time `json:"last_check"`
+	LastError string    `json:"last_error,omitempty"`
+	Failures  int       `json:"consecutive_failures"`
+}
+
+// healthRecord 单个存储源的健康记录
+type healthRecord struct {
+	mu        sync.Mutex
+	status    string
+	lastCheck time.Time
+	lastError string
+	failures  int
+}
+
+var sourceHealth = sync.Map{} // map[string]*healthRecord
+
+func healthOf(sourceKey string) *healthRecord {
+	rec, _ := sourceHealth.LoadOrStore(sourceKey, &healthRecord{status: HealthUnknown})
+	return rec.(*healthRecord)
+}
+
+// due 判断是否需要检查；返回 true 时会立即占用本轮检查，防止并发请求同时 Ping
+func (r *healthRecord) due(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Sub(r.lastCheck) < healthCheckInterval {
+		return false
+	}
+	r.lastCheck = now
+	return true
+}
+
+// record 记录一次检查结果；被取消的检查不说明存储源的状态，不计入失败
+func (r *healthRecord) record(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if errors.Is(err, context.Canceled) {
+		r.lastCheck = time.Time{}
+		return
+	}
+	r.lastCheck = time.Now()
+	if err != nil {
+		r.status = HealthUnhealthy
+		r.lastError = err.Error()
+		r.failures++
+		return
+	}
+	r.status = HealthHealthy
+	r.lastError = ""
+	r.failures = 0
+}
+
+// expire 让下一次 GetDriver 立即触发检查
+func (r *healthRecord) expire() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastCheck = time.Time{}
+}
+
+func (r *healthRecord) snapshot() SourceHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return SourceHealth{
+		Status:    r.status,
+		LastCheck: r.lastCheck,
+		LastError: r.lastError,
+		Failures:  r.failures,
+	}
+}
+
+// pingDriver 对驱动做一次带超时的健康检查
+func pingDriver(ctx context.Context, driver vfs.StorageDriver) error {
+	hc, ok := driver.(vfs.HealthChecker)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	// 部分驱动的 Ping 不一定响应 ctx (例如重连中的 SMB)，这里额外保证不会超时后继续阻塞请求
+	done := make(chan error, 1)
+	go func() { done <- hc.Ping(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// evictDriver 将驱动移出缓存并关闭 (仍在使用它的请求结束后才断开)，下一次 GetDriver 会重新创建
+func evictDriver(sourceKey string) {
+	if cached, ok := dirverCache.LoadAndDelete(sourceKey); ok {
+		if c, valid := cached.(*cachedDriver); valid {
+			c.driver.Close()
+		}
+	}
+}
+
+// SourceHealth 返回所有存储源的健康状态
+// check 为 true 时立即检查 (尚未加载的驱动会被加载)
+func (s *FileService) SourceHealth(ctx context.Context, check bool) ([]SourceHealth, error) {
+	sources, err := s.sourceRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]SourceHealth, 0, len(sources))
+	for _, source := range sources {
+		rec := healthOf(source.Key)
+		if check {
+			rec.expire()
+			// 错误已记录在 healthRecord 中
+			_, _ = s.GetDriver(ctx, source.Key)
+		}
+		h := rec.snapshot()
+		h.Key = source.Key
+		h.Name = source.Name
+		h.Type = source.Type
+		result = append(result, h)
+	}
+	return result, nil
+}
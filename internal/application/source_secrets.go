@@ -0,0 +1,150 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/secrets"
+)
+
+// RedactedSecret API 响应中代替敏感配置的占位符；修改存储源时原样提交表示保留原值
+const RedactedSecret = "********"
+
+// sealConfig 加密驱动 Schema 中标记为 Secret 的配置项，返回新的配置
+// 取值为 RedactedSecret 时沿用已保存的 existing 中的原值，但驱动类型或连接目标 (Identity 配置项) 改变时必须重新填写，
+// 避免把原密码发往新的主机；${env:...}、${file:...} 引用本身不是机密，保持明文
+func (s *FileService) sealConfig(sourceType string, config model.JSONMap, existing *model.StorageSource) (model.JSONMap, error) {
+	sealed := make(model.JSONMap, len(config))
+	for k, v := range config {
+		sealed[k] = v
+	}
+	for _, name := range drivers.SecretFields(sourceType) {
+		v, ok := sealed[name].(string)
+		if !ok || v == "" || secrets.IsEncrypted(v) || secrets.IsReference(v) {
+			continue
+		}
+		if v == RedactedSecret {
+			if existing == nil {
+				return nil, fmt.Errorf("%w: %s must be entered again", ErrInvalidOperation, name)
+			}
+			if existing.Type != sourceType || !drivers.SameTarget(sourceType, config, existing.Config) {
+				return nil, fmt.Errorf("%w: %s must be entered again after changing the server or account", ErrInvalidOperation, name)
+			}
+			old, ok := existing.Config[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be entered again", ErrInvalidOperation, name)
+			}
+			sealed[name] = old
+			continue
+		}
+		encrypted, err := s.cipher.Load().Encrypt(v)
+		if err != nil {
+			return nil, err
+		}
+		sealed[name] = encrypted
+	}
+	return sealed, nil
+}
+
+// openConfig 解密敏感配置项并解析其中的 ${env:...}、${file:...} 引用，得到交给驱动的明文配置
+// 只处理驱动 Schema 中标记为 Secret 的配置项，其他配置项原样传递，不能借引用读取服务器上的文件或环境变量
+func (s *FileService) openConfig(sourceType string, config model.JSONMap) (map[string]any, error) {
+	opened := make(map[string]any, len(config))
+	for k, v := range config {
+		opened[k] = v
+	}
+	for _, name := range drivers.SecretFields(sourceType) {
+		str, ok := opened[name].(string)
+		if !ok {
+			continue
+		}
+		plain, err := s.cipher.Load().Decrypt(str)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %w", name, err)
+		}
+		if plain, err = secrets.Resolve(plain); err != nil {
+			return nil, fmt.Errorf("config %s: %w", name, err)
+		}
+		opened[name] = plain
+	}
+	return opened, nil
+}
+
+// redactSource 返回将已加密的配置项替换为 RedactedSecret 的副本，用于 API 响应
+func redactSource(source *model.StorageSource) *model.StorageSource {
+	redacted := *source
+	redacted.Config = make(model.JSONMap, len(source.Config))
+	for k, v := range source.Config {
+		if str, ok := v.(string); ok && secrets.IsEncrypted(str) {
+			v = RedactedSecret
+		}
+		redacted.Config[k] = v
+	}
+	return &redacted
+}
+
+// SealSources 加密数据库中仍为明文的敏感配置 (升级前保存的存储源)，返回更新的存储源数
+func (s *FileService) SealSources(ctx context.Context) (int, error) {
+	sources, err := s.sourceRepo.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, source := range sources {
+		plain := false
+		for _, name := range drivers.SecretFields(source.Type) {
+			if v, ok := source.Config[name].(string); ok && v != "" && v != RedactedSecret && !secrets.IsEncrypted(v) && !secrets.IsReference(v) {
+				plain = true
+			}
+		}
+		if !plain {
+			continue
+		}
+		if source.Config, err = s.sealConfig(source.Type, source.Config, nil); err != nil {
+			return updated, err
+		}
+		if err := s.sourceRepo.Save(ctx, source); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+	return updated, nil
+}
+
+// RotateSecrets 用 next 重新加密全部存储源的敏感配置，返回更新的存储源数
+// 先全部解密再在一个事务中保存，当前主密钥不正确或保存失败时不会写入任何数据
+func (s *FileService) RotateSecrets(ctx context.Context, next *secrets.Cipher) (int, error) {
+	sources, err := s.sourceRepo.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+	current := s.cipher.Load()
+	var changed []*model.StorageSource
+	for _, source := range sources {
+		rotated := false
+		for _, name := range drivers.SecretFields(source.Type) {
+			v, ok := source.Config[name].(string)
+			if !ok || !secrets.IsEncrypted(v) {
+				continue
+			}
+			plain, err := current.Decrypt(v)
+			if err != nil {
+				return 0, fmt.Errorf("source %s config %s: %w", source.Key, name, err)
+			}
+			if source.Config[name], err = next.Encrypt(plain); err != nil {
+				return 0, err
+			}
+			rotated = true
+		}
+		if rotated {
+			changed = append(changed, source)
+		}
+	}
+	if err := s.sourceRepo.SaveAll(ctx, changed); err != nil {
+		return 0, err
+	}
+	s.cipher.Store(next)
+	return len(changed), nil
+}
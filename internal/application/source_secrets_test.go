@@ -0,0 +1,186 @@
+package application
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/secrets"
+
+	_ "github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/smb"
+)
+
+func newTestCipher(t *testing.T) *secrets.Cipher {
+	t.Helper()
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := secrets.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// addSMBSource 保存一个 SMB 存储源 (不会连接)，password 为 cipher 加密后的值
+func addSMBSource(t *testing.T, env *testEnv, key, password string, cipher *secrets.Cipher) *model.StorageSource {
+	t.Helper()
+	encrypted, err := cipher.Encrypt(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := &model.StorageSource{Key: key, Name: key, Type: "smb", Config: model.JSONMap{"host": "nas", "user": "u", "share_name": "s", "password": encrypted}}
+	if err := env.files.sourceRepo.Save(env.ctx, source); err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+// storedPassword 读取数据库中保存的密码并用 cipher 解密
+func storedPassword(t *testing.T, env *testEnv, key string, cipher *secrets.Cipher) (string, error) {
+	t.Helper()
+	source, err := env.files.sourceRepo.FindByKey(env.ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cipher.Decrypt(source.Config["password"].(string))
+}
+
+func TestRotateSecrets(t *testing.T) {
+	env := newTestEnv(t)
+	current := env.files.cipher.Load()
+	addSMBSource(t, env, "nas1", "one", current)
+	addSMBSource(t, env, "nas2", "two", current)
+
+	next := newTestCipher(t)
+	n, err := env.files.RotateSecrets(env.ctx, next)
+	if err != nil || n != 2 {
+		t.Fatalf("RotateSecrets = %d, %v; want 2", n, err)
+	}
+	for key, want := range map[string]string{"nas1": "one", "nas2": "two"} {
+		if got, err := storedPassword(t, env, key, next); err != nil || got != want {
+			t.Fatalf("%s: password = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	if env.files.cipher.Load() != next {
+		t.Fatalf("RotateSecrets did not switch to the new cipher")
+	}
+}
+
+func TestRotateSecretsWrongKey(t *testing.T) {
+	env := newTestEnv(t)
+	current := env.files.cipher.Load()
+	addSMBSource(t, env, "nas1", "one", current)
+	// 用其他主密钥加密的取值无法解密，整个更换中止
+	addSMBSource(t, env, "nas2", "two", newTestCipher(t))
+
+	if n, err := env.files.RotateSecrets(env.ctx, newTestCipher(t)); err == nil || n != 0 {
+		t.Fatalf("RotateSecrets = %d, %v; want error", n, err)
+	}
+	if got, err := storedPassword(t, env, "nas1", current); err != nil || got != "one" {
+		t.Fatalf("nas1 was modified: %q, %v", got, err)
+	}
+	if env.files.cipher.Load() != current {
+		t.Fatalf("cipher switched after a failed rotation")
+	}
+}
+
+func TestOpenConfigResolvesOnlySecrets(t *testing.T) {
+	env := newTestEnv(t)
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ref := "${file:" + file + "}"
+	encrypted, err := env.files.cipher.Load().Encrypt("plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		config model.JSONMap
+		want   map[string]any
+	}{
+		{"encrypted secret", model.JSONMap{"host": "nas", "password": encrypted}, map[string]any{"host": "nas", "password": "plain"}},
+		{"secret reference", model.JSONMap{"host": "nas", "password": ref}, map[string]any{"host": "nas", "password": "from-file"}},
+		// 非敏感配置项中的引用原样传递
+		{"plain field reference", model.JSONMap{"host": ref, "user": ref}, map[string]any{"host": ref, "user": ref}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := env.files.openConfig("smb", tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, want := range tt.want {
+				if got[k] != want {
+					t.Fatalf("%s = %v, want %v", k, got[k], want)
+				}
+			}
+		})
+	}
+	// 本地驱动没有敏感配置项
+	got, err := env.files.openConfig("local", model.JSONMap{"root_path": ref})
+	if err != nil || got["root_path"] != ref {
+		t.Fatalf("local root_path = %v, %v; want unresolved", got["root_path"], err)
+	}
+}
+
+// 提交 RedactedSecret 时只有连接目标不变才沿用已保存的密码，否则原密码会被发往新的主机
+func TestRedactedSecretRequiresSameTarget(t *testing.T) {
+	env := newTestEnv(t)
+	cipher := env.files.cipher.Load()
+	addSMBSource(t, env, "nas1", "secret", cipher)
+
+	tests := []struct {
+		name   string
+		config model.JSONMap
+		ok     bool
+	}{
+		{"unchanged", model.JSONMap{"host": "nas", "user": "u", "share_name": "s"}, true},
+		{"default port written out", model.JSONMap{"host": "nas", "port": "445", "user": "u", "share_name": "s"}, true},
+		{"other settings changed", model.JSONMap{"host": "nas", "user": "u", "share_name": "s", "pool_max": 2}, true},
+		{"host changed", model.JSONMap{"host": "evil", "user": "u", "share_name": "s"}, false},
+		{"port changed", model.JSONMap{"host": "nas", "port": 4455, "user": "u", "share_name": "s"}, false},
+		{"user changed", model.JSONMap{"host": "nas", "user": "other", "share_name": "s"}, false},
+		{"share changed", model.JSONMap{"host": "nas", "user": "u", "share_name": "other"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := maps.Clone(tt.config)
+			config["password"] = RedactedSecret
+			source := &model.StorageSource{Name: "nas1", Type: "smb", Config: config}
+			_, err := env.files.UpdateSource(env.ctx, "nas1", source)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidOperation) {
+					t.Fatalf("UpdateSource = %v, want ErrInvalidOperation", err)
+				}
+				// 测试连接同样不能把原密码发往新的目标
+				test := &model.StorageSource{Key: "nas1", Type: "smb", Config: maps.Clone(config)}
+				if _, err := env.files.TestSource(env.ctx, test); !errors.Is(err, ErrInvalidOperation) {
+					t.Fatalf("TestSource = %v, want ErrInvalidOperation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateSource: %v", err)
+			}
+			if got, err := storedPassword(t, env, "nas1", cipher); err != nil || got != "secret" {
+				t.Fatalf("stored password = %q, %v; want the original", got, err)
+			}
+		})
+	}
+
+	// 修改连接目标时重新填写密码即可
+	source := &model.StorageSource{Name: "nas1", Type: "smb", Config: model.JSONMap{"host": "nas2", "user": "u", "share_name": "s", "password": "new"}}
+	if _, err := env.files.UpdateSource(env.ctx, "nas1", source); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := storedPassword(t, env, "nas1", cipher); err != nil || got != "new" {
+		t.Fatalf("stored password = %q, %v; want new", got, err)
+	}
+}
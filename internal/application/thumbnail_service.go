@@ -0,0 +1,403 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"github.com/HugoSmits86/nativewebp"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+	"golang.org/x/sync/semaphore"
+)
+
+// 缩略图相关错误
+var (
+	ErrThumbUnsupported = errors.New("thumbnail not supported for this file")
+	// ErrThumbPending 生成尚未完成，客户端稍后重试
+	ErrThumbPending = errors.New("thumbnail is being generated")
+	// ErrThumbFormat 不支持的输出格式
+	ErrThumbFormat = errors.New("thumbnail format must be jpeg or webp")
+)
+
+const (
+	DefaultThumbSize = 256
+	// thumbWorkers 同时生成缩略图的协程数，限制对 NAS 的并发读取
+	thumbWorkers = 4
+	// thumbQueueSize 等待生成的任务上限，队列满时请求直接返回 ErrThumbPending
+	thumbQueueSize = 256
+	// thumbWait 请求等待生成结果的最长时间，超时后任务继续在后台执行
+	thumbWait = 10 * time.Second
+	// thumbMaxFileSize 超过该大小的原图不生成缩略图
+	thumbMaxFileSize = 64 << 20
+	// thumbMaxPixels 解码前按图片头检查像素数，防止解压炸弹耗尽内存 (6000 万像素可覆盖常见相机原图)
+	thumbMaxPixels = 60_000_000
+	// thumbDecodeBudget 同时解码的图片占用内存的上限，超大图片排队依次解码，
+	// 最坏情况下的内存约为 thumbWorkers * thumbMaxFileSize (读入的原图) 加上该预算
+	thumbDecodeBudget = 256 << 20
+	thumbQuality      = 80
+	// thumbCacheTTL 缓存文件超过该时间未被访问则清理
+	thumbCacheTTL          = 30 * 24 * time.Hour
+	thumbCleanupInterval   = 24 * time.Hour
+	thumbFailureCacheLimit = 10000
+)
+
+// ThumbSizes 可用的缩略图边长，请求的尺寸向上取整到其中之一，避免缓存被任意尺寸撑爆
+var ThumbSizes = []int{128, 256, 512, 1024}
+
+// 缩略图的输出格式
+const (
+	ThumbJPEG = "jpeg" // 默认，透明区域铺白底
+	ThumbWebP = "webp" // 无损 WebP，保留透明度，适合图标与截图
+)
+
+// thumbFormats 输出格式对应的 Content-Type
+var thumbFormats = map[string]string{
+	ThumbJPEG: "image/jpeg",
+	ThumbWebP: "image/webp",
+}
+
+// thumbExts 可生成缩略图的扩展名 (标准库与 x/image 的纯 Go 解码器)
+var thumbExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".webp": true, ".bmp": true, ".tif": true, ".tiff": true,
+}
+
+// ThumbnailService 生成并缓存图片缩略图
+// 缩略图编码为 JPEG 或 WebP (纯 Go 的无损编码器)，缓存在磁盘上，
+// 以存储源、路径、修改时间、大小、边长与格式为键，原图变化后自然失效
+type ThumbnailService struct {
+	files    *FileService
+	cacheDir string
+	queue    chan *thumbJob
+	decoding *semaphore.Weighted // 按估算的解码内存占用 thumbDecodeBudget
+
+	pending sync.Map // map[string]*thumbJob，合并同一缩略图的并发请求
+	failed  sync.Map // map[string]error，无法解码的原图不再重复读取
+}
+
+type thumbJob struct {
+	key       string
+	ctx       context.Context
+	sourceKey string
+	path      string
+	info      vfs.FileInfo
+	size      int
+	format    string
+	done      chan struct{}
+	err       error
+}
+
+// Thumbnail 一张已缓存的缩略图
+type Thumbnail struct {
+	Path        string // 缓存文件在本地磁盘上的路径
+	ContentType string
+	ETag        string
+	ModTime     time.Time
+}
+
+func NewThumbnailService(files *FileService, cacheDir string) (*ThumbnailService, error) {
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create thumbnail cache dir: %v", err)
+	}
+	s := &ThumbnailService{
+		files:    files,
+		cacheDir: cacheDir,
+		queue:    make(chan *thumbJob, thumbQueueSize),
+		decoding: semaphore.NewWeighted(thumbDecodeBudget),
+	}
+	for i := 0; i < thumbWorkers; i++ {
+		go s.worker()
+	}
+	go s.cleanupLoop()
+	return s, nil
+}
+
+// ThumbSize 将请求的边长向上取整到 ThumbSizes，0 使用默认值
+func ThumbSize(requested int) int {
+	if requested <= 0 {
+		return DefaultThumbSize
+	}
+	for _, size := range ThumbSizes {
+		if requested <= size {
+			return size
+		}
+	}
+	return ThumbSizes[len(ThumbSizes)-1]
+}
+
+// Thumbnail 返回缩略图，缓存未命中时交给工作协程生成并等待最多 thumbWait
+// format 为 ThumbJPEG 或 ThumbWebP，空字符串使用 JPEG
+func (s *ThumbnailService) Thumbnail(ctx context.Context, sourceKey, path string, size int, format string) (*Thumbnail, error) {
+	if format == "" {
+		format = ThumbJPEG
+	}
+	if _, ok := thumbFormats[format]; !ok {
+		return nil, ErrThumbFormat
+	}
+	// Stat 经过 SecureDriver，顺带完成读权限检查
+	info, err := s.files.Stat(ctx, sourceKey, path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir || !thumbExts[strings.ToLower(pathpkg.Ext(info.Name))] {
+		return nil, fmt.Errorf("%w: %s", ErrThumbUnsupported, path)
+	}
+	if info.Size > thumbMaxFileSize {
+		return nil, fmt.Errorf("%w: %s is too large", ErrThumbUnsupported, path)
+	}
+	size = ThumbSize(size)
+	key := thumbKey(sourceKey, path, info, size, format)
+	if thumb, ok := s.cached(key, format); ok {
+		return thumb, nil
+	}
+	if err, ok := s.failed.Load(key); ok {
+		return nil, err.(error)
+	}
+
+	job := &thumbJob{
+		key: key,
+		// 请求结束后任务仍可能在执行，保留用户信息但不随请求取消
+		ctx:       context.WithoutCancel(ctx),
+		sourceKey: sourceKey,
+		path:      path,
+		info:      info,
+		size:      size,
+		format:    format,
+		done:      make(chan struct{}),
+	}
+	if existing, loaded := s.pending.LoadOrStore(key, job); loaded {
+		job = existing.(*thumbJob)
+	} else {
+		select {
+		case s.queue <- job:
+		default:
+			s.pending.Delete(key)
+			return nil, ErrThumbPending
+		}
+	}
+
+	timer := time.NewTimer(thumbWait)
+	defer timer.Stop()
+	select {
+	case <-job.done:
+		if job.err != nil {
+			return nil, job.err
+		}
+		if thumb, ok := s.cached(key, format); ok {
+			return thumb, nil
+		}
+		return nil, ErrThumbPending
+	case <-timer.C:
+		return nil, ErrThumbPending
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// thumbKey 缓存键，原图修改时间或大小变化后对应新的文件
+func thumbKey(sourceKey, path string, info vfs.FileInfo, size int, format string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%d\x00%d\x00%s",
+		sourceKey, path, info.ModTime.UnixNano(), info.Size, size, format)))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheFile 按键的前两位分目录，避免单个目录文件过多
+func (s *ThumbnailService) cacheFile(key, format string) string {
+	return filepath.Join(s.cacheDir, key[:2], key+"."+format)
+}
+
+func (s *ThumbnailService) cached(key, format string) (*Thumbnail, bool) {
+	file := s.cacheFile(key, format)
+	st, err := os.Stat(file)
+	if err != nil {
+		return nil, false
+	}
+	// 记录访问时间，供清理任务判断
+	now := time.Now()
+	if now.Sub(st.ModTime()) > time.Hour {
+		_ = os.Chtimes(file, now, now)
+	}
+	return &Thumbnail{Path: file, ContentType: thumbFormats[format], ETag: `"` + key[:32] + `"`, ModTime: st.ModTime()}, true
+}
+
+func (s *ThumbnailService) worker() {
+	for job := range s.queue {
+		job.err = s.generate(job)
+		if job.err != nil && errors.Is(job.err, ErrThumbUnsupported) {
+			// 原图本身无法解码，记住结果；存储不可用等临时错误下次仍会重试
+			if s.failedCount() < thumbFailureCacheLimit {
+				s.failed.Store(job.key, job.err)
+			}
+		}
+		s.pending.Delete(job.key)
+		close(job.done)
+	}
+}
+
+func (s *ThumbnailService) failedCount() int {
+	n := 0
+	s.failed.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+func (s *ThumbnailService) generate(job *thumbJob) error {
+	rc, err := s.files.GetFileStream(job.ctx, job.sourceKey, job.path)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, thumbMaxFileSize+1))
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	if len(data) > thumbMaxFileSize {
+		return fmt.Errorf("%w: %s is too large", ErrThumbUnsupported, job.path)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrThumbUnsupported, err)
+	}
+	pixels := int64(cfg.Width) * int64(cfg.Height)
+	if cfg.Width <= 0 || cfg.Height <= 0 || pixels > thumbMaxPixels {
+		return fmt.Errorf("%w: image dimensions %dx%d", ErrThumbUnsupported, cfg.Width, cfg.Height)
+	}
+	thumb, err := s.decodeAndResize(job.ctx, data, pixels*bytesPerPixel(cfg.ColorModel), job.size, job.format == ThumbJPEG)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if job.format == ThumbWebP {
+		err = nativewebp.Encode(&buf, thumb, nil)
+	} else {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: thumbQuality})
+	}
+	if err != nil {
+		return err
+	}
+	return s.store(job.key, job.format, buf.Bytes())
+}
+
+// decodeAndResize 在解码内存预算内解码原图并缩放，返回后原图即可回收
+func (s *ThumbnailService) decodeAndResize(ctx context.Context, data []byte, decoded int64, size int, opaque bool) (image.Image, error) {
+	weight := min(decoded, thumbDecodeBudget)
+	if err := s.decoding.Acquire(ctx, weight); err != nil {
+		return nil, err
+	}
+	defer s.decoding.Release(weight)
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrThumbUnsupported, err)
+	}
+	return resize(src, size, opaque), nil
+}
+
+// bytesPerPixel 估算解码后每个像素占用的内存，16 位色深的 PNG/TIFF 解码为 64 位像素
+func bytesPerPixel(m color.Model) int64 {
+	switch m {
+	case color.RGBA64Model, color.NRGBA64Model, color.Gray16Model:
+		return 8
+	}
+	return 4
+}
+
+// resize 等比缩放到 size 以内 (不放大)；opaque 时透明区域铺白底 (JPEG 不支持透明)
+func resize(src image.Image, size int, opaque bool) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if opaque {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+// store 先写临时文件再重命名，读取方不会看到写了一半的缓存
+func (s *ThumbnailService) store(key, format string, data []byte) error {
+	file := s.cacheFile(key, format)
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), ".thumb-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *ThumbnailService) cleanupLoop() {
+	ticker := time.NewTicker(thumbCleanupInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.cleanupExpired()
+	}
+}
+
+// cleanupExpired 删除长时间未访问的缓存，原图修改后旧的缩略图也由此回收
+func (s *ThumbnailService) cleanupExpired() {
+	cutoff := time.Now().Add(-thumbCacheTTL)
+	removed := 0
+	err := filepath.WalkDir(s.cacheDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().Before(cutoff) {
+			if os.Remove(p) == nil {
+				removed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("[Thumb] Failed to clean cache: %v\n", err)
+		return
+	}
+	if removed > 0 {
+		fmt.Printf("[Thumb] Removed %d expired thumbnails\n", removed)
+	}
+}
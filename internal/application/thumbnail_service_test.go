@@ -0,0 +1,120 @@
+package application
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// writePNG 左半透明、右半不透明红色的图片
+func writePNG(t *testing.T, file string, w, h int) {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := w / 2; x < w; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestThumbnailFormats(t *testing.T) {
+	env := newTestEnv(t)
+	if err := os.MkdirAll(env.root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writePNG(t, filepath.Join(env.root, "icon.png"), 600, 300)
+	thumbs, err := NewThumbnailService(env.files, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format      string
+		contentType string
+		transparent bool // 左上角是否保留透明
+	}{
+		{"", "image/jpeg", false},
+		{ThumbJPEG, "image/jpeg", false},
+		{ThumbWebP, "image/webp", true},
+	}
+	for _, tt := range tests {
+		t.Run(cmp.Or(tt.format, "default"), func(t *testing.T) {
+			thumb, err := thumbs.Thumbnail(env.ctx, env.sourceKey, "/icon.png", 128, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if thumb.ContentType != tt.contentType {
+				t.Fatalf("content type = %s, want %s", thumb.ContentType, tt.contentType)
+			}
+			f, err := os.Open(thumb.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			var img image.Image
+			if tt.contentType == "image/webp" {
+				img, err = webp.Decode(f)
+			} else {
+				img, _, err = image.Decode(f)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := img.Bounds(); b.Dx() != 128 || b.Dy() != 64 {
+				t.Fatalf("thumbnail size = %v, want 128x64", b.Size())
+			}
+			_, _, _, a := img.At(0, 0).RGBA()
+			if transparent := a == 0; transparent != tt.transparent {
+				t.Fatalf("top-left alpha = %d, want transparent=%v", a, tt.transparent)
+			}
+		})
+	}
+
+	if _, err := thumbs.Thumbnail(env.ctx, env.sourceKey, "/icon.png", 128, "avif"); !errors.Is(err, ErrThumbFormat) {
+		t.Fatalf("unsupported format = %v, want ErrThumbFormat", err)
+	}
+}
+
+// 超过像素上限的图片在解码前拒绝
+func TestThumbnailPixelLimit(t *testing.T) {
+	env := newTestEnv(t)
+	if err := os.MkdirAll(env.root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// 只有文件头的 PNG，声明的像素数超过 thumbMaxPixels (解压炸弹的文件同样很小)
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], 10000)
+	binary.BigEndian.PutUint32(ihdr[4:], thumbMaxPixels/10000+1)
+	ihdr[8], ihdr[9] = 8, 6 // 8 位 RGBA
+	chunk := append([]byte("IHDR"), ihdr...)
+	data := append([]byte("\x89PNG\r\n\x1a\n"), binary.BigEndian.AppendUint32(nil, uint32(len(ihdr)))...)
+	data = append(data, chunk...)
+	data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+	if err := os.WriteFile(filepath.Join(env.root, "huge.png"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	thumbs, err := NewThumbnailService(env.files, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := thumbs.Thumbnail(env.ctx, env.sourceKey, "/huge.png", 128, ""); !errors.Is(err, ErrThumbUnsupported) {
+		t.Fatalf("huge image = %v, want ErrThumbUnsupported", err)
+	}
+}
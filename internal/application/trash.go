@@ -0,0 +1,368 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	pathpkg "path"
+	"strings"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/config"
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// TrashDir 每个存储源根目录下的隐藏回收站目录，经 HiddenDriver 对用户不可见
+const TrashDir = "/.gofilehub-trash"
+
+// trashCleanupInterval 回收站过期条目清理周期
+const trashCleanupInterval = time.Hour
+
+// ErrTrashItemNotFound 回收站条目不存在或不属于当前用户，归类为 vfs.ErrNotFound
+var ErrTrashItemNotFound = fmt.Errorf("trash item %w", vfs.ErrNotFound)
+
+// isReservedPath 判断虚拟路径是否位于回收站或版本目录
+// 按不区分大小写比较，SMB 等后端上 "/.GoFileHub-Trash" 指向同一个目录
+func isReservedPath(p string) bool {
+	p = pathpkg.Clean("/" + strings.ReplaceAll(p, "\\", "/"))
+	first, _, _ := strings.Cut(p[1:], "/")
+	return strings.EqualFold(first, TrashDir[1:]) || strings.EqualFold(first, VersionDir[1:])
+}
+
+// reservedAccess 去掉 SecureDriver、WatchDriver、SnapshotDriver 与 HiddenDriver，得到可以访问回收站与版本目录的驱动
+// 调用方需要自行检查权限并发出变更通知
+func reservedAccess(driver vfs.StorageDriver) vfs.StorageDriver {
+	for {
+		switch d := driver.(type) {
+		case *vfs.SecureDriver:
+			driver = d.Base()
+		case *vfs.WatchDriver:
+			driver = d.Base()
+		case *vfs.SnapshotDriver:
+			driver = d.Base()
+		case *vfs.HiddenDriver:
+			return d.Base()
+		default:
+			return driver
+		}
+	}
+}
+
+// trashRetention 回收站保留时长：源自身配置优先，否则使用全局默认值；0 表示不自动清理
+func trashRetention(source *model.StorageSource) time.Duration {
+	days := config.AppConfig.TrashDays
+	if source.TrashDays > 0 {
+		days = source.TrashDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// trashDriver 返回存放 sourceKey 回收站内容的存储源及其驱动
+// 配置了统一的回收站存储源时使用它，否则使用存储源自己的隐藏目录
+func (s *FileService) trashDriver(ctx context.Context, sourceKey string) (string, vfs.StorageDriver, error) {
+	if config.AppConfig.TrashSource != "" {
+		sourceKey = config.AppConfig.TrashSource
+	}
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return sourceKey, reservedAccess(driver), nil
+}
+
+// moveToTrash 将文件或目录移入回收站并记录原路径、删除者与时间
+// 权限要求与普通删除一致：对 path 有写权限
+func (s *FileService) moveToTrash(ctx context.Context, source *model.StorageSource, driver vfs.StorageDriver, path string) (*model.TrashItem, error) {
+	return s.trashAs(ctx, source, driver, path, path)
+}
+
+// trashAs 将 path 移入回收站，但记录为 original 被删除 (恢复时回到 original)
+func (s *FileService) trashAs(ctx context.Context, source *model.StorageSource, driver vfs.StorageDriver, path, original string) (*model.TrashItem, error) {
+	p := pathpkg.Clean(cleanPath(path))
+	if p == "/" {
+		return nil, fmt.Errorf("%w: cannot delete root", ErrInvalidOperation)
+	}
+	username, _ := ctx.Value("username").(string)
+	if username == "" {
+		return nil, fmt.Errorf("%w: user context missing", vfs.ErrPermissionDenied)
+	}
+	if !s.permService.CheckPermission(ctx, username, source.ID, p, "write") {
+		return nil, vfs.NewError("delete", p, vfs.ErrPermissionDenied)
+	}
+	// 经过 HiddenDriver 的 Stat，回收站目录本身不能再被删除
+	info, err := driver.Stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	trashKey, trashRaw, err := s.trashDriver(ctx, source.Key)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	item := &model.TrashItem{
+		SourceID:       source.ID,
+		SourceKey:      source.Key,
+		Path:           pathpkg.Clean(cleanPath(original)),
+		Name:           pathpkg.Base(original),
+		IsDir:          info.IsDir,
+		Size:           info.Size,
+		ModTime:        info.ModTime,
+		TrashSourceKey: trashKey,
+		TrashPath:      pathpkg.Join(TrashDir, id),
+		DeletedBy:      username,
+		DeletedAt:      time.Now(),
+	}
+	if err := trashRaw.Mkdir(ctx, TrashDir, 0700); err != nil {
+		return nil, err
+	}
+	raw := reservedAccess(driver)
+	if err := transfer(ctx, raw, p, trashRaw, item.TrashPath, info, trashKey == source.Key); err != nil {
+		return nil, err
+	}
+	if err := s.trashRepo.Save(ctx, item); err != nil {
+		// 没有记录的条目无法恢复，移回原处
+		cleanupCtx := context.WithoutCancel(ctx)
+		if moveErr := transfer(cleanupCtx, trashRaw, item.TrashPath, raw, p, info, trashKey == source.Key); moveErr != nil {
+			fmt.Printf("[Trash] Failed to move %s:%s back after save error: %v\n", source.Key, p, moveErr)
+		}
+		return nil, err
+	}
+	s.notifier(source.Key)(ctx, vfs.ChangeEvent{Op: vfs.ChangeDelete, Path: p})
+	return item, nil
+}
+
+// replacePath 用 tmpPath (同目录下已写好的新内容) 替换已存在的 path，path 可以是文件或目录
+// 旧内容先改名为隐藏的备份，新内容就位后再移入回收站 (存储源未启用回收站时删除)；替换失败时改回原名
+// 在新内容就位之前不会删除任何东西
+func (s *FileService) replacePath(ctx context.Context, source *model.StorageSource, driver vfs.StorageDriver, tmpPath, path string) error {
+	username, _ := ctx.Value("username").(string)
+	if !s.permService.CheckPermission(ctx, username, source.ID, path, "write") {
+		return vfs.NewError("write", path, vfs.ErrPermissionDenied)
+	}
+	// 内部的改名不应触发分享、版本等记录跟随移动，完成后再统一通知
+	raw := reservedAccess(driver)
+	info, err := raw.Stat(ctx, path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir {
+		// 被覆盖的文件先保存历史版本
+		if err := s.snapshotter(source.Key)(ctx, raw, path, info); err != nil {
+			return err
+		}
+	}
+	backup := tempSibling(path, "replaced")
+	if err := raw.Rename(ctx, path, backup); err != nil {
+		return err
+	}
+	if err := raw.Rename(ctx, tmpPath, path); err != nil {
+		if restoreErr := raw.Rename(context.WithoutCancel(ctx), backup, path); restoreErr != nil {
+			fmt.Printf("[Trash] Failed to restore %s:%s from %s: %v\n", source.Key, path, backup, restoreErr)
+		}
+		return err
+	}
+	notify := s.notifier(source.Key)
+	notify(ctx, vfs.ChangeEvent{Op: vfs.ChangeDelete, Path: path})
+	notify(ctx, vfs.ChangeEvent{Op: vfs.ChangeRename, Path: tmpPath, Dest: path})
+
+	// 新内容已经就位，丢弃旧内容失败时保留备份，不影响替换结果
+	cleanupCtx := context.WithoutCancel(ctx)
+	if source.TrashDays < 0 {
+		err = raw.Delete(cleanupCtx, backup)
+	} else {
+		_, err = s.trashAs(cleanupCtx, source, driver, backup, path)
+	}
+	if err != nil {
+		fmt.Printf("[Trash] Failed to discard replaced %s:%s, kept as %s: %v\n", source.Key, path, backup, err)
+	}
+	return nil
+}
+
+// tempSibling 返回 path 同目录下的隐藏临时名，tag 标明用途
+func tempSibling(path, tag string) string {
+	return pathpkg.Join(pathpkg.Dir(path), fmt.Sprintf(".%s.%s-%d", pathpkg.Base(path), tag, time.Now().UnixNano()))
+}
+
+// transfer 同一存储源内直接重命名，跨存储源时先复制再删除
+func transfer(ctx context.Context, srcDriver vfs.StorageDriver, src string, dstDriver vfs.StorageDriver, dst string, info vfs.FileInfo, sameSource bool) error {
+	if sameSource {
+		return srcDriver.Rename(ctx, src, dst)
+	}
+	if err := copyTree(ctx, srcDriver, src, dstDriver, dst, info); err != nil {
+		// 清理复制了一半的内容
+		dstDriver.Delete(context.WithoutCancel(ctx), dst)
+		return err
+	}
+	return srcDriver.Delete(ctx, src)
+}
+
+// OnUserDeleted 将用户删除的回收站条目转给执行删除的管理员 (UserDeleteHook)
+func (s *FileService) OnUserDeleted(ctx context.Context, username, actor string) error {
+	return s.trashRepo.Reassign(ctx, username, actor)
+}
+
+// ListTrash 列出用户删除的条目，管理员 all 为 true 时列出全部；sourceKey 为空时不限存储源
+func (s *FileService) ListTrash(ctx context.Context, username string, sourceKey string, all bool) ([]*model.TrashItem, error) {
+	if all && !s.permService.IsAdmin(ctx, username) {
+		return nil, vfs.NewError("trash", "/", vfs.ErrPermissionDenied)
+	}
+	if all {
+		username = ""
+	}
+	return s.trashRepo.FindByUser(ctx, username, sourceKey)
+}
+
+// RestoreTrash 将条目移回原路径 (缺失的父目录会重新创建)，返回实际恢复到的路径
+// 原路径已被占用时按 conflict 处理：fail (默认) 报错，rename 自动重命名，overwrite 将占用者移入回收站
+func (s *FileService) RestoreTrash(ctx context.Context, username string, id uint, conflict string) (string, error) {
+	if !ValidConflictPolicy(conflict) {
+		return "", fmt.Errorf("%w: invalid conflict policy %q", ErrInvalidOperation, conflict)
+	}
+	item, err := s.trashItem(ctx, username, id)
+	if err != nil {
+		return "", err
+	}
+	source, err := s.sourceRepo.FindByID(ctx, item.SourceID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrSourceNotFound, item.SourceKey)
+	}
+	if !s.permService.CheckPermission(ctx, username, source.ID, item.Path, "write") {
+		return "", vfs.NewError("restore", item.Path, vfs.ErrPermissionDenied)
+	}
+	driver, err := s.GetDriver(ctx, source.Key)
+	if err != nil {
+		return "", err
+	}
+	trashDriver, err := s.GetDriver(ctx, item.TrashSourceKey)
+	if err != nil {
+		return "", err
+	}
+	raw, trashRaw := reservedAccess(driver), reservedAccess(trashDriver)
+	info, err := trashRaw.Stat(ctx, item.TrashPath)
+	if err != nil {
+		return "", err
+	}
+
+	dest := item.Path
+	if _, err := raw.Stat(ctx, dest); err == nil {
+		switch conflict {
+		case ConflictRename:
+			if dest, err = s.availableName(ctx, raw, dest); err != nil {
+				return "", err
+			}
+		case ConflictOverwrite:
+			if _, err := s.moveToTrash(ctx, source, driver, dest); err != nil {
+				return "", err
+			}
+		default:
+			return "", vfs.NewError("restore", dest, vfs.ErrAlreadyExists)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	if err := raw.Mkdir(ctx, pathpkg.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	if err := transfer(ctx, trashRaw, item.TrashPath, raw, dest, info, item.TrashSourceKey == source.Key); err != nil {
+		return "", err
+	}
+	if err := s.trashRepo.Delete(ctx, item.ID); err != nil {
+		return "", err
+	}
+	s.notifier(source.Key)(ctx, vfs.ChangeEvent{Op: vfs.ChangeWrite, Path: dest})
+	return dest, nil
+}
+
+// PurgeTrash 永久删除回收站中的一个条目
+func (s *FileService) PurgeTrash(ctx context.Context, username string, id uint) error {
+	item, err := s.trashItem(ctx, username, id)
+	if err != nil {
+		return err
+	}
+	return s.purge(ctx, item)
+}
+
+// EmptyTrash 永久删除用户的全部条目 (管理员 all 为 true 时删除所有人的)，返回删除的条目数
+// 单个条目失败不影响其他条目，返回最后一个错误
+func (s *FileService) EmptyTrash(ctx context.Context, username string, sourceKey string, all bool) (int, error) {
+	items, err := s.ListTrash(ctx, username, sourceKey, all)
+	if err != nil {
+		return 0, err
+	}
+	var lastErr error
+	purged := 0
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			return purged, err
+		}
+		if err := s.purge(ctx, item); err != nil {
+			lastErr = err
+			continue
+		}
+		purged++
+	}
+	return purged, lastErr
+}
+
+// trashItem 其他用户删除的条目同样返回不存在，管理员可以操作全部条目
+func (s *FileService) trashItem(ctx context.Context, username string, id uint) (*model.TrashItem, error) {
+	item, err := s.trashRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrTrashItemNotFound
+	}
+	if item.DeletedBy != username && !s.permService.IsAdmin(ctx, username) {
+		return nil, ErrTrashItemNotFound
+	}
+	return item, nil
+}
+
+// purge 删除回收站中的内容与记录，内容已不存在时只删除记录
+func (s *FileService) purge(ctx context.Context, item *model.TrashItem) error {
+	driver, err := s.GetDriver(ctx, item.TrashSourceKey)
+	if err != nil {
+		return err
+	}
+	if err := reservedAccess(driver).Delete(ctx, item.TrashPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.trashRepo.Delete(ctx, item.ID)
+}
+
+// trashCleanupLoop 定期永久删除超过保留期限的条目
+func (s *FileService) trashCleanupLoop() {
+	ticker := time.NewTicker(trashCleanupInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.cleanupTrash(context.Background())
+	}
+}
+
+func (s *FileService) cleanupTrash(ctx context.Context) {
+	sources, err := s.sourceRepo.FindAll(ctx)
+	if err != nil {
+		fmt.Printf("[Trash] Failed to query sources: %v\n", err)
+		return
+	}
+	for _, source := range sources {
+		retention := trashRetention(source)
+		if retention <= 0 {
+			continue
+		}
+		items, err := s.trashRepo.FindExpired(ctx, source.ID, time.Now().Add(-retention))
+		if err != nil {
+			fmt.Printf("[Trash] Failed to query expired items for %s: %v\n", source.Key, err)
+			continue
+		}
+		for _, item := range items {
+			if err := s.purge(ctx, item); err != nil {
+				fmt.Printf("[Trash] Failed to purge %s:%s: %v\n", item.SourceKey, item.Path, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package application
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// trashFile 创建文件后删除到回收站，返回回收站条目 ID
+func trashFile(t *testing.T, env *testEnv, path, content string) uint {
+	t.Helper()
+	local := filepath.Join(env.root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.files.Delete(env.ctx, env.sourceKey, path); err != nil {
+		t.Fatal(err)
+	}
+	items, err := env.files.ListTrash(env.ctx, "admin", env.sourceKey, false)
+	if err != nil || len(items) == 0 {
+		t.Fatalf("ListTrash = %d items, %v", len(items), err)
+	}
+	return items[0].ID
+}
+
+func TestRestoreTrashConflicts(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    string
+		err     error
+		content map[string]string // 恢复后目录中各文件的内容
+		trashed int               // 恢复后回收站中的条目数
+	}{
+		{"", "", vfs.ErrAlreadyExists, map[string]string{"a.txt": "new"}, 1},
+		{ConflictFail, "", vfs.ErrAlreadyExists, map[string]string{"a.txt": "new"}, 1},
+		{ConflictRename, "/dir/a (1).txt", nil, map[string]string{"a.txt": "new", "a (1).txt": "old"}, 0},
+		// 占用者移入回收站，仍可恢复
+		{ConflictOverwrite, "/dir/a.txt", nil, map[string]string{"a.txt": "old"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run("policy="+tt.policy, func(t *testing.T) {
+			env := newTestEnv(t)
+			id := trashFile(t, env, "/dir/a.txt", "old")
+			if err := os.WriteFile(filepath.Join(env.root, "dir", "a.txt"), []byte("new"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := env.files.RestoreTrash(env.ctx, "admin", id, tt.policy)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Fatalf("RestoreTrash = %q, %v; want %q, %v", got, err, tt.want, tt.err)
+			}
+			entries, err := os.ReadDir(filepath.Join(env.root, "dir"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.content) {
+				t.Fatalf("dir has %d entries, want %d", len(entries), len(tt.content))
+			}
+			for name, want := range tt.content {
+				data, err := os.ReadFile(filepath.Join(env.root, "dir", name))
+				if err != nil || string(data) != want {
+					t.Fatalf("%s = %q (%v), want %q", name, data, err, want)
+				}
+			}
+			items, err := env.files.ListTrash(env.ctx, "admin", env.sourceKey, false)
+			if err != nil || len(items) != tt.trashed {
+				t.Fatalf("ListTrash = %d items (%v), want %d", len(items), err, tt.trashed)
+			}
+		})
+	}
+}
+
+func TestRestoreTrashRecreatesParent(t *testing.T) {
+	env := newTestEnv(t)
+	id := trashFile(t, env, "/dir/sub/a.txt", "old")
+	if err := os.RemoveAll(filepath.Join(env.root, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := env.files.RestoreTrash(env.ctx, "admin", id, "")
+	if err != nil || got != "/dir/sub/a.txt" {
+		t.Fatalf("RestoreTrash = %q, %v", got, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(env.root, "dir", "sub", "a.txt")); err != nil || string(data) != "old" {
+		t.Fatalf("restored file = %q (%v)", data, err)
+	}
+}
+
+func TestRestoreTrashRejects(t *testing.T) {
+	env := newTestEnv(t)
+	env.addUser(t, "bob", RoleUser)
+	id := trashFile(t, env, "/a.txt", "old")
+
+	if _, err := env.files.RestoreTrash(env.ctx, "admin", id, "skip"); !errors.Is(err, ErrInvalidOperation) {
+		t.Fatalf("invalid policy: %v, want ErrInvalidOperation", err)
+	}
+	// 其他用户删除的条目不可见
+	if _, err := env.files.RestoreTrash(env.ctx, "bob", id, ""); !errors.Is(err, ErrTrashItemNotFound) {
+		t.Fatalf("other user: %v, want ErrTrashItemNotFound", err)
+	}
+}
@@ -0,0 +1,359 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	pathpkg "path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// tus 断点续传相关错误，由 Handler 映射为协议规定的状态码
+var (
+	ErrUploadNotFound    = errors.New("upload not found")
+	ErrOffsetMismatch    = errors.New("upload offset mismatch")
+	ErrChecksumMismatch  = errors.New("checksum mismatch")
+	ErrChecksumAlgorithm = errors.New("unsupported checksum algorithm")
+	ErrUploadLocked      = errors.New("upload is being written by another request")
+	ErrInvalidMetadata   = errors.New("invalid Upload-Metadata")
+)
+
+// uploadExpiry 会话在最后一次写入后保留多久
+const uploadExpiry = 24 * time.Hour
+
+// uploadCleanupInterval 过期会话清理周期
+const uploadCleanupInterval = time.Hour
+
+// TusChecksumAlgorithms 支持的校验算法 (tus checksum 扩展)
+var TusChecksumAlgorithms = []string{"sha1", "md5", "sha256"}
+
+// TusService 实现 tus 1.0 断点续传
+// 分块先写入本地暂存目录 (重启后仍可续传)，全部到达后通过 FileService.Upload 原子提交到目标存储源
+type TusService struct {
+	fileService *FileService
+	uploadRepo  repository.UploadSessionRepository
+	stagingDir  string
+
+	locks sync.Map // map[string]*sync.Mutex，同一会话同时只允许一个 PATCH
+}
+
+func NewTusService(fileService *FileService, repo repository.UploadSessionRepository, stagingDir string) (*TusService, error) {
+	if err := os.MkdirAll(stagingDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create staging dir: %v", err)
+	}
+	s := &TusService{fileService: fileService, uploadRepo: repo, stagingDir: stagingDir}
+	go s.cleanupLoop()
+	return s, nil
+}
+
+// MaxSize 返回存储源允许的最大上传大小，0 表示不限制
+func (s *TusService) MaxSize(ctx context.Context, sourceKey string) (int64, error) {
+	source, err := s.fileService.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	return source.MaxUploadSize, nil
+}
+
+// CreateUpload 创建上传会话，Upload-Length 为 0 时立即提交空文件
+// metadata 是解析后的 Upload-Metadata，必须包含 filename，可选 dir (目标目录) 与 conflict (冲突策略)
+func (s *TusService) CreateUpload(ctx context.Context, username, sourceKey string, length int64, metadata map[string]string, rawMetadata string) (*model.UploadSession, error) {
+	source, err := s.fileService.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	if source.MaxUploadSize > 0 && length > source.MaxUploadSize {
+		return nil, ErrFileTooLarge
+	}
+	name := metadata["filename"]
+	if name == "" || strings.ContainsAny(name, "/\\") || name == "." || name == ".." {
+		return nil, fmt.Errorf("%w: invalid or missing filename", ErrInvalidMetadata)
+	}
+	conflict := metadata["conflict"]
+	if !ValidConflictPolicy(conflict) {
+		return nil, fmt.Errorf("%w: invalid conflict policy %q", ErrInvalidMetadata, conflict)
+	}
+	target := pathpkg.Join("/", metadata["dir"], name)
+
+	// 提前检查写权限，避免用户传完 20GB 才发现没有权限
+	if !s.fileService.permService.CheckPermission(ctx, username, source.ID, target, "write") {
+		return nil, vfs.NewError("upload", target, vfs.ErrPermissionDenied)
+	}
+
+	id, err := newRandomID()
+	if err != nil {
+		return nil, err
+	}
+	session := &model.UploadSession{
+		ID:          id,
+		Username:    username,
+		SourceKey:   sourceKey,
+		Path:        target,
+		Length:      length,
+		Metadata:    rawMetadata,
+		Conflict:    conflict,
+		StagingPath: filepath.Join(s.stagingDir, id+".part"),
+		ExpiresAt:   time.Now().Add(uploadExpiry),
+	}
+	f, err := os.OpenFile(session.StagingPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	if err := s.uploadRepo.Save(ctx, session); err != nil {
+		os.Remove(session.StagingPath)
+		return nil, err
+	}
+	// 空文件不会有 PATCH 请求，创建时直接提交
+	if length == 0 {
+		if err := s.commit(ctx, session); err != nil {
+			s.remove(context.WithoutCancel(ctx), session)
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+// GetUpload 获取会话 (HEAD)，只有创建者可见，且必须通过创建时的存储源访问
+func (s *TusService) GetUpload(ctx context.Context, username, sourceKey, id string) (*model.UploadSession, error) {
+	session, err := s.uploadRepo.FindByID(ctx, id)
+	if err != nil || session.Username != username || session.SourceKey != sourceKey {
+		return nil, ErrUploadNotFound
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, ErrUploadNotFound
+	}
+	return session, nil
+}
+
+// WriteChunk 追加一个分块 (PATCH)
+// offset 必须等于已接收的字节数；checksum 形如 "sha1 <base64>"，为空表示不校验
+// 返回新的偏移量；数据全部到达后自动提交到目标存储源
+func (s *TusService) WriteChunk(ctx context.Context, username, sourceKey, id string, offset int64, body io.Reader, checksum string) (int64, error) {
+	session, lock, err := s.acquire(ctx, username, sourceKey, id)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+	if offset != session.Offset {
+		return session.Offset, ErrOffsetMismatch
+	}
+
+	var hasher hash.Hash
+	var expected []byte
+	if checksum != "" {
+		if hasher, expected, err = parseChecksum(checksum); err != nil {
+			return session.Offset, err
+		}
+	}
+
+	f, err := os.OpenFile(session.StagingPath, os.O_WRONLY, 0600)
+	if err != nil {
+		return session.Offset, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return session.Offset, err
+	}
+
+	// 最多接收到 Upload-Length 为止
+	var w io.Writer = f
+	if hasher != nil {
+		w = io.MultiWriter(f, hasher)
+	}
+	n, copyErr := io.Copy(w, io.LimitReader(body, session.Length-offset))
+
+	if hasher != nil {
+		// 校验失败 (或分块不完整无法校验) 时丢弃整个分块
+		if copyErr != nil || !bytes.Equal(hasher.Sum(nil), expected) {
+			f.Truncate(offset)
+			if copyErr != nil {
+				return session.Offset, copyErr
+			}
+			return session.Offset, ErrChecksumMismatch
+		}
+	}
+	// 未校验的分块中途断开时保留已写入的部分，客户端通过 HEAD 获取偏移后续传
+	if err := f.Sync(); err != nil {
+		return session.Offset, err
+	}
+	session.Offset = offset + n
+	session.ExpiresAt = time.Now().Add(uploadExpiry)
+	if err := s.uploadRepo.UpdateOffset(ctx, id, session.Offset, session.ExpiresAt); err != nil {
+		return offset, err
+	}
+	if copyErr != nil {
+		return session.Offset, copyErr
+	}
+
+	if session.Offset == session.Length {
+		if err := s.commit(ctx, session); err != nil {
+			return session.Offset, err
+		}
+	}
+	return session.Offset, nil
+}
+
+// commit 将暂存文件写入目标存储源，成功后删除会话
+// 提交失败时保留会话，客户端可以发送空 PATCH 重试提交
+func (s *TusService) commit(ctx context.Context, session *model.UploadSession) error {
+	f, err := os.Open(session.StagingPath)
+	if err != nil {
+		return err
+	}
+	_, err = s.fileService.Upload(ctx, session.SourceKey, session.Path, f, session.Length, session.Conflict)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("commit upload failed: %w", err)
+	}
+	s.remove(context.WithoutCancel(ctx), session)
+	return nil
+}
+
+// Terminate 取消上传并删除暂存数据 (tus termination 扩展)
+func (s *TusService) Terminate(ctx context.Context, username, sourceKey, id string) error {
+	session, lock, err := s.acquire(ctx, username, sourceKey, id)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	s.remove(ctx, session)
+	return nil
+}
+
+// OnUserDeleted 取消用户未完成的上传并删除暂存数据 (UserDeleteHook)
+func (s *TusService) OnUserDeleted(ctx context.Context, username, actor string) error {
+	sessions, err := s.uploadRepo.FindByUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		lock := s.lockFor(session.ID)
+		lock.Lock()
+		s.remove(ctx, session)
+		lock.Unlock()
+	}
+	return nil
+}
+
+func (s *TusService) remove(ctx context.Context, session *model.UploadSession) {
+	os.Remove(session.StagingPath)
+	s.uploadRepo.Delete(ctx, session.ID)
+	s.locks.Delete(session.ID)
+}
+
+// acquire 校验会话归属后占用会话锁，返回持锁期间读取的会话
+// 先查询再加锁，不存在的 ID 不会在 locks 中留下条目
+func (s *TusService) acquire(ctx context.Context, username, sourceKey, id string) (*model.UploadSession, *sync.Mutex, error) {
+	if _, err := s.GetUpload(ctx, username, sourceKey, id); err != nil {
+		return nil, nil, err
+	}
+	lock := s.lockFor(id)
+	if !lock.TryLock() {
+		return nil, nil, ErrUploadLocked
+	}
+	// 重新读取：等待期间会话可能已提交或被删除，偏移量也可能已变化
+	session, err := s.GetUpload(ctx, username, sourceKey, id)
+	if err != nil {
+		lock.Unlock()
+		s.locks.CompareAndDelete(id, lock)
+		return nil, nil, err
+	}
+	return session, lock, nil
+}
+
+func (s *TusService) lockFor(id string) *sync.Mutex {
+	lock, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// cleanupLoop 定期删除过期会话及其暂存文件
+func (s *TusService) cleanupLoop() {
+	ticker := time.NewTicker(uploadCleanupInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.cleanupExpired(context.Background())
+	}
+}
+
+func (s *TusService) cleanupExpired(ctx context.Context) {
+	sessions, err := s.uploadRepo.FindExpired(ctx, time.Now())
+	if err != nil {
+		fmt.Printf("[Tus] Failed to query expired uploads: %v\n", err)
+		return
+	}
+	for _, session := range sessions {
+		lock := s.lockFor(session.ID)
+		if !lock.TryLock() {
+			continue
+		}
+		s.remove(ctx, session)
+		lock.Unlock()
+	}
+}
+
+// ParseTusMetadata 解析 Upload-Metadata 头: "key base64value,key2 base64value2"
+func ParseTusMetadata(header string) (map[string]string, error) {
+	meta := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, ErrInvalidMetadata
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, ErrInvalidMetadata
+		}
+		meta[key] = string(value)
+	}
+	return meta, nil
+}
+
+// parseChecksum 解析 Upload-Checksum 头: "<algorithm> <base64 digest>"
+func parseChecksum(header string) (hash.Hash, []byte, error) {
+	algo, encoded, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok {
+		return nil, nil, ErrChecksumAlgorithm
+	}
+	expected, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, ErrChecksumMismatch
+	}
+	switch algo {
+	case "sha1":
+		return sha1.New(), expected, nil
+	case "md5":
+		return md5.New(), expected, nil
+	case "sha256":
+		return sha256.New(), expected, nil
+	}
+	return nil, nil, ErrChecksumAlgorithm
+}
+
+func newRandomID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,155 @@
+package application
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+)
+
+func newTestTus(t *testing.T) (*testEnv, *TusService) {
+	t.Helper()
+	env := newTestEnv(t)
+	tus, err := NewTusService(env.files, persistence.NewUploadSessionRepository(env.db), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env, tus
+}
+
+func sha1Checksum(data string) string {
+	sum := sha1.Sum([]byte(data))
+	return "sha1 " + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestTusWriteChunk(t *testing.T) {
+	tests := []struct {
+		name     string
+		offset   int64
+		body     string
+		checksum string
+		err      error
+		want     int64 // 写入后的偏移量
+	}{
+		{"append", 3, "def", "", nil, 6},
+		{"offset behind", 0, "abc", "", ErrOffsetMismatch, 3},
+		{"offset ahead", 5, "f", "", ErrOffsetMismatch, 3},
+		{"checksum ok", 3, "def", sha1Checksum("def"), nil, 6},
+		{"checksum mismatch", 3, "def", sha1Checksum("xyz"), ErrChecksumMismatch, 3},
+		{"unknown algorithm", 3, "def", "crc32 AAAA", ErrChecksumAlgorithm, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, tus := newTestTus(t)
+			session, err := tus.CreateUpload(env.ctx, "admin", env.sourceKey, 10, map[string]string{"filename": "a.txt"}, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tus.WriteChunk(env.ctx, "admin", env.sourceKey, session.ID, 0, strings.NewReader("abc"), ""); err != nil {
+				t.Fatal(err)
+			}
+
+			offset, err := tus.WriteChunk(env.ctx, "admin", env.sourceKey, session.ID, tt.offset, strings.NewReader(tt.body), tt.checksum)
+			if !errors.Is(err, tt.err) || offset != tt.want {
+				t.Fatalf("WriteChunk = %d, %v; want %d, %v", offset, err, tt.want, tt.err)
+			}
+			// 失败的分块不会留在暂存文件中
+			staged, err := os.Stat(session.StagingPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if staged.Size() != tt.want {
+				t.Fatalf("staging file has %d bytes, want %d", staged.Size(), tt.want)
+			}
+			got, err := tus.GetUpload(env.ctx, "admin", env.sourceKey, session.ID)
+			if err != nil || got.Offset != tt.want {
+				t.Fatalf("GetUpload offset = %v (%v), want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTusCommit(t *testing.T) {
+	env, tus := newTestTus(t)
+	session, err := tus.CreateUpload(env.ctx, "admin", env.sourceKey, 6, map[string]string{"filename": "a.txt", "dir": "/sub"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, err := tus.WriteChunk(env.ctx, "admin", env.sourceKey, session.ID, 0, strings.NewReader("abc"), sha1Checksum("abc")); err != nil || offset != 3 {
+		t.Fatalf("WriteChunk = %d, %v; want 3", offset, err)
+	}
+	// 超出 Upload-Length 的数据被忽略
+	if offset, err := tus.WriteChunk(env.ctx, "admin", env.sourceKey, session.ID, 3, strings.NewReader("defghi"), ""); err != nil || offset != 6 {
+		t.Fatalf("WriteChunk = %d, %v; want 6", offset, err)
+	}
+	got, err := os.ReadFile(filepath.Join(env.root, "sub", "a.txt"))
+	if err != nil || string(got) != "abcdef" {
+		t.Fatalf("committed file = %q (%v), want abcdef", got, err)
+	}
+	// 提交后会话与暂存文件一并删除
+	if _, err := tus.GetUpload(env.ctx, "admin", env.sourceKey, session.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Fatalf("GetUpload after commit = %v, want ErrUploadNotFound", err)
+	}
+	if _, err := os.Stat(session.StagingPath); !os.IsNotExist(err) {
+		t.Fatalf("staging file still exists: %v", err)
+	}
+}
+
+func TestTusZeroLengthCommitsOnCreate(t *testing.T) {
+	env, tus := newTestTus(t)
+	session, err := tus.CreateUpload(env.ctx, "admin", env.sourceKey, 0, map[string]string{"filename": "empty.txt"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(env.root, "empty.txt"))
+	if err != nil || info.Size() != 0 {
+		t.Fatalf("empty file not committed: %v", err)
+	}
+	if _, err := tus.GetUpload(env.ctx, "admin", env.sourceKey, session.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Fatalf("session still exists after commit: %v", err)
+	}
+}
+
+func TestTusSessionAccess(t *testing.T) {
+	env, tus := newTestTus(t)
+	env.addUser(t, "bob", RoleUser)
+	session, err := tus.CreateUpload(env.ctx, "admin", env.sourceKey, 10, map[string]string{"filename": "a.txt"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name                 string
+		username, source, id string
+	}{
+		{"other user", "bob", env.sourceKey, session.ID},
+		{"other source", "admin", "other", session.ID},
+		{"unknown id", "admin", env.sourceKey, "missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tus.WriteChunk(env.ctx, tt.username, tt.source, tt.id, 0, strings.NewReader("abc"), ""); !errors.Is(err, ErrUploadNotFound) {
+				t.Fatalf("WriteChunk = %v, want ErrUploadNotFound", err)
+			}
+			if err := tus.Terminate(env.ctx, tt.username, tt.source, tt.id); !errors.Is(err, ErrUploadNotFound) {
+				t.Fatalf("Terminate = %v, want ErrUploadNotFound", err)
+			}
+		})
+	}
+	// 被拒绝的请求不会在 locks 中留下条目
+	tus.locks.Range(func(key, _ any) bool {
+		t.Errorf("lock left for %v", key)
+		return true
+	})
+
+	if err := tus.Terminate(env.ctx, "admin", env.sourceKey, session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(session.StagingPath); !os.IsNotExist(err) {
+		t.Fatalf("staging file still exists after Terminate: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	pathpkg "path"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// ErrFileTooLarge 上传文件超过存储源的大小限制
+var ErrFileTooLarge = errors.New("file exceeds upload size limit")
+
+// 同名文件冲突策略
+const (
+	ConflictOverwrite = "overwrite" // 覆盖 (默认)
+	ConflictFail      = "fail"      // 返回错误
+	ConflictRename    = "rename"    // 自动重命名为 "name (1).ext"
+)
+
+// maxRenameAttempts 自动重命名时最多尝试的序号
+const maxRenameAttempts = 1000
+
+// UploadResult 单个文件的上传结果
+type UploadResult struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`            // 实际写入的路径 (自动重命名后可能与请求不同)
+	Size   int64  `json:"size"`            // 实际写入的字节数
+	Status string `json:"status"`          // created, overwritten, renamed, failed
+	Error  string `json:"error,omitempty"` // 失败原因
+}
+
+// ValidConflictPolicy 检查冲突策略是否合法，空字符串视为默认值
+func ValidConflictPolicy(policy string) bool {
+	switch policy {
+	case "", ConflictOverwrite, ConflictFail, ConflictRename:
+		return true
+	}
+	return false
+}
+
+// Upload 将数据流写入存储源，全程流式处理，不在内存中缓存文件内容
+// size 为 -1 表示大小未知 (例如 multipart 中的单个文件)
+func (s *FileService) Upload(ctx context.Context, sourceKey string, path string, reader io.Reader, size int64, policy string) (UploadResult, error) {
+	result := UploadResult{Name: pathpkg.Base(path), Path: path}
+	source, err := s.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return result, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	limit := source.MaxUploadSize
+	if limit > 0 && size > limit {
+		return result, ErrFileTooLarge
+	}
+
+	driver, err := s.GetDriver(ctx, sourceKey)
+	if err != nil {
+		return result, err
+	}
+
+	// 处理同名冲突
+	status := "created"
+	if info, err := driver.Stat(ctx, path); err == nil {
+		switch {
+		case policy == ConflictRename:
+			if path, err = s.availableName(ctx, driver, path); err != nil {
+				return result, err
+			}
+			result.Path, result.Name = path, pathpkg.Base(path)
+			status = "renamed"
+		// 文件不能覆盖目录
+		case policy == ConflictFail || info.IsDir:
+			return result, vfs.NewError("upload", path, vfs.ErrAlreadyExists)
+		default:
+			status = "overwritten"
+		}
+	}
+
+	counter := &countingReader{r: reader}
+	var r io.Reader = counter
+	if limit > 0 {
+		r = &limitedReader{r: counter, remaining: limit}
+	}
+	// 先写入同目录下的临时文件，成功后再改名 (覆盖时旧文件移入回收站)，避免上传中断或超限时破坏已有文件
+	tmpPath := tempSibling(path, "upload")
+	cleanupCtx := context.WithoutCancel(ctx)
+	if err := driver.Create(ctx, tmpPath, r, size); err != nil {
+		_ = driver.Delete(cleanupCtx, tmpPath)
+		return result, err
+	}
+	if status == "overwritten" {
+		err = s.replacePath(ctx, source, driver, tmpPath, path)
+	} else {
+		err = driver.Rename(ctx, tmpPath, path)
+	}
+	if err != nil {
+		_ = driver.Delete(cleanupCtx, tmpPath)
+		return result, err
+	}
+	result.Size = counter.n
+	result.Status = status
+	return result, nil
+}
+
+// availableName 为冲突的文件生成 "name (1).ext" 形式的新名字
+func (s *FileService) availableName(ctx context.Context, driver vfs.StorageDriver, path string) (string, error) {
+	dir, name := pathpkg.Split(path)
+	base, ext := splitExt(name)
+	for i := 1; i <= maxRenameAttempts; i++ {
+		candidate := dir + fmt.Sprintf("%s (%d)%s", base, i, ext)
+		if _, err := driver.Stat(ctx, candidate); errors.Is(err, os.ErrNotExist) {
+			return candidate, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no available name for %s", path)
+}
+
+// countingReader 统计实际读取的字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// limitedReader 超过上限时返回 ErrFileTooLarge (io.LimitReader 只会静默截断)
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrFileTooLarge
+	}
+	// 多读一个字节，用于判断是否超限
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrFileTooLarge
+	}
+	return n, err
+}
+
+// splitExt 拆分文件名与扩展名，"a.tar.gz" 视为 "a.tar" + ".gz"
+func splitExt(name string) (string, string) {
+	ext := pathpkg.Ext(name)
+	if ext == name || strings.HasPrefix(name, ".") && strings.Count(name, ".") == 1 {
+		return name, ""
+	}
+	return strings.TrimSuffix(name, ext), ext
+}
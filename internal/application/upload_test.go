@@ -0,0 +1,183 @@
+package application
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+func TestUploadConflictPolicies(t *testing.T) {
+	tests := []struct {
+		policy   string
+		wantPath string
+		status   string
+		err      error
+		content  map[string]string // 上传后各文件的内容
+	}{
+		{ConflictFail, "", "", vfs.ErrAlreadyExists, map[string]string{"a.txt": "old"}},
+		{ConflictRename, "/dir/a (1).txt", "renamed", nil, map[string]string{"a.txt": "old", "a (1).txt": "new"}},
+		{ConflictOverwrite, "/dir/a.txt", "overwritten", nil, map[string]string{"a.txt": "new"}},
+		{"", "/dir/a.txt", "overwritten", nil, map[string]string{"a.txt": "new"}},
+	}
+	for _, tt := range tests {
+		t.Run("policy="+tt.policy, func(t *testing.T) {
+			env := newTestEnv(t)
+			if err := os.MkdirAll(filepath.Join(env.root, "dir"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(env.root, "dir", "a.txt"), []byte("old"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := env.files.Upload(env.ctx, env.sourceKey, "/dir/a.txt", strings.NewReader("new"), 3, tt.policy)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Upload error = %v, want %v", err, tt.err)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Upload: %v", err)
+				}
+				if result.Path != tt.wantPath || result.Status != tt.status || result.Size != 3 {
+					t.Fatalf("Upload = %+v, want path %s status %s size 3", result, tt.wantPath, tt.status)
+				}
+			}
+
+			entries, err := os.ReadDir(filepath.Join(env.root, "dir"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.content) {
+				t.Fatalf("dir has %d entries, want %d (temporary file left behind?)", len(entries), len(tt.content))
+			}
+			for name, want := range tt.content {
+				got, err := os.ReadFile(filepath.Join(env.root, "dir", name))
+				if err != nil || string(got) != want {
+					t.Fatalf("%s = %q (%v), want %q", name, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadOverwriteMovesOldFileToTrash(t *testing.T) {
+	env := newTestEnv(t)
+	if err := os.MkdirAll(env.root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(env.root, "a.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.files.Upload(env.ctx, env.sourceKey, "/a.txt", strings.NewReader("new"), 3, ConflictOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	items, err := env.files.ListTrash(env.ctx, "admin", env.sourceKey, false)
+	if err != nil || len(items) != 1 || items[0].Path != "/a.txt" {
+		t.Fatalf("ListTrash = %v, %v; want the old /a.txt", items, err)
+	}
+	if _, err := env.files.RestoreTrash(env.ctx, "admin", items[0].ID, ConflictRename); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(env.root, "a (1).txt")); err != nil || string(data) != "old" {
+		t.Fatalf("restored file = %q (%v), want old", data, err)
+	}
+}
+
+func TestUploadOverwriteWithoutTrash(t *testing.T) {
+	env := newTestEnv(t)
+	env.source.TrashDays = -1
+	if err := env.files.sourceRepo.Save(env.ctx, env.source); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(env.root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(env.root, "a.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.files.Upload(env.ctx, env.sourceKey, "/a.txt", strings.NewReader("new"), 3, ConflictOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	// 旧文件的备份在替换成功后删除
+	entries, _ := os.ReadDir(env.root)
+	if len(entries) != 1 {
+		t.Fatalf("root has %d entries, want only a.txt", len(entries))
+	}
+}
+
+// 上传到已存在的目录不能删除该目录
+func TestUploadOntoDirectory(t *testing.T) {
+	for _, policy := range []string{"", ConflictOverwrite, ConflictFail} {
+		t.Run("policy="+policy, func(t *testing.T) {
+			env := newTestEnv(t)
+			if err := os.MkdirAll(filepath.Join(env.root, "dir", "sub"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(env.root, "dir", "sub", "keep.txt"), []byte("keep"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := env.files.Upload(env.ctx, env.sourceKey, "/dir", strings.NewReader("new"), 3, policy)
+			if !errors.Is(err, vfs.ErrAlreadyExists) {
+				t.Fatalf("Upload onto directory = %v, want ErrAlreadyExists", err)
+			}
+			if data, err := os.ReadFile(filepath.Join(env.root, "dir", "sub", "keep.txt")); err != nil || string(data) != "keep" {
+				t.Fatalf("directory content lost: %q, %v", data, err)
+			}
+			entries, _ := os.ReadDir(env.root)
+			if len(entries) != 1 {
+				t.Fatalf("root has %d entries after rejected upload", len(entries))
+			}
+		})
+	}
+
+	env := newTestEnv(t)
+	if err := os.MkdirAll(filepath.Join(env.root, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	result, err := env.files.Upload(env.ctx, env.sourceKey, "/dir", strings.NewReader("new"), 3, ConflictRename)
+	if err != nil || result.Path != "/dir (1)" {
+		t.Fatalf("Upload with rename = %+v, %v; want /dir (1)", result, err)
+	}
+}
+
+func TestUploadRenameSkipsTakenNames(t *testing.T) {
+	env := newTestEnv(t)
+	if err := os.MkdirAll(env.root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "a (1).txt"} {
+		if err := os.WriteFile(filepath.Join(env.root, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := env.files.Upload(env.ctx, env.sourceKey, "/a.txt", strings.NewReader("x"), -1, ConflictRename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Path != "/a (2).txt" {
+		t.Fatalf("Upload path = %s, want /a (2).txt", result.Path)
+	}
+}
+
+func TestUploadSizeLimit(t *testing.T) {
+	env := newTestEnv(t)
+	env.source.MaxUploadSize = 4
+	if err := env.files.sourceRepo.Save(env.ctx, env.source); err != nil {
+		t.Fatal(err)
+	}
+	// 声明的大小超限时直接拒绝，未知大小时写入超限后拒绝且不留下临时文件
+	for _, size := range []int64{5, -1} {
+		_, err := env.files.Upload(env.ctx, env.sourceKey, "/big.bin", strings.NewReader("12345"), size, "")
+		if !errors.Is(err, ErrFileTooLarge) {
+			t.Fatalf("size %d: error = %v, want ErrFileTooLarge", size, err)
+		}
+	}
+	entries, _ := os.ReadDir(env.root)
+	if len(entries) != 0 {
+		t.Fatalf("root has %d entries after rejected uploads", len(entries))
+	}
+}
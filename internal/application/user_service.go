@@ -0,0 +1,330 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength 新密码的最小长度
+const minPasswordLength = 8
+
+// 用户角色
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// usernamePattern 用户名会出现在 WebDAV Basic 认证与回收站、分享等记录中，不允许空格与冒号
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]{1,64}$`)
+
+var (
+	// ErrUserNotFound 用户不存在，归类为 vfs.ErrNotFound
+	ErrUserNotFound = fmt.Errorf("user %w", vfs.ErrNotFound)
+	// ErrUserDisabled 用户已停用
+	ErrUserDisabled = errors.New("account is disabled")
+	// ErrWrongPassword 修改密码时当前密码不正确
+	ErrWrongPassword = errors.New("current password is incorrect")
+)
+
+// UserInput 创建用户的参数
+type UserInput struct {
+	Username    string
+	Password    string
+	Role        string
+	DisplayName string
+	Email       string
+}
+
+// UserUpdate 修改用户的参数，nil 表示不修改
+type UserUpdate struct {
+	Password    *string // 管理员重置密码
+	Role        *string
+	DisplayName *string
+	Email       *string
+}
+
+// cacheGen 记录每个用户的缓存被清除的次数，由 cacheMu 保护
+// 缓存未命中时先记下代数再读数据库，写回缓存时代数已变化说明读取期间发生了修改，
+// 读到的可能是修改前的数据，不能写入缓存 (见 cacheStore)
+var (
+	cacheMu  sync.Mutex
+	cacheGen = map[string]uint64{}
+)
+
+// invalidateUser 用户信息变化后清除缓存，下一次鉴权与权限检查重新读取数据库
+func invalidateUser(username string) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cacheGen[username]++
+	userCache.Delete(username)
+	prefix := username + "_"
+	permissionCache.Range(func(key, _ any) bool {
+		if strings.HasPrefix(key.(string), prefix) {
+			permissionCache.Delete(key)
+		}
+		return true
+	})
+}
+
+// userGeneration 返回用户缓存的当前代数，在读取数据库之前调用
+func userGeneration(username string) uint64 {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	return cacheGen[username]
+}
+
+// cacheStore 用户缓存的代数仍为 gen 时写入 cache 并返回缓存中的值，否则不写入，直接返回 value
+func cacheStore(cache *sync.Map, key, username string, gen uint64, value any) any {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	if cacheGen[username] != gen {
+		return value
+	}
+	actual, _ := cache.LoadOrStore(key, value)
+	return actual
+}
+
+func validatePassword(password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("%w: password must be at least %d characters", ErrInvalidOperation, minPasswordLength)
+	}
+	return nil
+}
+
+func validateRole(role string) error {
+	if role != RoleAdmin && role != RoleUser {
+		return fmt.Errorf("%w: role must be %q or %q", ErrInvalidOperation, RoleAdmin, RoleUser)
+	}
+	return nil
+}
+
+// applyProfile 检查并写入显示名称与邮箱
+func applyProfile(user *model.User, displayName, email *string) error {
+	if displayName != nil {
+		name := strings.TrimSpace(*displayName)
+		if len(name) > 64 {
+			return fmt.Errorf("%w: display name must be at most 64 bytes", ErrInvalidOperation)
+		}
+		user.DisplayName = name
+	}
+	if email != nil {
+		addr := strings.TrimSpace(*email)
+		if addr != "" {
+			if parsed, err := mail.ParseAddress(addr); err != nil || parsed.Address != addr || len(addr) > 255 {
+				return fmt.Errorf("%w: invalid email address", ErrInvalidOperation)
+			}
+		}
+		user.Email = addr
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// ListUsers 列出全部用户 (仅管理员)
+func (s *AuthService) ListUsers(ctx context.Context) ([]*model.User, error) {
+	return s.userRepo.FindAll(ctx)
+}
+
+// CreateUser 创建启用状态的用户，角色默认为普通用户
+func (s *AuthService) CreateUser(ctx context.Context, input UserInput) (*model.User, error) {
+	if !usernamePattern.MatchString(input.Username) {
+		return nil, fmt.Errorf("%w: username must be 1-64 letters, digits or '_', '.', '@', '-'", ErrInvalidOperation)
+	}
+	if input.Role == "" {
+		input.Role = RoleUser
+	}
+	if err := validateRole(input.Role); err != nil {
+		return nil, err
+	}
+	if err := validatePassword(input.Password); err != nil {
+		return nil, err
+	}
+	if _, err := s.userRepo.FindByUsername(ctx, input.Username); err == nil {
+		return nil, fmt.Errorf("%w: user %s", vfs.ErrAlreadyExists, input.Username)
+	}
+	user := &model.User{Username: input.Username, Role: input.Role, IsActive: true}
+	if err := applyProfile(user, &input.DisplayName, &input.Email); err != nil {
+		return nil, err
+	}
+	hashed, err := hashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = hashed
+	if err := s.userRepo.Save(ctx, user); err != nil {
+		return nil, err
+	}
+	// 同名用户被删除前可能留下了缓存
+	invalidateUser(user.Username)
+	return user, nil
+}
+
+// UpdateUser 管理员修改用户的角色、资料或重置密码；重置密码后该用户已签发的 Token 失效
+// 不能取消最后一个启用的管理员的管理员角色
+func (s *AuthService) UpdateUser(ctx context.Context, username string, update UserUpdate) (*model.User, error) {
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if update.Role != nil && *update.Role != user.Role {
+		if err := validateRole(*update.Role); err != nil {
+			return nil, err
+		}
+		if user.Role == RoleAdmin {
+			if err := s.keepAdmin(ctx, user); err != nil {
+				return nil, err
+			}
+		}
+		user.Role = *update.Role
+	}
+	if err := applyProfile(user, update.DisplayName, update.Email); err != nil {
+		return nil, err
+	}
+	if update.Password != nil {
+		if err := validatePassword(*update.Password); err != nil {
+			return nil, err
+		}
+		if user.PasswordHash, err = hashPassword(*update.Password); err != nil {
+			return nil, err
+		}
+		user.TokenVersion++
+	}
+	if err := s.userRepo.Save(ctx, user); err != nil {
+		return nil, err
+	}
+	invalidateUser(username)
+	return user, nil
+}
+
+// SetActive 停用或重新启用用户；停用立即生效，已签发的 Token 不能再使用
+// actor 为执行操作的管理员，不能停用自己
+func (s *AuthService) SetActive(ctx context.Context, actor, username string, active bool) (*model.User, error) {
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.IsActive == active {
+		return user, nil
+	}
+	if !active {
+		if username == actor {
+			return nil, fmt.Errorf("%w: cannot deactivate yourself", ErrInvalidOperation)
+		}
+		if user.Role == RoleAdmin {
+			if err := s.keepAdmin(ctx, user); err != nil {
+				return nil, err
+			}
+		}
+	}
+	user.IsActive = active
+	if err := s.userRepo.Save(ctx, user); err != nil {
+		return nil, err
+	}
+	invalidateUser(username)
+	return user, nil
+}
+
+// DeleteUser 删除用户及其权限规则，并通过 OnDelete 回调清理分享、文件收集请求等以用户名关联的记录，
+// 其回收站条目转给执行删除的管理员，同名的新用户不会继承这些记录
+// actor 为执行操作的管理员，不能删除自己
+func (s *AuthService) DeleteUser(ctx context.Context, actor, username string) error {
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if username == actor {
+		return fmt.Errorf("%w: cannot delete yourself", ErrInvalidOperation)
+	}
+	if user.Role == RoleAdmin && user.IsActive {
+		if err := s.keepAdmin(ctx, user); err != nil {
+			return err
+		}
+	}
+	s.hookMu.RLock()
+	hooks := s.deleteHooks
+	s.hookMu.RUnlock()
+	for _, h := range hooks {
+		if err := h(ctx, username, actor); err != nil {
+			return err
+		}
+	}
+	if err := s.permRepo.DeleteByUser(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.userRepo.Delete(ctx, user.ID); err != nil {
+		return err
+	}
+	invalidateUser(username)
+	return nil
+}
+
+// keepAdmin user 失去管理员身份后必须还有其他启用的管理员
+func (s *AuthService) keepAdmin(ctx context.Context, user *model.User) error {
+	users, err := s.userRepo.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if u.ID != user.ID && u.Role == RoleAdmin && u.IsActive {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: at least one active admin is required", ErrInvalidOperation)
+}
+
+// ChangePassword 用户修改自己的密码，需要提供当前密码
+// 之前签发的 Token (包括其他设备上的登录) 全部失效，返回替换当前登录的新 Token
+func (s *AuthService) ChangePassword(ctx context.Context, username, current, password string) (string, error) {
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)); err != nil {
+		return "", ErrWrongPassword
+	}
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	if user.PasswordHash, err = hashPassword(password); err != nil {
+		return "", err
+	}
+	user.TokenVersion++
+	if err := s.userRepo.Save(ctx, user); err != nil {
+		return "", err
+	}
+	invalidateUser(username)
+	return issueToken(user)
+}
+
+// UpdateProfile 用户修改自己的显示名称与邮箱，nil 表示不修改
+func (s *AuthService) UpdateProfile(ctx context.Context, username string, displayName, email *string) (*model.User, error) {
+	user, err := s.userRepo.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if err := applyProfile(user, displayName, email); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Save(ctx, user); err != nil {
+		return nil, err
+	}
+	invalidateUser(username)
+	return user, nil
+}
@@ -0,0 +1,365 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	pathpkg "path"
+	"strings"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/config"
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/diff"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/extract"
+)
+
+// VersionDir 每个存储源根目录下的隐藏版本目录，经 HiddenDriver 对用户不可见
+const VersionDir = "/.gofilehub-versions"
+
+// versionCleanupInterval 过期版本清理周期
+const versionCleanupInterval = time.Hour
+
+// MaxDiffBytes 参与比较的文本文件大小上限
+const MaxDiffBytes = 1 << 20
+
+// diffContext unified diff 中每处修改前后保留的行数
+const diffContext = 3
+
+// ErrVersionNotFound 历史版本不存在，归类为 vfs.ErrNotFound
+var ErrVersionNotFound = fmt.Errorf("file version %w", vfs.ErrNotFound)
+
+// VersionService 文件历史版本
+// 对配置了 VersionPaths 的存储源，已有文件被覆盖 (REST 上传、tus、WebDAV PUT 等) 前保存旧内容
+type VersionService struct {
+	files *FileService
+	repo  repository.FileVersionRepository
+}
+
+// VersionDiff 两个文本版本的逐行差异
+type VersionDiff struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Diff    string `json:"diff"` // unified 格式，没有差异时为空
+}
+
+func NewVersionService(files *FileService, repo repository.FileVersionRepository) *VersionService {
+	s := &VersionService{files: files, repo: repo}
+	files.OnOverwrite(s.snapshot)
+	files.OnChange(s.onChange)
+	go s.cleanupLoop()
+	return s
+}
+
+// versioned 路径是否位于存储源配置的版本目录中
+func versioned(source *model.StorageSource, path string) bool {
+	for _, prefix := range strings.Split(source.VersionPaths, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		prefix = pathpkg.Clean("/" + prefix)
+		if prefix == "/" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// versionKeep 每个文件最多保留的版本数：源自身配置优先，否则使用全局默认值；0 表示不限制
+func versionKeep(source *model.StorageSource) int {
+	if source.VersionKeep > 0 {
+		return source.VersionKeep
+	}
+	return config.AppConfig.VersionKeep
+}
+
+// versionRetention 版本保留时长：源自身配置优先，否则使用全局默认值；0 表示不限制
+func versionRetention(source *model.StorageSource) time.Duration {
+	days := config.AppConfig.VersionDays
+	if source.VersionDays > 0 {
+		days = source.VersionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// snapshot 覆盖前将旧内容复制到版本目录
+// 旧内容的作者是上一次覆盖它的用户 (即最新版本的 ReplacedBy)
+func (s *VersionService) snapshot(ctx context.Context, sourceKey string, driver vfs.StorageDriver, path string, info vfs.FileInfo) error {
+	source, err := s.files.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return nil
+	}
+	p := pathpkg.Clean(cleanPath(path))
+	if !versioned(source, p) {
+		return nil
+	}
+	raw := reservedAccess(driver)
+	if err := raw.Mkdir(ctx, VersionDir, 0700); err != nil {
+		return err
+	}
+	id, err := newRandomID()
+	if err != nil {
+		return err
+	}
+	version := &model.FileVersion{
+		SourceID:  source.ID,
+		SourceKey: source.Key,
+		Path:      p,
+		Size:      info.Size,
+		ModTime:   info.ModTime,
+		StorePath: pathpkg.Join(VersionDir, id),
+	}
+	version.ReplacedBy, _ = ctx.Value("username").(string)
+	if previous, err := s.repo.FindByPath(ctx, source.ID, p); err == nil && len(previous) > 0 {
+		version.Author = previous[0].ReplacedBy
+	}
+
+	rc, err := driver.Open(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := raw.Create(ctx, version.StorePath, rc, info.Size); err != nil {
+		raw.Delete(context.WithoutCancel(ctx), version.StorePath)
+		return fmt.Errorf("failed to save previous version: %w", err)
+	}
+	if err := s.repo.Save(ctx, version); err != nil {
+		raw.Delete(context.WithoutCancel(ctx), version.StorePath)
+		return err
+	}
+	s.prune(ctx, source, p)
+	return nil
+}
+
+// prune 超出数量上限时删除最旧的版本
+func (s *VersionService) prune(ctx context.Context, source *model.StorageSource, path string) {
+	keep := versionKeep(source)
+	if keep <= 0 {
+		return
+	}
+	versions, err := s.repo.FindByPath(ctx, source.ID, path)
+	if err != nil || len(versions) <= keep {
+		return
+	}
+	for _, v := range versions[keep:] {
+		if err := s.remove(ctx, v); err != nil {
+			fmt.Printf("[Version] Failed to prune %s:%s version %d: %v\n", v.SourceKey, v.Path, v.ID, err)
+		}
+	}
+}
+
+// List 按时间倒序返回文件的历史版本，需要对文件有读权限
+func (s *VersionService) List(ctx context.Context, username, sourceKey, path string) ([]*model.FileVersion, error) {
+	source, err := s.files.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrSourceNotFound, sourceKey)
+	}
+	p := pathpkg.Clean(cleanPath(path))
+	if !s.files.permService.CheckPermission(ctx, username, source.ID, p, "read") {
+		return nil, vfs.NewError("versions", p, vfs.ErrPermissionDenied)
+	}
+	return s.repo.FindByPath(ctx, source.ID, p)
+}
+
+// Open 读取一个历史版本的内容，需要对文件有读权限
+func (s *VersionService) Open(ctx context.Context, username string, id uint) (*model.FileVersion, io.ReadCloser, error) {
+	version, err := s.version(ctx, username, id, "read")
+	if err != nil {
+		return nil, nil, err
+	}
+	driver, err := s.files.GetDriver(ctx, version.SourceKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := reservedAccess(driver).Open(ctx, version.StorePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return version, rc, nil
+}
+
+// Restore 用历史版本覆盖当前文件，当前内容同样会保存为一个新版本，因此恢复本身可以撤销
+// 原路径已被目录占用时返回 vfs.ErrAlreadyExists
+func (s *VersionService) Restore(ctx context.Context, username string, id uint) (UploadResult, error) {
+	version, err := s.version(ctx, username, id, "write")
+	if err != nil {
+		return UploadResult{}, err
+	}
+	driver, err := s.files.GetDriver(ctx, version.SourceKey)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	// 原路径现在是目录时不能覆盖
+	if info, err := driver.Stat(ctx, version.Path); err == nil && info.IsDir {
+		return UploadResult{}, vfs.NewError("restore", version.Path, vfs.ErrAlreadyExists)
+	}
+	rc, err := reservedAccess(driver).Open(ctx, version.StorePath)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer rc.Close()
+	result, err := s.files.Upload(ctx, version.SourceKey, version.Path, rc, version.Size, ConflictOverwrite)
+	if err != nil {
+		return result, err
+	}
+	// 恢复后的内容保留原来的修改时间，失败不影响恢复结果
+	driver.SetModTime(ctx, result.Path, version.ModTime)
+	return result, nil
+}
+
+// Diff 比较两个文本版本，against 为 0 时与当前文件比较；较旧的一方作为 From
+func (s *VersionService) Diff(ctx context.Context, username string, id, against uint) (*VersionDiff, error) {
+	version, err := s.version(ctx, username, id, "read")
+	if err != nil {
+		return nil, err
+	}
+	driver, err := s.files.GetDriver(ctx, version.SourceKey)
+	if err != nil {
+		return nil, err
+	}
+	raw := reservedAccess(driver)
+	from, err := readText(ctx, raw, version.StorePath)
+	if err != nil {
+		return nil, err
+	}
+	fromLabel := versionLabel(version.Path, version.ModTime)
+
+	var to, toLabel string
+	if against == 0 {
+		info, err := driver.Stat(ctx, version.Path)
+		if err != nil {
+			return nil, err
+		}
+		if to, err = readText(ctx, driver, version.Path); err != nil {
+			return nil, err
+		}
+		toLabel = versionLabel(version.Path, info.ModTime)
+	} else {
+		other, err := s.version(ctx, username, against, "read")
+		if err != nil {
+			return nil, err
+		}
+		if other.SourceID != version.SourceID || other.Path != version.Path {
+			return nil, fmt.Errorf("%w: versions belong to different files", ErrInvalidOperation)
+		}
+		if to, err = readText(ctx, raw, other.StorePath); err != nil {
+			return nil, err
+		}
+		toLabel = versionLabel(other.Path, other.ModTime)
+		if other.CreatedAt.Before(version.CreatedAt) {
+			from, to = to, from
+			fromLabel, toLabel = toLabel, fromLabel
+		}
+	}
+
+	result := diff.Compare(diff.SplitLines(from), diff.SplitLines(to))
+	return &VersionDiff{
+		From:    fromLabel,
+		To:      toLabel,
+		Added:   result.Added,
+		Removed: result.Removed,
+		Diff:    result.Unified(fromLabel, toLabel, diffContext),
+	}, nil
+}
+
+// version 查找版本并检查对文件的 action 权限
+func (s *VersionService) version(ctx context.Context, username string, id uint, action string) (*model.FileVersion, error) {
+	version, err := s.repo.FindByID(ctx, id)
+	if err != nil || !s.files.sourceIs(ctx, version.SourceKey, version.SourceID) {
+		return nil, ErrVersionNotFound
+	}
+	if !s.files.permService.CheckPermission(ctx, username, version.SourceID, version.Path, action) {
+		return nil, vfs.NewError("versions", version.Path, vfs.ErrPermissionDenied)
+	}
+	return version, nil
+}
+
+// remove 删除版本内容与记录，内容已不存在时只删除记录
+func (s *VersionService) remove(ctx context.Context, version *model.FileVersion) error {
+	driver, err := s.files.GetDriver(ctx, version.SourceKey)
+	if err != nil {
+		return err
+	}
+	if err := reservedAccess(driver).Delete(ctx, version.StorePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return s.repo.Delete(ctx, version.ID)
+}
+
+// onChange 文件被移动后，历史版本随之指向新路径；删除文件时保留版本，从回收站恢复后仍可查看
+func (s *VersionService) onChange(ctx context.Context, sourceKey string, ev vfs.ChangeEvent) {
+	if ev.Op != vfs.ChangeRename {
+		return
+	}
+	source, err := s.files.sourceRepo.FindByKey(ctx, sourceKey)
+	if err != nil {
+		return
+	}
+	if err := s.repo.Rename(ctx, source.ID, ev.Path, ev.Dest); err != nil {
+		fmt.Printf("[Version] Failed to update versions for %s:%s: %v\n", sourceKey, ev.Path, err)
+	}
+}
+
+// cleanupLoop 定期删除超过保留期限的版本
+func (s *VersionService) cleanupLoop() {
+	ticker := time.NewTicker(versionCleanupInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.cleanupExpired(context.Background())
+	}
+}
+
+func (s *VersionService) cleanupExpired(ctx context.Context) {
+	sources, err := s.files.sourceRepo.FindAll(ctx)
+	if err != nil {
+		fmt.Printf("[Version] Failed to query sources: %v\n", err)
+		return
+	}
+	for _, source := range sources {
+		retention := versionRetention(source)
+		if retention <= 0 {
+			continue
+		}
+		versions, err := s.repo.FindExpired(ctx, source.ID, time.Now().Add(-retention))
+		if err != nil {
+			fmt.Printf("[Version] Failed to query expired versions for %s: %v\n", source.Key, err)
+			continue
+		}
+		for _, v := range versions {
+			if err := s.remove(ctx, v); err != nil {
+				fmt.Printf("[Version] Failed to remove %s:%s version %d: %v\n", v.SourceKey, v.Path, v.ID, err)
+			}
+		}
+	}
+}
+
+// readText 读取文本文件并转为 UTF-8，二进制文件返回 ErrNotText
+func readText(ctx context.Context, driver vfs.StorageDriver, path string) (string, error) {
+	rc, err := driver.Open(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, MaxDiffBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > MaxDiffBytes {
+		return "", fmt.Errorf("%w: diff is limited to %d bytes", ErrFileTooLarge, MaxDiffBytes)
+	}
+	if extract.IsBinary(data) {
+		return "", fmt.Errorf("%w: %s", ErrNotText, path)
+	}
+	return extract.DecodeText(data), nil
+}
+
+func versionLabel(path string, mtime time.Time) string {
+	return path + "\t" + mtime.Format(time.RFC3339)
+}
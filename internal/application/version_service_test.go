@@ -0,0 +1,89 @@
+package application
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+)
+
+// newTestVersions 整个存储源保留历史版本，/a.txt 依次写入 v1、v2，返回 v1 对应的版本
+func newTestVersions(t *testing.T) (*testEnv, *VersionService, uint) {
+	t.Helper()
+	env := newTestEnv(t)
+	env.source.VersionPaths = "/"
+	if err := env.files.sourceRepo.Save(env.ctx, env.source); err != nil {
+		t.Fatal(err)
+	}
+	versions := NewVersionService(env.files, persistence.NewFileVersionRepository(env.db))
+	for _, content := range []string{"v1", "v2"} {
+		if _, err := env.files.Upload(env.ctx, env.sourceKey, "/a.txt", strings.NewReader(content), 2, ConflictOverwrite); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list, err := versions.List(env.ctx, "admin", env.sourceKey, "/a.txt")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List = %d versions, %v; want 1", len(list), err)
+	}
+	return env, versions, list[0].ID
+}
+
+func TestVersionRestore(t *testing.T) {
+	env, versions, id := newTestVersions(t)
+	result, err := versions.Restore(env.ctx, "admin", id)
+	if err != nil || result.Status != "overwritten" {
+		t.Fatalf("Restore = %+v, %v", result, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(env.root, "a.txt")); string(data) != "v1" {
+		t.Fatalf("a.txt = %q, want v1", data)
+	}
+	// 被替换的 v2 保存为新版本，恢复可以撤销
+	list, err := versions.List(env.ctx, "admin", env.sourceKey, "/a.txt")
+	if err != nil || len(list) != 2 || list[0].Size != 2 {
+		t.Fatalf("List after restore = %d versions, %v; want 2", len(list), err)
+	}
+	if _, err := versions.Restore(env.ctx, "admin", list[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(env.root, "a.txt")); string(data) != "v2" {
+		t.Fatalf("a.txt after undo = %q, want v2", data)
+	}
+}
+
+func TestVersionRestoreDeletedFile(t *testing.T) {
+	env, versions, id := newTestVersions(t)
+	if err := env.files.Delete(env.ctx, env.sourceKey, "/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := versions.Restore(env.ctx, "admin", id)
+	if err != nil || result.Status != "created" {
+		t.Fatalf("Restore = %+v, %v", result, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(env.root, "a.txt")); string(data) != "v1" {
+		t.Fatalf("a.txt = %q, want v1", data)
+	}
+}
+
+// 原路径被目录占用时拒绝恢复，目录内容保持不变
+func TestVersionRestoreOntoDirectory(t *testing.T) {
+	env, versions, id := newTestVersions(t)
+	if err := os.Remove(filepath.Join(env.root, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(env.root, "a.txt", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(env.root, "a.txt", "sub", "keep.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := versions.Restore(env.ctx, "admin", id); !errors.Is(err, vfs.ErrAlreadyExists) {
+		t.Fatalf("Restore onto directory = %v, want ErrAlreadyExists", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(env.root, "a.txt", "sub", "keep.txt")); err != nil || string(data) != "keep" {
+		t.Fatalf("directory content lost: %q, %v", data, err)
+	}
+}
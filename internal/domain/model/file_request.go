@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// FileRequest 文件收集请求：外部用户通过带令牌的地址只能上传，看不到目录中的任何内容
+// 创建时在目标目录下建立专属子目录，上传的文件都放在其中
+type FileRequest struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Token        string     `gorm:"size:32;uniqueIndex;not null" json:"token"`
+	Username     string     `gorm:"size:64;index;not null" json:"username"` // 创建者，上传以其身份写入
+	SourceID     uint       `gorm:"index;not null" json:"source_id"`
+	SourceKey    string     `gorm:"size:32;not null" json:"source_key"`
+	Path         string     `gorm:"size:1024;not null" json:"path"` // 专属子目录
+	Title        string     `gorm:"size:255;not null" json:"title"`
+	Description  string     `gorm:"type:text" json:"description"`
+	MaxFileSize  int64      `json:"max_file_size"`                 // 单个文件上限，0 表示只受存储源限制
+	AllowedTypes string     `gorm:"size:255" json:"allowed_types"` // 允许的扩展名 (小写，逗号分隔)，为空表示不限制
+	ExpiresAt    *time.Time `json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (FileRequest) TableName() string {
+	return "file_requests"
+}
+
+// FileRequestUpload 通过文件收集请求收到的文件，关闭请求后保留
+type FileRequestUpload struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	RequestID     uint      `gorm:"index;not null" json:"request_id"`
+	Name          string    `gorm:"size:255;not null" json:"name"`
+	Path          string    `gorm:"size:1024;not null" json:"path"` // 实际写入的路径 (同名时自动重命名)
+	Size          int64     `json:"size"`
+	UploaderName  string    `gorm:"size:64" json:"uploader_name"`
+	UploaderEmail string    `gorm:"size:255" json:"uploader_email"`
+	IP            string    `gorm:"size:64" json:"ip"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (FileRequestUpload) TableName() string {
+	return "file_request_uploads"
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// FileVersion 文件被覆盖前保存的历史版本，内容存放在存储源的隐藏版本目录中
+type FileVersion struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	SourceID   uint      `gorm:"index:idx_version_path;not null" json:"source_id"`
+	SourceKey  string    `gorm:"size:32;not null" json:"source_key"`
+	Path       string    `gorm:"size:1024;index:idx_version_path;not null" json:"path"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`                    // 该版本内容的修改时间
+	Author     string    `gorm:"size:64" json:"author"`       // 写入该版本内容的用户，早于版本记录的内容为空
+	ReplacedBy string    `gorm:"size:64" json:"replaced_by"`  // 覆盖该版本的用户
+	StorePath  string    `gorm:"size:1024;not null" json:"-"` // 在版本目录中的路径
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`     // 被覆盖的时间
+}
+
+func (FileVersion) TableName() string {
+	return "file_versions"
+}
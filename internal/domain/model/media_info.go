@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// MediaInfo 图片、音频、视频元数据的缓存
+// Size 与 ModTime 记录提取时的文件状态，文件变化后重新提取
+type MediaInfo struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	SourceID    uint      `gorm:"uniqueIndex:idx_media_source_path;not null" json:"source_id"`
+	Path        string    `gorm:"size:1024;uniqueIndex:idx_media_source_path;not null" json:"path"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	Data        string    `gorm:"type:text" json:"data"` // media.Metadata 的 JSON
+	Error       string    `gorm:"size:255" json:"error"` // 无法解析的文件记录原因，避免重复读取
+	ExtractedAt time.Time `json:"extracted_at"`
+}
+
+func (MediaInfo) TableName() string {
+	return "media_infos"
+}
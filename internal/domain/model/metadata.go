@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// FileMetadata 文件自定义元数据 (数据库回退存储)
+// 当驱动不支持原生元数据 (xattr / ADS) 时，键值对保存在这张表里
+type FileMetadata struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	SourceID uint   `gorm:"uniqueIndex:idx_meta_source_path_key;not null" json:"source_id"`
+	Path     string `gorm:"size:1024;uniqueIndex:idx_meta_source_path_key;not null" json:"path"`
+	Key      string `gorm:"size:255;uniqueIndex:idx_meta_source_path_key;not null" json:"key"`
+	Value    string `gorm:"type:text" json:"value"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (FileMetadata) TableName() string {
+	return "file_metadata"
+}
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// 通知类型
+const (
+	NotifyFileRequest = "file_request" // 文件收集请求收到新文件
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Username  string    `gorm:"size:64;index:idx_notification_user;not null" json:"username"`
+	Type      string    `gorm:"size:32;not null" json:"type"`
+	Title     string    `gorm:"size:255;not null" json:"title"`
+	Message   string    `gorm:"type:text" json:"message"`
+	Read      bool      `gorm:"index:idx_notification_user;not null;default:false" json:"read"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// SearchEntry 搜索索引中的一个文件或目录
+// 每次重建索引使用新的 Generation，写完后再切换，重建期间旧索引仍然可用
+type SearchEntry struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	SourceID   uint      `gorm:"index:idx_search_source_gen;not null" json:"source_id"`
+	Generation int64     `gorm:"index:idx_search_source_gen;not null" json:"generation"`
+	Path       string    `gorm:"size:1024;not null" json:"path"`
+	Name       string    `gorm:"size:255;not null" json:"name"`
+	IsDir      bool      `json:"is_dir"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`
+}
+
+func (SearchEntry) TableName() string {
+	return "search_entries"
+}
+
+// SearchIndexState 存储源当前生效的索引版本
+type SearchIndexState struct {
+	SourceID   uint      `gorm:"primaryKey;autoIncrement:false" json:"source_id"`
+	Generation int64     `gorm:"not null" json:"generation"`
+	Entries    int64     `json:"entries"`
+	IndexedAt  time.Time `json:"indexed_at"`
+}
+
+func (SearchIndexState) TableName() string {
+	return "search_index_states"
+}
@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// 分享链接的访问模式
+const (
+	ShareModeRead   = "read"   // 只读：浏览与下载
+	ShareModeUpload = "upload" // 只传：只能向分享的目录上传，看不到已有文件
+)
+
+// Share 公开分享链接，外部访问者无需账号
+// 访问时以创建者的身份经过 SecureDriver，创建者失去权限后分享随之失效
+type Share struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	Token        string     `gorm:"size:32;uniqueIndex;not null" json:"token"` // 链接中的随机令牌
+	Username     string     `gorm:"size:64;index;not null" json:"username"`    // 创建者
+	SourceID     uint       `gorm:"index;not null" json:"source_id"`
+	SourceKey    string     `gorm:"size:32;not null" json:"source_key"`
+	Path         string     `gorm:"size:1024;not null" json:"path"`
+	IsDir        bool       `json:"is_dir"`
+	Mode         string     `gorm:"size:16;not null;default:'read'" json:"mode"`
+	PasswordHash string     `gorm:"size:255" json:"-"`
+	ExpiresAt    *time.Time `json:"expires_at"`    // 为空表示永不过期
+	MaxDownloads int        `json:"max_downloads"` // 0 表示不限制
+	Downloads    int        `gorm:"not null;default:0" json:"downloads"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (Share) TableName() string {
+	return "shares"
+}
+
+// HasPassword 是否需要密码
+func (s *Share) HasPassword() bool {
+	return s.PasswordHash != ""
+}
+
+// 访问日志中的操作
+const (
+	ShareActionView     = "view"     // 查看分享信息或列出目录
+	ShareActionAuth     = "auth"     // 输入密码
+	ShareActionDownload = "download" // 下载文件
+	ShareActionUpload   = "upload"   // 上传文件
+)
+
+// ShareAccess 分享链接的访问日志，撤销分享后保留
+type ShareAccess struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ShareID   uint      `gorm:"index;not null" json:"share_id"`
+	Action    string    `gorm:"size:16;not null" json:"action"`
+	Path      string    `gorm:"size:1024" json:"path"`
+	Status    int       `json:"status"` // HTTP 状态码
+	IP        string    `gorm:"size:64" json:"ip"`
+	UserAgent string    `gorm:"size:255" json:"user_agent"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (ShareAccess) TableName() string {
+	return "share_accesses"
+}
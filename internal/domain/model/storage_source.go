@@ -29,13 +29,20 @@ func (m *JSONMap) Scan(value interface{}) error {
 
 // StorageSource 存储源实体
 type StorageSource struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Key       string    `gorm:"size:32;uniqueIndex;not null" json:"key"` // 唯一标识符,同时也作为访问数据源的路径
-	Name      string    `gorm:"size:64;not null" json:"name"`            // 例如: "我的NAS", "本地磁盘"
-	Type      string    `gorm:"size:16;not null" json:"type"`            // 例如: "smb", "local"
-	Config    JSONMap   `gorm:"type:text" json:"config"`                 // 存储具体的连接配置
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Key           string    `gorm:"size:32;uniqueIndex;not null" json:"key"` // 唯一标识符,同时也作为访问数据源的路径
+	Name          string    `gorm:"size:64;not null" json:"name"`            // 例如: "我的NAS", "本地磁盘"
+	Type          string    `gorm:"size:16;not null" json:"type"`            // 例如: "smb", "local"
+	Config        JSONMap   `gorm:"type:text" json:"config"`                 // 存储具体的连接配置
+	Timeout       int       `gorm:"default:0" json:"timeout"`                // 单次操作超时(秒)，0 使用全局默认值，负数表示不限制
+	MaxUploadSize int64     `gorm:"default:0" json:"max_upload_size"`        // 单个文件上传大小上限(字节)，0 表示不限制
+	TrashDays     int       `gorm:"default:0" json:"trash_days"`             // 回收站保留天数，0 使用全局默认值，负数表示不使用回收站 (直接删除)
+	VersionPaths  string    `gorm:"size:1024" json:"version_paths"`          // 保留历史版本的目录，逗号分隔 ("/" 表示整个存储源)，为空表示不保留
+	VersionKeep   int       `gorm:"default:0" json:"version_keep"`           // 每个文件最多保留的版本数，0 使用全局默认值
+	VersionDays   int       `gorm:"default:0" json:"version_days"`           // 版本保留天数，0 使用全局默认值
+	DisableIndex  bool      `gorm:"default:false" json:"disable_index"`      // 不建立搜索索引与全文索引 (例如按流量计费的远程存储)，搜索时实时遍历
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 func (StorageSource) TableName() string {
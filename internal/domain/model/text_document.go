@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// TextDocument 全文索引中的一个文件
+// 提取出的文本与分词结果保存在 FTS 虚拟表 fulltext_index 中，docid 与 ID 相同
+type TextDocument struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	SourceID  uint      `gorm:"uniqueIndex:idx_text_source_path;not null" json:"source_id"`
+	Path      string    `gorm:"size:1024;uniqueIndex:idx_text_source_path;not null" json:"path"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	Title     string    `gorm:"size:512" json:"title"`
+	Author    string    `gorm:"size:255" json:"author"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`              // 建立索引时文件的修改时间，用于判断是否需要重建
+	Error     string    `gorm:"size:255" json:"error"` // 提取失败的原因，文件名仍然可以被搜到
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+func (TextDocument) TableName() string {
+	return "text_documents"
+}
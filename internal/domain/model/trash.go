@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// TrashItem 回收站中的一个条目 (文件或整个目录)
+// 删除时条目被移动到存储源的隐藏回收站目录 (或管理员指定的回收站存储源)，恢复时移回原路径
+type TrashItem struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	SourceID       uint      `gorm:"index;not null" json:"source_id"`
+	SourceKey      string    `gorm:"size:32;not null" json:"source_key"`
+	Path           string    `gorm:"size:1024;not null" json:"path"` // 删除前的路径
+	Name           string    `gorm:"size:255;not null" json:"name"`
+	IsDir          bool      `json:"is_dir"`
+	Size           int64     `json:"size"`
+	ModTime        time.Time `json:"mod_time"`
+	TrashSourceKey string    `gorm:"size:32;not null" json:"-"`   // 实际存放的存储源
+	TrashPath      string    `gorm:"size:1024;not null" json:"-"` // 在回收站目录中的路径
+	DeletedBy      string    `gorm:"size:64;index;not null" json:"deleted_by"`
+	DeletedAt      time.Time `gorm:"index;not null" json:"deleted_at"`
+}
+
+func (TrashItem) TableName() string {
+	return "trash_items"
+}
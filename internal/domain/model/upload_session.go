@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// UploadSession 断点续传 (tus) 上传会话
+// 分块数据暂存在本地磁盘 (StagingPath)，全部到达后一次性写入目标存储源
+type UploadSession struct {
+	ID          string    `gorm:"primaryKey;size:32" json:"id"`
+	Username    string    `gorm:"size:64;index;not null" json:"username"` // 只有创建者可以续传
+	SourceKey   string    `gorm:"size:32;not null" json:"source_key"`
+	Path        string    `gorm:"size:1024;not null" json:"path"`   // 目标文件路径
+	Length      int64     `gorm:"not null" json:"length"`           // Upload-Length
+	Offset      int64     `gorm:"not null;default:0" json:"offset"` // 已接收的字节数
+	Metadata    string    `gorm:"type:text" json:"metadata"`        // 原始 Upload-Metadata 头
+	Conflict    string    `gorm:"size:16" json:"conflict"`          // 提交时的同名冲突策略
+	StagingPath string    `gorm:"size:1024;not null" json:"-"`      // 本地暂存文件
+	ExpiresAt   time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
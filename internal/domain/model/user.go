@@ -9,7 +9,10 @@ type User struct {
 	Username     string    `gorm:"size:64;uniqueIndex;not null" json:"username"`
 	PasswordHash string    `gorm:"size:255;not null" json:"-"`         // json:"-" 确保密码哈希永远不会返回给前端
 	Role         string    `gorm:"size:16;default:'user'" json:"role"` // admin, user
-	IsActive     bool      `gorm:"default:true" json:"is_active"`
+	IsActive     bool      `gorm:"default:true" json:"is_active"`      // 停用后不能登录，已签发的 Token 与分享链接同时失效
+	TokenVersion int       `gorm:"not null;default:0" json:"-"`        // 修改或重置密码时加一，之前签发的 Token 随之失效
+	DisplayName  string    `gorm:"size:64" json:"display_name"`
+	Email        string    `gorm:"size:255" json:"email"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/wentf9/MyGoFileHub/internal/domain/model"
 )
@@ -10,7 +11,10 @@ import (
 type UserRepository interface {
 	FindByID(ctx context.Context, id uint) (*model.User, error)
 	FindByUsername(ctx context.Context, username string) (*model.User, error)
+	// FindAll 按用户名排序
+	FindAll(ctx context.Context) ([]*model.User, error)
 	Save(ctx context.Context, user *model.User) error
+	Delete(ctx context.Context, id uint) error
 }
 
 // SourceRepository 存储源配置存取
@@ -19,6 +23,9 @@ type SourceRepository interface {
 	FindByID(ctx context.Context, id uint) (*model.StorageSource, error)
 	FindByKey(ctx context.Context, key string) (*model.StorageSource, error)
 	Save(ctx context.Context, source *model.StorageSource) error
+	// SaveAll 在一个事务中保存多个存储源，任何一个失败时全部回滚
+	SaveAll(ctx context.Context, sources []*model.StorageSource) error
+	// Delete 同时删除分享、文件收集请求、回收站、历史版本、元数据、索引与权限等引用该存储源的记录
 	Delete(ctx context.Context, id uint) error
 }
 
@@ -33,4 +40,162 @@ type SourceRepository interface {
 type PermissionRepository interface {
 	FindByUserAndSource(ctx context.Context, userID, sourceID uint) ([]*model.UserPermission, error)
 	Save(ctx context.Context, perm *model.UserPermission) error
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+// MetadataRepository 文件元数据存取 (驱动不支持原生元数据时的回退存储)
+type MetadataRepository interface {
+	FindByPath(ctx context.Context, sourceID uint, path string) ([]*model.FileMetadata, error)
+	Upsert(ctx context.Context, meta *model.FileMetadata) error
+	Delete(ctx context.Context, sourceID uint, path string, key string) error
+}
+
+// UploadSessionRepository 断点续传会话存取
+type UploadSessionRepository interface {
+	FindByID(ctx context.Context, id string) (*model.UploadSession, error)
+	FindExpired(ctx context.Context, before time.Time) ([]*model.UploadSession, error)
+	Save(ctx context.Context, session *model.UploadSession) error
+	UpdateOffset(ctx context.Context, id string, offset int64, expiresAt time.Time) error
+	Delete(ctx context.Context, id string) error
+	FindByUser(ctx context.Context, username string) ([]*model.UploadSession, error)
+}
+
+// PathRule 一条路径权限规则，Prefix 按字节前缀匹配路径
+type PathRule struct {
+	Prefix string
+	Allow  bool
+}
+
+// ReadScope 用户在一个存储源中可读的范围：All 时整个存储源可读，
+// 否则按 Rules 中最长匹配的规则判断 (长度相同时靠前的优先)，没有匹配的规则时不可读
+type ReadScope struct {
+	SourceID uint
+	All      bool
+	Rules    []PathRule
+}
+
+// SearchFilter 可以下推到索引查询的过滤条件，零值表示不限制
+type SearchFilter struct {
+	PathPrefix string // 只返回该目录下的条目
+	MinSize    int64
+	MaxSize    int64
+	After      time.Time
+	Before     time.Time
+	IsDir      *bool
+	Name       string     // 小写的文件名 glob (path.Match 语法)，不区分大小写
+	Scope      *ReadScope // 只返回可读的条目
+	Limit      int        // 最多读取的条目数
+}
+
+// SearchIndexRepository 文件搜索索引存取
+type SearchIndexRepository interface {
+	FindState(ctx context.Context, sourceID uint) (*model.SearchIndexState, error)
+	InsertEntries(ctx context.Context, entries []*model.SearchEntry) error
+	// Commit 切换到 state 指定的版本并删除其他版本的条目
+	Commit(ctx context.Context, state *model.SearchIndexState) error
+	// Query 分批读取当前版本中符合条件的条目，fn 返回错误时停止
+	Query(ctx context.Context, state *model.SearchIndexState, filter SearchFilter, fn func(*model.SearchEntry) error) error
+}
+
+// MediaInfoRepository 媒体元数据缓存存取
+type MediaInfoRepository interface {
+	FindByPath(ctx context.Context, sourceID uint, path string) (*model.MediaInfo, error)
+	// Save 按 (source_id, path) 写入或覆盖
+	Save(ctx context.Context, info *model.MediaInfo) error
+	// Delete 删除 path 及其下所有记录
+	Delete(ctx context.Context, sourceID uint, path string) error
+}
+
+// FullTextHit 一条全文搜索命中
+type FullTextHit struct {
+	Doc   *model.TextDocument
+	Score float64 // BM25 相关度，越大越相关
+}
+
+// FullTextRepository 全文索引存取
+type FullTextRepository interface {
+	FindBySource(ctx context.Context, sourceID uint) ([]*model.TextDocument, error)
+	// Upsert 按 (source_id, path) 写入文档及其文本
+	Upsert(ctx context.Context, doc *model.TextDocument, text string) error
+	// Delete 删除 path 及其下所有文档
+	Delete(ctx context.Context, sourceID uint, path string) error
+	// Rename 将 oldPath 及其下所有文档移动到 newPath
+	Rename(ctx context.Context, sourceID uint, oldPath, newPath string) error
+	// Search 返回 scopes 范围内按相关度降序排列的一页命中，以及命中总数
+	Search(ctx context.Context, query string, scopes []ReadScope, limit, offset int) ([]FullTextHit, int, error)
+	// Snippet 返回文档中包含查询词的片段
+	Snippet(ctx context.Context, docID uint, query string) (string, error)
+}
+
+// ShareRepository 分享链接与访问日志存取
+type ShareRepository interface {
+	FindByID(ctx context.Context, id uint) (*model.Share, error)
+	FindByToken(ctx context.Context, token string) (*model.Share, error)
+	// FindByUser username 为空时返回全部分享
+	FindByUser(ctx context.Context, username string) ([]*model.Share, error)
+	Save(ctx context.Context, share *model.Share) error
+	Delete(ctx context.Context, id uint) error
+	// Rename 将 oldPath 及其下的分享移动到 newPath
+	Rename(ctx context.Context, sourceID uint, oldPath, newPath string) error
+	// AddDownload 下载次数加一，已达上限时返回 false
+	AddDownload(ctx context.Context, id uint) (bool, error)
+	// RefundDownload 撤销一次 AddDownload
+	RefundDownload(ctx context.Context, id uint) error
+	// DeleteByUser 删除用户的全部分享及其访问日志
+	DeleteByUser(ctx context.Context, username string) error
+	LogAccess(ctx context.Context, access *model.ShareAccess) error
+	// FindAccess 按时间倒序返回最近 limit 条访问日志
+	FindAccess(ctx context.Context, shareID uint, limit int) ([]*model.ShareAccess, error)
+}
+
+// FileRequestRepository 文件收集请求与收到的文件存取
+type FileRequestRepository interface {
+	FindByID(ctx context.Context, id uint) (*model.FileRequest, error)
+	FindByToken(ctx context.Context, token string) (*model.FileRequest, error)
+	// FindByUser username 为空时返回全部请求
+	FindByUser(ctx context.Context, username string) ([]*model.FileRequest, error)
+	Save(ctx context.Context, req *model.FileRequest) error
+	Delete(ctx context.Context, id uint) error
+	// Rename 将 oldPath 及其下的请求移动到 newPath
+	Rename(ctx context.Context, sourceID uint, oldPath, newPath string) error
+	AddUploads(ctx context.Context, uploads []*model.FileRequestUpload) error
+	FindUploads(ctx context.Context, requestID uint) ([]*model.FileRequestUpload, error)
+	// DeleteByUser 删除用户的全部请求及收到文件的记录 (文件本身保留)
+	DeleteByUser(ctx context.Context, username string) error
+}
+
+// NotificationRepository 站内通知存取
+type NotificationRepository interface {
+	// FindByUser 按时间倒序返回最近 limit 条通知
+	FindByUser(ctx context.Context, username string, unreadOnly bool, limit int) ([]*model.Notification, error)
+	Save(ctx context.Context, n *model.Notification) error
+	// MarkRead 将用户的通知标记为已读，ids 为空时标记全部
+	MarkRead(ctx context.Context, username string, ids []uint) error
+	DeleteByUser(ctx context.Context, username string) error
+}
+
+// TrashRepository 回收站条目存取
+type TrashRepository interface {
+	FindByID(ctx context.Context, id uint) (*model.TrashItem, error)
+	// FindByUser 按删除时间倒序返回，username 为空时不限删除者，sourceKey 为空时不限存储源
+	FindByUser(ctx context.Context, username string, sourceKey string) ([]*model.TrashItem, error)
+	// FindExpired 返回存储源中删除时间早于 before 的条目
+	FindExpired(ctx context.Context, sourceID uint, before time.Time) ([]*model.TrashItem, error)
+	Save(ctx context.Context, item *model.TrashItem) error
+	Delete(ctx context.Context, id uint) error
+	// Reassign 将 from 删除的条目转给 to
+	Reassign(ctx context.Context, from, to string) error
+}
+
+// FileVersionRepository 文件历史版本存取
+type FileVersionRepository interface {
+	FindByID(ctx context.Context, id uint) (*model.FileVersion, error)
+	// FindByPath 按时间倒序返回文件的全部版本
+	FindByPath(ctx context.Context, sourceID uint, path string) ([]*model.FileVersion, error)
+	// FindExpired 返回存储源中早于 before 的版本
+	FindExpired(ctx context.Context, sourceID uint, before time.Time) ([]*model.FileVersion, error)
+	Save(ctx context.Context, version *model.FileVersion) error
+	Delete(ctx context.Context, id uint) error
+	// Rename 将 oldPath 及其下文件的版本移动到 newPath
+	Rename(ctx context.Context, sourceID uint, oldPath, newPath string) error
 }
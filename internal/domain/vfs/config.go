@@ -0,0 +1,7 @@
+package vfs
+
+// ConfigValidator 是可选能力接口：驱动在不建立连接的情况下检查配置是否完整合法
+// 管理员保存存储源前调用，避免把无法 Init 的配置写入数据库
+type ConfigValidator interface {
+	ValidateConfig(config map[string]any) error
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"io/fs"
+	"time"
 )
 
 // StorageDriver 定义了所有存储源必须具备的行为
@@ -14,6 +15,7 @@ type StorageDriver interface {
 
 	// Init 初始化连接
 	// config 是 JSON 解析后的 map，包含 host, user, password 等
+	// ctx 只约束初始化本身，驱动不应保存它用于之后的后台工作 (如连接池的回收与重连)
 	Init(ctx context.Context, config map[string]any) error
 
 	// List 列出指定路径下的文件
@@ -40,6 +42,15 @@ type StorageDriver interface {
 	// Rename 重命名或移动（在同一源内）
 	Rename(ctx context.Context, srcPath, dstPath string) error
 
+	// SetModTime 修改文件的最后修改时间 (同步/备份工具上传后会回写原始时间)
+	SetModTime(ctx context.Context, path string, mtime time.Time) error
+
+	// SetMode 修改文件权限位，不支持 POSIX 权限的存储尽力映射 (如 SMB 的只读属性)
+	SetMode(ctx context.Context, path string, mode fs.FileMode) error
+
+	// Truncate 将文件截断或扩展到指定大小
+	Truncate(ctx context.Context, path string, size int64) error
+
 	// Close 释放资源（如断开 SMB 连接）
 	Close() error
 }
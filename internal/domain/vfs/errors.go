@@ -0,0 +1,115 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"syscall"
+)
+
+// errorKind 领域错误分类，驱动返回的错误都应归入其中之一，API 层据此选择状态码
+type errorKind string
+
+func (k errorKind) Error() string {
+	return string(k)
+}
+
+// Is 让分类错误与标准库的 fs 错误互通，已有的 errors.Is(err, os.ErrNotExist) 判断继续有效
+func (k errorKind) Is(target error) bool {
+	switch k {
+	case ErrNotFound:
+		return target == fs.ErrNotExist
+	case ErrPermissionDenied, ErrReadOnly:
+		return target == fs.ErrPermission
+	case ErrAlreadyExists:
+		return target == fs.ErrExist
+	}
+	return false
+}
+
+// 领域错误分类
+const (
+	ErrNotFound         errorKind = "not found"
+	ErrPermissionDenied errorKind = "permission denied"
+	ErrAlreadyExists    errorKind = "already exists"
+	ErrNotEmpty         errorKind = "directory not empty"
+	ErrReadOnly         errorKind = "read-only storage"
+	ErrQuotaExceeded    errorKind = "quota exceeded"
+	ErrUnavailable      errorKind = "storage unavailable"
+)
+
+// PathError 记录失败的操作、虚拟路径与错误分类
+// Error() 只输出虚拟路径和分类，不包含底层错误，避免把服务器真实路径、NAS 地址暴露给客户端
+type PathError struct {
+	Op   string
+	Path string
+	Kind error // 上面的分类之一
+	Err  error // 底层原始错误，可为 nil
+}
+
+func (e *PathError) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Kind.Error()
+}
+
+func (e *PathError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// NewError 构造一个分类错误
+func NewError(op, path string, kind error) error {
+	return &PathError{Op: op, Path: path, Kind: kind}
+}
+
+// WrapError 将底层 OS / 网络错误归类为领域错误
+// 已分类的错误、超时和取消保持原样；无法归类的错误原样返回 (API 层按 500 处理)
+func WrapError(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var pe *PathError
+	if errors.As(err, &pe) || errors.Is(err, ErrTimeout) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if kind := classify(err); kind != nil {
+		return &PathError{Op: op, Path: path, Kind: kind, Err: err}
+	}
+	return err
+}
+
+// KindOf 返回错误所属的分类，无法归类时返回 nil
+func KindOf(err error) error {
+	for _, kind := range []errorKind{ErrNotEmpty, ErrReadOnly, ErrQuotaExceeded, ErrUnavailable, ErrNotFound, ErrPermissionDenied, ErrAlreadyExists} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return classify(err)
+}
+
+func classify(err error) error {
+	switch {
+	// ENOTEMPTY 在部分平台上同时匹配 fs.ErrExist，需要先判断
+	case errors.Is(err, syscall.ENOTEMPTY):
+		return ErrNotEmpty
+	case errors.Is(err, syscall.EROFS):
+		return ErrReadOnly
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		return ErrQuotaExceeded
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return ErrPermissionDenied
+	case errors.Is(err, fs.ErrExist):
+		return ErrAlreadyExists
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EHOSTUNREACH) {
+		return ErrUnavailable
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestWrapErrorClassifies(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"not exist", &fs.PathError{Op: "open", Path: "/srv/a", Err: syscall.ENOENT}, ErrNotFound},
+		{"permission", &fs.PathError{Op: "open", Path: "/srv/a", Err: syscall.EACCES}, ErrPermissionDenied},
+		{"exist", &fs.PathError{Op: "mkdir", Path: "/srv/a", Err: syscall.EEXIST}, ErrAlreadyExists},
+		{"not empty", &fs.PathError{Op: "rmdir", Path: "/srv/a", Err: syscall.ENOTEMPTY}, ErrNotEmpty},
+		{"read-only", &fs.PathError{Op: "open", Path: "/srv/a", Err: syscall.EROFS}, ErrReadOnly},
+		{"no space", &fs.PathError{Op: "write", Path: "/srv/a", Err: syscall.ENOSPC}, ErrQuotaExceeded},
+		{"quota", &fs.PathError{Op: "write", Path: "/srv/a", Err: syscall.EDQUOT}, ErrQuotaExceeded},
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, ErrUnavailable},
+		{"unreachable", fmt.Errorf("dial: %w", syscall.EHOSTUNREACH), ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := WrapError("op", "/a", tt.err)
+			if !errors.Is(err, tt.kind) {
+				t.Fatalf("WrapError(%v) = %v, want kind %v", tt.err, err, tt.kind)
+			}
+			if got := KindOf(err); got != tt.kind {
+				t.Fatalf("KindOf = %v, want %v", got, tt.kind)
+			}
+			// 底层错误仍可判断，但不出现在 Error() 中
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("WrapError lost the underlying error")
+			}
+			if want := "op /a: " + tt.kind.Error(); err.Error() != want {
+				t.Fatalf("Error() = %q, want %q", err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestWrapErrorKeepsUnclassified(t *testing.T) {
+	timeout := &TimeoutError{Op: "stat", Path: "/a", Err: context.DeadlineExceeded}
+	classified := NewError("stat", "/a", ErrNotFound)
+	plain := errors.New("boom")
+	for _, err := range []error{nil, timeout, classified, context.Canceled, context.DeadlineExceeded, plain} {
+		if got := WrapError("op", "/b", err); got != err {
+			t.Errorf("WrapError(%v) = %v, want it unchanged", err, got)
+		}
+	}
+	if KindOf(plain) != nil {
+		t.Errorf("KindOf(plain error) should be nil")
+	}
+	if !errors.Is(timeout, ErrTimeout) {
+		t.Errorf("TimeoutError should match ErrTimeout")
+	}
+}
+
+// 分类错误与标准库的 fs 错误互通
+func TestKindMatchesStdlib(t *testing.T) {
+	tests := []struct {
+		kind   error
+		target error
+		want   bool
+	}{
+		{ErrNotFound, os.ErrNotExist, true},
+		{ErrPermissionDenied, os.ErrPermission, true},
+		{ErrReadOnly, os.ErrPermission, true},
+		{ErrAlreadyExists, os.ErrExist, true},
+		{ErrNotEmpty, os.ErrExist, false},
+		{ErrUnavailable, os.ErrNotExist, false},
+	}
+	for _, tt := range tests {
+		err := NewError("op", "/a", tt.kind)
+		if got := errors.Is(err, tt.target); got != tt.want {
+			t.Errorf("errors.Is(%v, %v) = %v, want %v", tt.kind, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package vfs
+
+import "context"
+
+// HealthChecker 是可选能力接口：驱动实现 Ping 以报告后端 (NAS、对象存储等) 是否可用
+// 没有实现该接口的驱动被视为始终健康
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,209 @@
+package vfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	pathpkg "path"
+	"time"
+)
+
+// HiddenDriver 装饰器：隐藏存储源中的保留区域 (如回收站目录)
+// 保留区域不出现在目录列表中，对它的任何访问都返回 ErrNotFound；需要访问保留区域的服务通过 Base() 绕过
+type HiddenDriver struct {
+	base   StorageDriver
+	hidden func(path string) bool
+}
+
+// NewHiddenDriver 包装一个驱动，hidden 判断虚拟路径是否位于保留区域
+func NewHiddenDriver(base StorageDriver, hidden func(path string) bool) StorageDriver {
+	return &HiddenDriver{base: base, hidden: hidden}
+}
+
+// Base 返回被包装的驱动，可以访问保留区域
+func (d *HiddenDriver) Base() StorageDriver {
+	return d.base
+}
+
+// check 路径位于保留区域时返回 ErrNotFound
+func (d *HiddenDriver) check(op, path string) error {
+	if d.hidden(path) {
+		return NewError(op, path, ErrNotFound)
+	}
+	return nil
+}
+
+// filter 去掉目录中属于保留区域的条目
+func (d *HiddenDriver) filter(dir string, files []FileInfo) []FileInfo {
+	visible := files[:0]
+	for _, f := range files {
+		if !d.hidden(pathpkg.Join(dir, f.Name)) {
+			visible = append(visible, f)
+		}
+	}
+	return visible
+}
+
+func (d *HiddenDriver) DriverName() string {
+	return d.base.DriverName()
+}
+
+func (d *HiddenDriver) Init(ctx context.Context, config map[string]any) error {
+	return d.base.Init(ctx, config)
+}
+
+func (d *HiddenDriver) Ping(ctx context.Context) error {
+	if hc, ok := d.base.(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
+func (d *HiddenDriver) List(ctx context.Context, path string) ([]FileInfo, error) {
+	if err := d.check("list", path); err != nil {
+		return nil, err
+	}
+	files, err := d.base.List(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return d.filter(path, files), nil
+}
+
+func (d *HiddenDriver) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	if err := d.check("open", path); err != nil {
+		return nil, err
+	}
+	return d.base.Open(ctx, path)
+}
+
+// OpenFile 打开目录时同样过滤 Readdir 的结果 (WebDAV PROPFIND 通过这里列目录)
+func (d *HiddenDriver) OpenFile(ctx context.Context, path string, flag int, perm fs.FileMode) (File, error) {
+	if err := d.check("open", path); err != nil {
+		return nil, err
+	}
+	f, err := d.base.OpenFile(ctx, path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &hiddenFile{File: f, dir: path, hidden: d.hidden}, nil
+}
+
+func (d *HiddenDriver) Create(ctx context.Context, path string, reader io.Reader, size int64) error {
+	if err := d.check("create", path); err != nil {
+		return err
+	}
+	return d.base.Create(ctx, path, reader, size)
+}
+
+func (d *HiddenDriver) Mkdir(ctx context.Context, path string, perm fs.FileMode) error {
+	if err := d.check("mkdir", path); err != nil {
+		return err
+	}
+	return d.base.Mkdir(ctx, path, perm)
+}
+
+func (d *HiddenDriver) Stat(ctx context.Context, path string) (FileInfo, error) {
+	if err := d.check("stat", path); err != nil {
+		return FileInfo{}, err
+	}
+	return d.base.Stat(ctx, path)
+}
+
+func (d *HiddenDriver) Delete(ctx context.Context, path string) error {
+	if err := d.check("delete", path); err != nil {
+		return err
+	}
+	return d.base.Delete(ctx, path)
+}
+
+func (d *HiddenDriver) Rename(ctx context.Context, srcPath, dstPath string) error {
+	if err := d.check("rename", srcPath); err != nil {
+		return err
+	}
+	if err := d.check("rename", dstPath); err != nil {
+		return err
+	}
+	return d.base.Rename(ctx, srcPath, dstPath)
+}
+
+func (d *HiddenDriver) SetModTime(ctx context.Context, path string, mtime time.Time) error {
+	if err := d.check("chtimes", path); err != nil {
+		return err
+	}
+	return d.base.SetModTime(ctx, path, mtime)
+}
+
+func (d *HiddenDriver) SetMode(ctx context.Context, path string, mode fs.FileMode) error {
+	if err := d.check("chmod", path); err != nil {
+		return err
+	}
+	return d.base.SetMode(ctx, path, mode)
+}
+
+func (d *HiddenDriver) Truncate(ctx context.Context, path string, size int64) error {
+	if err := d.check("truncate", path); err != nil {
+		return err
+	}
+	return d.base.Truncate(ctx, path, size)
+}
+
+func (d *HiddenDriver) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	if err := d.check("getmeta", path); err != nil {
+		return nil, err
+	}
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return nil, ErrMetadataNotSupported
+	}
+	return md.GetMetadata(ctx, path)
+}
+
+func (d *HiddenDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	if err := d.check("setmeta", path); err != nil {
+		return err
+	}
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return md.SetMetadata(ctx, path, key, value)
+}
+
+func (d *HiddenDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	if err := d.check("delmeta", path); err != nil {
+		return err
+	}
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return md.DeleteMetadata(ctx, path, key)
+}
+
+func (d *HiddenDriver) Close() error {
+	return d.base.Close()
+}
+
+// hiddenFile 过滤目录读取结果
+type hiddenFile struct {
+	File
+	dir    string
+	hidden func(path string) bool
+}
+
+// Readdir 过滤后条目可能少于 count，但只要目录未读完就不会返回空结果
+func (f *hiddenFile) Readdir(count int) ([]fs.FileInfo, error) {
+	for {
+		infos, err := f.File.Readdir(count)
+		visible := infos[:0]
+		for _, info := range infos {
+			if !f.hidden(pathpkg.Join(f.dir, info.Name())) {
+				visible = append(visible, info)
+			}
+		}
+		if len(visible) > 0 || err != nil || count <= 0 || len(infos) == 0 {
+			return visible, err
+		}
+	}
+}
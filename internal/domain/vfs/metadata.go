@@ -0,0 +1,23 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMetadataNotSupported 表示底层存储不支持原生元数据（xattr / ADS / 对象元数据）
+// 上层收到该错误后应回退到数据库存储
+var ErrMetadataNotSupported = errors.New("metadata not supported by driver")
+
+// MetadataDriver 是可选能力接口：支持为文件附加自定义键值元数据的驱动可以实现它
+// 例如 Local 映射到 xattr，SMB 映射到备用数据流 (ADS)，对象存储映射到对象元数据
+type MetadataDriver interface {
+	// GetMetadata 返回路径上的全部自定义元数据
+	GetMetadata(ctx context.Context, path string) (map[string]string, error)
+
+	// SetMetadata 设置（或覆盖）单个键
+	SetMetadata(ctx context.Context, path string, key, value string) error
+
+	// DeleteMetadata 删除单个键，键不存在时不报错
+	DeleteMetadata(ctx context.Context, path string, key string) error
+}
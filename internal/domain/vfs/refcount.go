@@ -0,0 +1,225 @@
+package vfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// RefCountDriver 装饰器：统计驱动的使用者，Close 之后等最后一个使用者释放才关闭底层驱动
+// 使用者包括进行中的调用、尚未关闭的文件句柄，以及通过 Hold 登记的请求
+// 驱动缓存驱逐实例 (健康检查失败、修改存储源) 时，正在下载或复制的请求不会被中途断开
+type RefCountDriver struct {
+	base    StorageDriver
+	mu      sync.Mutex
+	refs    int
+	closing bool
+}
+
+// NewRefCountDriver 包装一个驱动，应位于装饰器链的最内层，使绕过上层装饰器的访问同样被统计
+func NewRefCountDriver(base StorageDriver) *RefCountDriver {
+	return &RefCountDriver{base: base}
+}
+
+// acquire 登记一个使用者，返回的 release 只生效一次
+func (d *RefCountDriver) acquire() func() {
+	d.mu.Lock()
+	d.refs++
+	d.mu.Unlock()
+	var once sync.Once
+	return func() { once.Do(d.release) }
+}
+
+func (d *RefCountDriver) release() {
+	d.mu.Lock()
+	d.refs--
+	last := d.closing && d.refs == 0
+	d.mu.Unlock()
+	if last {
+		d.base.Close()
+	}
+}
+
+// Hold 在 ctx 结束前保持驱动可用，覆盖同一请求中两次调用之间的间隔
+// 不会结束的 ctx (如 context.Background()) 不登记，只按调用统计
+func (d *RefCountDriver) Hold(ctx context.Context) {
+	if ctx.Done() == nil {
+		return
+	}
+	context.AfterFunc(ctx, d.acquire())
+}
+
+// Close 标记为关闭，没有使用者时立即关闭底层驱动，否则由最后一个使用者关闭
+func (d *RefCountDriver) Close() error {
+	d.mu.Lock()
+	if d.closing {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closing = true
+	idle := d.refs == 0
+	d.mu.Unlock()
+	if idle {
+		return d.base.Close()
+	}
+	return nil
+}
+
+// hold 文件句柄关闭前保持驱动可用，保留底层文件的 io.ReaderAt
+func (d *RefCountDriver) hold(rc io.ReadCloser) io.ReadCloser {
+	release := d.acquire()
+	if f, ok := rc.(File); ok {
+		return d.holdFile(f, release)
+	}
+	return &heldReader{ReadCloser: rc, release: release}
+}
+
+func (d *RefCountDriver) holdFile(f File, release func()) File {
+	held := &heldFile{File: f, release: release}
+	if r, ok := f.(io.ReaderAt); ok {
+		return &heldFileAt{heldFile: held, r: r}
+	}
+	return held
+}
+
+func (d *RefCountDriver) DriverName() string {
+	return d.base.DriverName()
+}
+
+func (d *RefCountDriver) Init(ctx context.Context, config map[string]any) error {
+	defer d.acquire()()
+	return d.base.Init(ctx, config)
+}
+
+func (d *RefCountDriver) Ping(ctx context.Context) error {
+	defer d.acquire()()
+	if hc, ok := d.base.(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
+func (d *RefCountDriver) List(ctx context.Context, path string) ([]FileInfo, error) {
+	defer d.acquire()()
+	return d.base.List(ctx, path)
+}
+
+func (d *RefCountDriver) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	defer d.acquire()()
+	rc, err := d.base.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return d.hold(rc), nil
+}
+
+func (d *RefCountDriver) OpenFile(ctx context.Context, path string, flag int, perm fs.FileMode) (File, error) {
+	defer d.acquire()()
+	f, err := d.base.OpenFile(ctx, path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return d.holdFile(f, d.acquire()), nil
+}
+
+func (d *RefCountDriver) Create(ctx context.Context, path string, reader io.Reader, size int64) error {
+	defer d.acquire()()
+	return d.base.Create(ctx, path, reader, size)
+}
+
+func (d *RefCountDriver) Mkdir(ctx context.Context, path string, perm fs.FileMode) error {
+	defer d.acquire()()
+	return d.base.Mkdir(ctx, path, perm)
+}
+
+func (d *RefCountDriver) Stat(ctx context.Context, path string) (FileInfo, error) {
+	defer d.acquire()()
+	return d.base.Stat(ctx, path)
+}
+
+func (d *RefCountDriver) Delete(ctx context.Context, path string) error {
+	defer d.acquire()()
+	return d.base.Delete(ctx, path)
+}
+
+func (d *RefCountDriver) Rename(ctx context.Context, srcPath, dstPath string) error {
+	defer d.acquire()()
+	return d.base.Rename(ctx, srcPath, dstPath)
+}
+
+func (d *RefCountDriver) SetModTime(ctx context.Context, path string, mtime time.Time) error {
+	defer d.acquire()()
+	return d.base.SetModTime(ctx, path, mtime)
+}
+
+func (d *RefCountDriver) SetMode(ctx context.Context, path string, mode fs.FileMode) error {
+	defer d.acquire()()
+	return d.base.SetMode(ctx, path, mode)
+}
+
+func (d *RefCountDriver) Truncate(ctx context.Context, path string, size int64) error {
+	defer d.acquire()()
+	return d.base.Truncate(ctx, path, size)
+}
+
+func (d *RefCountDriver) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return nil, ErrMetadataNotSupported
+	}
+	defer d.acquire()()
+	return md.GetMetadata(ctx, path)
+}
+
+func (d *RefCountDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	defer d.acquire()()
+	return md.SetMetadata(ctx, path, key, value)
+}
+
+func (d *RefCountDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	defer d.acquire()()
+	return md.DeleteMetadata(ctx, path, key)
+}
+
+// heldFile 关闭时释放对驱动的引用
+type heldFile struct {
+	File
+	release func()
+}
+
+func (f *heldFile) Close() error {
+	err := f.File.Close()
+	f.release()
+	return err
+}
+
+// heldFileAt 底层文件支持随机读取时保留 io.ReaderAt (内容提取与媒体信息解析依赖它)
+type heldFileAt struct {
+	*heldFile
+	r io.ReaderAt
+}
+
+func (f *heldFileAt) ReadAt(p []byte, off int64) (int, error) {
+	return f.r.ReadAt(p, off)
+}
+
+type heldReader struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r *heldReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
@@ -0,0 +1,95 @@
+package vfs_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/local"
+)
+
+// closeCounter 记录底层驱动是否已关闭
+type closeCounter struct {
+	vfs.StorageDriver
+	closed atomic.Int32
+}
+
+func (d *closeCounter) Close() error {
+	d.closed.Add(1)
+	return d.StorageDriver.Close()
+}
+
+func newRefCountTest(t *testing.T) (*vfs.RefCountDriver, *closeCounter) {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("abc"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	base := local.NewLocalDriver()
+	if err := base.Init(context.Background(), map[string]any{"root_path": root}); err != nil {
+		t.Fatal(err)
+	}
+	counter := &closeCounter{StorageDriver: base}
+	return vfs.NewRefCountDriver(counter), counter
+}
+
+// 驱逐时仍在读取的文件不受影响，关闭最后一个句柄后才关闭底层驱动
+func TestRefCountClosesAfterLastFile(t *testing.T) {
+	driver, counter := newRefCountTest(t)
+	rc, err := driver.Open(context.Background(), "/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rc.(io.ReaderAt); !ok {
+		t.Fatal("io.ReaderAt of the underlying file was hidden")
+	}
+	driver.Close()
+	if n := counter.closed.Load(); n != 0 {
+		t.Fatalf("base closed %d times with a file still open", n)
+	}
+	if data, err := io.ReadAll(rc); err != nil || string(data) != "abc" {
+		t.Fatalf("read after eviction = %q, %v", data, err)
+	}
+	rc.Close()
+	rc.Close()
+	if n := counter.closed.Load(); n != 1 {
+		t.Fatalf("base closed %d times after the last file, want 1", n)
+	}
+}
+
+// Hold 覆盖同一请求中两次调用之间的间隔，请求结束后才关闭
+func TestRefCountHoldUntilContextDone(t *testing.T) {
+	driver, counter := newRefCountTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	driver.Hold(ctx)
+	driver.Close()
+	if _, err := driver.Stat(ctx, "/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if n := counter.closed.Load(); n != 0 {
+		t.Fatalf("base closed %d times during the request", n)
+	}
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for counter.closed.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := counter.closed.Load(); n != 1 {
+		t.Fatalf("base closed %d times after the request ended, want 1", n)
+	}
+}
+
+func TestRefCountCloseIdle(t *testing.T) {
+	driver, counter := newRefCountTest(t)
+	driver.Hold(context.Background())
+	driver.Close()
+	driver.Close()
+	if n := counter.closed.Load(); n != 1 {
+		t.Fatalf("idle driver closed %d times, want 1", n)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"time"
 )
 
 // PermissionChecker 定义检查接口 (解耦 Application 层)
 type PermissionChecker func(ctx context.Context, path string, action string) (bool, error)
 
 // SecureDriver 装饰器：为普通 Driver 增加权限检查功能
+// 同时兜底将底层驱动未归类的 OS 错误转换为领域错误
 type SecureDriver struct {
 	base    StorageDriver     // 底层驱动 (Local, SMB 等)
 	checker PermissionChecker // 检查函数
@@ -24,6 +26,23 @@ func NewSecureDriver(base StorageDriver, checker PermissionChecker) StorageDrive
 	}
 }
 
+// check 权限不足时返回 ErrPermissionDenied 分类错误
+func (d *SecureDriver) check(ctx context.Context, op, path, action string) error {
+	ok, err := d.checker(ctx, path, action)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewError(op, path, ErrPermissionDenied)
+	}
+	return nil
+}
+
+// Base 返回被包装的驱动，供自行逐条判断权限的场景使用 (如搜索遍历、建立索引)
+func (d *SecureDriver) Base() StorageDriver {
+	return d.base
+}
+
 func (d *SecureDriver) DriverName() string {
 	return "secure-" + d.base.DriverName()
 }
@@ -32,37 +51,42 @@ func (d *SecureDriver) Init(ctx context.Context, config map[string]any) error {
 	return d.base.Init(ctx, config)
 }
 
+// Ping 健康检查不涉及具体路径，无需权限检查
+func (d *SecureDriver) Ping(ctx context.Context) error {
+	if hc, ok := d.base.(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
 // --- 读操作 (检查 "read") ---
 
 func (d *SecureDriver) List(ctx context.Context, path string) ([]FileInfo, error) {
-	if ok, err := d.checker(ctx, path, "read"); !ok {
-		if err != nil {
-			return nil, err
-		}
-		return nil, os.ErrPermission
+	if err := d.check(ctx, "list", path, "read"); err != nil {
+		return nil, err
 	}
-	return d.base.List(ctx, path)
+	files, err := d.base.List(ctx, path)
+	return files, WrapError("list", path, err)
 }
 
 func (d *SecureDriver) Open(ctx context.Context, path string) (io.ReadCloser, error) {
-	if ok, err := d.checker(ctx, path, "read"); !ok {
-		if err != nil {
-			return nil, err
-		}
-		return nil, os.ErrPermission
+	if err := d.check(ctx, "open", path, "read"); err != nil {
+		return nil, err
 	}
-	return d.base.Open(ctx, path)
+	rc, err := d.base.Open(ctx, path)
+	if err != nil {
+		return nil, WrapError("open", path, err)
+	}
+	return rc, nil
 }
 
 func (d *SecureDriver) Stat(ctx context.Context, path string) (FileInfo, error) {
 	// Stat 通常允许 read 权限即可
-	if ok, err := d.checker(ctx, path, "read"); !ok {
-		if err != nil {
-			return FileInfo{}, err
-		}
-		return FileInfo{}, os.ErrPermission
+	if err := d.check(ctx, "stat", path, "read"); err != nil {
+		return FileInfo{}, err
 	}
-	return d.base.Stat(ctx, path)
+	info, err := d.base.Stat(ctx, path)
+	return info, WrapError("stat", path, err)
 }
 
 func (d *SecureDriver) OpenFile(ctx context.Context, path string, flag int, perm fs.FileMode) (File, error) {
@@ -72,62 +96,104 @@ func (d *SecureDriver) OpenFile(ctx context.Context, path string, flag int, perm
 		action = "write"
 	}
 
-	if ok, err := d.checker(ctx, path, action); !ok {
-		if err != nil {
-			return nil, err
-		}
-		return nil, os.ErrPermission
+	if err := d.check(ctx, "open", path, action); err != nil {
+		return nil, err
+	}
+	f, err := d.base.OpenFile(ctx, path, flag, perm)
+	if err != nil {
+		return nil, WrapError("open", path, err)
 	}
-	return d.base.OpenFile(ctx, path, flag, perm)
+	return f, nil
 }
 
 // --- 写操作 (检查 "write") ---
 
 func (d *SecureDriver) Create(ctx context.Context, path string, reader io.Reader, size int64) error {
-	if ok, err := d.checker(ctx, path, "write"); !ok {
-		if err != nil {
-			return err
-		}
-		return os.ErrPermission
+	if err := d.check(ctx, "create", path, "write"); err != nil {
+		return err
 	}
-	return d.base.Create(ctx, path, reader, size)
+	return WrapError("create", path, d.base.Create(ctx, path, reader, size))
 }
 
 func (d *SecureDriver) Mkdir(ctx context.Context, path string, perm fs.FileMode) error {
-	if ok, err := d.checker(ctx, path, "write"); !ok {
-		if err != nil {
-			return err
-		}
-		return os.ErrPermission
+	if err := d.check(ctx, "mkdir", path, "write"); err != nil {
+		return err
 	}
-	return d.base.Mkdir(ctx, path, perm)
+	return WrapError("mkdir", path, d.base.Mkdir(ctx, path, perm))
 }
 
 func (d *SecureDriver) Delete(ctx context.Context, path string) error {
-	if ok, err := d.checker(ctx, path, "write"); !ok {
-		if err != nil {
-			return err
-		}
-		return os.ErrPermission
+	if err := d.check(ctx, "delete", path, "write"); err != nil {
+		return err
 	}
-	return d.base.Delete(ctx, path)
+	return WrapError("delete", path, d.base.Delete(ctx, path))
 }
 
 func (d *SecureDriver) Rename(ctx context.Context, srcPath, dstPath string) error {
 	// 重命名需要：源路径(写/删权限) + 目标路径(写权限)
-	if ok, err := d.checker(ctx, srcPath, "write"); !ok {
-		if err != nil {
-			return err
-		}
-		return os.ErrPermission
-	}
-	if ok, err := d.checker(ctx, dstPath, "write"); !ok {
-		if err != nil {
-			return err
-		}
-		return os.ErrPermission
-	}
-	return d.base.Rename(ctx, srcPath, dstPath)
+	if err := d.check(ctx, "rename", srcPath, "write"); err != nil {
+		return err
+	}
+	if err := d.check(ctx, "rename", dstPath, "write"); err != nil {
+		return err
+	}
+	return WrapError("rename", srcPath, d.base.Rename(ctx, srcPath, dstPath))
+}
+
+func (d *SecureDriver) SetModTime(ctx context.Context, path string, mtime time.Time) error {
+	if err := d.check(ctx, "chtimes", path, "write"); err != nil {
+		return err
+	}
+	return WrapError("chtimes", path, d.base.SetModTime(ctx, path, mtime))
+}
+
+func (d *SecureDriver) SetMode(ctx context.Context, path string, mode fs.FileMode) error {
+	if err := d.check(ctx, "chmod", path, "write"); err != nil {
+		return err
+	}
+	return WrapError("chmod", path, d.base.SetMode(ctx, path, mode))
+}
+
+func (d *SecureDriver) Truncate(ctx context.Context, path string, size int64) error {
+	if err := d.check(ctx, "truncate", path, "write"); err != nil {
+		return err
+	}
+	return WrapError("truncate", path, d.base.Truncate(ctx, path, size))
+}
+
+// --- 元数据 (可选能力，底层不支持时返回 ErrMetadataNotSupported) ---
+
+func (d *SecureDriver) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	if err := d.check(ctx, "getmeta", path, "read"); err != nil {
+		return nil, err
+	}
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return nil, ErrMetadataNotSupported
+	}
+	return md.GetMetadata(ctx, path)
+}
+
+func (d *SecureDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	if err := d.check(ctx, "setmeta", path, "write"); err != nil {
+		return err
+	}
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return md.SetMetadata(ctx, path, key, value)
+}
+
+func (d *SecureDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	if err := d.check(ctx, "delmeta", path, "write"); err != nil {
+		return err
+	}
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return md.DeleteMetadata(ctx, path, key)
 }
 
 func (d *SecureDriver) Close() error {
@@ -0,0 +1,211 @@
+package vfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// SnapshotFunc 在已有文件被覆盖前调用，base 为被包装的驱动，可以读取旧内容；返回错误时中止写入
+type SnapshotFunc func(ctx context.Context, base StorageDriver, path string, info FileInfo) error
+
+// SnapshotDriver 装饰器：Create、写方式 OpenFile、Truncate 与覆盖目标的 Rename 执行前保存旧内容 (文件版本等)
+type SnapshotDriver struct {
+	base     StorageDriver
+	snapshot SnapshotFunc
+}
+
+// NewSnapshotDriver 包装一个驱动
+func NewSnapshotDriver(base StorageDriver, snapshot SnapshotFunc) StorageDriver {
+	return &SnapshotDriver{base: base, snapshot: snapshot}
+}
+
+// Base 返回被包装的驱动，通过它的写操作不会保存旧内容
+func (d *SnapshotDriver) Base() StorageDriver {
+	return d.base
+}
+
+// before path 是已存在的文件时调用 snapshot，新建文件无需处理
+func (d *SnapshotDriver) before(ctx context.Context, path string) error {
+	info, err := d.base.Stat(ctx, path)
+	if err != nil || info.IsDir {
+		return nil
+	}
+	return d.snapshot(ctx, d.base, path, info)
+}
+
+func (d *SnapshotDriver) DriverName() string {
+	return d.base.DriverName()
+}
+
+func (d *SnapshotDriver) Init(ctx context.Context, config map[string]any) error {
+	return d.base.Init(ctx, config)
+}
+
+func (d *SnapshotDriver) Ping(ctx context.Context) error {
+	if hc, ok := d.base.(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
+func (d *SnapshotDriver) List(ctx context.Context, path string) ([]FileInfo, error) {
+	return d.base.List(ctx, path)
+}
+
+func (d *SnapshotDriver) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	return d.base.Open(ctx, path)
+}
+
+// OpenFile 带 O_TRUNC 打开已有文件时先保存旧内容 (WebDAV PUT 通过这里写入)
+// 其他写方式 (如 PROPPATCH 以 O_RDWR 打开) 推迟到第一次 Write 时保存，只打开不写入时不产生版本
+// 保存旧内容时不持有写句柄：先以只读方式打开，第一次 Write 时关闭只读句柄、保存旧内容后再以原方式重新打开
+func (d *SnapshotDriver) OpenFile(ctx context.Context, path string, flag int, perm fs.FileMode) (File, error) {
+	if flag&os.O_TRUNC != 0 {
+		if err := d.before(ctx, path); err != nil {
+			return nil, err
+		}
+		return d.base.OpenFile(ctx, path, flag, perm)
+	}
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 {
+		return d.base.OpenFile(ctx, path, flag, perm)
+	}
+	// O_CREATE 新建的空文件不需要保存
+	if _, err := d.base.Stat(ctx, path); err != nil {
+		return d.base.OpenFile(ctx, path, flag, perm)
+	}
+	f, err := d.base.OpenFile(ctx, path, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshotFile{File: f, reopen: func() (File, error) {
+		if err := d.before(ctx, path); err != nil {
+			return nil, err
+		}
+		return d.base.OpenFile(ctx, path, flag&^(os.O_CREATE|os.O_EXCL), perm)
+	}}, nil
+}
+
+func (d *SnapshotDriver) Create(ctx context.Context, path string, reader io.Reader, size int64) error {
+	if err := d.before(ctx, path); err != nil {
+		return err
+	}
+	return d.base.Create(ctx, path, reader, size)
+}
+
+func (d *SnapshotDriver) Mkdir(ctx context.Context, path string, perm fs.FileMode) error {
+	return d.base.Mkdir(ctx, path, perm)
+}
+
+func (d *SnapshotDriver) Stat(ctx context.Context, path string) (FileInfo, error) {
+	return d.base.Stat(ctx, path)
+}
+
+func (d *SnapshotDriver) Delete(ctx context.Context, path string) error {
+	return d.base.Delete(ctx, path)
+}
+
+// Rename 目标是已有文件时先保存目标的旧内容 (REST 上传先写临时文件再改名覆盖)
+func (d *SnapshotDriver) Rename(ctx context.Context, srcPath, dstPath string) error {
+	if srcPath != dstPath {
+		if err := d.before(ctx, dstPath); err != nil {
+			return err
+		}
+	}
+	return d.base.Rename(ctx, srcPath, dstPath)
+}
+
+func (d *SnapshotDriver) SetModTime(ctx context.Context, path string, mtime time.Time) error {
+	return d.base.SetModTime(ctx, path, mtime)
+}
+
+func (d *SnapshotDriver) SetMode(ctx context.Context, path string, mode fs.FileMode) error {
+	return d.base.SetMode(ctx, path, mode)
+}
+
+func (d *SnapshotDriver) Truncate(ctx context.Context, path string, size int64) error {
+	if err := d.before(ctx, path); err != nil {
+		return err
+	}
+	return d.base.Truncate(ctx, path, size)
+}
+
+func (d *SnapshotDriver) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return nil, ErrMetadataNotSupported
+	}
+	return md.GetMetadata(ctx, path)
+}
+
+func (d *SnapshotDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return md.SetMetadata(ctx, path, key, value)
+}
+
+func (d *SnapshotDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return md.DeleteMetadata(ctx, path, key)
+}
+
+func (d *SnapshotDriver) Close() error {
+	return d.base.Close()
+}
+
+// snapshotFile 第一次写入前保存旧内容，之前只持有只读句柄
+type snapshotFile struct {
+	File
+	reopen func() (File, error)
+}
+
+func (f *snapshotFile) Write(p []byte) (int, error) {
+	if f.reopen != nil {
+		if err := f.upgrade(); err != nil {
+			return 0, err
+		}
+	}
+	return f.File.Write(p)
+}
+
+// upgrade 关闭只读句柄，保存旧内容后以写方式重新打开，并恢复读写位置
+func (f *snapshotFile) upgrade() error {
+	reopen := f.reopen
+	f.reopen = nil
+	pos, err := f.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	w, err := reopen()
+	if err != nil {
+		f.File = closedFile{}
+		return err
+	}
+	if _, err := w.Seek(pos, io.SeekStart); err != nil {
+		w.Close()
+		f.File = closedFile{}
+		return err
+	}
+	f.File = w
+	return nil
+}
+
+// closedFile 重新打开失败后的占位，所有操作返回 fs.ErrClosed
+type closedFile struct{}
+
+func (closedFile) Close() error                       { return nil }
+func (closedFile) Read([]byte) (int, error)           { return 0, fs.ErrClosed }
+func (closedFile) Write([]byte) (int, error)          { return 0, fs.ErrClosed }
+func (closedFile) Seek(int64, int) (int64, error)     { return 0, fs.ErrClosed }
+func (closedFile) Readdir(int) ([]fs.FileInfo, error) { return nil, fs.ErrClosed }
+func (closedFile) Stat() (fs.FileInfo, error)         { return nil, fs.ErrClosed }
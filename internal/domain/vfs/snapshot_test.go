@@ -0,0 +1,140 @@
+package vfs_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/local"
+)
+
+// handleCounter 统计同时打开的文件句柄 (SMB 上每个句柄占用一个连接)
+type handleCounter struct {
+	vfs.StorageDriver
+	open atomic.Int32
+}
+
+func (d *handleCounter) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	rc, err := d.StorageDriver.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	d.open.Add(1)
+	return &countedFile{File: rc.(vfs.File), open: &d.open}, nil
+}
+
+func (d *handleCounter) OpenFile(ctx context.Context, path string, flag int, perm os.FileMode) (vfs.File, error) {
+	f, err := d.StorageDriver.OpenFile(ctx, path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	d.open.Add(1)
+	return &countedFile{File: f, open: &d.open}, nil
+}
+
+type countedFile struct {
+	vfs.File
+	open *atomic.Int32
+}
+
+func (f *countedFile) Close() error {
+	f.open.Add(-1)
+	return f.File.Close()
+}
+
+func newSnapshotTest(t *testing.T, content string) (*handleCounter, string) {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	base := local.NewLocalDriver()
+	if err := base.Init(context.Background(), map[string]any{"root_path": root}); err != nil {
+		t.Fatal(err)
+	}
+	return &handleCounter{StorageDriver: base}, root
+}
+
+func TestSnapshotBeforeWriteHandle(t *testing.T) {
+	tests := []struct {
+		name  string
+		flag  int
+		write bool
+		want  string // 写入后的文件内容
+		saved string // 保存的旧内容，空表示没有保存
+	}{
+		{"rdwr write", os.O_RDWR, true, "NEW", "old"},
+		{"wronly write", os.O_WRONLY, true, "NEW", "old"},
+		{"trunc write", os.O_RDWR | os.O_TRUNC, true, "NEW", "old"},
+		{"rdwr without write", os.O_RDWR, false, "old", ""},
+		{"read only", os.O_RDONLY, false, "old", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, root := newSnapshotTest(t, "old")
+			var saved string
+			driver := vfs.NewSnapshotDriver(counter, func(ctx context.Context, base vfs.StorageDriver, path string, info vfs.FileInfo) error {
+				// 保存旧内容时调用方不应持有任何句柄
+				if n := counter.open.Load(); n != 0 {
+					t.Errorf("%d handles open during snapshot", n)
+				}
+				rc, err := base.Open(ctx, path)
+				if err != nil {
+					return err
+				}
+				defer rc.Close()
+				data, err := io.ReadAll(rc)
+				saved = string(data)
+				return err
+			})
+			ctx := context.Background()
+
+			f, err := driver.OpenFile(ctx, "/a.txt", tt.flag, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.write {
+				if _, err := f.Write([]byte("NEW")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if n := counter.open.Load(); n != 0 {
+				t.Fatalf("%d handles left open", n)
+			}
+			if saved != tt.saved {
+				t.Fatalf("saved %q, want %q", saved, tt.saved)
+			}
+			data, err := os.ReadFile(filepath.Join(root, "a.txt"))
+			if err != nil || string(data) != tt.want {
+				t.Fatalf("file = %q (%v), want %q", data, err, tt.want)
+			}
+		})
+	}
+}
+
+// 重新打开写句柄后保持原来的读写位置
+func TestSnapshotKeepsOffset(t *testing.T) {
+	counter, root := newSnapshotTest(t, "abcdef")
+	driver := vfs.NewSnapshotDriver(counter, func(context.Context, vfs.StorageDriver, string, vfs.FileInfo) error { return nil })
+	f, err := driver.OpenFile(context.Background(), "/a.txt", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(2, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("XY")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "abXYef" {
+		t.Fatalf("file = %q, want abXYef", data)
+	}
+}
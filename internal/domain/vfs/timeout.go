@@ -0,0 +1,206 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// ErrTimeout 存储操作超时 (NAS 无响应等)，API 层映射为 504
+var ErrTimeout = errors.New("storage operation timed out")
+
+// TimeoutError 携带操作与路径信息的超时错误，errors.Is(err, ErrTimeout) 为 true
+type TimeoutError struct {
+	Op   string
+	Path string
+	Err  error
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Op + " " + e.Path + ": " + ErrTimeout.Error()
+}
+
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+func (e *TimeoutError) Is(target error) bool { return target == ErrTimeout }
+
+// Timeout 兼容 os.IsTimeout / net.Error 风格的判断
+func (e *TimeoutError) Timeout() bool { return true }
+
+// TimeoutDriver 装饰器：为非流式操作 (List、Stat、Mkdir 等) 增加超时
+// Open/OpenFile/Create 返回或消费数据流，耗时取决于文件大小，只受请求本身的 ctx 控制
+type TimeoutDriver struct {
+	base    StorageDriver
+	timeout time.Duration
+}
+
+// NewTimeoutDriver 包装一个驱动，timeout <= 0 时直接返回原驱动
+func NewTimeoutDriver(base StorageDriver, timeout time.Duration) StorageDriver {
+	if timeout <= 0 {
+		return base
+	}
+	return &TimeoutDriver{base: base, timeout: timeout}
+}
+
+// run 在带超时的 ctx 中执行操作，并把超时统一转换为 *TimeoutError
+func (d *TimeoutDriver) run(ctx context.Context, op, path string, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	return wrapTimeout(ctx, op, path, fn(ctx))
+}
+
+// wrapTimeout ctx 已超时或底层返回了超时错误时，转换为 *TimeoutError
+func wrapTimeout(ctx context.Context, op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, ErrTimeout) {
+		return err
+	}
+	var t interface{ Timeout() bool }
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) ||
+		(errors.As(err, &t) && t.Timeout()) {
+		return &TimeoutError{Op: op, Path: path, Err: err}
+	}
+	return err
+}
+
+func (d *TimeoutDriver) DriverName() string {
+	return d.base.DriverName()
+}
+
+func (d *TimeoutDriver) Init(ctx context.Context, config map[string]any) error {
+	return d.run(ctx, "init", "/", func(ctx context.Context) error {
+		return d.base.Init(ctx, config)
+	})
+}
+
+func (d *TimeoutDriver) List(ctx context.Context, path string) (files []FileInfo, err error) {
+	err = d.run(ctx, "list", path, func(ctx context.Context) error {
+		files, err = d.base.List(ctx, path)
+		return err
+	})
+	return files, err
+}
+
+func (d *TimeoutDriver) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	rc, err := d.base.Open(ctx, path)
+	return rc, wrapTimeout(ctx, "open", path, err)
+}
+
+func (d *TimeoutDriver) OpenFile(ctx context.Context, path string, flag int, perm fs.FileMode) (File, error) {
+	f, err := d.base.OpenFile(ctx, path, flag, perm)
+	return f, wrapTimeout(ctx, "open", path, err)
+}
+
+func (d *TimeoutDriver) Create(ctx context.Context, path string, reader io.Reader, size int64) error {
+	return wrapTimeout(ctx, "create", path, d.base.Create(ctx, path, reader, size))
+}
+
+func (d *TimeoutDriver) Mkdir(ctx context.Context, path string, perm fs.FileMode) error {
+	return d.run(ctx, "mkdir", path, func(ctx context.Context) error {
+		return d.base.Mkdir(ctx, path, perm)
+	})
+}
+
+func (d *TimeoutDriver) Stat(ctx context.Context, path string) (info FileInfo, err error) {
+	err = d.run(ctx, "stat", path, func(ctx context.Context) error {
+		info, err = d.base.Stat(ctx, path)
+		return err
+	})
+	return info, err
+}
+
+// Delete 递归删除大目录可能很慢，但它仍然是一次完整的操作，超时后由调用方决定是否重试
+func (d *TimeoutDriver) Delete(ctx context.Context, path string) error {
+	return d.run(ctx, "delete", path, func(ctx context.Context) error {
+		return d.base.Delete(ctx, path)
+	})
+}
+
+func (d *TimeoutDriver) Rename(ctx context.Context, srcPath, dstPath string) error {
+	return d.run(ctx, "rename", srcPath, func(ctx context.Context) error {
+		return d.base.Rename(ctx, srcPath, dstPath)
+	})
+}
+
+func (d *TimeoutDriver) SetModTime(ctx context.Context, path string, mtime time.Time) error {
+	return d.run(ctx, "chtimes", path, func(ctx context.Context) error {
+		return d.base.SetModTime(ctx, path, mtime)
+	})
+}
+
+func (d *TimeoutDriver) SetMode(ctx context.Context, path string, mode fs.FileMode) error {
+	return d.run(ctx, "chmod", path, func(ctx context.Context) error {
+		return d.base.SetMode(ctx, path, mode)
+	})
+}
+
+func (d *TimeoutDriver) Truncate(ctx context.Context, path string, size int64) error {
+	return d.run(ctx, "truncate", path, func(ctx context.Context) error {
+		return d.base.Truncate(ctx, path, size)
+	})
+}
+
+func (d *TimeoutDriver) Ping(ctx context.Context) error {
+	hc, ok := d.base.(HealthChecker)
+	if !ok {
+		return nil
+	}
+	return d.run(ctx, "ping", "/", hc.Ping)
+}
+
+func (d *TimeoutDriver) GetMetadata(ctx context.Context, path string) (meta map[string]string, err error) {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return nil, ErrMetadataNotSupported
+	}
+	err = d.run(ctx, "getmeta", path, func(ctx context.Context) error {
+		meta, err = md.GetMetadata(ctx, path)
+		return err
+	})
+	return meta, err
+}
+
+func (d *TimeoutDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return d.run(ctx, "setmeta", path, func(ctx context.Context) error {
+		return md.SetMetadata(ctx, path, key, value)
+	})
+}
+
+func (d *TimeoutDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return d.run(ctx, "delmeta", path, func(ctx context.Context) error {
+		return md.DeleteMetadata(ctx, path, key)
+	})
+}
+
+func (d *TimeoutDriver) Close() error {
+	return d.base.Close()
+}
+
+// CopyContext 与 io.Copy 相同，但每个分块之间检查 ctx，客户端断开后及时停止写入
+func CopyContext(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	return io.Copy(dst, &contextReader{ctx: ctx, r: src})
+}
+
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
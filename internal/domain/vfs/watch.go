@@ -0,0 +1,172 @@
+package vfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// 变更类型
+const (
+	ChangeWrite  = "write"  // 文件内容被创建、覆盖或截断
+	ChangeDelete = "delete" // 文件或目录被删除
+	ChangeRename = "rename" // 文件或目录被移动到 Dest
+)
+
+// ChangeEvent 一次成功的写操作
+type ChangeEvent struct {
+	Op   string
+	Path string
+	Dest string // 仅 ChangeRename
+}
+
+// ChangeListener 接收变更通知，在写操作所在的协程中同步调用，耗时操作应自行异步处理
+type ChangeListener func(ctx context.Context, ev ChangeEvent)
+
+// WatchDriver 装饰器：写操作成功后发出变更通知 (全文索引等据此增量更新)
+type WatchDriver struct {
+	base   StorageDriver
+	notify ChangeListener
+}
+
+// NewWatchDriver 包装一个驱动
+func NewWatchDriver(base StorageDriver, notify ChangeListener) StorageDriver {
+	return &WatchDriver{base: base, notify: notify}
+}
+
+// Base 返回被包装的驱动，通过它的写操作不会发出通知
+func (d *WatchDriver) Base() StorageDriver {
+	return d.base
+}
+
+func (d *WatchDriver) DriverName() string {
+	return d.base.DriverName()
+}
+
+func (d *WatchDriver) Init(ctx context.Context, config map[string]any) error {
+	return d.base.Init(ctx, config)
+}
+
+func (d *WatchDriver) Ping(ctx context.Context) error {
+	if hc, ok := d.base.(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
+func (d *WatchDriver) List(ctx context.Context, path string) ([]FileInfo, error) {
+	return d.base.List(ctx, path)
+}
+
+func (d *WatchDriver) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	return d.base.Open(ctx, path)
+}
+
+func (d *WatchDriver) Stat(ctx context.Context, path string) (FileInfo, error) {
+	return d.base.Stat(ctx, path)
+}
+
+// OpenFile 以写方式打开时，在文件关闭后通知 (WebDAV PUT 通过这里写入)
+func (d *WatchDriver) OpenFile(ctx context.Context, path string, flag int, perm fs.FileMode) (File, error) {
+	f, err := d.base.OpenFile(ctx, path, flag, perm)
+	if err != nil || flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) == 0 {
+		return f, err
+	}
+	return &watchedFile{
+		File:    f,
+		changed: flag&(os.O_CREATE|os.O_TRUNC) != 0,
+		done: func() {
+			d.notify(context.WithoutCancel(ctx), ChangeEvent{Op: ChangeWrite, Path: path})
+		},
+	}, nil
+}
+
+func (d *WatchDriver) Create(ctx context.Context, path string, reader io.Reader, size int64) error {
+	return d.after(ctx, ChangeEvent{Op: ChangeWrite, Path: path}, d.base.Create(ctx, path, reader, size))
+}
+
+func (d *WatchDriver) Mkdir(ctx context.Context, path string, perm fs.FileMode) error {
+	return d.base.Mkdir(ctx, path, perm)
+}
+
+func (d *WatchDriver) Delete(ctx context.Context, path string) error {
+	return d.after(ctx, ChangeEvent{Op: ChangeDelete, Path: path}, d.base.Delete(ctx, path))
+}
+
+func (d *WatchDriver) Rename(ctx context.Context, srcPath, dstPath string) error {
+	return d.after(ctx, ChangeEvent{Op: ChangeRename, Path: srcPath, Dest: dstPath}, d.base.Rename(ctx, srcPath, dstPath))
+}
+
+func (d *WatchDriver) SetModTime(ctx context.Context, path string, mtime time.Time) error {
+	return d.base.SetModTime(ctx, path, mtime)
+}
+
+func (d *WatchDriver) SetMode(ctx context.Context, path string, mode fs.FileMode) error {
+	return d.base.SetMode(ctx, path, mode)
+}
+
+func (d *WatchDriver) Truncate(ctx context.Context, path string, size int64) error {
+	return d.after(ctx, ChangeEvent{Op: ChangeWrite, Path: path}, d.base.Truncate(ctx, path, size))
+}
+
+func (d *WatchDriver) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return nil, ErrMetadataNotSupported
+	}
+	return md.GetMetadata(ctx, path)
+}
+
+func (d *WatchDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return md.SetMetadata(ctx, path, key, value)
+}
+
+func (d *WatchDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	md, ok := d.base.(MetadataDriver)
+	if !ok {
+		return ErrMetadataNotSupported
+	}
+	return md.DeleteMetadata(ctx, path, key)
+}
+
+func (d *WatchDriver) Close() error {
+	return d.base.Close()
+}
+
+// after 操作成功时发出通知，原样返回 err
+func (d *WatchDriver) after(ctx context.Context, ev ChangeEvent, err error) error {
+	if err == nil {
+		d.notify(ctx, ev)
+	}
+	return err
+}
+
+// watchedFile 写入过内容的文件在关闭时通知
+type watchedFile struct {
+	File
+	changed bool
+	done    func()
+}
+
+func (f *watchedFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if n > 0 {
+		f.changed = true
+	}
+	return n, err
+}
+
+func (f *watchedFile) Close() error {
+	err := f.File.Close()
+	if f.changed {
+		f.changed = false
+		f.done()
+	}
+	return err
+}
@@ -0,0 +1,223 @@
+// Package diff 按行比较两段文本并输出 unified 格式 (Myers 算法)
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op 行的变化类型
+type Op byte
+
+const (
+	Equal  Op = ' '
+	Delete Op = '-'
+	Insert Op = '+'
+)
+
+// maxEdits 编辑距离超过该值时不再寻找最短编辑序列，剩余部分整体视为删除后插入
+// Myers 算法的回溯表占用 O(D²) 内存，需要设置上限
+const maxEdits = 2000
+
+// Line 比较结果中的一行
+type Line struct {
+	Op   Op
+	Text string
+}
+
+// Result 比较结果
+type Result struct {
+	Lines   []Line
+	Added   int
+	Removed int
+}
+
+// SplitLines 按行切分，兼容 CRLF，末尾换行不产生空行
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines
+}
+
+// Compare 计算 a 到 b 的逐行差异
+func Compare(a, b []string) *Result {
+	// 去掉相同的首尾部分，大多数修改只涉及文件中的一小段
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	r := &Result{}
+	for _, l := range a[:prefix] {
+		r.Lines = append(r.Lines, Line{Equal, l})
+	}
+	r.Lines = append(r.Lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		r.Lines = append(r.Lines, Line{Equal, l})
+	}
+	for _, l := range r.Lines {
+		switch l.Op {
+		case Insert:
+			r.Added++
+		case Delete:
+			r.Removed++
+		}
+	}
+	return r
+}
+
+// myers 返回最短编辑序列；编辑距离超过 maxEdits 时退化为整体替换
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return replace(a, b)
+	}
+	// v[k] 为对角线 k 上能到达的最远 x，trace[d] 保存第 d 步开始前 k ∈ [-d-1, d+1] 范围内的 v
+	size := n + m
+	v := make([]int, 2*size+3)
+	offset := size + 1
+	var trace [][]int
+	for d := 0; d <= min(size, maxEdits); d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+	return replace(a, b)
+}
+
+// backtrack 从终点沿 trace 倒推出编辑序列
+func backtrack(a, b []string, trace [][]int) []Line {
+	var out []Line
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		w := trace[d]
+		at := func(k int) int { return w[k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			out = append(out, Line{Equal, a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				out = append(out, Line{Insert, b[y-1]})
+			} else {
+				out = append(out, Line{Delete, a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+func replace(a, b []string) []Line {
+	out := make([]Line, 0, len(a)+len(b))
+	for _, l := range a {
+		out = append(out, Line{Delete, l})
+	}
+	for _, l := range b {
+		out = append(out, Line{Insert, l})
+	}
+	return out
+}
+
+// Unified 输出 unified diff，每处修改前后保留 context 行上下文；没有差异时返回空字符串
+func (r *Result) Unified(from, to string, context int) string {
+	if r.Added == 0 && r.Removed == 0 {
+		return ""
+	}
+	// aLine[i]、bLine[i] 为第 i 行之前两侧各有多少行
+	aLine := make([]int, len(r.Lines)+1)
+	bLine := make([]int, len(r.Lines)+1)
+	for i, l := range r.Lines {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if l.Op != Insert {
+			aLine[i+1]++
+		}
+		if l.Op != Delete {
+			bLine[i+1]++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", from, to)
+	n := len(r.Lines)
+	for i := 0; i < n; {
+		for i < n && r.Lines[i].Op == Equal {
+			i++
+		}
+		if i == n {
+			break
+		}
+		start := max(i-context, 0)
+		// 两处修改之间的相同行不超过 2*context 时合并为一个 hunk
+		end := i
+		for {
+			for end < n && r.Lines[end].Op != Equal {
+				end++
+			}
+			run := 0
+			for end+run < n && r.Lines[end+run].Op == Equal {
+				run++
+			}
+			if end+run == n || run > 2*context {
+				end += min(run, context)
+				break
+			}
+			end += run
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aLine[start], aLine[end]), hunkRange(bLine[start], bLine[end]))
+		for _, l := range r.Lines[start:end] {
+			sb.WriteByte(byte(l.Op))
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// hunkRange 行号从 1 开始；行数为 0 时起始行号为前一行
+func hunkRange(from, to int) string {
+	count := to - from
+	if count == 0 {
+		return fmt.Sprintf("%d,0", from)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", from+1)
+	}
+	return fmt.Sprintf("%d,%d", from+1, count)
+}
@@ -2,6 +2,8 @@ package drivers
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
@@ -10,29 +12,118 @@ import (
 // DriverFactory 定义创建驱动的函数签名
 type DriverFactory func() vfs.StorageDriver
 
+// registration 已注册的驱动
+type registration struct {
+	factory DriverFactory
+	schema  Schema
+}
+
 var (
-	driverFactories = make(map[string]DriverFactory)
+	driverFactories = make(map[string]registration)
 	mu              sync.RWMutex
 )
 
-// Register 注册一个新的驱动类型 (在各驱动的 init() 函数中调用)
-// 比如：drivers.Register("smb", NewSMBDriver)
-func Register(name string, factory DriverFactory) {
+// Register 注册一个新的驱动类型及其配置说明 (在各驱动的 init() 函数中调用)
+// 比如：drivers.Register("smb", NewSMBDriver, smbSchema)
+func Register(name string, factory DriverFactory, schema Schema) {
 	mu.Lock()
 	defer mu.Unlock()
 	if factory == nil {
 		panic("driver factory is nil")
 	}
-	driverFactories[name] = factory
+	schema.Type = name
+	driverFactories[name] = registration{factory: factory, schema: schema}
 }
 
 // CreateInstance 根据类型名称创建一个新的驱动实例
 func CreateInstance(driverType string) (vfs.StorageDriver, error) {
 	mu.RLock()
 	defer mu.RUnlock()
-	factory, ok := driverFactories[driverType]
+	reg, ok := driverFactories[driverType]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver type: %s", driverType)
+	}
+	return reg.factory(), nil
+}
+
+// Schemas 按类型名称排序返回全部驱动的配置说明
+func Schemas() []Schema {
+	mu.RLock()
+	defer mu.RUnlock()
+	schemas := make([]Schema, 0, len(driverFactories))
+	for _, reg := range driverFactories {
+		schemas = append(schemas, reg.schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Type < schemas[j].Type })
+	return schemas
+}
+
+// SecretFields 返回驱动配置中标记为 Secret 的配置项名称，未知驱动返回 nil
+func SecretFields(driverType string) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var names []string
+	for _, f := range driverFactories[driverType].schema.Fields {
+		if f.Secret {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// SameTarget 判断两份配置中标记为 Identity 的配置项是否都相同 (缺省时按默认值比较)
+// 敏感配置项只能沿用到同一个目标上，否则修改主机后已保存的密码会被发往新的主机
+func SameTarget(driverType string, a, b map[string]any) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, f := range driverFactories[driverType].schema.Fields {
+		if f.Identity && identityValue(f, a) != identityValue(f, b) {
+			return false
+		}
+	}
+	return true
+}
+
+// identityValue 配置项的比较值，"445" 与 445 视为相同
+func identityValue(f Field, config map[string]any) string {
+	v, ok := config[f.Name]
+	if !ok || v == nil {
+		v = f.Default
+	}
+	if v == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(v))
+}
+
+// UnknownFields 返回驱动 Schema 中未声明的配置项，未知驱动返回 nil
+func UnknownFields(driverType string, config map[string]any) []string {
+	mu.RLock()
+	reg, ok := driverFactories[driverType]
+	mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	return reg.schema.UnknownFields(config)
+}
+
+// Normalize 按驱动的 Schema 校验配置，返回交给 Init 的规范化副本 (忽略未声明的配置项)
+// 驱动实现 vfs.ConfigValidator 时再检查字段之间的约束
+func Normalize(driverType string, config map[string]any) (map[string]any, error) {
+	mu.RLock()
+	reg, ok := driverFactories[driverType]
+	mu.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("unknown driver type: %s", driverType)
 	}
-	return factory(), nil
+	normalized, err := reg.schema.Normalize(config)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := reg.factory().(vfs.ConfigValidator); ok {
+		if err := v.ValidateConfig(normalized); err != nil {
+			return nil, err
+		}
+	}
+	return normalized, nil
 }
@@ -2,12 +2,14 @@ package local
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
 	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers"
@@ -15,7 +17,12 @@ import (
 
 // init 注册驱动到工厂
 func init() {
-	drivers.Register("local", NewLocalDriver)
+	drivers.Register("local", NewLocalDriver, drivers.Schema{
+		Description: "服务器本地目录",
+		Fields: []drivers.Field{
+			{Name: "root_path", Type: drivers.FieldString, Required: true, Description: "挂载的根目录，不存在时自动创建"},
+		},
+	})
 }
 
 // LocalDriver 本地文件系统实现
@@ -59,6 +66,18 @@ func (d *LocalDriver) Init(ctx context.Context, config map[string]any) error {
 	return nil
 }
 
+// Ping 检查根目录是否仍然可访问 (例如外置磁盘被拔出、网络挂载失效)
+func (d *LocalDriver) Ping(ctx context.Context) error {
+	info, err := os.Stat(d.rootPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("root_path '%s' is not a directory", d.rootPath)
+	}
+	return nil
+}
+
 // safePath 安全路径转换：将虚拟路径转换为物理路径，并防止 ../ 越权
 func (d *LocalDriver) safePath(virtualPath string) (string, error) {
 	// 拼接完整路径
@@ -69,8 +88,9 @@ func (d *LocalDriver) safePath(virtualPath string) (string, error) {
 
 	// 核心安全检查：确保最终路径以前缀 rootPath 开头
 	// 这防止了 virtualPath = "../../etc/passwd" 的情况
-	if !strings.HasPrefix(cleanPath, d.rootPath) {
-		return "", fmt.Errorf("access denied: path traversal attempt")
+	// 注意要带上分隔符比较，否则 /data/files2 也会匹配 /data/files
+	if cleanPath != d.rootPath && !strings.HasPrefix(cleanPath, d.rootPath+string(filepath.Separator)) {
+		return "", &vfs.PathError{Op: "resolve", Path: virtualPath, Kind: vfs.ErrPermissionDenied, Err: errors.New("path traversal attempt")}
 	}
 
 	return cleanPath, nil
@@ -84,11 +104,15 @@ func (d *LocalDriver) List(ctx context.Context, path string) ([]vfs.FileInfo, er
 
 	entries, err := os.ReadDir(realPath)
 	if err != nil {
-		return nil, err
+		return nil, vfs.WrapError("list", path, err)
 	}
 
 	files := make([]vfs.FileInfo, 0, len(entries))
 	for _, entry := range entries {
+		// 大目录逐项 lstat 较慢，请求取消后不再继续
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		info, err := entry.Info()
 		if err != nil {
 			continue // 跳过无法获取信息的项
@@ -108,7 +132,11 @@ func (d *LocalDriver) Open(ctx context.Context, path string) (io.ReadCloser, err
 	if err != nil {
 		return nil, err
 	}
-	return os.Open(realPath)
+	f, err := os.Open(realPath)
+	if err != nil {
+		return nil, vfs.WrapError("open", path, err)
+	}
+	return f, nil
 }
 
 func (d *LocalDriver) OpenFile(ctx context.Context, path string, flag int, perm fs.FileMode) (vfs.File, error) {
@@ -117,7 +145,11 @@ func (d *LocalDriver) OpenFile(ctx context.Context, path string, flag int, perm
 		return nil, err
 	}
 	// os.OpenFile 直接返回 *os.File，它完美实现了 vfs.File 接口
-	return os.OpenFile(realPath, flag, perm)
+	f, err := os.OpenFile(realPath, flag, perm)
+	if err != nil {
+		return nil, vfs.WrapError("open", path, err)
+	}
+	return f, nil
 }
 
 func (d *LocalDriver) Create(ctx context.Context, path string, reader io.Reader, size int64) error {
@@ -129,20 +161,20 @@ func (d *LocalDriver) Create(ctx context.Context, path string, reader io.Reader,
 	// 确保父目录存在
 	parentDir := filepath.Dir(realPath)
 	if err := os.MkdirAll(parentDir, 0755); err != nil {
-		return err
+		return vfs.WrapError("create", path, err)
 	}
 
 	// 创建文件
 	out, err := os.Create(realPath)
 	if err != nil {
-		return err
+		return vfs.WrapError("create", path, err)
 	}
 	defer out.Close()
 
 	// 写入数据
-	// 这里的 Copy 是流式的，内存占用小
-	_, err = io.Copy(out, reader)
-	return err
+	// 这里的 Copy 是流式的，内存占用小；客户端中断上传时停止写入
+	_, err = vfs.CopyContext(ctx, out, reader)
+	return vfs.WrapError("create", path, err)
 }
 
 func (d *LocalDriver) Mkdir(ctx context.Context, path string, perm fs.FileMode) error {
@@ -150,7 +182,7 @@ func (d *LocalDriver) Mkdir(ctx context.Context, path string, perm fs.FileMode)
 	if err != nil {
 		return err
 	}
-	return os.MkdirAll(realPath, perm)
+	return vfs.WrapError("mkdir", path, os.MkdirAll(realPath, perm))
 }
 
 func (d *LocalDriver) Stat(ctx context.Context, path string) (vfs.FileInfo, error) {
@@ -161,7 +193,7 @@ func (d *LocalDriver) Stat(ctx context.Context, path string) (vfs.FileInfo, erro
 
 	info, err := os.Stat(realPath)
 	if err != nil {
-		return vfs.FileInfo{}, err
+		return vfs.FileInfo{}, vfs.WrapError("stat", path, err)
 	}
 
 	return vfs.FileInfo{
@@ -177,7 +209,7 @@ func (d *LocalDriver) Delete(ctx context.Context, path string) error {
 	if err != nil {
 		return err
 	}
-	return os.RemoveAll(realPath) // RemoveAll 删除文件或目录
+	return vfs.WrapError("delete", path, os.RemoveAll(realPath)) // RemoveAll 删除文件或目录
 }
 
 func (d *LocalDriver) Rename(ctx context.Context, srcPath, dstPath string) error {
@@ -189,7 +221,32 @@ func (d *LocalDriver) Rename(ctx context.Context, srcPath, dstPath string) error
 	if err != nil {
 		return err
 	}
-	return os.Rename(realSrc, realDst)
+	return vfs.WrapError("rename", srcPath, os.Rename(realSrc, realDst))
+}
+
+func (d *LocalDriver) SetModTime(ctx context.Context, path string, mtime time.Time) error {
+	realPath, err := d.safePath(path)
+	if err != nil {
+		return err
+	}
+	// atime 传零值表示保持不变
+	return vfs.WrapError("chtimes", path, os.Chtimes(realPath, time.Time{}, mtime))
+}
+
+func (d *LocalDriver) SetMode(ctx context.Context, path string, mode fs.FileMode) error {
+	realPath, err := d.safePath(path)
+	if err != nil {
+		return err
+	}
+	return vfs.WrapError("chmod", path, os.Chmod(realPath, mode.Perm()))
+}
+
+func (d *LocalDriver) Truncate(ctx context.Context, path string, size int64) error {
+	realPath, err := d.safePath(path)
+	if err != nil {
+		return err
+	}
+	return vfs.WrapError("truncate", path, os.Truncate(realPath, size))
 }
 
 func (d *LocalDriver) Close() error {
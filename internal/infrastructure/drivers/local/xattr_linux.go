@@ -0,0 +1,93 @@
+//go:build linux
+
+package local
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"golang.org/x/sys/unix"
+)
+
+// xattrPrefix 所有自定义元数据都放在 user 命名空间下，避免与系统属性冲突
+const xattrPrefix = "user.gofilehub."
+
+// GetMetadata 读取 user.gofilehub.* 扩展属性
+func (d *LocalDriver) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	realPath, err := d.safePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先探测所需缓冲区大小
+	size, err := unix.Listxattr(realPath, nil)
+	if err != nil {
+		return nil, xattrError(err)
+	}
+	result := make(map[string]string)
+	if size == 0 {
+		return result, nil
+	}
+	buf := make([]byte, size)
+	size, err = unix.Listxattr(realPath, buf)
+	if err != nil {
+		return nil, xattrError(err)
+	}
+
+	// 属性名以 \0 分隔
+	for _, name := range strings.Split(string(buf[:size]), "\x00") {
+		if !strings.HasPrefix(name, xattrPrefix) {
+			continue
+		}
+		value, err := getxattr(realPath, name)
+		if err != nil {
+			continue // 读取期间被删除等情况，跳过
+		}
+		result[strings.TrimPrefix(name, xattrPrefix)] = value
+	}
+	return result, nil
+}
+
+func (d *LocalDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	realPath, err := d.safePath(path)
+	if err != nil {
+		return err
+	}
+	return xattrError(unix.Setxattr(realPath, xattrPrefix+key, []byte(value), 0))
+}
+
+func (d *LocalDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	realPath, err := d.safePath(path)
+	if err != nil {
+		return err
+	}
+	err = unix.Removexattr(realPath, xattrPrefix+key)
+	if errors.Is(err, unix.ENODATA) {
+		return nil
+	}
+	return xattrError(err)
+}
+
+func getxattr(realPath, name string) (string, error) {
+	size, err := unix.Getxattr(realPath, name, nil)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, size)
+	size, err = unix.Getxattr(realPath, name, buf)
+	if err != nil {
+		return "", err
+	}
+	return string(buf[:size]), nil
+}
+
+// xattrError 将 "文件系统不支持 xattr" 转换为 vfs.ErrMetadataNotSupported，以便上层回退到数据库
+func xattrError(err error) error {
+	if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP) {
+		return vfs.ErrMetadataNotSupported
+	}
+	return err
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package local
+
+import (
+	"context"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+// 非 Linux 平台暂不支持 xattr，统一回退到数据库存储
+
+func (d *LocalDriver) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	return nil, vfs.ErrMetadataNotSupported
+}
+
+func (d *LocalDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	return vfs.ErrMetadataNotSupported
+}
+
+func (d *LocalDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	return vfs.ErrMetadataNotSupported
+}
@@ -0,0 +1,118 @@
+package drivers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FieldType 配置项的取值类型
+type FieldType string
+
+const (
+	FieldString  FieldType = "string"
+	FieldInteger FieldType = "integer"
+	FieldBoolean FieldType = "boolean"
+)
+
+// Field 一个配置项
+type Field struct {
+	Name        string    `json:"name"`
+	Type        FieldType `json:"type"`
+	Required    bool      `json:"required"`
+	Default     any       `json:"default,omitempty"`
+	Secret      bool      `json:"secret"`   // 密码等敏感信息，界面上应使用密码输入框
+	Identity    bool      `json:"identity"` // 决定连接目标的配置项 (主机、端口、账号等)，修改后必须重新填写敏感信息
+	Description string    `json:"description"`
+}
+
+// Schema 驱动的配置说明，供校验与前端生成表单
+type Schema struct {
+	Type        string  `json:"type"`
+	Description string  `json:"description"`
+	Fields      []Field `json:"fields"`
+}
+
+// UnknownFields 按名称排序返回 Schema 中未声明的配置项
+func (s Schema) UnknownFields(config map[string]any) []string {
+	known := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		known[f.Name] = true
+	}
+	var unknown []string
+	for k := range config {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// Normalize 按 Schema 校验配置并返回规范化后的副本：
+// 取值转换为声明的类型 (兼容 "445" 与 445 两种写法)，缺省的可选项填入默认值
+// 未声明的配置项不会出现在副本中，是否视为错误由调用方通过 UnknownFields 决定
+func (s Schema) Normalize(config map[string]any) (map[string]any, error) {
+	out := make(map[string]any, len(s.Fields))
+	for _, f := range s.Fields {
+		v, ok := config[f.Name]
+		if !ok || v == nil || v == "" {
+			if f.Required {
+				return nil, fmt.Errorf("%s config missing: %s", s.Type, f.Name)
+			}
+			if f.Default != nil {
+				out[f.Name] = f.Default
+			}
+			continue
+		}
+		converted, err := convert(f.Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s config invalid: %s %v", s.Type, f.Name, err)
+		}
+		out[f.Name] = converted
+	}
+	return out, nil
+}
+
+// convert 将 JSON 解码得到的取值转换为声明的类型
+func convert(t FieldType, v any) (any, error) {
+	switch t {
+	case FieldString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("must be a string")
+	case FieldInteger:
+		switch n := v.(type) {
+		case int:
+			return n, nil
+		case float64:
+			if n == math.Trunc(n) && math.Abs(n) <= math.MaxInt32 {
+				return int(n), nil
+			}
+		case json.Number:
+			if i, err := strconv.Atoi(n.String()); err == nil {
+				return i, nil
+			}
+		case string:
+			if i, err := strconv.Atoi(strings.TrimSpace(n)); err == nil {
+				return i, nil
+			}
+		}
+		return nil, fmt.Errorf("must be an integer")
+	case FieldBoolean:
+		switch b := v.(type) {
+		case bool:
+			return b, nil
+		case string:
+			if parsed, err := strconv.ParseBool(b); err == nil {
+				return parsed, nil
+			}
+		}
+		return nil, fmt.Errorf("must be a boolean")
+	}
+	return v, nil
+}
@@ -0,0 +1,52 @@
+package smb
+
+import (
+	"errors"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+// go-smb2 只把少数 NTSTATUS 转换成 os 错误，其余以 ResponseError 返回
+const (
+	statusSharingViolation    = 0xC0000043
+	statusQuotaExceeded       = 0xC0000044
+	statusDiskFull            = 0xC000007F
+	statusMediaWriteProtected = 0xC00000A2
+	statusNetworkNameDeleted  = 0xC00000C9
+	statusBadNetworkName      = 0xC00000CC
+	statusDirectoryNotEmpty   = 0xC0000101
+	statusUserSessionDeleted  = 0xC0000203
+)
+
+// wrapError 将 SMB 错误归类为领域错误，path 为虚拟路径
+func wrapError(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var respErr *smb2.ResponseError
+	if errors.As(err, &respErr) {
+		var kind error
+		switch respErr.Code {
+		case statusDirectoryNotEmpty:
+			kind = vfs.ErrNotEmpty
+		case statusMediaWriteProtected:
+			kind = vfs.ErrReadOnly
+		case statusQuotaExceeded, statusDiskFull:
+			kind = vfs.ErrQuotaExceeded
+		case statusSharingViolation:
+			kind = vfs.ErrPermissionDenied
+		case statusNetworkNameDeleted, statusUserSessionDeleted, statusBadNetworkName:
+			kind = vfs.ErrUnavailable
+		}
+		if kind != nil {
+			return &vfs.PathError{Op: op, Path: path, Kind: kind, Err: err}
+		}
+	}
+	// 重连后仍失败的连接错误说明 NAS 不可达
+	if isConnError(err) {
+		return &vfs.PathError{Op: op, Path: path, Kind: vfs.ErrUnavailable, Err: err}
+	}
+	return vfs.WrapError(op, path, err)
+}
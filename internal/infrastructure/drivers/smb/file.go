@@ -0,0 +1,29 @@
+package smb
+
+import (
+	"sync"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+// pooledFile 作为连接池中某个连接的使用者，Close 时注销；连接同时可被其他请求共享
+type pooledFile struct {
+	*smb2.File
+	conn *connection
+	pool *connPool
+	once sync.Once
+}
+
+func (f *pooledFile) Close() error {
+	err := f.File.Close()
+	f.once.Do(func() {
+		// 连接是共享的，只有连接本身损坏时才丢弃；其他关闭失败 (例如请求 ctx 已取消) 留下的服务端句柄
+		// 会在连接关闭时由服务端回收
+		if isConnError(err) {
+			f.pool.discard(f.conn)
+		} else {
+			f.pool.release(f.conn)
+		}
+	})
+	return err
+}
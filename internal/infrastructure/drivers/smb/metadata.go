@@ -0,0 +1,124 @@
+package smb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+// metaStream 元数据保存在文件的备用数据流 (ADS) 中，内容为 JSON 对象
+// Windows (NTFS) 原生支持；Samba 需要启用 vfs_streams_xattr
+const metaStream = ":gofilehub.meta"
+
+// 服务端不支持 ADS 时可能返回的 NTSTATUS
+const (
+	statusInvalidParameter  = 0xC000000D
+	statusObjectNameInvalid = 0xC0000033
+	statusNotSupported      = 0xC00000BB
+)
+
+func (d *SMBDriver) streamPath(path string) (string, error) {
+	normPath := d.normalizePath(path)
+	if normPath == "." {
+		// 共享根目录无法附加数据流
+		return "", vfs.ErrMetadataNotSupported
+	}
+	return normPath + metaStream, nil
+}
+
+func (d *SMBDriver) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	// 先确认主文件存在，避免把 "文件不存在" 误判为 "没有元数据"
+	err := d.do(ctx, func(share *smb2.Share) error {
+		_, err := share.Stat(d.normalizePath(path))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.readStream(ctx, path)
+}
+
+func (d *SMBDriver) SetMetadata(ctx context.Context, path string, key, value string) error {
+	meta, err := d.readStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	meta[key] = value
+	return d.writeStream(ctx, path, meta)
+}
+
+func (d *SMBDriver) DeleteMetadata(ctx context.Context, path string, key string) error {
+	meta, err := d.readStream(ctx, path)
+	if err != nil {
+		return err
+	}
+	if _, ok := meta[key]; !ok {
+		return nil
+	}
+	delete(meta, key)
+	return d.writeStream(ctx, path, meta)
+}
+
+func (d *SMBDriver) readStream(ctx context.Context, path string) (map[string]string, error) {
+	sp, err := d.streamPath(path)
+	if err != nil {
+		return nil, err
+	}
+	meta := make(map[string]string)
+	f, err := d.openFile(ctx, sp, os.O_RDONLY, 0)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return meta, nil // 还没有写过元数据
+		}
+		return nil, streamError(err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return meta, nil
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func (d *SMBDriver) writeStream(ctx context.Context, path string, meta map[string]string) error {
+	sp, err := d.streamPath(path)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	f, err := d.openFile(ctx, sp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return streamError(err)
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	return err
+}
+
+// streamError 服务端不支持数据流时转换为 vfs.ErrMetadataNotSupported，由上层回退到数据库
+func streamError(err error) error {
+	var respErr *smb2.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.Code {
+		case statusInvalidParameter, statusObjectNameInvalid, statusNotSupported:
+			return vfs.ErrMetadataNotSupported
+		}
+	}
+	return err
+}
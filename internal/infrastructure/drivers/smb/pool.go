@@ -0,0 +1,398 @@
+package smb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hirochachacha/go-smb2"
+)
+
+// 重连参数：NAS 重启通常需要几十秒，这里只做有限次数的快速重试，
+// 更长时间的不可用由 FileService 的健康检查负责驱逐和重建
+const (
+	maxRedialAttempts = 4
+	redialBaseDelay   = 250 * time.Millisecond
+)
+
+// errDriverClosed 驱动已经 Close，不再建立连接
+var errDriverClosed = errors.New("smb driver closed")
+
+// connection 一次完整的 SMB 连接：TCP 连接 + 认证会话 + 共享挂载
+// go-smb2 按 MessageId 分发响应，同一会话上可以并发发起多个请求，
+// 因此连接可以被打开的文件和短操作共享；多建几个连接只是为了分摊 NAS 单连接的带宽
+type connection struct {
+	conn     net.Conn
+	session  *smb2.Session
+	share    *smb2.Share // 类似于 os 包，拥有 Open, Create, Stat 等方法
+	lastUsed time.Time
+
+	users  int  // 正在使用该连接的请求与打开的文件数，由 connPool.mu 保护
+	broken bool // 已被 discard 移出连接池
+}
+
+// dial 建立 TCP 连接、完成认证并挂载共享
+func dial(ctx context.Context, opts smbOptions) (*connection, error) {
+	// 1. 建立 TCP 连接
+	dialer := net.Dialer{Timeout: opts.dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(opts.host, opts.port))
+	if err != nil {
+		return nil, fmt.Errorf("tcp dial failed: %v", err)
+	}
+
+	// 2. 建立 SMB 会话 (认证)
+	d := &smb2.Dialer{
+		Initiator: &smb2.NTLMInitiator{
+			User:     opts.user,
+			Password: opts.password,
+			// Domain: domain, // 如果需要域认证，可扩展配置
+		},
+	}
+
+	session, err := d.DialContext(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("smb session setup failed: %v", err)
+	}
+
+	// 3. 挂载共享目录
+	share, err := session.WithContext(ctx).Mount(opts.shareName)
+	if err != nil {
+		session.Logoff()
+		conn.Close()
+		return nil, fmt.Errorf("mount share '%s' failed: %v", opts.shareName, err)
+	}
+
+	return &connection{conn: conn, session: session, share: share, lastUsed: time.Now()}, nil
+}
+
+func (c *connection) close() {
+	// 会话已断开时 Umount/Logoff 会返回错误，忽略即可
+	if c.share != nil {
+		c.share.Umount()
+	}
+	if c.session != nil {
+		c.session.Logoff()
+	}
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// connPool SMB 连接池
+// 连接是共享的：每次 acquire 只给连接增加一个使用者，不独占连接，
+// 因此持有打开文件的同时再做 Stat/GetMetadata 也不会等待 (pool_max=1 时同样如此)。
+// 总连接数不超过 max，新请求优先使用空闲连接，其次在未达上限时新建，否则复用负载最小的连接
+type connPool struct {
+	opts        smbOptions
+	min, max    int
+	idleTimeout time.Duration
+	dial        func(ctx context.Context) (*connection, error)
+
+	mu      sync.Mutex
+	conns   []*connection
+	dialing int           // 正在建立的连接数，计入上限
+	dialed  chan struct{} // 每次建连结束时关闭并替换，用于唤醒等待者
+	closed  bool
+	stop    chan struct{}
+}
+
+func newConnPool(opts smbOptions, min, max int, idleTimeout time.Duration) *connPool {
+	p := &connPool{
+		opts:        opts,
+		min:         min,
+		max:         max,
+		idleTimeout: idleTimeout,
+		dialed:      make(chan struct{}),
+		stop:        make(chan struct{}),
+	}
+	p.dial = func(ctx context.Context) (*connection, error) {
+		return dial(ctx, p.opts)
+	}
+	go p.reapLoop()
+	return p
+}
+
+// fill 预先建立 min 个连接，同时用于校验配置
+func (p *connPool) fill(ctx context.Context) error {
+	for {
+		p.mu.Lock()
+		n := len(p.conns)
+		p.mu.Unlock()
+		if n >= p.min {
+			return nil
+		}
+		c, err := p.dial(ctx)
+		if err != nil {
+			return err
+		}
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			c.close()
+			return errDriverClosed
+		}
+		p.conns = append(p.conns, c)
+		p.mu.Unlock()
+	}
+}
+
+// acquire 获取一个连接并登记为使用者：优先空闲连接，其次新建，连接数已达上限时与其他使用者共享
+// 只有在连接池里还没有任何可用连接、且上限都被正在建立的连接占满时才会等待，直到 ctx 取消
+func (p *connPool) acquire(ctx context.Context) (*connection, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errDriverClosed
+		}
+		c := p.leastUsed()
+		full := len(p.conns)+p.dialing >= p.max
+		if c != nil && (c.users == 0 || full) {
+			c.users++
+			p.mu.Unlock()
+			return c, nil
+		}
+		if !full {
+			p.dialing++
+			p.mu.Unlock()
+			return p.dialNew(ctx)
+		}
+		wait := p.dialed
+		p.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// dialNew 新建连接并登记一个使用者，调用前已占用 dialing 名额
+func (p *connPool) dialNew(ctx context.Context) (*connection, error) {
+	c, err := p.dial(ctx)
+
+	p.mu.Lock()
+	p.dialing--
+	close(p.dialed)
+	p.dialed = make(chan struct{})
+	if err == nil && p.closed {
+		err = errDriverClosed
+		defer c.close()
+	}
+	if err == nil {
+		c.users = 1
+		p.conns = append(p.conns, c)
+	}
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// leastUsed 返回使用者最少的连接，相同时取最近用过的；调用方需持有 mu
+func (p *connPool) leastUsed() *connection {
+	var best *connection
+	for _, c := range p.conns {
+		if best == nil || c.users < best.users || (c.users == best.users && c.lastUsed.After(best.lastUsed)) {
+			best = c
+		}
+	}
+	return best
+}
+
+// release 注销一个使用者
+func (p *connPool) release(c *connection) {
+	p.mu.Lock()
+	c.users--
+	c.lastUsed = time.Now()
+	// 连接池关闭或连接已被丢弃后，最后一个使用者负责关闭连接
+	closeNow := c.users == 0 && (p.closed || c.broken)
+	p.mu.Unlock()
+	if closeNow {
+		c.close()
+	}
+}
+
+// discard 丢弃已损坏的连接；同一时刻断开的通常是整个 NAS，顺便关闭没有使用者的连接
+// 其他仍在使用该连接的请求会收到连接错误并各自重试，连接在最后一个使用者离开时关闭
+func (p *connPool) discard(c *connection) {
+	p.mu.Lock()
+	c.users--
+	var closing []*connection
+	if !c.broken {
+		c.broken = true
+		// 立即关闭底层 TCP 连接，让共享该连接的请求尽快失败而不是等待超时
+		if c.conn != nil {
+			c.conn.Close()
+		}
+	}
+	if c.users == 0 {
+		closing = append(closing, c)
+	}
+	kept := p.conns[:0]
+	for _, pc := range p.conns {
+		switch {
+		case pc == c:
+		case pc.users == 0:
+			closing = append(closing, pc)
+		default:
+			kept = append(kept, pc)
+		}
+	}
+	clear(p.conns[len(kept):])
+	p.conns = kept
+	p.mu.Unlock()
+	for _, cc := range closing {
+		cc.close()
+	}
+}
+
+// redial 在连接断开后带指数退避地重新获取连接
+func (p *connPool) redial(ctx context.Context) (*connection, error) {
+	var lastErr error
+	delay := redialBaseDelay
+	for attempt := 0; attempt < maxRedialAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			delay *= 2
+		}
+		c, err := p.acquire(ctx)
+		if err == nil {
+			return c, nil
+		}
+		if errors.Is(err, errDriverClosed) || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// reapLoop 定期关闭空闲超时的连接，但保留至少 min 个
+func (p *connPool) reapLoop() {
+	interval := p.idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.reap(now)
+		}
+	}
+}
+
+func (p *connPool) reap(now time.Time) {
+	p.mu.Lock()
+	var expired []*connection
+	kept := p.conns[:0]
+	for _, c := range p.conns {
+		// 超时的空闲连接才关闭，并且保留至少 min 个
+		if c.users == 0 && now.Sub(c.lastUsed) > p.idleTimeout && len(p.conns)-len(expired) > p.min {
+			expired = append(expired, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	clear(p.conns[len(kept):])
+	p.conns = kept
+	p.mu.Unlock()
+	for _, c := range expired {
+		c.close()
+	}
+}
+
+// close 关闭所有空闲连接；正在使用的连接在最后一个使用者归还时关闭
+func (p *connPool) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	var idle []*connection
+	for _, c := range p.conns {
+		if c.users == 0 {
+			idle = append(idle, c)
+		}
+	}
+	p.conns = nil
+	p.mu.Unlock()
+	close(p.stop)
+	for _, c := range idle {
+		c.close()
+	}
+}
+
+// do 从连接池取一个连接执行操作；如果因为会话断开而失败，换一个新连接重试一次
+// 传给 fn 的 share 已绑定 ctx，客户端断开或超时会中断正在进行的 SMB 请求
+func (d *SMBDriver) do(ctx context.Context, fn func(share *smb2.Share) error) error {
+	c, err := d.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(c.share.WithContext(ctx))
+	if !isConnError(err) {
+		d.pool.release(c)
+		return err
+	}
+	d.pool.discard(c)
+	fresh, rerr := d.pool.redial(ctx)
+	if rerr != nil {
+		return fmt.Errorf("%v (reconnect failed: %v)", err, rerr)
+	}
+	err = fn(fresh.share.WithContext(ctx))
+	if isConnError(err) {
+		d.pool.discard(fresh)
+	} else {
+		d.pool.release(fresh)
+	}
+	return err
+}
+
+func (d *SMBDriver) acquire(ctx context.Context) (*connection, error) {
+	if d.pool == nil {
+		return nil, errors.New("smb driver not initialized")
+	}
+	return d.pool.acquire(ctx)
+}
+
+// isConnError 判断错误是否由底层连接断开引起 (而不是文件不存在、权限不足等业务错误)
+func isConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var transportErr *smb2.TransportError
+	if errors.As(err, &transportErr) {
+		return true
+	}
+	// ContextError 是请求被取消，连接本身仍然可用
+	var ctxErr *smb2.ContextError
+	if errors.As(err, &ctxErr) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,167 @@
+package smb
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestPool 返回使用假连接的连接池，dials 记录建立过的连接数
+func newTestPool(t *testing.T, max int) (*connPool, *atomic.Int32) {
+	t.Helper()
+	p := newConnPool(smbOptions{}, 0, max, time.Minute)
+	var dials atomic.Int32
+	p.dial = func(ctx context.Context) (*connection, error) {
+		dials.Add(1)
+		return &connection{lastUsed: time.Now()}, nil
+	}
+	t.Cleanup(p.close)
+	return p, &dials
+}
+
+// 打开的文件不独占连接：pool_max=1 时持有文件的同时还能做元数据操作 (WebDAV PROPFIND 的调用顺序)
+func TestPoolNestedAcquire(t *testing.T) {
+	p, dials := newTestPool(t, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	file, err := p.acquire(ctx)
+	if err != nil {
+		t.Fatalf("open file: %v", err)
+	}
+	meta, err := p.acquire(ctx)
+	if err != nil {
+		t.Fatalf("metadata call while file is open: %v", err)
+	}
+	if meta != file {
+		t.Fatal("expected the metadata call to share the file's connection")
+	}
+	p.release(meta)
+	p.release(file)
+	if n := dials.Load(); n != 1 {
+		t.Fatalf("dials = %d, want 1", n)
+	}
+}
+
+// pool_max=N 时 N 个并发请求各自打开文件后再做元数据操作，不能互相等待
+func TestPoolConcurrentNestedAcquire(t *testing.T) {
+	const n = 4
+	p, dials := newTestPool(t, n)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var opened, wg sync.WaitGroup
+	opened.Add(n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			file, err := p.acquire(ctx)
+			opened.Done()
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer p.release(file)
+			// 等所有请求都持有文件后再做元数据操作
+			opened.Wait()
+			meta, err := p.acquire(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			p.release(meta)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("acquire: %v", err)
+	}
+	if got := dials.Load(); got > n {
+		t.Fatalf("dials = %d, want at most %d", got, n)
+	}
+}
+
+func TestPoolPrefersIdleConnections(t *testing.T) {
+	p, dials := newTestPool(t, 2)
+	ctx := context.Background()
+
+	a, _ := p.acquire(ctx)
+	b, _ := p.acquire(ctx)
+	if a == b {
+		t.Fatal("expected a second connection while under the limit")
+	}
+	// 达到上限后与负载最小的连接共享
+	c, err := p.acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire at limit: %v", err)
+	}
+	if c != a && c != b {
+		t.Fatal("expected an existing connection at the limit")
+	}
+	p.release(c)
+	p.release(b)
+	// b 空闲后优先使用 b
+	if d, _ := p.acquire(ctx); d != b {
+		t.Fatal("expected the idle connection to be reused")
+	}
+	if n := dials.Load(); n != 2 {
+		t.Fatalf("dials = %d, want 2", n)
+	}
+}
+
+func TestPoolDiscardSharedConnection(t *testing.T) {
+	p, dials := newTestPool(t, 1)
+	ctx := context.Background()
+
+	a, _ := p.acquire(ctx)
+	b, _ := p.acquire(ctx)
+	p.discard(a)
+	// 损坏的连接移出连接池，后续请求建立新连接
+	c, err := p.acquire(ctx)
+	if err != nil {
+		t.Fatalf("acquire after discard: %v", err)
+	}
+	if c == b {
+		t.Fatal("expected a fresh connection after discard")
+	}
+	// 仍在使用旧连接的请求归还时不会把它放回连接池
+	p.release(b)
+	p.release(c)
+	p.mu.Lock()
+	conns := len(p.conns)
+	p.mu.Unlock()
+	if conns != 1 {
+		t.Fatalf("pool holds %d connections, want 1", conns)
+	}
+	if n := dials.Load(); n != 2 {
+		t.Fatalf("dials = %d, want 2", n)
+	}
+}
+
+func TestPoolReapKeepsMin(t *testing.T) {
+	p, _ := newTestPool(t, 3)
+	p.min = 2
+	ctx := context.Background()
+
+	var held []*connection
+	for i := 0; i < 3; i++ {
+		c, _ := p.acquire(ctx)
+		held = append(held, c)
+	}
+	p.release(held[0])
+	p.release(held[1])
+	p.reap(time.Now().Add(2 * time.Minute))
+	p.mu.Lock()
+	conns := len(p.conns)
+	p.mu.Unlock()
+	// 两个空闲连接都已超时，但连同仍在使用的第三个在内要保留 min 个
+	if conns != 2 {
+		t.Fatalf("pool holds %d connections, want 2", conns)
+	}
+	p.release(held[2])
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
 	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers"
@@ -15,15 +16,47 @@ import (
 )
 
 func init() {
-	drivers.Register("smb", NewSMBDriver)
+	drivers.Register("smb", NewSMBDriver, drivers.Schema{
+		Description: "SMB/CIFS 共享 (Windows 共享、NAS)",
+		Fields: []drivers.Field{
+			{Name: "host", Type: drivers.FieldString, Required: true, Identity: true, Description: "服务器地址"},
+			{Name: "port", Type: drivers.FieldInteger, Identity: true, Default: defaultPort, Description: "端口"},
+			{Name: "user", Type: drivers.FieldString, Required: true, Identity: true, Description: "用户名"},
+			{Name: "password", Type: drivers.FieldString, Secret: true, Description: "密码"},
+			{Name: "share_name", Type: drivers.FieldString, Required: true, Identity: true, Description: "共享名称"},
+			{Name: "dial_timeout", Type: drivers.FieldInteger, Default: int(defaultDialTimeout / time.Second), Description: "TCP 建连超时 (秒)"},
+			{Name: "pool_min", Type: drivers.FieldInteger, Default: defaultPoolMin, Description: "连接池保持的最少连接数"},
+			{Name: "pool_max", Type: drivers.FieldInteger, Default: defaultPoolMax, Description: "连接池最多连接数"},
+			{Name: "pool_idle_timeout", Type: drivers.FieldInteger, Default: int(defaultPoolIdleTimeout / time.Second), Description: "空闲连接关闭前等待的时间 (秒)"},
+		},
+	})
 }
 
 type SMBDriver struct {
-	conn    net.Conn
-	session *smb2.Session
-	share   *smb2.Share // 类似于 os 包，拥有 Open, Create, Stat 等方法
+	opts smbOptions
+	pool *connPool // 每个操作、每个打开的文件各占用一个连接
+}
+
+// smbOptions Init 时解析出的连接参数，新建连接时复用
+type smbOptions struct {
+	host      string
+	port      string
+	user      string
+	password  string
+	shareName string
+	// dialTimeout TCP 建连超时，NAS 关机时避免长时间挂起
+	dialTimeout time.Duration
 }
 
+// 连接池默认参数
+const (
+	defaultPort            = 445
+	defaultPoolMin         = 1
+	defaultPoolMax         = 4
+	defaultPoolIdleTimeout = 5 * time.Minute
+	defaultDialTimeout     = 10 * time.Second
+)
+
 func NewSMBDriver() vfs.StorageDriver {
 	return &SMBDriver{}
 }
@@ -32,54 +65,94 @@ func (d *SMBDriver) DriverName() string {
 	return "smb"
 }
 
-// Init 初始化 SMB 连接
-// Config 需求: host, port(可选,默认445), user, password, share_name
+// Init 初始化 SMB 连接池
+// 配置项见 init 中注册的 Schema
 func (d *SMBDriver) Init(ctx context.Context, config map[string]interface{}) error {
+	opts, pc, err := parseConfig(config)
+	if err != nil {
+		return err
+	}
+	d.opts = opts
+
+	pool := newConnPool(d.opts, pc.min, pc.max, pc.idleTimeout)
+	if err := pool.fill(ctx); err != nil {
+		pool.close()
+		return err
+	}
+	d.pool = pool
+	return nil
+}
+
+// ValidateConfig 检查 Schema 无法表达的约束 (取值范围、pool_min 不超过 pool_max)
+func (d *SMBDriver) ValidateConfig(config map[string]any) error {
+	_, _, err := parseConfig(config)
+	return err
+}
+
+// poolConfig 连接池参数
+type poolConfig struct {
+	min, max    int
+	idleTimeout time.Duration
+}
+
+// parseConfig 解析并校验 Init 所需的配置
+func parseConfig(config map[string]any) (smbOptions, poolConfig, error) {
 	host, _ := config["host"].(string)
-	port, _ := config["port"].(string)
+	port := intOption(config, "port", defaultPort)
 	user, _ := config["user"].(string)
 	password, _ := config["password"].(string)
 	shareName, _ := config["share_name"].(string)
 
 	if host == "" || user == "" || shareName == "" {
-		return fmt.Errorf("smb config missing: host, user, or share_name")
+		return smbOptions{}, poolConfig{}, fmt.Errorf("smb config missing: host, user, or share_name")
 	}
-	if port == "" {
-		port = "445"
+	if port < 1 || port > 65535 {
+		return smbOptions{}, poolConfig{}, fmt.Errorf("smb config invalid: port must be between 1 and 65535")
 	}
-	// 1. 建立 TCP 连接
-	conn, err := net.Dial("tcp", net.JoinHostPort(host, port))
-	if err != nil {
-		return fmt.Errorf("tcp dial failed: %v", err)
+	dialTimeout := time.Duration(intOption(config, "dial_timeout", int(defaultDialTimeout/time.Second))) * time.Second
+	if dialTimeout <= 0 {
+		return smbOptions{}, poolConfig{}, fmt.Errorf("smb config invalid: dial_timeout must be positive")
 	}
-	d.conn = conn
+	opts := smbOptions{host: host, port: strconv.Itoa(port), user: user, password: password, shareName: shareName, dialTimeout: dialTimeout}
 
-	// 2. 建立 SMB 会话 (认证)
-	dialer := &smb2.Dialer{
-		Initiator: &smb2.NTLMInitiator{
-			User:     user,
-			Password: password,
-			// Domain: domain, // 如果需要域认证，可扩展配置
-		},
+	pc := poolConfig{
+		min:         intOption(config, "pool_min", defaultPoolMin),
+		max:         intOption(config, "pool_max", defaultPoolMax),
+		idleTimeout: time.Duration(intOption(config, "pool_idle_timeout", int(defaultPoolIdleTimeout/time.Second))) * time.Second,
 	}
-
-	session, err := dialer.Dial(conn)
-	if err != nil {
-		conn.Close()
-		return fmt.Errorf("smb session setup failed: %v", err)
+	if pc.max < 1 {
+		return smbOptions{}, poolConfig{}, fmt.Errorf("smb config invalid: pool_max must be at least 1")
 	}
-	d.session = session
+	if pc.min < 1 || pc.min > pc.max {
+		return smbOptions{}, poolConfig{}, fmt.Errorf("smb config invalid: pool_min must be between 1 and pool_max")
+	}
+	if pc.idleTimeout <= 0 {
+		return smbOptions{}, poolConfig{}, fmt.Errorf("smb config invalid: pool_idle_timeout must be positive")
+	}
+	return opts, pc, nil
+}
 
-	// 3. 挂载共享目录
-	fs, err := session.Mount(shareName)
-	if err != nil {
-		session.Logoff()
-		conn.Close()
-		return fmt.Errorf("mount share '%s' failed: %v", shareName, err)
+// intOption 读取整数配置，兼容 JSON 数字与字符串两种写法
+func intOption(config map[string]any, key string, def int) int {
+	switch v := config[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
 	}
-	d.share = fs
+	return def
+}
 
-	return nil
+// Ping 检查会话是否可用，会话断开时会尝试重连
+func (d *SMBDriver) Ping(ctx context.Context) error {
+	return d.do(ctx, func(share *smb2.Share) error {
+		_, err := share.Stat(".")
+		return err
+	})
 }
 
 // normalizePath 处理路径分隔符
@@ -99,9 +172,13 @@ func (d *SMBDriver) List(ctx context.Context, path string) ([]vfs.FileInfo, erro
 	normPath := d.normalizePath(path)
 
 	// 使用 share.ReadDir，用法几乎和 os.ReadDir 一样
-	entries, err := d.share.ReadDir(normPath)
+	var entries []os.FileInfo
+	err := d.do(ctx, func(share *smb2.Share) (err error) {
+		entries, err = share.ReadDir(normPath)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, wrapError("list", path, err)
 	}
 
 	files := make([]vfs.FileInfo, 0, len(entries))
@@ -122,13 +199,47 @@ func (d *SMBDriver) List(ctx context.Context, path string) ([]vfs.FileInfo, erro
 }
 
 func (d *SMBDriver) Open(ctx context.Context, path string) (io.ReadCloser, error) {
-	return d.share.Open(d.normalizePath(path))
+	f, err := d.openFile(ctx, d.normalizePath(path), os.O_RDONLY, 0)
+	if err != nil {
+		return nil, wrapError("open", path, err)
+	}
+	return f, nil
 }
 
 func (d *SMBDriver) OpenFile(ctx context.Context, path string, flag int, perm os.FileMode) (vfs.File, error) {
 	normPath := d.normalizePath(path)
 	// go-smb2 的 OpenFile 也返回实现了 vfs.File 的对象
-	return d.share.OpenFile(normPath, flag, perm)
+	f, err := d.openFile(ctx, normPath, flag, perm)
+	if err != nil {
+		return nil, wrapError("open", path, err)
+	}
+	return f, nil
+}
+
+// openFile 打开的文件登记为连接的使用者直到 Close，但不独占连接：
+// 同一请求里打开文件后再 Stat/GetMetadata 可以复用同一个连接，不会因为连接数上限而互相等待
+func (d *SMBDriver) openFile(ctx context.Context, normPath string, flag int, perm os.FileMode) (*pooledFile, error) {
+	c, err := d.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f, err := c.share.WithContext(ctx).OpenFile(normPath, flag, perm)
+	if isConnError(err) {
+		d.pool.discard(c)
+		if c, err = d.pool.redial(ctx); err != nil {
+			return nil, err
+		}
+		f, err = c.share.WithContext(ctx).OpenFile(normPath, flag, perm)
+	}
+	if err != nil {
+		if isConnError(err) {
+			d.pool.discard(c)
+		} else {
+			d.pool.release(c)
+		}
+		return nil, err
+	}
+	return &pooledFile{File: f, conn: c, pool: d.pool}, nil
 }
 
 func (d *SMBDriver) Create(ctx context.Context, path string, reader io.Reader, size int64) error {
@@ -137,25 +248,33 @@ func (d *SMBDriver) Create(ctx context.Context, path string, reader io.Reader, s
 	// 确保父目录存在 (SMB 协议不支持 mkdir -p，需要逐级检查，这里简化处理，假设目录存在)
 	// 如果要健壮实现，需要在这里写递归创建目录的逻辑
 
-	f, err := d.share.Create(normPath)
+	// 只有打开文件这一步可以在重连后重试，数据流被消费后无法重放
+	f, err := d.openFile(ctx, normPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
-		return err
+		return wrapError("create", path, err)
 	}
 	defer f.Close()
 
-	_, err = io.Copy(f, reader)
-	return err
+	// 客户端中断上传时停止写入
+	_, err = vfs.CopyContext(ctx, f, reader)
+	return wrapError("create", path, err)
 }
 
 func (d *SMBDriver) Mkdir(ctx context.Context, path string, perm os.FileMode) error {
 	normPath := d.normalizePath(path)
-	return d.share.MkdirAll(normPath, perm)
+	return wrapError("mkdir", path, d.do(ctx, func(share *smb2.Share) error {
+		return share.MkdirAll(normPath, perm)
+	}))
 }
 
 func (d *SMBDriver) Stat(ctx context.Context, path string) (vfs.FileInfo, error) {
-	info, err := d.share.Stat(d.normalizePath(path))
+	var info os.FileInfo
+	err := d.do(ctx, func(share *smb2.Share) (err error) {
+		info, err = share.Stat(d.normalizePath(path))
+		return err
+	})
 	if err != nil {
-		return vfs.FileInfo{}, err
+		return vfs.FileInfo{}, wrapError("stat", path, err)
 	}
 	return vfs.FileInfo{
 		Name:    info.Name(),
@@ -167,22 +286,40 @@ func (d *SMBDriver) Stat(ctx context.Context, path string) (vfs.FileInfo, error)
 
 func (d *SMBDriver) Delete(ctx context.Context, path string) error {
 	// go-smb2 的 RemoveAll 类似于 os.RemoveAll
-	return d.share.RemoveAll(d.normalizePath(path))
+	return wrapError("delete", path, d.do(ctx, func(share *smb2.Share) error {
+		return share.RemoveAll(d.normalizePath(path))
+	}))
 }
 
 func (d *SMBDriver) Rename(ctx context.Context, srcPath, dstPath string) error {
-	return d.share.Rename(d.normalizePath(srcPath), d.normalizePath(dstPath))
+	return wrapError("rename", srcPath, d.do(ctx, func(share *smb2.Share) error {
+		return share.Rename(d.normalizePath(srcPath), d.normalizePath(dstPath))
+	}))
+}
+
+func (d *SMBDriver) SetModTime(ctx context.Context, path string, mtime time.Time) error {
+	// go-smb2 的 Chtimes 不支持 "保持 atime 不变"，这里与 mtime 一起设置
+	return wrapError("chtimes", path, d.do(ctx, func(share *smb2.Share) error {
+		return share.Chtimes(d.normalizePath(path), mtime, mtime)
+	}))
+}
+
+// SetMode SMB 没有 POSIX 权限位，go-smb2 会根据 owner 写权限切换只读属性
+func (d *SMBDriver) SetMode(ctx context.Context, path string, mode os.FileMode) error {
+	return wrapError("chmod", path, d.do(ctx, func(share *smb2.Share) error {
+		return share.Chmod(d.normalizePath(path), mode)
+	}))
+}
+
+func (d *SMBDriver) Truncate(ctx context.Context, path string, size int64) error {
+	return wrapError("truncate", path, d.do(ctx, func(share *smb2.Share) error {
+		return share.Truncate(d.normalizePath(path), size)
+	}))
 }
 
 func (d *SMBDriver) Close() error {
-	if d.share != nil {
-		d.share.Umount()
-	}
-	if d.session != nil {
-		d.session.Logoff()
-	}
-	if d.conn != nil {
-		d.conn.Close()
+	if d.pool != nil {
+		d.pool.close()
 	}
 	return nil
 }
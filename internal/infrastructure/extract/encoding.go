@@ -0,0 +1,76 @@
+package extract
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// 识别出的文本编码
+const (
+	EncodingUTF8    = "UTF-8"
+	EncodingUTF16LE = "UTF-16LE"
+	EncodingUTF16BE = "UTF-16BE"
+	EncodingGB18030 = "GB18030"
+)
+
+// binarySniffLen 判断二进制文件时检查的字节数
+const binarySniffLen = 8 << 10
+
+// DecodeText 将文本转换为 UTF-8：识别 BOM，非法 UTF-8 按 GB18030 (兼容 GBK) 解码
+func DecodeText(data []byte) string {
+	text, _ := DecodeTextEncoding(data)
+	return text
+}
+
+// DecodeTextEncoding 同 DecodeText，同时返回识别出的编码
+func DecodeTextEncoding(data []byte) (string, string) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), EncodingUTF8
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decode(data, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()), EncodingUTF16LE
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decode(data, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()), EncodingUTF16BE
+	}
+	// 截断可能切在多字节字符中间，只看完整部分
+	if validUTF8Prefix(data) {
+		return string(data), EncodingUTF8
+	}
+	// 截断在 GBK 双字节字符中间时解码器会输出替换字符，去掉即可
+	text := decode(data, simplifiedchinese.GB18030.NewDecoder())
+	return strings.TrimSuffix(text, "�"), EncodingGB18030
+}
+
+// IsBinary 开头出现 NUL 字节视为二进制文件；带 BOM 的 UTF-16 文本除外
+func IsBinary(data []byte) bool {
+	if bytes.HasPrefix(data, []byte{0xFF, 0xFE}) || bytes.HasPrefix(data, []byte{0xFE, 0xFF}) {
+		return false
+	}
+	return bytes.IndexByte(data[:min(len(data), binarySniffLen)], 0) >= 0
+}
+
+func decode(data []byte, t transform.Transformer) string {
+	out, _, err := transform.Bytes(t, data)
+	if err != nil {
+		return strings.ToValidUTF8(string(data), "")
+	}
+	return string(out)
+}
+
+// validUTF8Prefix 除末尾被截断的不完整字符外都是合法 UTF-8
+func validUTF8Prefix(data []byte) bool {
+	if utf8.Valid(data) {
+		return true
+	}
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			return !utf8.FullRune(data[i:]) && utf8.Valid(data[:i])
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+// Package extract 从常见文档格式中提取纯文本，供全文索引使用
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxFileSize 超过该大小的文件不提取内容
+	MaxFileSize = 32 << 20
+	// MaxTextSize 每个文件最多保留的文本字节数
+	MaxTextSize = 1 << 20
+)
+
+// ErrUnsupported 不支持的文件类型
+var ErrUnsupported = errors.New("unsupported file type")
+
+// ErrTooLarge 文件超过 MaxFileSize
+var ErrTooLarge = errors.New("file too large to extract")
+
+// Document 提取结果
+type Document struct {
+	Title  string // 文档属性中的标题，Markdown 取第一个标题
+	Author string
+	Text   string
+}
+
+type extractor func(data readerAt, size int64) (*Document, error)
+
+// readerAt zip 与 PDF 解析都需要随机读取
+type readerAt interface {
+	io.Reader
+	io.ReaderAt
+}
+
+var extractors = map[string]extractor{
+	".txt":      plainText,
+	".text":     plainText,
+	".log":      plainText,
+	".csv":      plainText,
+	".tsv":      plainText,
+	".json":     plainText,
+	".xml":      plainText,
+	".yaml":     plainText,
+	".yml":      plainText,
+	".ini":      plainText,
+	".conf":     plainText,
+	".md":       markdown,
+	".markdown": markdown,
+	".htm":      htmlText,
+	".html":     htmlText,
+	".pdf":      pdfText,
+	".docx":     docxText,
+	".xlsx":     xlsxText,
+	".pptx":     pptxText,
+}
+
+// Supported 判断文件类型是否可以提取文本
+func Supported(name string) bool {
+	_, ok := extractors[strings.ToLower(path.Ext(name))]
+	return ok
+}
+
+// Extract 提取文件内容，r 同时实现 io.ReaderAt 时不会整体读入内存 (本地文件、SMB 文件)
+// 解析器遇到畸形文件可能 panic，这里统一转换为错误
+func Extract(name string, r io.Reader, size int64) (doc *Document, err error) {
+	fn, ok := extractors[strings.ToLower(path.Ext(name))]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	if size > MaxFileSize {
+		return nil, ErrTooLarge
+	}
+	ra, ok := r.(readerAt)
+	if !ok {
+		data, err := io.ReadAll(io.LimitReader(r, MaxFileSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > MaxFileSize {
+			return nil, ErrTooLarge
+		}
+		ra, size = bytes.NewReader(data), int64(len(data))
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			doc, err = nil, fmt.Errorf("malformed %s file: %v", path.Ext(name), p)
+		}
+	}()
+	doc, err = fn(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	doc.Text = truncate(doc.Text, MaxTextSize)
+	return doc, nil
+}
+
+func plainText(r readerAt, size int64) (*Document, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxTextSize))
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Text: DecodeText(data)}, nil
+}
+
+func markdown(r readerAt, size int64) (*Document, error) {
+	doc, err := plainText(r, size)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.SplitN(doc.Text, "\n", 50) {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			doc.Title = strings.TrimSpace(title)
+			break
+		}
+	}
+	return doc, nil
+}
+
+// truncate 按字节截断且不切断 UTF-8 字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
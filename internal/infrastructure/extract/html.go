@@ -0,0 +1,53 @@
+package extract
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// htmlText 提取可见文本，跳过脚本与样式
+func htmlText(r readerAt, size int64) (*Document, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxFileSize))
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	var sb strings.Builder
+	z := html.NewTokenizer(strings.NewReader(DecodeText(data)))
+	skip, inTitle := 0, false
+	for sb.Len() < MaxTextSize {
+		switch z.Next() {
+		case html.ErrorToken:
+			doc.Text = sb.String()
+			return doc, nil
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "noscript":
+				skip++
+			case "title":
+				inTitle = true
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "script", "style", "noscript":
+				skip = max(skip-1, 0)
+			case "title":
+				inTitle = false
+			case "p", "div", "br", "li", "tr", "h1", "h2", "h3", "h4", "h5", "h6":
+				sb.WriteByte('\n')
+			}
+		case html.TextToken:
+			if inTitle {
+				doc.Title = strings.TrimSpace(string(z.Text()))
+			} else if skip == 0 {
+				sb.Write(z.Text())
+			}
+		}
+	}
+	doc.Text = sb.String()
+	return doc, nil
+}
@@ -0,0 +1,138 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxPartSize 单个 XML 部件解压后的上限，防止 zip 炸弹
+const maxPartSize = 64 << 20
+
+// ooxmlPart 文本所在的元素与需要换行的元素
+type ooxmlPart struct {
+	text   string          // 文本元素的本地名，如 w:t / a:t 都是 t
+	breaks map[string]bool // 结束时插入换行的元素，如段落
+}
+
+var (
+	wordPart  = ooxmlPart{text: "t", breaks: map[string]bool{"p": true, "tab": true, "br": true}}
+	sheetPart = ooxmlPart{text: "t", breaks: map[string]bool{"si": true}}
+	slidePart = ooxmlPart{text: "t", breaks: map[string]bool{"p": true}}
+)
+
+func docxText(r readerAt, size int64) (*Document, error) {
+	return ooxml(r, size, func(name string) bool {
+		return name == "word/document.xml"
+	}, wordPart)
+}
+
+func xlsxText(r readerAt, size int64) (*Document, error) {
+	return ooxml(r, size, func(name string) bool {
+		return name == "xl/sharedStrings.xml"
+	}, sheetPart)
+}
+
+func pptxText(r readerAt, size int64) (*Document, error) {
+	return ooxml(r, size, func(name string) bool {
+		return strings.HasPrefix(name, "ppt/slides/slide") && path.Ext(name) == ".xml"
+	}, slidePart)
+}
+
+func ooxml(r readerAt, size int64, match func(string) bool, part ooxmlPart) (*Document, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	var parts []*zip.File
+	for _, f := range zr.File {
+		switch {
+		case f.Name == "docProps/core.xml":
+			readCoreProps(f, doc)
+		case match(f.Name):
+			parts = append(parts, f)
+		}
+	}
+	// 幻灯片按编号排序: slide2.xml 在 slide10.xml 之前
+	sort.Slice(parts, func(i, j int) bool {
+		return partNumber(parts[i].Name) < partNumber(parts[j].Name)
+	})
+	var sb strings.Builder
+	for _, f := range parts {
+		if sb.Len() >= MaxTextSize {
+			break
+		}
+		if err := xmlText(f, part, &sb); err != nil {
+			return nil, err
+		}
+	}
+	doc.Text = sb.String()
+	return doc, nil
+}
+
+func xmlText(f *zip.File, part ooxmlPart, sb *strings.Builder) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	decoder := xml.NewDecoder(io.LimitReader(rc, maxPartSize))
+	inText := false
+	for sb.Len() < MaxTextSize {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			inText = t.Name.Local == part.text
+		case xml.EndElement:
+			if t.Name.Local == part.text {
+				inText = false
+			}
+			if part.breaks[t.Name.Local] {
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+	return nil
+}
+
+// readCoreProps 读取标题与作者，失败时忽略
+func readCoreProps(f *zip.File, doc *Document) {
+	rc, err := f.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+	var props struct {
+		Title   string `xml:"title"`
+		Creator string `xml:"creator"`
+	}
+	if xml.NewDecoder(io.LimitReader(rc, maxPartSize)).Decode(&props) == nil {
+		doc.Title = strings.TrimSpace(props.Title)
+		doc.Author = strings.TrimSpace(props.Creator)
+	}
+}
+
+func partNumber(name string) int {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	i := len(base)
+	for i > 0 && base[i-1] >= '0' && base[i-1] <= '9' {
+		i--
+	}
+	n, _ := strconv.Atoi(base[i:])
+	return n
+}
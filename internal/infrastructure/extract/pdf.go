@@ -0,0 +1,31 @@
+package extract
+
+import (
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+func pdfText(r readerAt, size int64) (*Document, error) {
+	reader, err := pdf.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	if info := reader.Trailer().Key("Info"); !info.IsNull() {
+		doc.Title = strings.TrimSpace(info.Key("Title").Text())
+		doc.Author = strings.TrimSpace(info.Key("Author").Text())
+	}
+	var sb strings.Builder
+	for i := 1; i <= reader.NumPage() && sb.Len() < MaxTextSize; i++ {
+		text, err := reader.Page(i).GetPlainText(nil)
+		if err != nil {
+			// 个别页面解析失败不影响其他页面
+			continue
+		}
+		sb.WriteString(text)
+		sb.WriteByte('\n')
+	}
+	doc.Text = sb.String()
+	return doc, nil
+}
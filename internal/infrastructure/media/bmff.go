@@ -0,0 +1,86 @@
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errStop 遍历回调返回它以提前结束遍历
+var errStop = errors.New("stop")
+
+// box ISO BMFF (MP4 / MOV / HEIF) 的一个 box，off 与 size 指有效载荷
+type box struct {
+	typ  string
+	off  int64
+	size int64
+}
+
+func (b box) end() int64 { return b.off + b.size }
+
+// boxes 遍历 [off, end) 范围内的同级 box，只读取 box 头
+func boxes(r io.ReaderAt, off, end int64, fn func(b box) error) error {
+	for off+8 <= end {
+		h, err := readAt(r, off, 8)
+		if err != nil {
+			return err
+		}
+		size, hdr := int64(be.Uint32(h)), int64(8)
+		switch size {
+		case 0: // 延伸到文件末尾
+			size = end - off
+		case 1: // 64 位长度
+			ext, err := readAt(r, off+8, 8)
+			if err != nil {
+				return err
+			}
+			size, hdr = int64(be.Uint64(ext)), 16
+		}
+		if size < hdr || size > end-off {
+			return fmt.Errorf("%w: invalid box %q size", ErrMalformed, h[4:8])
+		}
+		if err := fn(box{typ: string(h[4:8]), off: off + hdr, size: size - hdr}); err != nil {
+			if err == errStop {
+				return nil
+			}
+			return err
+		}
+		off += size
+	}
+	return nil
+}
+
+// child 返回第一个指定类型的子 box
+func child(r io.ReaderAt, parent box, typ string) (box, bool, error) {
+	var found box
+	ok := false
+	err := boxes(r, parent.off, parent.end(), func(b box) error {
+		if b.typ == typ {
+			found, ok = b, true
+			return errStop
+		}
+		return nil
+	})
+	return found, ok, err
+}
+
+// payload 读取整个 box 的内容，只用于体积很小的 box
+func payload(r io.ReaderAt, b box) ([]byte, error) {
+	if b.size > 1<<20 {
+		return nil, fmt.Errorf("%w: box %q too large", ErrMalformed, b.typ)
+	}
+	return readAt(r, b.off, b.size)
+}
+
+// uintN 读取大端的 n 字节无符号整数 (n 为 0、2、4、8)
+func uintN(b []byte, n int) uint64 {
+	switch n {
+	case 2:
+		return uint64(be.Uint16(b))
+	case 4:
+		return uint64(be.Uint32(b))
+	case 8:
+		return be.Uint64(b)
+	}
+	return 0
+}
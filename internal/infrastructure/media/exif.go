@@ -0,0 +1,260 @@
+package media
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// EXIF 标签
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagExposureTime     = 0x829a
+	tagFNumber          = 0x829d
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagOffsetOriginal   = 0x9011
+	tagFocalLength      = 0x920a
+	tagPixelXDimension  = 0xa002
+	tagPixelYDimension  = 0xa003
+	tagLensModel        = 0xa434
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+)
+
+// maxIFDEntries 单个 IFD 的条目上限，超过视为损坏
+const maxIFDEntries = 1000
+
+// typeSizes TIFF 字段类型对应的字节数
+var typeSizes = map[uint16]int64{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+type tiff struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	raw   []byte // 4 字节的值或偏移
+}
+
+// parseEXIF 解析 TIFF 结构的 EXIF 数据 (JPEG APP1、HEIF 的 Exif 项与 TIFF 文件本身)
+// r 的偏移 0 为 TIFF 头
+func parseEXIF(r io.ReaderAt, md *Metadata) error {
+	h, err := readAt(r, 0, 8)
+	if err != nil {
+		return err
+	}
+	t := &tiff{r: r}
+	switch string(h[:4]) {
+	case "II*\x00":
+		t.order = binary.LittleEndian
+	case "MM\x00*":
+		t.order = binary.BigEndian
+	default:
+		return fmt.Errorf("%w: invalid TIFF header", ErrMalformed)
+	}
+	ifd0, err := t.ifd(int64(t.order.Uint32(h[4:])))
+	if err != nil {
+		return err
+	}
+	camera := &Camera{}
+	camera.Make = t.str(ifd0[tagMake])
+	camera.Model = t.str(ifd0[tagModel])
+	if v, ok := t.uint(ifd0[tagOrientation]); ok {
+		md.Orientation = int(v)
+	}
+	taken := t.str(ifd0[tagDateTime])
+	var offset string
+
+	if off, ok := t.uint(ifd0[tagExifIFD]); ok {
+		if exif, err := t.ifd(int64(off)); err == nil {
+			if v := t.str(exif[tagDateTimeOriginal]); v != "" {
+				taken, offset = v, t.str(exif[tagOffsetOriginal])
+			}
+			if v := t.rationals(exif[tagExposureTime]); len(v) > 0 && v[0] > 0 {
+				camera.ExposureTime = formatExposure(v[0])
+			}
+			if v := t.rationals(exif[tagFNumber]); len(v) > 0 {
+				camera.FNumber = round(v[0], 1)
+			}
+			if v, ok := t.uint(exif[tagISO]); ok {
+				camera.ISO = int(v)
+			}
+			if v := t.rationals(exif[tagFocalLength]); len(v) > 0 {
+				camera.FocalLength = round(v[0], 1)
+			}
+			camera.Lens = t.str(exif[tagLensModel])
+			if md.Width == 0 {
+				w, _ := t.uint(exif[tagPixelXDimension])
+				h, _ := t.uint(exif[tagPixelYDimension])
+				md.Width, md.Height = int(w), int(h)
+			}
+		}
+	}
+	if off, ok := t.uint(ifd0[tagGPSIFD]); ok {
+		if gps, err := t.ifd(int64(off)); err == nil {
+			md.GPS = t.gps(gps)
+		}
+	}
+	if ts, ok := parseEXIFTime(taken, offset); ok {
+		md.TakenAt = &ts
+	}
+	md.Camera = camera
+	return nil
+}
+
+func (t *tiff) ifd(off int64) (map[uint16]ifdEntry, error) {
+	h, err := readAt(t.r, off, 2)
+	if err != nil {
+		return nil, err
+	}
+	count := int64(t.order.Uint16(h))
+	if count > maxIFDEntries {
+		return nil, fmt.Errorf("%w: too many IFD entries", ErrMalformed)
+	}
+	data, err := readAt(t.r, off+2, count*12)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[uint16]ifdEntry, count)
+	for i := int64(0); i < count; i++ {
+		e := data[i*12 : i*12+12]
+		entries[t.order.Uint16(e)] = ifdEntry{
+			typ:   t.order.Uint16(e[2:]),
+			count: t.order.Uint32(e[4:]),
+			raw:   e[8:12],
+		}
+	}
+	return entries, nil
+}
+
+// value 返回字段的原始字节，不超过 4 字节的值直接存放在条目中
+func (t *tiff) value(e ifdEntry) []byte {
+	size, ok := typeSizes[e.typ]
+	if !ok || e.count == 0 {
+		return nil
+	}
+	n := size * int64(e.count)
+	if n <= 4 {
+		return e.raw[:n]
+	}
+	if n > 64<<10 {
+		return nil
+	}
+	b, err := readAt(t.r, int64(t.order.Uint32(e.raw)), n)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+func (t *tiff) str(e ifdEntry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	s, _, _ := strings.Cut(string(t.value(e)), "\x00")
+	return strings.TrimSpace(s)
+}
+
+// uint 返回 SHORT / LONG 字段的第一个值
+func (t *tiff) uint(e ifdEntry) (uint32, bool) {
+	b := t.value(e)
+	switch {
+	case e.typ == 3 && len(b) >= 2:
+		return uint32(t.order.Uint16(b)), true
+	case e.typ == 4 && len(b) >= 4:
+		return t.order.Uint32(b), true
+	}
+	return 0, false
+}
+
+func (t *tiff) rationals(e ifdEntry) []float64 {
+	if e.typ != 5 && e.typ != 10 {
+		return nil
+	}
+	b := t.value(e)
+	out := make([]float64, 0, len(b)/8)
+	for i := 0; i+8 <= len(b); i += 8 {
+		num, den := t.order.Uint32(b[i:]), t.order.Uint32(b[i+4:])
+		if den == 0 {
+			return nil
+		}
+		if e.typ == 10 {
+			out = append(out, float64(int32(num))/float64(int32(den)))
+		} else {
+			out = append(out, float64(num)/float64(den))
+		}
+	}
+	return out
+}
+
+func (t *tiff) gps(ifd map[uint16]ifdEntry) *GPS {
+	lat, lon := t.rationals(ifd[tagGPSLatitude]), t.rationals(ifd[tagGPSLongitude])
+	if len(lat) != 3 || len(lon) != 3 {
+		return nil
+	}
+	g := &GPS{
+		Latitude:  round(lat[0]+lat[1]/60+lat[2]/3600, 6),
+		Longitude: round(lon[0]+lon[1]/60+lon[2]/3600, 6),
+	}
+	if t.str(ifd[tagGPSLatitudeRef]) == "S" {
+		g.Latitude = -g.Latitude
+	}
+	if t.str(ifd[tagGPSLongitudeRef]) == "W" {
+		g.Longitude = -g.Longitude
+	}
+	// 没有定位成功的设备常写入 0,0
+	if g.Latitude == 0 && g.Longitude == 0 {
+		return nil
+	}
+	if alt := t.rationals(ifd[tagGPSAltitude]); len(alt) == 1 {
+		a := round(alt[0], 1)
+		if ref := t.value(ifd[tagGPSAltitudeRef]); len(ref) == 1 && ref[0] == 1 {
+			a = -a
+		}
+		g.Altitude = &a
+	}
+	return g
+}
+
+// parseEXIFTime EXIF 时间没有时区，只有较新的设备会写入 OffsetTimeOriginal；没有时按 UTC 处理
+func parseEXIFTime(s, offset string) (time.Time, bool) {
+	if s == "" || strings.HasPrefix(s, "0000") {
+		return time.Time{}, false
+	}
+	if offset != "" {
+		if ts, err := time.Parse("2006:01:02 15:04:05-07:00", s+offset); err == nil {
+			return ts, true
+		}
+	}
+	ts, err := time.Parse("2006:01:02 15:04:05", s)
+	return ts, err == nil
+}
+
+// formatExposure 快门速度：短于 1 秒写成 1/N
+func formatExposure(v float64) string {
+	if v < 1 {
+		return fmt.Sprintf("1/%d", int(math.Round(1/v)))
+	}
+	return fmt.Sprintf("%g", round(v, 1))
+}
+
+func round(v float64, digits int) float64 {
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
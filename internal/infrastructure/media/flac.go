@@ -0,0 +1,92 @@
+package media
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	Register("audio/flac", flacMetadata)
+}
+
+// flacMetadata 读取元数据块：STREAMINFO 给出采样率、声道与总采样数，VORBIS_COMMENT 给出标签
+// 封面 (PICTURE) 等其他块只读块头后跳过
+func flacMetadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	off := int64(0)
+	// 少数工具会在 FLAC 前写入 ID3v2
+	if h, err := readAt(r, 0, 10); err == nil && string(h[:3]) == "ID3" {
+		off = 10 + int64(syncsafe(h[6:10]))
+	}
+	magic, err := readAt(r, off, 4)
+	if err != nil {
+		return nil, err
+	}
+	if string(magic) != "fLaC" {
+		return nil, fmt.Errorf("%w: missing fLaC marker", ErrMalformed)
+	}
+	off += 4
+	md := &Metadata{Tags: &Tags{}, AudioCodec: "flac"}
+	for off+4 <= size {
+		h, err := readAt(r, off, 4)
+		if err != nil {
+			return nil, err
+		}
+		last, typ := h[0]&0x80 != 0, h[0]&0x7f
+		length := int64(h[1])<<16 | int64(h[2])<<8 | int64(h[3])
+		body := off + 4
+		switch typ {
+		case 0: // STREAMINFO
+			p, err := readAt(r, body, min(length, 34))
+			if err != nil {
+				return nil, err
+			}
+			if len(p) == 34 {
+				v := be.Uint64(p[10:18])
+				md.SampleRate = int(v >> 44)
+				md.Channels = int((v>>41)&7) + 1
+				if total := v & (1<<36 - 1); md.SampleRate > 0 && total > 0 {
+					md.Duration = round(float64(total)/float64(md.SampleRate), 3)
+					md.Bitrate = int(float64(size) * 8 / md.Duration)
+				}
+			}
+		case 4: // VORBIS_COMMENT
+			p, err := readAt(r, body, length)
+			if err != nil {
+				return nil, err
+			}
+			parseVorbisComment(p, md.Tags)
+		}
+		off = body + length
+		if last {
+			break
+		}
+	}
+	return md, nil
+}
+
+// parseVorbisComment 小端序：厂商字符串，然后是若干 "KEY=value"
+func parseVorbisComment(p []byte, tags *Tags) {
+	le := binary.LittleEndian
+	if len(p) < 4 {
+		return
+	}
+	pos := 4 + int(le.Uint32(p))
+	if pos+4 > len(p) || pos < 4 {
+		return
+	}
+	count := int(le.Uint32(p[pos:]))
+	pos += 4
+	for i := 0; i < count && pos+4 <= len(p); i++ {
+		n := int(le.Uint32(p[pos:]))
+		pos += 4
+		if n < 0 || pos+n > len(p) {
+			return
+		}
+		if key, value, ok := strings.Cut(string(p[pos:pos+n]), "="); ok {
+			setTag(tags, key, value)
+		}
+		pos += n
+	}
+}
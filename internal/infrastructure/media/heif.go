@@ -0,0 +1,270 @@
+package media
+
+import (
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register("image/heic", heifMetadata)
+	Register("image/heif", heifMetadata)
+	Register("image/avif", heifMetadata)
+}
+
+// heifItem meta 中的一个项目 (图像、Exif 块等)
+type heifItem struct {
+	typ    string
+	offset int64
+	length int64
+	props  []int // ipco 中关联属性的序号 (从 1 开始)
+}
+
+// heifMetadata 解析 meta box：主图像的 ispe 给出尺寸，Exif 项指向文件中的 TIFF 数据
+func heifMetadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	var meta box
+	found := false
+	err := boxes(r, 0, size, func(b box) error {
+		if b.typ == "meta" {
+			meta, found = b, true
+			return errStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found || meta.size < 4 {
+		return nil, fmt.Errorf("%w: meta box not found", ErrMalformed)
+	}
+
+	items := map[uint32]*heifItem{}
+	item := func(id uint32) *heifItem {
+		if items[id] == nil {
+			items[id] = &heifItem{}
+		}
+		return items[id]
+	}
+	var primary uint32
+	var sizes [][2]int // ipco 中每个属性的尺寸，非 ispe 的属性为零值
+	// meta 是 FullBox
+	err = boxes(r, meta.off+4, meta.end(), func(b box) error {
+		switch b.typ {
+		case "pitm":
+			p, err := payload(r, b)
+			if err != nil {
+				return err
+			}
+			if len(p) >= 6 && p[0] == 0 {
+				primary = uint32(be.Uint16(p[4:]))
+			} else if len(p) >= 8 {
+				primary = be.Uint32(p[4:])
+			}
+		case "iinf":
+			return parseIinf(r, b, item)
+		case "iloc":
+			p, err := payload(r, b)
+			if err != nil {
+				return err
+			}
+			parseIloc(p, item)
+		case "iprp":
+			return boxes(r, b.off, b.end(), func(c box) error {
+				switch c.typ {
+				case "ipco":
+					sizes = parseIpco(r, c)
+				case "ipma":
+					p, err := payload(r, c)
+					if err != nil {
+						return err
+					}
+					parseIpma(p, item)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	md := &Metadata{}
+	if it, ok := items[primary]; ok {
+		for _, idx := range it.props {
+			if idx > 0 && idx <= len(sizes) && sizes[idx-1][0] > 0 {
+				md.Width, md.Height = sizes[idx-1][0], sizes[idx-1][1]
+			}
+		}
+	}
+	for _, it := range items {
+		if it.typ != "Exif" || it.length <= 4 {
+			continue
+		}
+		// Exif 项以 4 字节的 TIFF 头偏移开头
+		h, err := readAt(r, it.offset, 4)
+		if err != nil {
+			return nil, err
+		}
+		skip := 4 + int64(be.Uint32(h))
+		if skip < it.length {
+			_ = parseEXIF(io.NewSectionReader(r, it.offset+skip, it.length-skip), md)
+		}
+		break
+	}
+	return md, nil
+}
+
+// parseIinf 读取每个 infe 的项目类型
+func parseIinf(r io.ReaderAt, iinf box, item func(uint32) *heifItem) error {
+	h, err := readAt(r, iinf.off, min(iinf.size, 8))
+	if err != nil || len(h) < 6 {
+		return err
+	}
+	start := iinf.off + 6
+	if h[0] != 0 {
+		start = iinf.off + 8
+	}
+	return boxes(r, start, iinf.end(), func(b box) error {
+		if b.typ != "infe" {
+			return nil
+		}
+		p, err := payload(r, b)
+		if err != nil || len(p) < 12 {
+			return err
+		}
+		// 只支持版本 2/3 (HEIF 规定使用)
+		switch p[0] {
+		case 2:
+			item(uint32(be.Uint16(p[4:]))).typ = string(p[8:12])
+		case 3:
+			if len(p) >= 14 {
+				item(be.Uint32(p[4:])).typ = string(p[10:14])
+			}
+		}
+		return nil
+	})
+}
+
+// parseIloc 记录每个项目第一个 extent 在文件中的位置
+func parseIloc(p []byte, item func(uint32) *heifItem) {
+	if len(p) < 8 {
+		return
+	}
+	version := p[0]
+	offSize, lenSize := int(p[4]>>4), int(p[4]&0x0f)
+	baseSize, idxSize := int(p[5]>>4), 0
+	if version == 1 || version == 2 {
+		idxSize = int(p[5] & 0x0f)
+	}
+	pos := 6
+	var count int
+	if version < 2 {
+		count, pos = int(be.Uint16(p[pos:])), pos+2
+	} else {
+		count, pos = int(be.Uint32(p[pos:])), pos+4
+	}
+	need := func(n int) bool { return pos+n <= len(p) }
+	for i := 0; i < count; i++ {
+		var id uint32
+		if version < 2 {
+			if !need(2) {
+				return
+			}
+			id, pos = uint32(be.Uint16(p[pos:])), pos+2
+		} else {
+			if !need(4) {
+				return
+			}
+			id, pos = be.Uint32(p[pos:]), pos+4
+		}
+		method := 0
+		if version == 1 || version == 2 {
+			if !need(2) {
+				return
+			}
+			method, pos = int(be.Uint16(p[pos:])&0x0f), pos+2
+		}
+		if !need(2 + baseSize + 2) {
+			return
+		}
+		pos += 2 // data_reference_index
+		base := uintN(p[pos:], baseSize)
+		pos += baseSize
+		extents := int(be.Uint16(p[pos:]))
+		pos += 2
+		for e := 0; e < extents; e++ {
+			if !need(idxSize + offSize + lenSize) {
+				return
+			}
+			pos += idxSize
+			off := uintN(p[pos:], offSize)
+			pos += offSize
+			length := uintN(p[pos:], lenSize)
+			pos += lenSize
+			// 只支持按文件偏移存放 (construction_method 0)
+			if e == 0 && method == 0 {
+				it := item(id)
+				it.offset, it.length = int64(base+off), int64(length)
+			}
+		}
+	}
+}
+
+// parseIpco 返回每个属性的尺寸 (只有 ispe 有值)
+func parseIpco(r io.ReaderAt, ipco box) [][2]int {
+	var sizes [][2]int
+	_ = boxes(r, ipco.off, ipco.end(), func(b box) error {
+		var s [2]int
+		if b.typ == "ispe" && b.size >= 12 {
+			if p, err := readAt(r, b.off, 12); err == nil {
+				s = [2]int{int(be.Uint32(p[4:])), int(be.Uint32(p[8:]))}
+			}
+		}
+		sizes = append(sizes, s)
+		return nil
+	})
+	return sizes
+}
+
+// parseIpma 记录每个项目关联的属性序号
+func parseIpma(p []byte, item func(uint32) *heifItem) {
+	if len(p) < 8 {
+		return
+	}
+	version, flags := p[0], p[3]
+	count := int(be.Uint32(p[4:]))
+	pos := 8
+	for i := 0; i < count; i++ {
+		var id uint32
+		if version < 1 {
+			if pos+3 > len(p) {
+				return
+			}
+			id, pos = uint32(be.Uint16(p[pos:])), pos+2
+		} else {
+			if pos+5 > len(p) {
+				return
+			}
+			id, pos = be.Uint32(p[pos:]), pos+4
+		}
+		n := int(p[pos])
+		pos++
+		it := item(id)
+		for j := 0; j < n; j++ {
+			// 最高位是 essential 标志
+			if flags&1 != 0 {
+				if pos+2 > len(p) {
+					return
+				}
+				it.props = append(it.props, int(be.Uint16(p[pos:])&0x7fff))
+				pos += 2
+			} else {
+				if pos+1 > len(p) {
+					return
+				}
+				it.props = append(it.props, int(p[pos]&0x7f))
+				pos++
+			}
+		}
+	}
+}
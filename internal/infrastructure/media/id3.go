@@ -0,0 +1,301 @@
+package media
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/extract"
+)
+
+func init() {
+	Register("audio/mpeg", mp3Metadata)
+}
+
+// id3Frames ID3v2.3/2.4 与 v2.2 帧对应的标签名
+var id3Frames = map[string]string{
+	"TIT2": "TITLE", "TPE1": "ARTIST", "TALB": "ALBUM", "TPE2": "ALBUMARTIST",
+	"TCON": "GENRE", "TYER": "YEAR", "TDRC": "DATE", "TRCK": "TRACKNUMBER", "TPOS": "DISCNUMBER",
+	"TT2": "TITLE", "TP1": "ARTIST", "TAL": "ALBUM", "TP2": "ALBUMARTIST",
+	"TCO": "GENRE", "TYE": "YEAR", "TRK": "TRACKNUMBER", "TPA": "DISCNUMBER",
+}
+
+// id3Genre ID3v1 风格的流派编号 "(13)" 或 "(13)Pop"
+var id3Genre = regexp.MustCompile(`^\((\d+)\)(.*)$`)
+
+// MPEG 音频帧头中的码率 (kbit/s) 与采样率表
+var (
+	mpeg1Bitrates = [3][16]int{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	}
+	mpeg2Bitrates = [3][16]int{
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+	}
+	mpegSampleRates = [3]int{44100, 48000, 32000}
+)
+
+// mp3Metadata 读取 ID3v2 标签 (跳过封面等大帧)、ID3v1 标签和第一个音频帧
+// 时长优先使用 Xing/Info/VBRI 头中的帧数，没有时按固定码率估算
+func mp3Metadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	md := &Metadata{Tags: &Tags{}, AudioCodec: "mp3"}
+	audioStart := int64(0)
+	if h, err := readAt(r, 0, 10); err == nil && string(h[:3]) == "ID3" {
+		tagSize := int64(syncsafe(h[6:10]))
+		audioStart = 10 + tagSize
+		if h[5]&0x10 != 0 { // footer
+			audioStart += 10
+		}
+		if err := parseID3v2(r, h, tagSize, md.Tags); err != nil {
+			return nil, err
+		}
+	}
+	audioEnd := size
+	if size >= 128 {
+		if v1, err := readAt(r, size-128, 128); err == nil && string(v1[:3]) == "TAG" {
+			audioEnd -= 128
+			if *md.Tags == (Tags{}) {
+				parseID3v1(v1, md.Tags)
+			}
+		}
+	}
+	parseMPEGFrame(r, audioStart, audioEnd, md)
+	return md, nil
+}
+
+func parseID3v2(r io.ReaderAt, h []byte, tagSize int64, tags *Tags) error {
+	version, flags := h[3], h[5]
+	if version < 2 || version > 4 {
+		return nil
+	}
+	var data io.ReaderAt = r
+	off, end := int64(10), 10+tagSize
+	// v2.3 及以下的整体反同步需要先还原整个标签，很少见，只在这种情况下读取整个标签
+	if flags&0x80 != 0 && version < 4 {
+		raw, err := readAt(r, 10, tagSize)
+		if err != nil {
+			return err
+		}
+		raw = bytes.ReplaceAll(raw, []byte{0xFF, 0x00}, []byte{0xFF})
+		data, off, end = bytes.NewReader(raw), 0, int64(len(raw))
+	}
+	if flags&0x40 != 0 && version >= 3 { // 扩展头
+		eh, err := readAt(data, off, 4)
+		if err != nil {
+			return err
+		}
+		if version == 3 {
+			off += 4 + int64(be.Uint32(eh))
+		} else {
+			off += int64(syncsafe(eh))
+		}
+	}
+
+	idLen, hdrLen := int64(4), int64(10)
+	if version == 2 {
+		idLen, hdrLen = 3, 6
+	}
+	for off+hdrLen <= end {
+		fh, err := readAt(data, off, hdrLen)
+		if err != nil {
+			return err
+		}
+		if fh[0] == 0 { // 填充区
+			break
+		}
+		id := string(fh[:idLen])
+		var frameSize int64
+		var frameFlags byte
+		switch version {
+		case 2:
+			frameSize = int64(fh[3])<<16 | int64(fh[4])<<8 | int64(fh[5])
+		case 3:
+			frameSize, frameFlags = int64(be.Uint32(fh[4:])), fh[9]
+		case 4:
+			frameSize, frameFlags = int64(syncsafe(fh[4:8])), fh[9]
+		}
+		body := off + hdrLen
+		off = body + frameSize
+		if frameSize <= 0 || off > end {
+			break
+		}
+		name, ok := id3Frames[id]
+		if !ok || frameSize > 64<<10 {
+			continue
+		}
+		// 压缩或加密的帧不解析
+		if version == 3 && frameFlags&0xC0 != 0 || version == 4 && frameFlags&0x0C != 0 {
+			continue
+		}
+		p, err := readAt(data, body, frameSize)
+		if err != nil {
+			return err
+		}
+		if version == 4 {
+			if frameFlags&0x01 != 0 && len(p) >= 4 { // 数据长度指示
+				p = p[4:]
+			}
+			if frameFlags&0x02 != 0 {
+				p = bytes.ReplaceAll(p, []byte{0xFF, 0x00}, []byte{0xFF})
+			}
+		}
+		value := decodeID3Text(p)
+		if name == "GENRE" {
+			if m := id3Genre.FindStringSubmatch(value); m != nil {
+				value = m[2]
+			}
+		}
+		setTag(tags, name, value)
+	}
+	return nil
+}
+
+func parseID3v1(b []byte, tags *Tags) {
+	field := func(s []byte) string {
+		s, _, _ = bytes.Cut(s, []byte{0})
+		return strings.TrimSpace(extract.DecodeText(s))
+	}
+	setTag(tags, "TITLE", field(b[3:33]))
+	setTag(tags, "ARTIST", field(b[33:63]))
+	setTag(tags, "ALBUM", field(b[63:93]))
+	setTag(tags, "YEAR", field(b[93:97]))
+	// ID3v1.1：注释最后一个字节为音轨号
+	if b[125] == 0 && b[126] != 0 {
+		tags.Track = int(b[126])
+	}
+}
+
+// decodeID3Text 解码文本帧；编码 0 声明为 ISO-8859-1，但国内的文件大多实际是 GBK
+func decodeID3Text(p []byte) string {
+	if len(p) < 2 {
+		return ""
+	}
+	enc, p := p[0], p[1:]
+	var s string
+	switch enc {
+	case 1, 2:
+		s = decodeUTF16(p, enc == 2)
+	case 3:
+		s = string(p)
+	default:
+		end := bytes.IndexByte(p, 0)
+		if end >= 0 {
+			p = p[:end]
+		}
+		s = extract.DecodeText(p)
+	}
+	// v2.4 多个值以 NUL 分隔，只取第一个
+	s, _, _ = strings.Cut(s, "\x00")
+	return strings.TrimSpace(s)
+}
+
+// decodeUTF16 编码 1 带 BOM，编码 2 为无 BOM 的大端序
+func decodeUTF16(p []byte, bigEndian bool) string {
+	if len(p) >= 2 {
+		switch {
+		case p[0] == 0xFF && p[1] == 0xFE:
+			p, bigEndian = p[2:], false
+		case p[0] == 0xFE && p[1] == 0xFF:
+			p, bigEndian = p[2:], true
+		}
+	}
+	u := make([]uint16, 0, len(p)/2)
+	for i := 0; i+1 < len(p); i += 2 {
+		var c uint16
+		if bigEndian {
+			c = uint16(p[i])<<8 | uint16(p[i+1])
+		} else {
+			c = uint16(p[i+1])<<8 | uint16(p[i])
+		}
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	return string(utf16.Decode(u))
+}
+
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+// parseMPEGFrame 在标签之后查找第一个有效的帧头
+func parseMPEGFrame(r io.ReaderAt, start, end int64, md *Metadata) {
+	buf, err := readAt(r, start, min(end-start, 64<<10))
+	if err != nil {
+		return
+	}
+	for i := 0; i+4 <= len(buf); i++ {
+		if buf[i] != 0xFF || buf[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		version := (buf[i+1] >> 3) & 3 // 3: MPEG1, 2: MPEG2, 0: MPEG2.5
+		layer := (buf[i+1] >> 1) & 3   // 1: Layer III, 2: Layer II, 3: Layer I
+		bitrateIdx := buf[i+2] >> 4
+		rateIdx := (buf[i+2] >> 2) & 3
+		if version == 1 || layer == 0 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+			continue
+		}
+		mono := buf[i+3]>>6 == 3
+		sampleRate := mpegSampleRates[rateIdx]
+		bitrate := mpeg1Bitrates[3-layer][bitrateIdx]
+		samples := 1152
+		switch {
+		case layer == 3:
+			samples = 384
+		case layer == 1 && version != 3:
+			samples = 576
+		}
+		if version != 3 {
+			sampleRate /= 2
+			if version == 0 {
+				sampleRate /= 2
+			}
+			bitrate = mpeg2Bitrates[3-layer][bitrateIdx]
+		}
+		md.SampleRate = sampleRate
+		md.Channels = 2
+		if mono {
+			md.Channels = 1
+		}
+		md.Bitrate = bitrate * 1000
+
+		frames := vbrFrames(buf[i:], version == 3, mono)
+		if frames > 0 {
+			md.Duration = round(float64(frames)*float64(samples)/float64(sampleRate), 3)
+			md.Bitrate = int(float64(end-start-int64(i)) * 8 / md.Duration)
+		} else {
+			md.Duration = round(float64(end-start-int64(i))*8/float64(md.Bitrate), 3)
+		}
+		return
+	}
+}
+
+// vbrFrames 读取 Xing/Info 或 VBRI 头中的总帧数，frame 从帧头开始
+func vbrFrames(frame []byte, mpeg1, mono bool) int {
+	side := 32
+	switch {
+	case mpeg1 && mono:
+		side = 17
+	case !mpeg1 && mono:
+		side = 9
+	case !mpeg1:
+		side = 17
+	}
+	if x := 4 + side; x+12 <= len(frame) {
+		if tag := string(frame[x : x+4]); tag == "Xing" || tag == "Info" {
+			if be.Uint32(frame[x+4:])&1 != 0 {
+				return int(be.Uint32(frame[x+8:]))
+			}
+		}
+	}
+	if v := 36; v+18 <= len(frame) && string(frame[v:v+4]) == "VBRI" {
+		return int(be.Uint32(frame[v+14:]))
+	}
+	return 0
+}
@@ -0,0 +1,39 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/png"
+	"io"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+func init() {
+	for _, mimeType := range []string{"image/png", "image/gif", "image/webp", "image/bmp"} {
+		Register(mimeType, imageMetadata)
+	}
+	Register("image/tiff", tiffMetadata)
+}
+
+// imageMetadata 只解码图片头获取尺寸
+func imageMetadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	cfg, _, err := image.DecodeConfig(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return &Metadata{Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// tiffMetadata TIFF 文件本身就是 EXIF 所用的结构 (相机 RAW 也大多基于 TIFF)
+func tiffMetadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	md, err := imageMetadata(r, size)
+	if err != nil {
+		return nil, err
+	}
+	_ = parseEXIF(r, md)
+	return md, nil
+}
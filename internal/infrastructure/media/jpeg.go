@@ -0,0 +1,68 @@
+package media
+
+import (
+	"fmt"
+	"io"
+)
+
+func init() {
+	Register("image/jpeg", jpegMetadata)
+}
+
+// jpegMetadata 逐个读取段头，只解析 APP1 (EXIF) 与 SOF (尺寸)，遇到图像数据即停止
+func jpegMetadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	soi, err := readAt(r, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	if soi[0] != 0xFF || soi[1] != 0xD8 {
+		return nil, fmt.Errorf("%w: missing JPEG SOI marker", ErrMalformed)
+	}
+	md := &Metadata{}
+	off := int64(2)
+	for off+4 <= size {
+		h, err := readAt(r, off, 4)
+		if err != nil {
+			return nil, err
+		}
+		if h[0] != 0xFF {
+			return nil, fmt.Errorf("%w: invalid JPEG marker at %d", ErrMalformed, off)
+		}
+		marker := h[1]
+		switch {
+		case marker == 0xFF: // 填充字节
+			off++
+			continue
+		case marker == 0x01 || marker >= 0xD0 && marker <= 0xD8:
+			off += 2
+			continue
+		case marker == 0xD9 || marker == 0xDA: // EOI / SOS
+			return md, nil
+		}
+		length := int64(be.Uint16(h[2:]))
+		if length < 2 {
+			return nil, fmt.Errorf("%w: invalid JPEG segment length", ErrMalformed)
+		}
+		seg := off + 4
+		switch {
+		case marker == 0xE1 && length >= 8:
+			id, err := readAt(r, seg, 6)
+			if err != nil {
+				return nil, err
+			}
+			// EXIF 损坏时仍返回尺寸
+			if string(id) == "Exif\x00\x00" {
+				_ = parseEXIF(io.NewSectionReader(r, seg+6, length-8), md)
+			}
+		case marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC:
+			sof, err := readAt(r, seg, 5)
+			if err != nil {
+				return nil, err
+			}
+			md.Height, md.Width = int(be.Uint16(sof[1:])), int(be.Uint16(sof[3:]))
+			return md, nil
+		}
+		off += 2 + length
+	}
+	return md, nil
+}
@@ -0,0 +1,128 @@
+// Package media 从图片、音频与视频文件中提取元数据 (EXIF、音频标签、容器信息)
+// 解析器只通过 io.ReaderAt 按需读取文件头、标签等少量字节，不会读取整个文件
+package media
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	pathpkg "path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported media type")
+	ErrMalformed   = errors.New("malformed media file")
+)
+
+// Metadata 提取结果，各格式只填充能取到的字段
+type Metadata struct {
+	MimeType    string     `json:"mime_type"`
+	Width       int        `json:"width,omitempty"`
+	Height      int        `json:"height,omitempty"`
+	Duration    float64    `json:"duration,omitempty"` // 秒
+	TakenAt     *time.Time `json:"taken_at,omitempty"` // 拍摄/录制时间
+	Orientation int        `json:"orientation,omitempty"`
+	Camera      *Camera    `json:"camera,omitempty"`
+	GPS         *GPS       `json:"gps,omitempty"`
+	Tags        *Tags      `json:"tags,omitempty"`
+	VideoCodec  string     `json:"video_codec,omitempty"`
+	AudioCodec  string     `json:"audio_codec,omitempty"`
+	SampleRate  int        `json:"sample_rate,omitempty"`
+	Channels    int        `json:"channels,omitempty"`
+	Bitrate     int        `json:"bitrate,omitempty"` // bit/s
+}
+
+// Camera 拍摄参数 (EXIF)
+type Camera struct {
+	Make         string  `json:"make,omitempty"`
+	Model        string  `json:"model,omitempty"`
+	Lens         string  `json:"lens,omitempty"`
+	ExposureTime string  `json:"exposure_time,omitempty"` // 如 "1/250"
+	FNumber      float64 `json:"f_number,omitempty"`
+	ISO          int     `json:"iso,omitempty"`
+	FocalLength  float64 `json:"focal_length,omitempty"` // mm
+}
+
+// GPS 拍摄位置 (WGS 84)
+type GPS struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+}
+
+// Tags 音频/视频标签 (ID3、Vorbis Comment、iTunes、Matroska)
+type Tags struct {
+	Title       string `json:"title,omitempty"`
+	Artist      string `json:"artist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	AlbumArtist string `json:"album_artist,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	Track       int    `json:"track,omitempty"`
+	Disc        int    `json:"disc,omitempty"`
+}
+
+// Extractor 解析一种格式的元数据
+type Extractor func(r io.ReaderAt, size int64) (*Metadata, error)
+
+// extractors 以 MIME 类型为键
+var extractors = map[string]Extractor{}
+
+// mimeTypes 按扩展名识别 MIME 类型，不依赖系统的 mime.types (HEIC、MKV 等常常缺失)
+var mimeTypes = map[string]string{
+	".jpg": "image/jpeg", ".jpeg": "image/jpeg",
+	".heic": "image/heic", ".heif": "image/heif", ".avif": "image/avif",
+	".tif": "image/tiff", ".tiff": "image/tiff",
+	".png": "image/png", ".gif": "image/gif", ".webp": "image/webp", ".bmp": "image/bmp",
+	".mp3":  "audio/mpeg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mp4":  "video/mp4", ".m4v": "video/mp4",
+	".mov": "video/quicktime",
+	".mkv": "video/x-matroska", ".mka": "audio/x-matroska",
+	".webm": "video/webm",
+}
+
+// Register 注册某个 MIME 类型的解析器
+func Register(mimeType string, fn Extractor) {
+	extractors[mimeType] = fn
+}
+
+// MimeType 按扩展名返回 MIME 类型，未知时返回空字符串
+func MimeType(name string) string {
+	return mimeTypes[strings.ToLower(pathpkg.Ext(name))]
+}
+
+// Supported 文件类型是否有对应的解析器
+func Supported(name string) bool {
+	_, ok := extractors[MimeType(name)]
+	return ok
+}
+
+// Extract 解析文件元数据，损坏的文件返回 ErrMalformed 而不是 panic
+func Extract(name string, r io.ReaderAt, size int64) (md *Metadata, err error) {
+	mimeType := MimeType(name)
+	fn, ok := extractors[mimeType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, name)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			md, err = nil, fmt.Errorf("%w: %v", ErrMalformed, p)
+		}
+	}()
+	md, err = fn(r, size)
+	if err != nil {
+		return nil, err
+	}
+	md.MimeType = mimeType
+	if md.Tags != nil && *md.Tags == (Tags{}) {
+		md.Tags = nil
+	}
+	if md.Camera != nil && *md.Camera == (Camera{}) {
+		md.Camera = nil
+	}
+	return md, nil
+}
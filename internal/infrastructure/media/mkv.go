@@ -0,0 +1,383 @@
+package media
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("video/x-matroska", mkvMetadata)
+	Register("audio/x-matroska", mkvMetadata)
+	Register("video/webm", mkvMetadata)
+}
+
+// Matroska / WebM 元素 ID
+const (
+	ebmlHeader        = 0x1A45DFA3
+	mkvSegment        = 0x18538067
+	mkvSeekHead       = 0x114D9B74
+	mkvSeek           = 0x4DBB
+	mkvSeekID         = 0x53AB
+	mkvSeekPosition   = 0x53AC
+	mkvInfo           = 0x1549A966
+	mkvTimecodeScale  = 0x2AD7B1
+	mkvDuration       = 0x4489
+	mkvTitle          = 0x7BA9
+	mkvDateUTC        = 0x4461
+	mkvTracks         = 0x1654AE6B
+	mkvTrackEntry     = 0xAE
+	mkvTrackType      = 0x83
+	mkvCodecID        = 0x86
+	mkvVideo          = 0xE0
+	mkvPixelWidth     = 0xB0
+	mkvPixelHeight    = 0xBA
+	mkvAudio          = 0xE1
+	mkvSamplingFreq   = 0xB5
+	mkvChannels       = 0x9F
+	mkvTags           = 0x1254C367
+	mkvTag            = 0x7373
+	mkvSimpleTag      = 0x67C8
+	mkvTagName        = 0x45A3
+	mkvTagString      = 0x4487
+	mkvCluster        = 0x1F43B675
+	mkvUnknownSize    = -1
+	mkvMaxElementSize = 1 << 20
+)
+
+// mkvEpoch DateUTC 从 2001-01-01 起算 (纳秒)
+var mkvEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// mkvCodecs CodecID 前缀
+var mkvCodecs = []struct{ prefix, name string }{
+	{"V_MPEG4/ISO/AVC", "h264"}, {"V_MPEGH/ISO/HEVC", "hevc"}, {"V_AV1", "av1"},
+	{"V_VP8", "vp8"}, {"V_VP9", "vp9"}, {"V_MPEG4", "mpeg4"}, {"V_MPEG2", "mpeg2"},
+	{"A_AAC", "aac"}, {"A_OPUS", "opus"}, {"A_VORBIS", "vorbis"}, {"A_FLAC", "flac"},
+	{"A_EAC3", "eac3"}, {"A_AC3", "ac3"}, {"A_DTS", "dts"}, {"A_TRUEHD", "truehd"},
+	{"A_MPEG/L3", "mp3"}, {"A_PCM", "pcm"},
+}
+
+// element EBML 元素，off 与 size 指数据部分；size 为 mkvUnknownSize 时长度未知 (直播流)
+type element struct {
+	id   uint32
+	off  int64
+	size int64
+}
+
+// mkvMetadata 只读取 Segment 中 Info、Tracks、Tags 三个元素
+// 按顺序扫描到第一个 Cluster 为止，仍缺少的元素通过 SeekHead 定位
+func mkvMetadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	head, err := readElement(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if head.id != ebmlHeader || head.size == mkvUnknownSize {
+		return nil, fmt.Errorf("%w: missing EBML header", ErrMalformed)
+	}
+	seg, err := readElement(r, head.off+head.size)
+	if err != nil {
+		return nil, err
+	}
+	if seg.id != mkvSegment {
+		return nil, fmt.Errorf("%w: missing Segment", ErrMalformed)
+	}
+	segEnd := size
+	if seg.size != mkvUnknownSize {
+		segEnd = min(size, seg.off+seg.size)
+	}
+
+	md := &Metadata{Tags: &Tags{}}
+	parsed := map[uint32]bool{}
+	seeks := map[uint32]int64{}
+	parse := func(e element) error {
+		if parsed[e.id] {
+			return nil
+		}
+		parsed[e.id] = true
+		switch e.id {
+		case mkvSeekHead:
+			return mkvChildren(r, e, func(s element) error {
+				if s.id == mkvSeek {
+					id, pos := parseSeek(r, s)
+					if id != 0 {
+						seeks[id] = seg.off + pos
+					}
+				}
+				return nil
+			})
+		case mkvInfo:
+			return parseMkvInfo(r, e, md)
+		case mkvTracks:
+			return mkvChildren(r, e, func(t element) error {
+				if t.id == mkvTrackEntry {
+					return parseMkvTrack(r, t, md)
+				}
+				return nil
+			})
+		case mkvTags:
+			return parseMkvTags(r, e, md.Tags)
+		}
+		return nil
+	}
+
+	for off := seg.off; off < segEnd; {
+		e, err := readElement(r, off)
+		if err != nil {
+			return nil, err
+		}
+		if e.id == mkvCluster || e.size == mkvUnknownSize {
+			break
+		}
+		if err := parse(e); err != nil {
+			return nil, err
+		}
+		off = e.off + e.size
+	}
+	// Tags 通常写在文件末尾，只能通过 SeekHead 找到
+	for _, id := range []uint32{mkvInfo, mkvTracks, mkvTags} {
+		pos, ok := seeks[id]
+		if parsed[id] || !ok || pos >= segEnd {
+			continue
+		}
+		e, err := readElement(r, pos)
+		if err != nil || e.id != id {
+			continue
+		}
+		if err := parse(e); err != nil {
+			return nil, err
+		}
+	}
+	return md, nil
+}
+
+// readElement 读取 off 处的元素头
+func readElement(r io.ReaderAt, off int64) (element, error) {
+	h, err := readAt(r, off, 12)
+	if err != nil {
+		// 文件末尾的小元素可能不足 12 字节
+		if h, err = readTail(r, off); err != nil {
+			return element{}, err
+		}
+	}
+	idLen := vintLen(h[0])
+	if idLen == 0 || idLen > 4 || idLen >= len(h) {
+		return element{}, fmt.Errorf("%w: invalid EBML ID at %d", ErrMalformed, off)
+	}
+	var id uint32
+	for _, b := range h[:idLen] {
+		id = id<<8 | uint32(b)
+	}
+	sizeLen := vintLen(h[idLen])
+	if sizeLen == 0 || idLen+sizeLen > len(h) {
+		return element{}, fmt.Errorf("%w: invalid EBML size at %d", ErrMalformed, off)
+	}
+	raw := h[idLen : idLen+sizeLen]
+	size := int64(raw[0] & (0xFF >> sizeLen))
+	allOnes := size == int64(0xFF>>sizeLen)
+	for _, b := range raw[1:] {
+		size = size<<8 | int64(b)
+		allOnes = allOnes && b == 0xFF
+	}
+	if allOnes {
+		size = mkvUnknownSize
+	}
+	return element{id: id, off: off + int64(idLen+sizeLen), size: size}, nil
+}
+
+func readTail(r io.ReaderAt, off int64) ([]byte, error) {
+	for n := int64(11); n >= 2; n-- {
+		if h, err := readAt(r, off, n); err == nil {
+			return h, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unexpected end of file", ErrMalformed)
+}
+
+// vintLen 由首字节前导零的个数得到变长整数的字节数
+func vintLen(b byte) int {
+	for i := 0; i < 8; i++ {
+		if b&(0x80>>i) != 0 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// mkvChildren 遍历主元素的子元素
+func mkvChildren(r io.ReaderAt, parent element, fn func(element) error) error {
+	if parent.size == mkvUnknownSize || parent.size > mkvMaxElementSize {
+		return nil
+	}
+	for off := parent.off; off < parent.off+parent.size; {
+		e, err := readElement(r, off)
+		if err != nil {
+			return err
+		}
+		if e.size == mkvUnknownSize || e.off+e.size > parent.off+parent.size {
+			return fmt.Errorf("%w: invalid element size", ErrMalformed)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+		off = e.off + e.size
+	}
+	return nil
+}
+
+func mkvData(r io.ReaderAt, e element) []byte {
+	if e.size <= 0 || e.size > 64<<10 {
+		return nil
+	}
+	b, _ := readAt(r, e.off, e.size)
+	return b
+}
+
+func mkvUint(r io.ReaderAt, e element) uint64 {
+	var v uint64
+	for _, b := range mkvData(r, e) {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+func mkvFloat(r io.ReaderAt, e element) float64 {
+	b := mkvData(r, e)
+	switch len(b) {
+	case 4:
+		return float64(math.Float32frombits(be.Uint32(b)))
+	case 8:
+		return math.Float64frombits(be.Uint64(b))
+	}
+	return 0
+}
+
+func mkvString(r io.ReaderAt, e element) string {
+	s, _, _ := strings.Cut(string(mkvData(r, e)), "\x00")
+	return strings.TrimSpace(s)
+}
+
+func parseSeek(r io.ReaderAt, seek element) (id uint32, pos int64) {
+	_ = mkvChildren(r, seek, func(e element) error {
+		switch e.id {
+		case mkvSeekID:
+			id = uint32(mkvUint(r, e))
+		case mkvSeekPosition:
+			pos = int64(mkvUint(r, e))
+		}
+		return nil
+	})
+	return id, pos
+}
+
+func parseMkvInfo(r io.ReaderAt, info element, md *Metadata) error {
+	scale := uint64(1000000)
+	var duration float64
+	err := mkvChildren(r, info, func(e element) error {
+		switch e.id {
+		case mkvTimecodeScale:
+			if v := mkvUint(r, e); v > 0 {
+				scale = v
+			}
+		case mkvDuration:
+			duration = mkvFloat(r, e)
+		case mkvTitle:
+			md.Tags.Title = mkvString(r, e)
+		case mkvDateUTC:
+			if b := mkvData(r, e); len(b) == 8 {
+				ts := mkvEpoch.Add(time.Duration(int64(be.Uint64(b))))
+				md.TakenAt = &ts
+			}
+		}
+		return nil
+	})
+	if duration > 0 {
+		md.Duration = round(duration*float64(scale)/1e9, 3)
+	}
+	return err
+}
+
+// parseMkvTrack 只记录第一条视频轨与第一条音频轨
+func parseMkvTrack(r io.ReaderAt, track element, md *Metadata) error {
+	var typ uint64
+	var codec string
+	var width, height, channels int
+	var rate float64
+	err := mkvChildren(r, track, func(e element) error {
+		switch e.id {
+		case mkvTrackType:
+			typ = mkvUint(r, e)
+		case mkvCodecID:
+			codec = mkvCodec(mkvString(r, e))
+		case mkvVideo:
+			return mkvChildren(r, e, func(v element) error {
+				switch v.id {
+				case mkvPixelWidth:
+					width = int(mkvUint(r, v))
+				case mkvPixelHeight:
+					height = int(mkvUint(r, v))
+				}
+				return nil
+			})
+		case mkvAudio:
+			return mkvChildren(r, e, func(a element) error {
+				switch a.id {
+				case mkvSamplingFreq:
+					rate = mkvFloat(r, a)
+				case mkvChannels:
+					channels = int(mkvUint(r, a))
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	switch {
+	case typ == 1 && md.VideoCodec == "":
+		md.VideoCodec, md.Width, md.Height = codec, width, height
+	case typ == 2 && md.AudioCodec == "":
+		md.AudioCodec, md.SampleRate, md.Channels = codec, int(rate), channels
+	}
+	return nil
+}
+
+func mkvCodec(id string) string {
+	for _, c := range mkvCodecs {
+		if strings.HasPrefix(id, c.prefix) {
+			return c.name
+		}
+	}
+	_, name, _ := strings.Cut(id, "_")
+	return strings.ToLower(name)
+}
+
+func parseMkvTags(r io.ReaderAt, tags element, t *Tags) error {
+	return mkvChildren(r, tags, func(tag element) error {
+		if tag.id != mkvTag {
+			return nil
+		}
+		return mkvChildren(r, tag, func(simple element) error {
+			if simple.id != mkvSimpleTag {
+				return nil
+			}
+			var name, value string
+			err := mkvChildren(r, simple, func(e element) error {
+				switch e.id {
+				case mkvTagName:
+					name = mkvString(r, e)
+				case mkvTagString:
+					value = mkvString(r, e)
+				}
+				return nil
+			})
+			if err == nil {
+				setTag(t, name, value)
+			}
+			return err
+		})
+	})
+}
@@ -0,0 +1,327 @@
+package media
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("video/mp4", mp4Metadata)
+	Register("video/quicktime", mp4Metadata)
+	Register("audio/mp4", mp4Metadata)
+}
+
+// mp4Epoch MP4 时间戳从 1904-01-01 起算
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// mp4Codecs 采样描述 (stsd) 中的格式代码
+var mp4Codecs = map[string]string{
+	"avc1": "h264", "avc3": "h264", "hvc1": "hevc", "hev1": "hevc",
+	"av01": "av1", "vp09": "vp9", "vp08": "vp8", "mp4v": "mpeg4",
+	"mp4a": "aac", "ac-3": "ac3", "ec-3": "eac3", "alac": "alac",
+	"Opus": "opus", "fLaC": "flac", ".mp3": "mp3", "apcn": "prores", "apch": "prores",
+}
+
+// iso6709 位置字符串，如 "+31.2304+121.4737+004.000/"
+var iso6709 = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)?`)
+
+// mp4Metadata 只读取 moov 及其子 box，跳过 mdat 中的媒体数据
+func mp4Metadata(r io.ReaderAt, size int64) (*Metadata, error) {
+	md := &Metadata{}
+	found := false
+	err := boxes(r, 0, size, func(b box) error {
+		if b.typ != "moov" {
+			return nil
+		}
+		found = true
+		if err := parseMoov(r, b, md); err != nil {
+			return err
+		}
+		return errStop
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: moov box not found", ErrMalformed)
+	}
+	if md.Duration > 0 {
+		md.Bitrate = int(float64(size) * 8 / md.Duration)
+	}
+	return md, nil
+}
+
+func parseMoov(r io.ReaderAt, moov box, md *Metadata) error {
+	tags := &Tags{}
+	md.Tags = tags
+	return boxes(r, moov.off, moov.end(), func(b box) error {
+		switch b.typ {
+		case "mvhd":
+			p, err := payload(r, b)
+			if err != nil {
+				return err
+			}
+			created, timescale, duration := mediaHeader(p)
+			if timescale > 0 {
+				md.Duration = round(float64(duration)/float64(timescale), 3)
+			}
+			// 很多设备不写创建时间 (0)，早于 2000 年的视为无效
+			if ts := mp4Epoch.Add(time.Duration(created) * time.Second); created > 0 && ts.Year() >= 2000 && md.TakenAt == nil {
+				md.TakenAt = &ts
+			}
+		case "trak":
+			return parseTrak(r, b, md)
+		case "udta":
+			return boxes(r, b.off, b.end(), func(u box) error {
+				switch u.typ {
+				case "meta":
+					return parseMeta(r, u, md)
+				case "\xa9xyz":
+					p, err := payload(r, u)
+					if err == nil && len(p) > 4 {
+						md.GPS = parseISO6709(string(p[4:]))
+					}
+				}
+				return nil
+			})
+		case "meta":
+			return parseMeta(r, b, md)
+		}
+		return nil
+	})
+}
+
+// mediaHeader 解析 mvhd / mdhd：版本 1 使用 64 位时间与时长
+func mediaHeader(p []byte) (created, timescale, duration uint64) {
+	if len(p) >= 32 && p[0] == 1 {
+		return be.Uint64(p[4:]), uint64(be.Uint32(p[20:])), be.Uint64(p[24:])
+	}
+	if len(p) >= 20 {
+		return uint64(be.Uint32(p[4:])), uint64(be.Uint32(p[12:])), uint64(be.Uint32(p[16:]))
+	}
+	return 0, 0, 0
+}
+
+// parseTrak 只记录第一条视频轨与第一条音频轨
+func parseTrak(r io.ReaderAt, trak box, md *Metadata) error {
+	var handler, format string
+	var width, height int
+	var entry []byte
+	var timescale, duration uint64
+	err := boxes(r, trak.off, trak.end(), func(b box) error {
+		switch b.typ {
+		case "tkhd":
+			p, err := payload(r, b)
+			if err != nil {
+				return err
+			}
+			// 宽高位于末尾，16.16 定点数
+			if len(p) >= 84 {
+				width, height = int(be.Uint32(p[len(p)-8:])>>16), int(be.Uint32(p[len(p)-4:])>>16)
+			}
+		case "mdia":
+			return boxes(r, b.off, b.end(), func(m box) error {
+				switch m.typ {
+				case "mdhd":
+					p, err := payload(r, m)
+					if err != nil {
+						return err
+					}
+					_, timescale, duration = mediaHeader(p)
+				case "hdlr":
+					p, err := payload(r, m)
+					if err != nil {
+						return err
+					}
+					if len(p) >= 12 {
+						handler = string(p[8:12])
+					}
+				case "minf":
+					stbl, ok, err := child(r, m, "stbl")
+					if err != nil || !ok {
+						return err
+					}
+					stsd, ok, err := child(r, stbl, "stsd")
+					if err != nil || !ok {
+						return err
+					}
+					// 版本/标志 4 字节 + 条目数 4 字节，之后是第一个采样描述
+					p, err := readAt(r, stsd.off, min(stsd.size, 64))
+					if err != nil {
+						return err
+					}
+					if len(p) >= 16 {
+						format, entry = string(p[12:16]), p[8:]
+					}
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	codec := mp4Codecs[format]
+	if codec == "" {
+		codec = strings.TrimSpace(format)
+	}
+	switch handler {
+	case "vide":
+		if md.VideoCodec != "" {
+			return nil
+		}
+		md.VideoCodec = codec
+		// 没有 tkhd 尺寸时使用采样描述中的编码尺寸
+		if (width == 0 || height == 0) && len(entry) >= 36 {
+			width, height = int(be.Uint16(entry[32:])), int(be.Uint16(entry[34:]))
+		}
+		md.Width, md.Height = width, height
+	case "soun":
+		if md.AudioCodec != "" {
+			return nil
+		}
+		md.AudioCodec = codec
+		if len(entry) >= 36 {
+			md.Channels = int(be.Uint16(entry[24:]))
+			md.SampleRate = int(be.Uint32(entry[32:]) >> 16)
+		}
+	default:
+		return nil
+	}
+	if md.Duration == 0 && timescale > 0 {
+		md.Duration = round(float64(duration)/float64(timescale), 3)
+	}
+	return nil
+}
+
+// parseMeta 解析 iTunes 风格 (udta/meta/ilst) 与 QuickTime 风格 (moov/meta/keys + ilst) 的元数据
+func parseMeta(r io.ReaderAt, meta box, md *Metadata) error {
+	// MP4 的 meta 是 FullBox (多 4 字节版本/标志)，QuickTime 的不是：看偏移 4 处是否为 hdlr
+	h, err := readAt(r, meta.off, min(meta.size, 8))
+	if err != nil {
+		return err
+	}
+	if len(h) == 8 && string(h[4:8]) != "hdlr" {
+		meta.off, meta.size = meta.off+4, meta.size-4
+	}
+	var keys []string
+	var ilst box
+	hasList := false
+	err = boxes(r, meta.off, meta.end(), func(b box) error {
+		switch b.typ {
+		case "keys":
+			p, err := payload(r, b)
+			if err != nil {
+				return err
+			}
+			keys = parseKeys(p)
+		case "ilst":
+			ilst, hasList = b, true
+		}
+		return nil
+	})
+	if err != nil || !hasList {
+		return err
+	}
+	return boxes(r, ilst.off, ilst.end(), func(item box) error {
+		data, ok, err := child(r, item, "data")
+		if err != nil || !ok {
+			return err
+		}
+		p, err := payload(r, data)
+		if err != nil || len(p) < 8 {
+			return err
+		}
+		key := item.typ
+		// QuickTime 风格的条目类型是 keys 中的序号 (从 1 开始)
+		if idx := int(be.Uint32([]byte(item.typ))); idx > 0 && idx <= len(keys) {
+			key = keys[idx-1]
+		}
+		applyMP4Tag(md, key, p[8:])
+		return nil
+	})
+}
+
+func parseKeys(p []byte) []string {
+	if len(p) < 8 {
+		return nil
+	}
+	count := int(be.Uint32(p[4:]))
+	keys := make([]string, 0, min(count, 256))
+	off := 8
+	for i := 0; i < count && off+8 <= len(p); i++ {
+		size := int(be.Uint32(p[off:]))
+		if size < 8 || off+size > len(p) {
+			break
+		}
+		keys = append(keys, string(p[off+8:off+size]))
+		off += size
+	}
+	return keys
+}
+
+func applyMP4Tag(md *Metadata, key string, value []byte) {
+	text := strings.TrimSpace(string(value))
+	switch key {
+	case "\xa9nam":
+		md.Tags.Title = text
+	case "\xa9ART":
+		md.Tags.Artist = text
+	case "\xa9alb":
+		md.Tags.Album = text
+	case "aART":
+		md.Tags.AlbumArtist = text
+	case "\xa9gen":
+		md.Tags.Genre = text
+	case "\xa9day":
+		md.Tags.Year = parseYear(text)
+	case "trkn", "disk":
+		// 2 字节保留 + 序号 + 总数
+		if len(value) >= 4 {
+			n := int(be.Uint16(value[2:]))
+			if key == "trkn" {
+				md.Tags.Track = n
+			} else {
+				md.Tags.Disc = n
+			}
+		}
+	case "com.apple.quicktime.location.ISO6709":
+		md.GPS = parseISO6709(text)
+	case "com.apple.quicktime.creationdate":
+		// 例如 2023-05-01T10:00:00+0800
+		if ts, err := time.Parse("2006-01-02T15:04:05-0700", text); err == nil {
+			md.TakenAt = &ts
+		}
+	case "com.apple.quicktime.make":
+		cameraOf(md).Make = text
+	case "com.apple.quicktime.model":
+		cameraOf(md).Model = text
+	}
+}
+
+func cameraOf(md *Metadata) *Camera {
+	if md.Camera == nil {
+		md.Camera = &Camera{}
+	}
+	return md.Camera
+}
+
+func parseISO6709(s string) *GPS {
+	m := iso6709.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil
+	}
+	lat, _ := strconv.ParseFloat(m[1], 64)
+	lon, _ := strconv.ParseFloat(m[2], 64)
+	g := &GPS{Latitude: lat, Longitude: lon}
+	if m[3] != "" {
+		alt, _ := strconv.ParseFloat(m[3], 64)
+		g.Altitude = &alt
+	}
+	return g
+}
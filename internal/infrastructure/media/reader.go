@@ -0,0 +1,98 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// blockSize 每次从底层读取的块大小，相邻的小范围读取合并为一次 (SMB 往返代价高)
+	blockSize = 64 << 10
+	maxBlocks = 64
+	// MaxRead 单个文件最多读取的字节数，损坏或刻意构造的文件不会导致读完整个文件
+	MaxRead = 32 << 20
+	// maxChunk 单次解析的字段上限 (标签、EXIF 段等)
+	maxChunk = 16 << 20
+)
+
+// Reader 将只能顺序读取与 Seek 的文件 (vfs.File) 适配为带块缓存的 io.ReaderAt
+type Reader struct {
+	rs     io.ReadSeeker
+	size   int64
+	blocks map[int64][]byte
+	read   int64
+}
+
+func NewReader(rs io.ReadSeeker, size int64) *Reader {
+	return &Reader{rs: rs, size: size, blocks: make(map[int64][]byte)}
+}
+
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("%w: negative offset", ErrMalformed)
+	}
+	n := 0
+	for n < len(p) && off+int64(n) < r.size {
+		pos := off + int64(n)
+		idx := pos / blockSize
+		block, err := r.block(idx)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], block[pos-idx*blockSize:])
+		if c == 0 {
+			return n, io.ErrUnexpectedEOF
+		}
+		n += c
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (r *Reader) block(idx int64) ([]byte, error) {
+	if b, ok := r.blocks[idx]; ok {
+		return b, nil
+	}
+	if r.read >= MaxRead {
+		return nil, fmt.Errorf("%w: read limit exceeded", ErrMalformed)
+	}
+	if len(r.blocks) >= maxBlocks {
+		for k := range r.blocks {
+			delete(r.blocks, k)
+			break
+		}
+	}
+	if _, err := r.rs.Seek(idx*blockSize, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b := make([]byte, min(blockSize, r.size-idx*blockSize))
+	if _, err := io.ReadFull(r.rs, b); err != nil {
+		return nil, err
+	}
+	r.read += int64(len(b))
+	r.blocks[idx] = b
+	return b, nil
+}
+
+// readAt 读取恰好 n 个字节
+func readAt(r io.ReaderAt, off int64, n int64) ([]byte, error) {
+	if n < 0 || n > maxChunk || off < 0 {
+		return nil, fmt.Errorf("%w: invalid range %d+%d", ErrMalformed, off, n)
+	}
+	b := make([]byte, n)
+	got, err := r.ReadAt(b, off)
+	if int64(got) == n {
+		return b, nil
+	}
+	// 文件比结构声明的短属于文件损坏；其他读取错误 (网络中断等) 原样返回
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("%w: unexpected end of file", ErrMalformed)
+	}
+	return nil, err
+}
+
+var be = binary.BigEndian
@@ -0,0 +1,56 @@
+package media
+
+import (
+	"strconv"
+	"strings"
+)
+
+// setTag 按 Vorbis Comment / Matroska 的标签名写入，ID3 帧先映射为同样的名称
+func setTag(t *Tags, name, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	switch strings.ToUpper(name) {
+	case "TITLE":
+		t.Title = value
+	case "ARTIST":
+		t.Artist = value
+	case "ALBUM":
+		t.Album = value
+	case "ALBUMARTIST", "ALBUM ARTIST", "ALBUM_ARTIST":
+		t.AlbumArtist = value
+	case "GENRE":
+		t.Genre = value
+	case "DATE", "YEAR", "DATE_RELEASED", "DATE_RECORDED":
+		if y := parseYear(value); y > 0 {
+			t.Year = y
+		}
+	case "TRACKNUMBER", "PART_NUMBER":
+		t.Track = leadingInt(value)
+	case "DISCNUMBER":
+		t.Disc = leadingInt(value)
+	}
+}
+
+// parseYear 取日期字符串开头的年份，如 "2019"、"2019-05-01"
+func parseYear(s string) int {
+	if len(s) < 4 {
+		return 0
+	}
+	y, err := strconv.Atoi(s[:4])
+	if err != nil || y <= 0 {
+		return 0
+	}
+	return y
+}
+
+// leadingInt 解析 "3/12" 这类序号中的第一个数字
+func leadingInt(s string) int {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, _ := strconv.Atoi(s[:end])
+	return n
+}
@@ -1,24 +1,54 @@
 package persistence
 
 import (
+	"database/sql"
+	pathpkg "path"
+	"strings"
+
 	"github.com/wentf9/MyGoFileHub/internal/domain/model"
 
+	"github.com/mattn/go-sqlite3"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// driverName 注册了自定义函数的 SQLite 驱动，使排序、分页与文件名匹配可以在查询中完成
+const driverName = "sqlite3_filehub"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := conn.RegisterFunc("bm25", bm25, true); err != nil {
+				return err
+			}
+			return conn.RegisterFunc("name_match", nameMatch, true)
+		},
+	})
+}
+
+// nameMatch 与实时遍历相同的文件名匹配：pattern 为小写的 path.Match glob，name 转为小写后比较
+// (SQLite 的 lower() 只转换 ASCII 字符)
+func nameMatch(pattern, name string) bool {
+	ok, _ := pathpkg.Match(pattern, strings.ToLower(name))
+	return ok
+}
+
 // InitDB 初始化 SQLite 连接并自动迁移表结构
 func InitDB(dbPath string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: driverName, DSN: dbPath}), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
 
 	// 自动迁移模式：自动创建表、缺少的字段
-	err = db.AutoMigrate(&model.StorageSource{}, &model.User{})
+	err = db.AutoMigrate(&model.StorageSource{}, &model.User{}, &model.UserPermission{}, &model.FileMetadata{}, &model.UploadSession{}, &model.SearchEntry{}, &model.SearchIndexState{}, &model.TextDocument{}, &model.MediaInfo{}, &model.Share{}, &model.ShareAccess{}, &model.FileRequest{}, &model.FileRequestUpload{}, &model.Notification{}, &model.TrashItem{}, &model.FileVersion{})
 	if err != nil {
 		return nil, err
 	}
+	// FTS 虚拟表不能通过 AutoMigrate 创建
+	if err := db.Exec(createFullTextTable).Error; err != nil {
+		return nil, err
+	}
 
 	return db, nil
 }
@@ -0,0 +1,82 @@
+package persistence
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type FileRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewFileRequestRepository(db *gorm.DB) repository.FileRequestRepository {
+	return &FileRequestRepository{db: db}
+}
+
+func (r *FileRequestRepository) FindByID(ctx context.Context, id uint) (*model.FileRequest, error) {
+	var req model.FileRequest
+	if err := r.db.WithContext(ctx).First(&req, id).Error; err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *FileRequestRepository) FindByToken(ctx context.Context, token string) (*model.FileRequest, error) {
+	var req model.FileRequest
+	if err := r.db.WithContext(ctx).Where("token = ?", token).First(&req).Error; err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *FileRequestRepository) FindByUser(ctx context.Context, username string) ([]*model.FileRequest, error) {
+	var reqs []*model.FileRequest
+	query := r.db.WithContext(ctx).Order("id DESC")
+	if username != "" {
+		query = query.Where("username = ?", username)
+	}
+	err := query.Find(&reqs).Error
+	return reqs, err
+}
+
+func (r *FileRequestRepository) Save(ctx context.Context, req *model.FileRequest) error {
+	return r.db.WithContext(ctx).Save(req).Error
+}
+
+func (r *FileRequestRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.FileRequest{}, id).Error
+}
+
+func (r *FileRequestRepository) Rename(ctx context.Context, sourceID uint, oldPath, newPath string) error {
+	oldPath, newPath = strings.TrimSuffix(oldPath, "/"), strings.TrimSuffix(newPath, "/")
+	return subtree(r.db.WithContext(ctx).Model(&model.FileRequest{}), sourceID, oldPath).
+		Update("path", gorm.Expr("? || substr(path, ?)", newPath, len(oldPath)+1)).Error
+}
+
+func (r *FileRequestRepository) AddUploads(ctx context.Context, uploads []*model.FileRequestUpload) error {
+	if len(uploads) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(uploads).Error
+}
+
+func (r *FileRequestRepository) FindUploads(ctx context.Context, requestID uint) ([]*model.FileRequestUpload, error) {
+	var uploads []*model.FileRequestUpload
+	err := r.db.WithContext(ctx).Where("request_id = ?", requestID).Order("id DESC").Find(&uploads).Error
+	return uploads, err
+}
+
+func (r *FileRequestRepository) DeleteByUser(ctx context.Context, username string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&model.FileRequest{}).Select("id").Where("username = ?", username)
+		if err := tx.Where("request_id IN (?)", ids).Delete(&model.FileRequestUpload{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("username = ?", username).Delete(&model.FileRequest{}).Error
+	})
+}
@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type FileVersionRepository struct {
+	db *gorm.DB
+}
+
+func NewFileVersionRepository(db *gorm.DB) repository.FileVersionRepository {
+	return &FileVersionRepository{db: db}
+}
+
+func (r *FileVersionRepository) FindByID(ctx context.Context, id uint) (*model.FileVersion, error) {
+	var version model.FileVersion
+	if err := r.db.WithContext(ctx).First(&version, id).Error; err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+func (r *FileVersionRepository) FindByPath(ctx context.Context, sourceID uint, path string) ([]*model.FileVersion, error) {
+	var versions []*model.FileVersion
+	err := r.db.WithContext(ctx).Where("source_id = ? AND path = ?", sourceID, path).Order("created_at DESC, id DESC").Find(&versions).Error
+	return versions, err
+}
+
+func (r *FileVersionRepository) FindExpired(ctx context.Context, sourceID uint, before time.Time) ([]*model.FileVersion, error) {
+	var versions []*model.FileVersion
+	err := r.db.WithContext(ctx).Where("source_id = ? AND created_at < ?", sourceID, before).Find(&versions).Error
+	return versions, err
+}
+
+func (r *FileVersionRepository) Save(ctx context.Context, version *model.FileVersion) error {
+	return r.db.WithContext(ctx).Save(version).Error
+}
+
+func (r *FileVersionRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.FileVersion{}, id).Error
+}
+
+func (r *FileVersionRepository) Rename(ctx context.Context, sourceID uint, oldPath, newPath string) error {
+	oldPath, newPath = strings.TrimSuffix(oldPath, "/"), strings.TrimSuffix(newPath, "/")
+	return subtree(r.db.WithContext(ctx).Model(&model.FileVersion{}), sourceID, oldPath).
+		Update("path", gorm.Expr("? || substr(path, ?)", newPath, len(oldPath)+1)).Error
+}
@@ -0,0 +1,214 @@
+package persistence
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	pathpkg "path"
+	"strings"
+	"unicode"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+// createFullTextTable FTS4 虚拟表：tokens 为分词结果，body 为原文 (只存储不索引，用于生成摘要)
+// 使用 FTS4 而不是 FTS5：go-sqlite3 默认编译了 FTS4，FTS5 需要额外的构建标签
+const createFullTextTable = `CREATE VIRTUAL TABLE IF NOT EXISTS fulltext_index USING fts4(tokens, body, notindexed=body, tokenize=simple)`
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// snippetRunes 摘要长度
+const snippetRunes = 160
+
+type FullTextRepository struct {
+	db *gorm.DB
+}
+
+func NewFullTextRepository(db *gorm.DB) repository.FullTextRepository {
+	return &FullTextRepository{db: db}
+}
+
+func (r *FullTextRepository) FindBySource(ctx context.Context, sourceID uint) ([]*model.TextDocument, error) {
+	var docs []*model.TextDocument
+	err := r.db.WithContext(ctx).Where("source_id = ?", sourceID).Find(&docs).Error
+	return docs, err
+}
+
+func (r *FullTextRepository) Upsert(ctx context.Context, doc *model.TextDocument, text string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing model.TextDocument
+		err := tx.Where("source_id = ? AND path = ?", doc.SourceID, doc.Path).First(&existing).Error
+		switch {
+		case err == nil:
+			doc.ID = existing.ID
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+		if err := tx.Save(doc).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM fulltext_index WHERE docid = ?", doc.ID).Error; err != nil {
+			return err
+		}
+		// 文件名、标题、作者也参与检索
+		tokens := indexTokens(doc.Name + "\n" + doc.Title + "\n" + doc.Author + "\n" + text)
+		return tx.Exec("INSERT INTO fulltext_index (docid, tokens, body) VALUES (?, ?, ?)", doc.ID, tokens, text).Error
+	})
+}
+
+func (r *FullTextRepository) Delete(ctx context.Context, sourceID uint, path string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteTree(tx, sourceID, path)
+	})
+}
+
+func deleteTree(tx *gorm.DB, sourceID uint, path string) error {
+	var ids []uint
+	err := subtree(tx.Model(&model.TextDocument{}), sourceID, path).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM fulltext_index WHERE docid IN ?", ids).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&model.TextDocument{}, ids).Error
+}
+
+// Rename 目标位置已有的文档 (覆盖移动) 先删除
+func (r *FullTextRepository) Rename(ctx context.Context, sourceID uint, oldPath, newPath string) error {
+	oldPath, newPath = strings.TrimSuffix(oldPath, "/"), strings.TrimSuffix(newPath, "/")
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteTree(tx, sourceID, newPath); err != nil {
+			return err
+		}
+		return subtree(tx.Model(&model.TextDocument{}), sourceID, oldPath).Updates(map[string]any{
+			"path": gorm.Expr("? || substr(path, ?)", newPath, len(oldPath)+1),
+			"name": gorm.Expr("CASE WHEN path = ? THEN ? ELSE name END", oldPath, pathpkg.Base(newPath)),
+		}).Error
+	})
+}
+
+// subtree path 本身及其下的所有文档
+func subtree(query *gorm.DB, sourceID uint, path string) *gorm.DB {
+	path = strings.TrimSuffix(path, "/")
+	return query.Where("source_id = ? AND (path = ? OR path LIKE ? ESCAPE '\\')", sourceID, path, escapeLike(path)+"/%")
+}
+
+type fullTextRow struct {
+	model.TextDocument
+	Score float64 `gorm:"column:score"`
+}
+
+// Search 在查询中完成权限过滤、排序与分页 (bm25 注册为 SQL 函数)，只读取当前页
+func (r *FullTextRepository) Search(ctx context.Context, query string, scopes []repository.ReadScope, limit, offset int) ([]repository.FullTextHit, int, error) {
+	expr := matchExpr(query)
+	cond, args := scopeCondition("d.", scopes)
+	if expr == "" || cond == "" {
+		return nil, 0, nil
+	}
+	from := `FROM fulltext_index JOIN text_documents d ON d.id = fulltext_index.docid
+		WHERE fulltext_index MATCH ? AND ` + cond
+	args = append([]any{expr}, args...)
+
+	var total int
+	if err := r.db.WithContext(ctx).Raw("SELECT count(*) "+from, args...).Row().Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	if total <= offset {
+		return nil, total, nil
+	}
+	var rows []fullTextRow
+	err := r.db.WithContext(ctx).Raw(`SELECT d.*, bm25(matchinfo(fulltext_index, 'pcnalx')) AS score `+from+`
+		ORDER BY score DESC, d.id LIMIT ? OFFSET ?`, append(args, limit, offset)...).Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	hits := make([]repository.FullTextHit, len(rows))
+	for i := range rows {
+		hits[i] = repository.FullTextHit{Doc: &rows[i].TextDocument, Score: rows[i].Score}
+	}
+	return hits, total, nil
+}
+
+// bm25 根据 matchinfo('pcnalx') 计算 tokens 列 (第 0 列) 的 BM25 得分
+// 布局: p c n a[c] l[c] x[p*c*3]，x 每组为 (本行命中数, 全部行命中数, 命中的行数)
+func bm25(info []byte) float64 {
+	v := make([]uint32, len(info)/4)
+	for i := range v {
+		v[i] = binary.NativeEndian.Uint32(info[i*4:])
+	}
+	if len(v) < 3 {
+		return 0
+	}
+	p, c, n := int(v[0]), int(v[1]), float64(v[2])
+	if len(v) < 3+2*c+3*p*c {
+		return 0
+	}
+	avgdl := math.Max(float64(v[3]), 1)
+	dl := float64(v[3+c])
+	score := 0.0
+	for i := 0; i < p; i++ {
+		x := 3 + 2*c + 3*i*c
+		tf, df := float64(v[x]), float64(v[x+2])
+		idf := math.Log((n-df+0.5)/(df+0.5) + 1)
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*dl/avgdl))
+	}
+	return score
+}
+
+// Snippet 取第一个查询词附近的文本，找不到时取开头
+func (r *FullTextRepository) Snippet(ctx context.Context, docID uint, query string) (string, error) {
+	var body string
+	err := r.db.WithContext(ctx).Raw("SELECT body FROM fulltext_index WHERE docid = ?", docID).Row().Scan(&body)
+	if err != nil {
+		return "", err
+	}
+	text := []rune(body)
+	lower := make([]rune, len(text))
+	for i, ch := range text {
+		lower[i] = unicode.ToLower(ch)
+	}
+	start := 0
+	for _, word := range strings.Fields(query) {
+		if i := indexRunes(lower, []rune(strings.ToLower(word))); i >= 0 {
+			start = max(i-snippetRunes/4, 0)
+			break
+		}
+	}
+	end := min(start+snippetRunes, len(text))
+	snippet := strings.Join(strings.Fields(string(text[start:end])), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet, nil
+}
+
+func indexRunes(s, sub []rune) int {
+	if len(sub) == 0 {
+		return -1
+	}
+	for i := 0; i+len(sub) <= len(s); i++ {
+		match := true
+		for j := range sub {
+			if s[i+j] != sub[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
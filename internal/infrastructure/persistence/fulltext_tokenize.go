@@ -0,0 +1,88 @@
+package persistence
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxTokenLen 过长的 "单词" (base64、哈希等) 没有检索价值
+const maxTokenLen = 64
+
+// tokenize 分词：字母数字连续的片段作为一个词 (转小写)，
+// 中日韩文字没有空格分隔，按相邻两字切分 (二元组)，
+// 建立索引时 (index=true) 额外保留每段末尾的单字，使单字查询也能通过前缀匹配命中
+// 分词结果以空格连接后交给 FTS 的 simple 分词器，两边的切分结果一致
+func tokenize(text string, index bool, emit func(string)) {
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		if len(word) > 0 && len(word) <= maxTokenLen {
+			emit(string(word))
+		}
+		word = word[:0]
+	}
+	flushCJK := func() {
+		switch {
+		case len(cjk) == 1:
+			emit(string(cjk))
+		case len(cjk) > 1:
+			for i := 0; i+1 < len(cjk); i++ {
+				emit(string(cjk[i : i+2]))
+			}
+			if index {
+				emit(string(cjk[len(cjk)-1:]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// indexTokens 文档写入 FTS 表的分词结果
+func indexTokens(text string) string {
+	var sb strings.Builder
+	tokenize(text, true, func(t string) {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(t)
+	})
+	return sb.String()
+}
+
+// matchExpr 将用户输入转换为 FTS MATCH 表达式
+// 每个空格分隔的词作为一个短语 (词内多个分词须相邻)，词之间为 AND；单个汉字按前缀匹配
+func matchExpr(query string) string {
+	var parts []string
+	for _, field := range strings.Fields(query) {
+		var tokens []string
+		tokenize(field, false, func(t string) { tokens = append(tokens, t) })
+		switch {
+		case len(tokens) == 0:
+			continue
+		case len(tokens) == 1 && len([]rune(tokens[0])) == 1 && isCJK([]rune(tokens[0])[0]):
+			parts = append(parts, `"`+tokens[0]+`*"`)
+		default:
+			parts = append(parts, `"`+strings.Join(tokens, " ")+`"`)
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,38 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MediaInfoRepository struct {
+	db *gorm.DB
+}
+
+func NewMediaInfoRepository(db *gorm.DB) repository.MediaInfoRepository {
+	return &MediaInfoRepository{db: db}
+}
+
+func (r *MediaInfoRepository) FindByPath(ctx context.Context, sourceID uint, path string) (*model.MediaInfo, error) {
+	var info model.MediaInfo
+	if err := r.db.WithContext(ctx).Where("source_id = ? AND path = ?", sourceID, path).First(&info).Error; err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (r *MediaInfoRepository) Save(ctx context.Context, info *model.MediaInfo) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source_id"}, {Name: "path"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "mod_time", "data", "error", "extracted_at"}),
+	}).Create(info).Error
+}
+
+func (r *MediaInfoRepository) Delete(ctx context.Context, sourceID uint, path string) error {
+	return subtree(r.db.WithContext(ctx), sourceID, path).Delete(&model.MediaInfo{}).Error
+}
@@ -0,0 +1,42 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MetadataRepository struct {
+	db *gorm.DB
+}
+
+func NewMetadataRepository(db *gorm.DB) repository.MetadataRepository {
+	return &MetadataRepository{db: db}
+}
+
+// FindByPath 获取某个文件的全部元数据
+func (r *MetadataRepository) FindByPath(ctx context.Context, sourceID uint, path string) ([]*model.FileMetadata, error) {
+	var metas []*model.FileMetadata
+	err := r.db.WithContext(ctx).
+		Where("source_id = ? AND path = ?", sourceID, path).
+		Find(&metas).Error
+	return metas, err
+}
+
+// Upsert 按 (source_id, path, key) 插入或更新
+func (r *MetadataRepository) Upsert(ctx context.Context, meta *model.FileMetadata) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source_id"}, {Name: "path"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(meta).Error
+}
+
+func (r *MetadataRepository) Delete(ctx context.Context, sourceID uint, path string, key string) error {
+	return r.db.WithContext(ctx).
+		Where("source_id = ? AND path = ? AND key = ?", sourceID, path, key).
+		Delete(&model.FileMetadata{}).Error
+}
@@ -0,0 +1,44 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) repository.NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+func (r *NotificationRepository) FindByUser(ctx context.Context, username string, unreadOnly bool, limit int) ([]*model.Notification, error) {
+	var list []*model.Notification
+	query := r.db.WithContext(ctx).Where("username = ?", username)
+	if unreadOnly {
+		query = query.Where("read = ?", false)
+	}
+	err := query.Order("id DESC").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+func (r *NotificationRepository) Save(ctx context.Context, n *model.Notification) error {
+	return r.db.WithContext(ctx).Save(n).Error
+}
+
+func (r *NotificationRepository) MarkRead(ctx context.Context, username string, ids []uint) error {
+	query := r.db.WithContext(ctx).Model(&model.Notification{}).Where("username = ?", username)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	return query.Update("read", true).Error
+}
+
+func (r *NotificationRepository) DeleteByUser(ctx context.Context, username string) error {
+	return r.db.WithContext(ctx).Where("username = ?", username).Delete(&model.Notification{}).Error
+}
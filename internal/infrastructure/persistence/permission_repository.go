@@ -30,3 +30,8 @@ func (r *PermissionRepository) FindByUserAndSource(ctx context.Context, userID,
 func (r *PermissionRepository) Save(ctx context.Context, perm *model.UserPermission) error {
 	return r.db.WithContext(ctx).Save(perm).Error
 }
+
+// DeleteByUser 删除用户的全部权限规则 (删除用户时调用)
+func (r *PermissionRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.UserPermission{}).Error
+}
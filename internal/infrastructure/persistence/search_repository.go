@@ -0,0 +1,158 @@
+package persistence
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchBatchSize 查询与写入索引时每批的条目数
+const searchBatchSize = 500
+
+type SearchIndexRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchIndexRepository(db *gorm.DB) repository.SearchIndexRepository {
+	return &SearchIndexRepository{db: db}
+}
+
+func (r *SearchIndexRepository) FindState(ctx context.Context, sourceID uint) (*model.SearchIndexState, error) {
+	var state model.SearchIndexState
+	if err := r.db.WithContext(ctx).Where("source_id = ?", sourceID).First(&state).Error; err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *SearchIndexRepository) InsertEntries(ctx context.Context, entries []*model.SearchEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(entries, searchBatchSize).Error
+}
+
+// Commit 失败的重建留下的旧版本条目也会在这里一并清理
+func (r *SearchIndexRepository) Commit(ctx context.Context, state *model.SearchIndexState) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "source_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"generation", "entries", "indexed_at"}),
+		}).Create(state).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("source_id = ? AND generation <> ?", state.SourceID, state.Generation).
+			Delete(&model.SearchEntry{}).Error
+	})
+}
+
+// Query 使用 FindInBatches 分批查询，不在向客户端推送结果期间长时间占用读游标
+func (r *SearchIndexRepository) Query(ctx context.Context, state *model.SearchIndexState, filter repository.SearchFilter, fn func(*model.SearchEntry) error) error {
+	query := r.db.WithContext(ctx).Model(&model.SearchEntry{}).
+		Where("source_id = ? AND generation = ?", state.SourceID, state.Generation)
+	if prefix := strings.TrimSuffix(filter.PathPrefix, "/"); prefix != "" {
+		query = query.Where("path LIKE ? ESCAPE '\\'", escapeLike(prefix)+"/%")
+	}
+	if filter.MinSize > 0 {
+		query = query.Where("size >= ?", filter.MinSize)
+	}
+	if filter.MaxSize > 0 {
+		query = query.Where("size <= ?", filter.MaxSize)
+	}
+	if !filter.After.IsZero() {
+		query = query.Where("mod_time >= ?", filter.After)
+	}
+	if !filter.Before.IsZero() {
+		query = query.Where("mod_time <= ?", filter.Before)
+	}
+	if filter.IsDir != nil {
+		query = query.Where("is_dir = ?", *filter.IsDir)
+	}
+	if filter.Name != "" {
+		query = query.Where("name_match(?, name)", filter.Name)
+	}
+	if filter.Scope != nil {
+		cond, args := scopeCondition("", []repository.ReadScope{*filter.Scope})
+		if cond == "" {
+			return nil
+		}
+		query = query.Where(cond, args...)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var entries []*model.SearchEntry
+	var stop error
+	err := query.FindInBatches(&entries, searchBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, e := range entries {
+			if stop = fn(e); stop != nil {
+				return stop
+			}
+		}
+		return nil
+	}).Error
+	if stop != nil {
+		return stop
+	}
+	return err
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// scopeCondition 把用户的可读范围转换为查询条件，column 为 source_id 与 path 所在表的前缀
+// 与 PermissionService.CheckPermission 相同：路径可读当且仅当最长匹配的规则允许读取，
+// 即命中某条允许规则，且没有命中比它优先的拒绝规则 (更长，或长度相同但更靠前)
+// 没有可读范围时返回空字符串
+func scopeCondition(column string, scopes []repository.ReadScope) (string, []any) {
+	var ors []string
+	var args []any
+	// hasPrefix 按字节前缀匹配；substr 按字符计数，对合法的 UTF-8 两者等价
+	hasPrefix := func(prefix string) string {
+		args = append(args, utf8.RuneCountInString(prefix), prefix)
+		return "substr(" + column + "path, 1, ?) = ?"
+	}
+	for _, scope := range scopes {
+		if scope.All {
+			ors = append(ors, column+"source_id = ?")
+			args = append(args, scope.SourceID)
+			continue
+		}
+		var allows []string
+		for i, allow := range scope.Rules {
+			if !allow.Allow {
+				continue
+			}
+			if len(allows) == 0 {
+				args = append(args, scope.SourceID)
+			}
+			terms := []string{hasPrefix(allow.Prefix)}
+			for j, deny := range scope.Rules {
+				if deny.Allow || !strings.HasPrefix(deny.Prefix, allow.Prefix) {
+					continue
+				}
+				if len(deny.Prefix) > len(allow.Prefix) || (len(deny.Prefix) == len(allow.Prefix) && j < i) {
+					terms = append(terms, "NOT "+hasPrefix(deny.Prefix))
+				}
+			}
+			allows = append(allows, "("+strings.Join(terms, " AND ")+")")
+		}
+		if len(allows) > 0 {
+			ors = append(ors, "("+column+"source_id = ? AND ("+strings.Join(allows, " OR ")+"))")
+		}
+	}
+	if len(ors) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
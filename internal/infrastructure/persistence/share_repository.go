@@ -0,0 +1,93 @@
+package persistence
+
+import (
+	"context"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type ShareRepository struct {
+	db *gorm.DB
+}
+
+func NewShareRepository(db *gorm.DB) repository.ShareRepository {
+	return &ShareRepository{db: db}
+}
+
+func (r *ShareRepository) FindByID(ctx context.Context, id uint) (*model.Share, error) {
+	var share model.Share
+	if err := r.db.WithContext(ctx).First(&share, id).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (r *ShareRepository) FindByToken(ctx context.Context, token string) (*model.Share, error) {
+	var share model.Share
+	if err := r.db.WithContext(ctx).Where("token = ?", token).First(&share).Error; err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (r *ShareRepository) FindByUser(ctx context.Context, username string) ([]*model.Share, error) {
+	var shares []*model.Share
+	query := r.db.WithContext(ctx).Order("id DESC")
+	if username != "" {
+		query = query.Where("username = ?", username)
+	}
+	err := query.Find(&shares).Error
+	return shares, err
+}
+
+func (r *ShareRepository) Save(ctx context.Context, share *model.Share) error {
+	return r.db.WithContext(ctx).Save(share).Error
+}
+
+func (r *ShareRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Share{}, id).Error
+}
+
+func (r *ShareRepository) Rename(ctx context.Context, sourceID uint, oldPath, newPath string) error {
+	oldPath, newPath = strings.TrimSuffix(oldPath, "/"), strings.TrimSuffix(newPath, "/")
+	return subtree(r.db.WithContext(ctx).Model(&model.Share{}), sourceID, oldPath).
+		Update("path", gorm.Expr("? || substr(path, ?)", newPath, len(oldPath)+1)).Error
+}
+
+// AddDownload 在同一条 UPDATE 中判断上限，并发下载不会超出
+func (r *ShareRepository) AddDownload(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&model.Share{}).
+		Where("id = ? AND (max_downloads = 0 OR downloads < max_downloads)", id).
+		Update("downloads", gorm.Expr("downloads + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *ShareRepository) RefundDownload(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&model.Share{}).
+		Where("id = ? AND downloads > 0", id).
+		Update("downloads", gorm.Expr("downloads - 1")).Error
+}
+
+func (r *ShareRepository) DeleteByUser(ctx context.Context, username string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&model.Share{}).Select("id").Where("username = ?", username)
+		if err := tx.Where("share_id IN (?)", ids).Delete(&model.ShareAccess{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("username = ?", username).Delete(&model.Share{}).Error
+	})
+}
+
+func (r *ShareRepository) LogAccess(ctx context.Context, access *model.ShareAccess) error {
+	return r.db.WithContext(ctx).Create(access).Error
+}
+
+func (r *ShareRepository) FindAccess(ctx context.Context, shareID uint, limit int) ([]*model.ShareAccess, error) {
+	var logs []*model.ShareAccess
+	err := r.db.WithContext(ctx).Where("share_id = ?", shareID).Order("id DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
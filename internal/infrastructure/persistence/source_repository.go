@@ -2,6 +2,8 @@ package persistence
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/wentf9/MyGoFileHub/internal/domain/model"
 	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
@@ -47,6 +49,52 @@ func (r *SourceRepository) Save(ctx context.Context, source *model.StorageSource
 	return r.db.WithContext(ctx).Save(source).Error
 }
 
+func (r *SourceRepository) SaveAll(ctx context.Context, sources []*model.StorageSource) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, source := range sources {
+			if err := tx.Save(source).Error; err != nil {
+				return fmt.Errorf("source %s: %w", source.Key, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Delete 在一个事务中删除存储源及引用它的全部记录
+// 分享、回收站等按 Key 访问文件，留下的记录会在同名存储源重新创建后指向新数据
 func (r *SourceRepository) Delete(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).Delete(&model.StorageSource{}, id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var source model.StorageSource
+		if err := tx.First(&source, id).Error; err != nil {
+			return err
+		}
+		shares := tx.Model(&model.Share{}).Select("id").Where("source_id = ?", id)
+		if err := tx.Where("share_id IN (?)", shares).Delete(&model.ShareAccess{}).Error; err != nil {
+			return err
+		}
+		requests := tx.Model(&model.FileRequest{}).Select("id").Where("source_id = ?", id)
+		if err := tx.Where("request_id IN (?)", requests).Delete(&model.FileRequestUpload{}).Error; err != nil {
+			return err
+		}
+		// 存放在该存储源中的其他存储源的回收站条目同样无法恢复
+		if err := tx.Where("source_id = ? OR trash_source_key = ?", id, source.Key).Delete(&model.TrashItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM fulltext_index WHERE docid IN (SELECT id FROM text_documents WHERE source_id = ?)", id).Error; err != nil {
+			return err
+		}
+		for _, table := range []any{
+			&model.Share{}, &model.FileRequest{}, &model.FileVersion{}, &model.FileMetadata{}, &model.MediaInfo{},
+			&model.SearchEntry{}, &model.SearchIndexState{}, &model.TextDocument{}, &model.UserPermission{},
+		} {
+			if err := tx.Where("source_id = ?", id).Delete(table).Error; err != nil {
+				return err
+			}
+		}
+		// 未完成的上传只标记为过期，由定期清理连同暂存文件一起删除
+		if err := tx.Model(&model.UploadSession{}).Where("source_key = ?", source.Key).Update("expires_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.StorageSource{}, id).Error
+	})
 }
@@ -0,0 +1,58 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type TrashRepository struct {
+	db *gorm.DB
+}
+
+func NewTrashRepository(db *gorm.DB) repository.TrashRepository {
+	return &TrashRepository{db: db}
+}
+
+func (r *TrashRepository) FindByID(ctx context.Context, id uint) (*model.TrashItem, error) {
+	var item model.TrashItem
+	if err := r.db.WithContext(ctx).First(&item, id).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (r *TrashRepository) FindByUser(ctx context.Context, username string, sourceKey string) ([]*model.TrashItem, error) {
+	var items []*model.TrashItem
+	query := r.db.WithContext(ctx).Order("deleted_at DESC, id DESC")
+	if username != "" {
+		query = query.Where("deleted_by = ?", username)
+	}
+	if sourceKey != "" {
+		query = query.Where("source_key = ?", sourceKey)
+	}
+	err := query.Find(&items).Error
+	return items, err
+}
+
+func (r *TrashRepository) FindExpired(ctx context.Context, sourceID uint, before time.Time) ([]*model.TrashItem, error) {
+	var items []*model.TrashItem
+	err := r.db.WithContext(ctx).Where("source_id = ? AND deleted_at < ?", sourceID, before).Find(&items).Error
+	return items, err
+}
+
+func (r *TrashRepository) Save(ctx context.Context, item *model.TrashItem) error {
+	return r.db.WithContext(ctx).Save(item).Error
+}
+
+func (r *TrashRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.TrashItem{}, id).Error
+}
+
+func (r *TrashRepository) Reassign(ctx context.Context, from, to string) error {
+	return r.db.WithContext(ctx).Model(&model.TrashItem{}).Where("deleted_by = ?", from).Update("deleted_by", to).Error
+}
@@ -0,0 +1,55 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+	"github.com/wentf9/MyGoFileHub/internal/domain/repository"
+
+	"gorm.io/gorm"
+)
+
+type UploadSessionRepository struct {
+	db *gorm.DB
+}
+
+func NewUploadSessionRepository(db *gorm.DB) repository.UploadSessionRepository {
+	return &UploadSessionRepository{db: db}
+}
+
+func (r *UploadSessionRepository) FindByID(ctx context.Context, id string) (*model.UploadSession, error) {
+	var session model.UploadSession
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindExpired 查找过期的会话 (用于清理暂存文件)
+func (r *UploadSessionRepository) FindExpired(ctx context.Context, before time.Time) ([]*model.UploadSession, error) {
+	var sessions []*model.UploadSession
+	err := r.db.WithContext(ctx).Where("expires_at < ?", before).Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *UploadSessionRepository) Save(ctx context.Context, session *model.UploadSession) error {
+	return r.db.WithContext(ctx).Save(session).Error
+}
+
+// UpdateOffset 只更新偏移量与过期时间，避免覆盖其他字段
+func (r *UploadSessionRepository) UpdateOffset(ctx context.Context, id string, offset int64, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.UploadSession{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"offset": offset, "expires_at": expiresAt}).Error
+}
+
+func (r *UploadSessionRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&model.UploadSession{}, "id = ?", id).Error
+}
+
+func (r *UploadSessionRepository) FindByUser(ctx context.Context, username string) ([]*model.UploadSession, error) {
+	var sessions []*model.UploadSession
+	err := r.db.WithContext(ctx).Where("username = ?", username).Find(&sessions).Error
+	return sessions, err
+}
@@ -35,3 +35,15 @@ func (r *UserRepository) FindByID(ctx context.Context, id uint) (*model.User, er
 	}
 	return &user, nil
 }
+
+func (r *UserRepository) FindAll(ctx context.Context) ([]*model.User, error) {
+	var users []*model.User
+	if err := r.db.WithContext(ctx).Order("username").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.User{}, id).Error
+}
@@ -0,0 +1,128 @@
+// Package render 将文本文件渲染为可在浏览器中直接展示的 HTML：Markdown 与语法高亮
+// 高亮使用 CSS 类而不是内联样式，样式表由 CSS 单独提供
+package render
+
+import (
+	"bytes"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	gmrenderer "github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// DefaultStyle 默认的高亮配色
+const DefaultStyle = "github"
+
+// formatter 输出带 chroma 类名的 <pre>，不内联样式 (内联样式会被净化器移除)
+var formatter = chromahtml.New(chromahtml.WithClasses(true))
+
+// markdown GFM (表格、删除线、任务列表、自动链接)，原始 HTML 不输出
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(gmrenderer.WithNodeRenderers(util.Prioritized(&codeBlockRenderer{}, 100))),
+)
+
+// policy 在 UGC 策略的基础上允许高亮用到的类名、标题锚点与任务列表的复选框
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[\w\- ]+$`)).OnElements("pre", "code", "span", "div")
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}_\-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}()
+
+// Markdown 渲染为净化后的 HTML，围栏代码块按语言高亮
+func Markdown(text string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(text), &buf); err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
+
+// IsMarkdown 按扩展名判断
+func IsMarkdown(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".md") || strings.HasSuffix(lower, ".markdown")
+}
+
+// Language 按文件名识别语言，未知或纯文本返回 nil
+func Language(name string) chroma.Lexer {
+	lexer := lexers.Match(name)
+	if lexer == nil || lexer.Config().Name == "plaintext" {
+		return nil
+	}
+	return lexer
+}
+
+// Highlight 高亮代码，lexer 为 nil 时只转义
+func Highlight(lexer chroma.Lexer, code string) (string, error) {
+	var buf bytes.Buffer
+	if err := highlight(&buf, lexer, code); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func highlight(w io.Writer, lexer chroma.Lexer, code string) error {
+	if lexer == nil {
+		_, err := io.WriteString(w, `<pre class="chroma"><code>`+html.EscapeString(code)+`</code></pre>`)
+		return err
+	}
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return err
+	}
+	return formatter.Format(w, styles.Fallback, it)
+}
+
+// CSS 返回高亮配色的样式表，未知的配色使用默认配色
+func CSS(style string) (string, error) {
+	s, ok := styles.Registry[strings.ToLower(style)]
+	if !ok {
+		s = styles.Get(DefaultStyle)
+	}
+	var buf bytes.Buffer
+	if err := formatter.WriteCSS(&buf, s); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// codeBlockRenderer 替换 goldmark 默认的围栏代码块渲染，按 info 中的语言高亮
+type codeBlockRenderer struct{}
+
+func (r *codeBlockRenderer) RegisterFuncs(reg gmrenderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.render)
+}
+
+func (r *codeBlockRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.FencedCodeBlock)
+	var code strings.Builder
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		code.Write(line.Value(source))
+	}
+	var lexer chroma.Lexer
+	if lang := string(n.Language(source)); lang != "" {
+		lexer = lexers.Get(lang)
+	}
+	return ast.WalkSkipChildren, highlight(w, lexer, code.String())
+}
@@ -0,0 +1,165 @@
+// Package secrets 用主密钥加密存储源配置中的敏感字段 (AES-256-GCM)，并解析 ${env:NAME}、${file:/path} 引用
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeySize 主密钥长度 (AES-256)
+const KeySize = 32
+
+// prefix 密文前缀，带版本号便于以后更换算法
+const prefix = "enc:v1:"
+
+// Cipher 使用同一个主密钥加解密
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New 由 32 字节主密钥创建 Cipher
+func New(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// GenerateKey 生成随机主密钥
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EncodeKey 主密钥的文本形式 (标准 base64)，用于环境变量与密钥文件
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParseKey 解析 EncodeKey 的输出
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("master key is not valid base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+// LoadKey 优先使用环境变量中的主密钥 (envKey 非空)，否则读取密钥文件
+// 密钥文件不存在时生成一个新的密钥并以 0600 权限写入
+func LoadKey(envKey, keyFile string) ([]byte, error) {
+	if envKey != "" {
+		return ParseKey(envKey)
+	}
+	data, err := os.ReadFile(keyFile)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		if err := WriteKeyFile(keyFile, key); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(string(data))
+}
+
+// WriteKeyFile 先写临时文件再改名，写入中途失败不会损坏原有的密钥文件
+func WriteKeyFile(path string, key []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(EncodeKey(key)+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// IsEncrypted 判断取值是否为 Encrypt 的输出
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, prefix)
+}
+
+// Encrypt 加密，每次使用随机 nonce，相同明文得到不同密文
+func (c *Cipher) Encrypt(plain string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的输出，未加密的取值原样返回
+func (c *Cipher) Decrypt(v string) (string, error) {
+	if !IsEncrypted(v) {
+		return v, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, prefix))
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt value: wrong master key or corrupted data")
+	}
+	return string(plain), nil
+}
+
+// IsReference 判断取值是否为 ${env:NAME} 或 ${file:/path} 引用
+func IsReference(v string) bool {
+	return strings.HasPrefix(v, "${env:") || strings.HasPrefix(v, "${file:")
+}
+
+// Resolve 解析整个取值形如 ${env:NAME} 或 ${file:/path} 的引用，其他取值原样返回
+// 文件内容末尾的换行会被去掉
+func Resolve(v string) (string, error) {
+	if !IsReference(v) || !strings.HasSuffix(v, "}") {
+		return v, nil
+	}
+	kind, ref, _ := strings.Cut(v[2:len(v)-1], ":")
+	if ref == "" {
+		return "", fmt.Errorf("empty reference %s", v)
+	}
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref)
+		}
+		return value, nil
+	default: // file
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", ref, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+}
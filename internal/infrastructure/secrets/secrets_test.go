@@ -0,0 +1,156 @@
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestCipher(t *testing.T) *Cipher {
+	t.Helper()
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	c := newTestCipher(t)
+	for _, plain := range []string{"", "secret", "含有中文的密码", strings.Repeat("x", 4096)} {
+		encrypted, err := c.Encrypt(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(encrypted) || (plain != "" && strings.Contains(encrypted, plain)) {
+			t.Fatalf("Encrypt(%q) = %q", plain, encrypted)
+		}
+		again, _ := c.Encrypt(plain)
+		if again == encrypted {
+			t.Fatalf("Encrypt(%q) is deterministic", plain)
+		}
+		got, err := c.Decrypt(encrypted)
+		if err != nil || got != plain {
+			t.Fatalf("Decrypt = %q, %v; want %q", got, err, plain)
+		}
+	}
+}
+
+func TestDecryptErrors(t *testing.T) {
+	c := newTestCipher(t)
+	encrypted, err := c.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		cipher  *Cipher
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain passthrough", c, "secret", "secret", false},
+		{"wrong key", newTestCipher(t), encrypted, "", true},
+		{"tampered", c, encrypted[:len(encrypted)-4] + "AAAA", "", true},
+		{"bad base64", c, prefix + "!!!", "", true},
+		{"too short", c, prefix + "AAAA", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("Decrypt = %q, %v; want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"encoded", EncodeKey(key), false},
+		{"trailing newline", EncodeKey(key) + "\n", false},
+		{"not base64", "not a key", true},
+		{"too short", EncodeKey(key[:16]), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKey(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, key) {
+				t.Fatalf("ParseKey returned a different key")
+			}
+		})
+	}
+	if _, err := New(key[:16]); err == nil {
+		t.Fatalf("New accepted a 16-byte key")
+	}
+}
+
+func TestLoadKeyCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "master.key")
+	key, err := LoadKey("", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file: %v, %v", info, err)
+	}
+	again, err := LoadKey("", path)
+	if err != nil || !bytes.Equal(again, key) {
+		t.Fatalf("LoadKey did not reuse the key file: %v", err)
+	}
+	// 环境变量优先
+	other, _ := GenerateKey()
+	if got, err := LoadKey(EncodeKey(other), path); err != nil || !bytes.Equal(got, other) {
+		t.Fatalf("LoadKey ignored the env key: %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("GOFILEHUB_TEST_SECRET", "from-env")
+	file := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(file, []byte("from-file\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plain", "secret", "secret", false},
+		{"env", "${env:GOFILEHUB_TEST_SECRET}", "from-env", false},
+		{"file", "${file:" + file + "}", "from-file", false},
+		{"embedded", "x${env:GOFILEHUB_TEST_SECRET}", "x${env:GOFILEHUB_TEST_SECRET}", false},
+		{"unterminated", "${env:GOFILEHUB_TEST_SECRET", "${env:GOFILEHUB_TEST_SECRET", false},
+		{"unknown kind", "${vault:secret}", "${vault:secret}", false},
+		{"env missing", "${env:GOFILEHUB_TEST_MISSING}", "", true},
+		{"file missing", "${file:" + file + ".missing}", "", true},
+		{"empty", "${env:}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("Resolve(%q) = %q, %v; want %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	pathpkg "path"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ArchiveRequest struct {
+	Paths  []string `json:"paths" form:"path"`
+	Format string   `json:"format" form:"format"` // zip (默认) 或 tar.gz
+	Name   string   `json:"name" form:"name"`     // 下载文件名 (不含扩展名)
+}
+
+// ArchiveHandler 将目录或多个选中项打包下载
+//
+//	GET  /api/v1/archive/:source_key?path=/a&path=/b&format=zip
+//	POST /api/v1/archive/:source_key  {"paths": ["/a", "/b"], "format": "tar.gz"}
+func (h *FileHandler) ArchiveHandler(c *gin.Context) {
+	sourceKey := c.Param("source_key")
+	var req ArchiveRequest
+	var err error
+	if c.Request.Method == http.MethodPost {
+		err = c.ShouldBindJSON(&req)
+	} else {
+		err = c.ShouldBindQuery(&req)
+	}
+	if err != nil || len(req.Paths) == 0 {
+		respondBadRequest(c, "at least one path is required")
+		return
+	}
+	if req.Format == "" {
+		req.Format = application.ArchiveZip
+	}
+	ctype, err := application.ArchiveContentType(req.Format)
+	if err != nil {
+		respondBadRequest(c, err.Error()+": "+req.Format)
+		return
+	}
+
+	ctx := c.Request.Context()
+	entries, err := h.service.PrepareArchive(ctx, sourceKey, req.Paths)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = archiveName(sourceKey, entries)
+	}
+	c.Header("Content-Type", ctype)
+	c.Header("Content-Disposition", contentDisposition("attachment", name+"."+req.Format))
+	c.Status(http.StatusOK)
+
+	if err := h.service.WriteArchive(ctx, sourceKey, entries, req.Format, c.Writer); err != nil {
+		if ctx.Err() == nil {
+			fmt.Printf("[Archive] %s: %v\n", sourceKey, err)
+		}
+		abortConnection(c)
+	}
+}
+
+// abortConnection 响应头已经发出后出错时直接断开连接
+// 正常结束 chunked 响应会让客户端误以为拿到了完整 (但已损坏) 的压缩包
+func abortConnection(c *gin.Context) {
+	c.Abort()
+	if conn, _, err := c.Writer.Hijack(); err == nil {
+		conn.Close()
+	}
+}
+
+// archiveName 单个选中项使用其名称，多个选中项或根目录使用存储源名称
+func archiveName(sourceKey string, entries []application.ArchiveEntry) string {
+	if len(entries) == 1 && entries[0].Path != "/" {
+		return pathpkg.Base(entries[0].Path)
+	}
+	return sourceKey
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -20,21 +21,47 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// Login 用户名密码登录，返回 JWT
+// POST /api/v1/login
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		respondBadRequest(c, "Invalid request body")
 		return
 	}
 
 	token, err := h.service.LoginJwt(c.Request.Context(), req.Username, req.Password)
+	if errors.Is(err, application.ErrUserDisabled) {
+		respondError(c, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		Fail(c, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"msg":   "Login successful",
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"token": token,
+		},
+	})
+}
+
+// MeHandler 返回当前登录用户
+// GET /api/v1/users/me
+func (h *AuthHandler) MeHandler(c *gin.Context) {
+	user, err := h.service.GetUser(c.Request.Context(), currentUser(c))
+	if err != nil {
+		Fail(c, http.StatusUnauthorized, CodeUnauthorized, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"user": user,
+		},
 	})
 }
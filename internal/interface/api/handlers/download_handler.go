@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	pathpkg "path"
+	"strconv"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sniffLen http.DetectContentType 最多读取的字节数
+const sniffLen = 512
+
+// inlineTypes 允许在浏览器中直接预览的类型
+// HTML、SVG、XML 等可以执行脚本的类型始终作为附件下载
+var inlineTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/bmp",
+	"audio/", "video/",
+	"application/pdf",
+	"text/plain",
+}
+
+// DownloadHandler 下载文件内容
+//
+//	GET/HEAD /api/v1/raw/:source_key/*path   ?inline=1 在浏览器中预览
+//	GET      /api/v1/files/:source_key/*path?download=1
+//
+// 底层文件可 Seek 时支持 Range 与 If-Modified-Since
+func (h *FileHandler) DownloadHandler(c *gin.Context) {
+	serveFile(c, h.service, c.Param("source_key"), c.Param("path"))
+}
+
+// serveFile 以当前请求上下文中的用户身份读取文件并写入响应 (分享链接复用)
+func serveFile(c *gin.Context, service *application.FileService, sourceKey, path string) {
+	ctx := c.Request.Context()
+
+	info, err := service.Stat(ctx, sourceKey, path)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if info.IsDir {
+		respondBadRequest(c, "cannot download a directory")
+		return
+	}
+
+	stream, err := service.GetFileStream(ctx, sourceKey, path)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer stream.Close()
+	serveStream(c, pathpkg.Base(path), info, stream)
+}
+
+// serveStream 将已打开的文件内容写入响应，stream 由调用方关闭 (历史版本下载复用)
+func serveStream(c *gin.Context, name string, info vfs.FileInfo, stream io.ReadCloser) {
+	ctx := c.Request.Context()
+	inline := c.Query("inline") == "1" || c.Query("inline") == "true"
+
+	// 读取文件头判断类型，可 Seek 时读完后回到开头，否则用 bufio 预读
+	var head []byte
+	seeker, seekable := stream.(io.ReadSeeker)
+	var body io.Reader = stream
+	if seekable {
+		buf := make([]byte, sniffLen)
+		n, _ := io.ReadFull(seeker, buf)
+		head = buf[:n]
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			respondError(c, err)
+			return
+		}
+	} else {
+		br := bufio.NewReaderSize(stream, sniffLen)
+		head, _ = br.Peek(sniffLen)
+		body = br
+	}
+	ctype := detectContentType(name, head)
+
+	if inline && !isInlineSafe(ctype) {
+		inline = false
+	}
+	disposition := "attachment"
+	if inline {
+		disposition = "inline"
+	}
+	header := c.Writer.Header()
+	header.Set("Content-Type", ctype)
+	header.Set("Content-Disposition", contentDisposition(disposition, name))
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	if seekable {
+		// ServeContent 负责 Content-Length、Range、Last-Modified 和 HEAD
+		http.ServeContent(c.Writer, c.Request, name, info.ModTime, seeker)
+		return
+	}
+
+	header.Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	if !info.ModTime.IsZero() {
+		header.Set("Last-Modified", info.ModTime.UTC().Format(http.TimeFormat))
+	}
+	c.Status(http.StatusOK)
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	// 客户端断开时停止读取底层存储
+	vfs.CopyContext(ctx, c.Writer, body)
+}
+
+// detectContentType 以内容嗅探为准，嗅探结果过于笼统时参考扩展名
+// (例如 CSS/JS/Markdown 都会被嗅探成 text/plain)
+func detectContentType(name string, head []byte) string {
+	ctype := http.DetectContentType(head)
+	if ctype == "application/octet-stream" || strings.HasPrefix(ctype, "text/plain") {
+		if byExt := mime.TypeByExtension(pathpkg.Ext(name)); byExt != "" {
+			return byExt
+		}
+	}
+	return ctype
+}
+
+func isInlineSafe(ctype string) bool {
+	mediaType, _, _ := mime.ParseMediaType(ctype)
+	for _, t := range inlineTypes {
+		if mediaType == t || (strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// contentDisposition 按 RFC 6266 / RFC 5987 生成文件名
+// filename 为 ASCII 兜底 (老客户端)，filename* 携带 UTF-8 原名 (中文文件名)
+func contentDisposition(disposition, name string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' || r == '%' {
+			return '_'
+		}
+		return r
+	}, name)
+	v := disposition + `; filename="` + fallback + `"`
+	if fallback != name {
+		v += "; filename*=UTF-8''" + encodeRFC5987(name)
+	}
+	return v
+}
+
+// encodeRFC5987 只保留 attr-char，其余字节按 %XX 编码
+func encodeRFC5987(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 错误响应中的业务码，前三位与 HTTP 状态码一致，便于前端按类别处理
+const (
+	CodeInvalidRequest   = 40000
+	CodeUnauthorized     = 40100
+	CodePermissionDenied = 40300
+	CodeReadOnly         = 40301
+	CodeNotFound         = 40400
+	CodeAlreadyExists    = 40900
+	CodeNotEmpty         = 40901
+	CodeConflict         = 40902
+	CodeGone             = 41000
+	CodeFileTooLarge     = 41300
+	CodeUnsupportedMedia = 41500
+	CodeInternal         = 50000
+	CodeUnavailable      = 50300
+	CodeTimeout          = 50400
+	CodeQuotaExceeded    = 50700
+)
+
+// apiError 一类错误对应的状态码与业务码
+type apiError struct {
+	status int
+	code   int
+}
+
+// errorTable REST 与 WebDAV 共用的错误映射，按顺序匹配
+var errorTable = []struct {
+	target error
+	apiError
+}{
+	// 超时先于其他分类判断：超时错误可能同时包裹了网络错误
+	{vfs.ErrTimeout, apiError{http.StatusGatewayTimeout, CodeTimeout}},
+	{vfs.ErrNotEmpty, apiError{http.StatusConflict, CodeNotEmpty}},
+	{vfs.ErrReadOnly, apiError{http.StatusForbidden, CodeReadOnly}},
+	{vfs.ErrQuotaExceeded, apiError{http.StatusInsufficientStorage, CodeQuotaExceeded}},
+	{vfs.ErrUnavailable, apiError{http.StatusServiceUnavailable, CodeUnavailable}},
+	{vfs.ErrNotFound, apiError{http.StatusNotFound, CodeNotFound}},
+	{vfs.ErrPermissionDenied, apiError{http.StatusForbidden, CodePermissionDenied}},
+	{vfs.ErrAlreadyExists, apiError{http.StatusConflict, CodeAlreadyExists}},
+
+	{application.ErrFileTooLarge, apiError{http.StatusRequestEntityTooLarge, CodeFileTooLarge}},
+	{application.ErrThumbUnsupported, apiError{http.StatusUnsupportedMediaType, CodeUnsupportedMedia}},
+	{application.ErrThumbFormat, apiError{http.StatusBadRequest, CodeInvalidRequest}},
+	{application.ErrNotText, apiError{http.StatusUnsupportedMediaType, CodeUnsupportedMedia}},
+	{application.ErrInvalidOperation, apiError{http.StatusBadRequest, CodeInvalidRequest}},
+	{application.ErrInvalidQuery, apiError{http.StatusBadRequest, CodeInvalidRequest}},
+	{application.ErrArchiveFormat, apiError{http.StatusBadRequest, CodeInvalidRequest}},
+	{application.ErrInvalidMetadata, apiError{http.StatusBadRequest, CodeInvalidRequest}},
+	{application.ErrChecksumAlgorithm, apiError{http.StatusBadRequest, CodeInvalidRequest}},
+	{application.ErrShareExpired, apiError{http.StatusGone, CodeGone}},
+	{application.ErrShareExhausted, apiError{http.StatusGone, CodeGone}},
+	{application.ErrSharePassword, apiError{http.StatusUnauthorized, CodeUnauthorized}},
+	{application.ErrShareMode, apiError{http.StatusForbidden, CodePermissionDenied}},
+	{application.ErrInvalidShare, apiError{http.StatusBadRequest, CodeInvalidRequest}},
+	{application.ErrFileRequestExpired, apiError{http.StatusGone, CodeGone}},
+	{application.ErrFileTypeNotAllowed, apiError{http.StatusUnsupportedMediaType, CodeUnsupportedMedia}},
+	{application.ErrInvalidFileRequest, apiError{http.StatusBadRequest, CodeInvalidRequest}},
+	{application.ErrJobNotFound, apiError{http.StatusNotFound, CodeNotFound}},
+	{application.ErrUploadNotFound, apiError{http.StatusNotFound, CodeNotFound}},
+	{application.ErrOffsetMismatch, apiError{http.StatusConflict, CodeConflict}},
+	{application.ErrUploadLocked, apiError{http.StatusConflict, CodeConflict}},
+	{application.ErrUserDisabled, apiError{http.StatusForbidden, CodePermissionDenied}},
+	{application.ErrWrongPassword, apiError{http.StatusForbidden, CodePermissionDenied}},
+}
+
+// classifyError 返回错误对应的状态码与业务码
+// 未经驱动归类的原始 OS 错误也会在这里按 vfs.KindOf 兜底归类
+func classifyError(err error) apiError {
+	for _, e := range errorTable {
+		if errors.Is(err, e.target) {
+			return e.apiError
+		}
+	}
+	if kind := vfs.KindOf(err); kind != nil {
+		return classifyError(kind)
+	}
+	return apiError{http.StatusInternalServerError, CodeInternal}
+}
+
+// errorStatus 根据错误类型选择 HTTP 状态码
+func errorStatus(err error) int {
+	return classifyError(err).status
+}
+
+// respondError 统一的错误响应: {"code": 40400, "msg": "not found", "error": "stat /a.txt: not found"}
+// 500 错误不向客户端暴露内部细节，只记录日志
+func respondError(c *gin.Context, err error) {
+	e, msg := describeError(c, err)
+	Fail(c, e.status, e.code, msg)
+}
+
+// describeError 返回错误的分类与可以返回给客户端的描述
+func describeError(c *gin.Context, err error) (apiError, string) {
+	e := classifyError(err)
+	if e.status == http.StatusInternalServerError {
+		fmt.Printf("[API] %s %s: %v\n", c.Request.Method, c.Request.URL.Path, err)
+		return e, "internal server error"
+	}
+	return e, err.Error()
+}
+
+// respondBadRequest 请求参数不合法
+func respondBadRequest(c *gin.Context, msg string) {
+	Fail(c, http.StatusBadRequest, CodeInvalidRequest, msg)
+}
+
+// Fail 以统一格式终止请求，供中间件与参数校验复用
+func Fail(c *gin.Context, status, code int, msg string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"code":  code,
+		"msg":   http.StatusText(status),
+		"error": msg,
+	})
+}
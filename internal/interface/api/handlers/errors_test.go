@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+func TestClassifyError(t *testing.T) {
+	// 超时错误同时包裹了网络错误，应按超时处理
+	timeout := &vfs.TimeoutError{Op: "stat", Path: "/a", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}
+
+	tests := []struct {
+		name string
+		err  error
+		want apiError
+	}{
+		{"not found", vfs.NewError("stat", "/a", vfs.ErrNotFound), apiError{http.StatusNotFound, CodeNotFound}},
+		{"permission", vfs.NewError("open", "/a", vfs.ErrPermissionDenied), apiError{http.StatusForbidden, CodePermissionDenied}},
+		{"read-only", vfs.NewError("open", "/a", vfs.ErrReadOnly), apiError{http.StatusForbidden, CodeReadOnly}},
+		{"exists", vfs.NewError("mkdir", "/a", vfs.ErrAlreadyExists), apiError{http.StatusConflict, CodeAlreadyExists}},
+		{"not empty", vfs.NewError("rmdir", "/a", vfs.ErrNotEmpty), apiError{http.StatusConflict, CodeNotEmpty}},
+		{"quota", vfs.NewError("write", "/a", vfs.ErrQuotaExceeded), apiError{http.StatusInsufficientStorage, CodeQuotaExceeded}},
+		{"unavailable", vfs.NewError("list", "/", vfs.ErrUnavailable), apiError{http.StatusServiceUnavailable, CodeUnavailable}},
+		{"timeout first", timeout, apiError{http.StatusGatewayTimeout, CodeTimeout}},
+		{"wrapped timeout", fmt.Errorf("%w: test", vfs.ErrTimeout), apiError{http.StatusGatewayTimeout, CodeTimeout}},
+		{"raw os error", &fs.PathError{Op: "open", Path: "/a", Err: syscall.ENOENT}, apiError{http.StatusNotFound, CodeNotFound}},
+		{"raw enospc", &fs.PathError{Op: "write", Path: "/a", Err: syscall.ENOSPC}, apiError{http.StatusInsufficientStorage, CodeQuotaExceeded}},
+		{"source not found", application.ErrSourceNotFound, apiError{http.StatusNotFound, CodeNotFound}},
+		{"invalid operation", fmt.Errorf("%w: bad name", application.ErrInvalidOperation), apiError{http.StatusBadRequest, CodeInvalidRequest}},
+		{"too large", application.ErrFileTooLarge, apiError{http.StatusRequestEntityTooLarge, CodeFileTooLarge}},
+		{"share expired", application.ErrShareExpired, apiError{http.StatusGone, CodeGone}},
+		{"share password", application.ErrSharePassword, apiError{http.StatusUnauthorized, CodeUnauthorized}},
+		{"offset mismatch", application.ErrOffsetMismatch, apiError{http.StatusConflict, CodeConflict}},
+		{"user disabled", application.ErrUserDisabled, apiError{http.StatusForbidden, CodePermissionDenied}},
+		{"canceled", context.Canceled, apiError{http.StatusInternalServerError, CodeInternal}},
+		{"unknown", errors.New("boom"), apiError{http.StatusInternalServerError, CodeInternal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Fatalf("classifyError(%v) = %+v, want %+v", tt.err, got, tt.want)
+			}
+			if got := errorStatus(tt.err); got != tt.want.status {
+				t.Fatalf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want.status)
+			}
+		})
+	}
+}
+
+// 业务码的前三位与 HTTP 状态码一致
+func TestErrorTableCodes(t *testing.T) {
+	for _, e := range errorTable {
+		if e.code/100 != e.status {
+			t.Errorf("%v: code %d does not match status %d", e.target, e.code, e.status)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"io/fs"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/wentf9/MyGoFileHub/internal/application"
 
@@ -10,41 +13,45 @@ import (
 
 type FileHandler struct {
 	service *application.FileService
+	media   *application.MediaService
 }
 
-func NewFileHandler(s *application.FileService) *FileHandler {
-	return &FileHandler{service: s}
+func NewFileHandler(s *application.FileService, media *application.MediaService) *FileHandler {
+	return &FileHandler{service: s, media: media}
 }
 
+// GetHandler 列出目录或返回文件信息，?download=1 时下载文件内容
+// ?media=1 时附带图片 EXIF、音频标签与视频容器信息 (Media 字段)
+// GET /api/v1/files/:source_key/*path
 func (h *FileHandler) GetHandler(c *gin.Context) {
 	sourceKey := c.Param("source_key")
 	path := c.Param("path")
 	if path == "" {
 		path = "/"
 	}
-	if sourceKey == "" {
-		sources, err := h.service.GetAllSource(c.Request.Context())
+	pathStat, err := h.service.Stat(c.Request.Context(), sourceKey, path)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if pathStat.IsDir {
+		h.List(c, sourceKey, path)
+	} else if c.Query("download") != "" {
+		h.DownloadHandler(c)
+	} else if wantMedia(c) {
+		md, err := h.media.Metadata(c.Request.Context(), sourceKey, path, pathStat)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			respondError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{
 			"code": 0,
 			"msg":  "success",
 			"data": gin.H{
-				"files": sources,
+				"files": application.MediaFileInfo{FileInfo: pathStat, Media: md},
 			},
 		})
-		return
-	}
-	pathStat, err := h.service.Stat(c.Request.Context(), sourceKey, path)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	if pathStat.IsDir {
-		h.List(c, sourceKey, path)
 	} else {
 		// 返回标准化 JSON
 		c.JSON(http.StatusOK, gin.H{
@@ -57,16 +64,22 @@ func (h *FileHandler) GetHandler(c *gin.Context) {
 	}
 }
 
+func wantMedia(c *gin.Context) bool {
+	return c.Query("media") == "1" || c.Query("media") == "true"
+}
+
+// DeleteHandler 删除文件或目录
+// DELETE /api/v1/files/:source_key/*path
 func (h *FileHandler) DeleteHandler(c *gin.Context) {
 	sourceKey := c.Param("source_key")
 	path := c.Param("path")
 	if sourceKey == "" || path == "" || path == "/" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "不可直接删除根路径"})
+		respondBadRequest(c, "不可直接删除根路径")
 		return
 	}
 	err := h.service.Delete(c.Request.Context(), sourceKey, path)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
@@ -75,11 +88,80 @@ func (h *FileHandler) DeleteHandler(c *gin.Context) {
 	})
 }
 
+type SetAttrRequest struct {
+	ModTime *time.Time `json:"mtime"` // RFC 3339
+	Mode    *string    `json:"mode"`  // 八进制字符串，如 "0644"
+	Size    *int64     `json:"size"`  // 截断到指定大小
+}
+
+// AttrHandler 修改文件属性 (修改时间、权限、大小)，未提供的字段保持不变
+// PATCH /api/v1/attr/:source_key/*path
+func (h *FileHandler) AttrHandler(c *gin.Context) {
+	sourceKey := c.Param("source_key")
+	path := c.Param("path")
+	var req SetAttrRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	if req.ModTime == nil && req.Mode == nil && req.Size == nil {
+		respondBadRequest(c, "nothing to update")
+		return
+	}
+	var mode fs.FileMode
+	if req.Mode != nil {
+		m, err := strconv.ParseUint(*req.Mode, 8, 32)
+		if err != nil || m > 0o7777 {
+			respondBadRequest(c, "invalid mode: "+*req.Mode)
+			return
+		}
+		mode = fs.FileMode(m)
+	}
+	if req.Size != nil && *req.Size < 0 {
+		respondBadRequest(c, "size must not be negative")
+		return
+	}
+
+	ctx := c.Request.Context()
+	// 先截断再设置时间，否则截断会刷新修改时间
+	if req.Size != nil {
+		if err := h.service.Truncate(ctx, sourceKey, path, *req.Size); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	if req.Mode != nil {
+		if err := h.service.SetMode(ctx, sourceKey, path, mode); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	if req.ModTime != nil {
+		if err := h.service.SetModTime(ctx, sourceKey, path, *req.ModTime); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	info, err := h.service.Stat(ctx, sourceKey, path)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"files": info,
+		},
+	})
+}
+
 // List 处理 /files/list 请求
 // Query Param: source_key, path
 func (h *FileHandler) List(c *gin.Context, sourceKey, path string) {
 	if sourceKey == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "source_key is required"})
+		respondBadRequest(c, "source_key is required")
 		return
 	}
 
@@ -87,10 +169,20 @@ func (h *FileHandler) List(c *gin.Context, sourceKey, path string) {
 	files, err := h.service.ListFiles(c.Request.Context(), sourceKey, path)
 	if err != nil {
 		// 实际项目中应根据 error 类型返回 403, 404 或 500
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
+	if wantMedia(c) {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"msg":  "success",
+			"data": gin.H{
+				"files": h.media.Annotate(c.Request.Context(), sourceKey, path, files),
+			},
+		})
+		return
+	}
 	// 返回标准化 JSON
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
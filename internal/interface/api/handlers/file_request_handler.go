@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFormField 上传表单中普通字段 (姓名、邮箱) 的最大长度
+const maxFormField = 1024
+
+// FileRequestHandler 文件收集请求的管理 (登录用户) 与公开上传 (/r/:token，无需登录)
+type FileRequestHandler struct {
+	service *application.FileRequestService
+}
+
+func NewFileRequestHandler(s *application.FileRequestService) *FileRequestHandler {
+	return &FileRequestHandler{service: s}
+}
+
+type CreateFileRequestRequest struct {
+	SourceKey    string     `json:"source_key" binding:"required"`
+	Path         string     `json:"path" binding:"required"`  // 在该目录下建立专属子目录
+	Title        string     `json:"title" binding:"required"` // 同时作为子目录名
+	Description  string     `json:"description"`
+	MaxFileSize  int64      `json:"max_file_size"` // 单个文件上限 (字节)，0 表示只受存储源限制
+	AllowedTypes []string   `json:"allowed_types"` // 允许的扩展名，为空表示不限制
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+// CreateHandler 创建文件收集请求
+// POST /api/v1/file-requests
+func (h *FileRequestHandler) CreateHandler(c *gin.Context) {
+	var req CreateFileRequestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	fr, err := h.service.Create(c.Request.Context(), currentUser(c), application.FileRequestOptions{
+		SourceKey:    req.SourceKey,
+		Path:         req.Path,
+		Title:        req.Title,
+		Description:  req.Description,
+		MaxFileSize:  req.MaxFileSize,
+		AllowedTypes: req.AllowedTypes,
+		ExpiresAt:    req.ExpiresAt,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"request": fr,
+		},
+	})
+}
+
+// ListHandler 列出自己创建的请求，管理员 ?all=1 时列出全部
+// GET /api/v1/file-requests
+func (h *FileRequestHandler) ListHandler(c *gin.Context) {
+	all := c.Query("all") == "1" || c.Query("all") == "true"
+	list, err := h.service.List(c.Request.Context(), currentUser(c), all)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"requests": list,
+		},
+	})
+}
+
+// CloseHandler 关闭请求，已收到的文件保留
+// DELETE /api/v1/file-requests/:id
+func (h *FileRequestHandler) CloseHandler(c *gin.Context) {
+	id, ok := fileRequestID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Close(c.Request.Context(), currentUser(c), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// UploadsHandler 请求收到的文件及上传者信息
+// GET /api/v1/file-requests/:id/uploads
+func (h *FileRequestHandler) UploadsHandler(c *gin.Context) {
+	id, ok := fileRequestID(c)
+	if !ok {
+		return
+	}
+	uploads, err := h.service.Uploads(c.Request.Context(), currentUser(c), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"uploads": uploads,
+		},
+	})
+}
+
+func fileRequestID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondBadRequest(c, "invalid file request id")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// --- 公开上传 ---
+
+// InfoHandler 请求的标题、说明与限制，不包含目录内容
+// GET /api/v1/r/:token
+func (h *FileRequestHandler) InfoHandler(c *gin.Context) {
+	fr, err := h.service.Open(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"request": h.service.PublicInfo(fr),
+		},
+	})
+}
+
+// ReceivedFile 返回给上传者的结果，不包含实际写入的路径 (重命名会暴露目录中已有的文件)
+type ReceivedFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Status string `json:"status"` // received, failed
+	Error  string `json:"error,omitempty"`
+}
+
+// UploadHandler 上传文件 (multipart)
+// 可选的 uploader_name 与 uploader_email 表单字段记录上传者，放在文件之前或之后均可
+// POST /api/v1/r/:token
+func (h *FileRequestHandler) UploadHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	fr, err := h.service.Open(ctx, c.Param("token"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		respondBadRequest(c, "invalid multipart body")
+		return
+	}
+	// 以创建者的身份经过 SecureDriver
+	ctx = context.WithValue(ctx, "username", fr.Username)
+
+	uploader := application.Uploader{IP: c.ClientIP()}
+	var results []application.UploadResult
+	var received []ReceivedFile
+	var firstErr error
+	succeeded := 0
+	// 中途出错 (客户端断开、字段不合法) 时，已写入的文件同样需要记录并通知创建者
+	defer func() {
+		h.service.Complete(context.WithoutCancel(ctx), fr, uploader, results)
+	}()
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondBadRequest(c, "invalid multipart body: "+err.Error())
+			return
+		}
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormField))
+			part.Close()
+			if err != nil {
+				respondBadRequest(c, "invalid multipart body: "+err.Error())
+				return
+			}
+			next := uploader
+			switch part.FormName() {
+			case "uploader_name":
+				next.Name = string(value)
+			case "uploader_email":
+				next.Email = string(value)
+			default:
+				continue
+			}
+			if err := h.service.ValidateUploader(&next); err != nil {
+				respondError(c, err)
+				return
+			}
+			uploader = next
+			continue
+		}
+
+		result, err := h.service.Upload(ctx, fr, part.FileName(), part, -1)
+		part.Close()
+		file := ReceivedFile{Name: result.Name, Size: result.Size, Status: "received"}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			result.Status = "failed"
+			file.Status, file.Error = "failed", err.Error()
+		} else {
+			succeeded++
+		}
+		results = append(results, result)
+		received = append(received, file)
+	}
+	if len(received) == 0 {
+		respondBadRequest(c, "no file in request")
+		return
+	}
+	respondUploads(c, received, succeeded, firstErr)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+// batchSyncLimit 超过该数量的批量操作自动转为后台任务
+const batchSyncLimit = 20
+
+type BatchRequest struct {
+	Operations []application.FsOperation `json:"operations"`
+	Async      bool                      `json:"async"` // 强制后台执行
+}
+
+// MkdirHandler POST /api/v1/fs/mkdir {"source_key", "path"}
+func (h *FileHandler) MkdirHandler(c *gin.Context) {
+	h.fsOperation(c, application.OpMkdir)
+}
+
+// RenameHandler POST /api/v1/fs/rename {"source_key", "path", "dest": "新名称", "overwrite"}
+func (h *FileHandler) RenameHandler(c *gin.Context) {
+	h.fsOperation(c, application.OpRename)
+}
+
+// MoveHandler POST /api/v1/fs/move {"source_key", "path", "dest", "dest_source", "overwrite"}
+func (h *FileHandler) MoveHandler(c *gin.Context) {
+	h.fsOperation(c, application.OpMove)
+}
+
+// CopyHandler POST /api/v1/fs/copy {"source_key", "path", "dest", "dest_source", "overwrite"}
+func (h *FileHandler) CopyHandler(c *gin.Context) {
+	h.fsOperation(c, application.OpCopy)
+}
+
+func (h *FileHandler) fsOperation(c *gin.Context, op string) {
+	var req application.FsOperation
+	if err := c.ShouldBindJSON(&req); err != nil || req.SourceKey == "" || req.Path == "" {
+		respondBadRequest(c, "source_key and path are required")
+		return
+	}
+	req.Op = op
+	if err := h.service.Execute(c.Request.Context(), req); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// BatchHandler POST /api/v1/fs/batch
+// 少量操作同步执行并直接返回每项结果；数量较多或 async=true 时返回 202 与任务 ID，
+// 通过 GET /api/v1/fs/jobs/:id 查询进度
+func (h *FileHandler) BatchHandler(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Operations) == 0 {
+		respondBadRequest(c, "operations are required")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.Async || len(req.Operations) > batchSyncLimit {
+		job, err := h.service.StartBatchJob(ctx, currentUser(c), req.Operations)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.Header("Location", "/api/v1/fs/jobs/"+job.ID)
+		c.JSON(http.StatusAccepted, gin.H{
+			"code": 0,
+			"msg":  "accepted",
+			"data": gin.H{
+				"job": job,
+			},
+		})
+		return
+	}
+
+	results := h.service.ExecuteBatch(ctx, req.Operations, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"results": results,
+		},
+	})
+}
+
+// JobHandler GET /api/v1/fs/jobs/:id 查询后台任务
+func (h *FileHandler) JobHandler(c *gin.Context) {
+	job, err := h.service.GetJob(currentUser(c), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"job": job,
+		},
+	})
+}
+
+// CancelJobHandler DELETE /api/v1/fs/jobs/:id 取消后台任务
+func (h *FileHandler) CancelJobHandler(c *gin.Context) {
+	job, err := h.service.CancelJob(currentUser(c), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"job": job,
+		},
+	})
+}
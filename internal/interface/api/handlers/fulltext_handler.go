@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FullTextHandler struct {
+	service *application.FullTextService
+}
+
+func NewFullTextHandler(s *application.FullTextService) *FullTextHandler {
+	return &FullTextHandler{service: s}
+}
+
+// SearchHandler 按文件内容搜索，结果按相关度排序
+// GET /api/v1/search/content?q=季度报告&source=nas&limit=20&offset=0
+func (h *FullTextHandler) SearchHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		respondBadRequest(c, "limit must be a positive integer")
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		respondBadRequest(c, "offset must be a non-negative integer")
+		return
+	}
+	results, total, err := h.service.Search(c.Request.Context(), c.Query("q"), c.QueryArray("source"), limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"results": results,
+			"total":   total,
+		},
+	})
+}
+
+// ReindexHandler 在后台重新扫描存储源的全文索引
+// POST /api/v1/admin/sources/:source_key/fulltext
+func (h *FullTextHandler) ReindexHandler(c *gin.Context) {
+	if err := h.service.Reindex(c.Request.Context(), c.Param("source_key")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"code": 0,
+		"msg":  "accepted",
+	})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetadataHandler struct {
+	service *application.MetadataService
+}
+
+func NewMetadataHandler(s *application.MetadataService) *MetadataHandler {
+	return &MetadataHandler{service: s}
+}
+
+type SetMetadataRequest struct {
+	Metadata map[string]string `json:"metadata" binding:"required"`
+}
+
+// maxMetadataKeyLen xattr 名称长度上限为 255，扣除前缀后留出余量
+const maxMetadataKeyLen = 200
+
+// GetHandler 获取文件的自定义元数据
+// GET /api/v1/meta/:source_key/*path
+func (h *MetadataHandler) GetHandler(c *gin.Context) {
+	meta, err := h.service.GetMetadata(c.Request.Context(), c.Param("source_key"), c.Param("path"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"metadata": meta,
+		},
+	})
+}
+
+// SetHandler 批量设置元数据，已存在的键会被覆盖
+// PUT /api/v1/meta/:source_key/*path  Body: {"metadata": {"project": "P-001", "review": "approved"}}
+func (h *MetadataHandler) SetHandler(c *gin.Context) {
+	var req SetMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	for key := range req.Metadata {
+		if !validMetadataKey(key) {
+			respondBadRequest(c, "invalid metadata key: "+key)
+			return
+		}
+	}
+	err := h.service.SetMetadata(c.Request.Context(), c.Param("source_key"), c.Param("path"), req.Metadata)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// DeleteHandler 删除指定的元数据键
+// DELETE /api/v1/meta/:source_key/*path?key=project&key=review
+func (h *MetadataHandler) DeleteHandler(c *gin.Context) {
+	keys := c.QueryArray("key")
+	if len(keys) == 0 {
+		respondBadRequest(c, "key is required")
+		return
+	}
+	err := h.service.DeleteMetadata(c.Request.Context(), c.Param("source_key"), c.Param("path"), keys)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+func validMetadataKey(key string) bool {
+	return key != "" && len(key) <= maxMetadataKeyLen && !strings.ContainsRune(key, 0)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationHandler 当前用户的站内通知
+type NotificationHandler struct {
+	service *application.NotificationService
+}
+
+func NewNotificationHandler(s *application.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: s}
+}
+
+// ListHandler 最近的通知，?unread=1 只返回未读
+// GET /api/v1/notifications
+func (h *NotificationHandler) ListHandler(c *gin.Context) {
+	unread := c.Query("unread") == "1" || c.Query("unread") == "true"
+	list, err := h.service.List(c.Request.Context(), currentUser(c), unread)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"notifications": list,
+		},
+	})
+}
+
+type MarkReadRequest struct {
+	IDs []uint `json:"ids"` // 为空表示全部
+}
+
+// MarkReadHandler 标记为已读
+// POST /api/v1/notifications/read
+func (h *NotificationHandler) MarkReadHandler(c *gin.Context) {
+	var req MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	if err := h.service.MarkRead(c.Request.Context(), currentUser(c), req.IDs); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/render"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewHandler 预览文本文件：编码转换、Markdown 渲染与语法高亮
+// GET /api/v1/preview/:source_key/*path?max_bytes=262144
+func (h *FileHandler) PreviewHandler(c *gin.Context) {
+	maxBytes, err := strconv.ParseInt(c.DefaultQuery("max_bytes", "0"), 10, 64)
+	if err != nil || maxBytes < 0 {
+		respondBadRequest(c, "max_bytes must be a positive integer")
+		return
+	}
+	preview, err := h.service.Preview(c.Request.Context(), c.Param("source_key"), c.Param("path"), maxBytes)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": preview,
+	})
+}
+
+// HighlightCSSHandler 返回语法高亮的样式表
+// GET /api/v1/highlight.css?style=github
+func HighlightCSSHandler(c *gin.Context) {
+	css, err := render.CSS(c.DefaultQuery("style", render.DefaultStyle))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/css; charset=utf-8", []byte(css))
+}
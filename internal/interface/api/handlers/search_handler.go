@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchEvent 搜索结果流中的一行 (NDJSON)
+//
+//	{"type": "match", "data": {"source": "nas", "path": "/a/b.txt", ...}}
+//	{"type": "done",  "data": {"count": 12, "truncated": false, "failed": [...]}}
+type searchEvent struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// SearchHandler 按文件名、大小、修改时间、类型搜索
+//
+//	GET /api/v1/search?name=*.pdf&source=nas&path=/docs&min_size=1024&after=2024-01-01T00:00:00Z&type=file&index=1
+//
+// 结果以 NDJSON 逐行推送，客户端断开后停止遍历；参数错误、存储源不存在等在推送前按统一格式返回
+func (h *FileHandler) SearchHandler(c *gin.Context) {
+	q, ok := parseSearchQuery(c)
+	if !ok {
+		return
+	}
+
+	started := false
+	encoder := json.NewEncoder(c.Writer)
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+		c.Header("Cache-Control", "no-store")
+		// 禁止反向代理缓冲，保证结果逐条到达
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+	}
+	write := func(event searchEvent) error {
+		start()
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	summary, err := h.service.Search(c.Request.Context(), q, func(r application.SearchResult) error {
+		return write(searchEvent{Type: "match", Data: r})
+	})
+	if err != nil {
+		// 客户端已断开，无需响应
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		if !started {
+			respondError(c, err)
+		}
+		return
+	}
+	_ = write(searchEvent{Type: "done", Data: summary})
+}
+
+func parseSearchQuery(c *gin.Context) (application.SearchQuery, bool) {
+	q := application.SearchQuery{
+		Sources:  c.QueryArray("source"),
+		Path:     c.DefaultQuery("path", "/"),
+		Name:     c.Query("name"),
+		Regex:    c.Query("regex"),
+		Type:     c.Query("type"),
+		UseIndex: c.Query("index") == "1" || c.Query("index") == "true",
+	}
+	ints := []struct {
+		name string
+		dst  *int64
+	}{{"min_size", &q.MinSize}, {"max_size", &q.MaxSize}}
+	for _, p := range ints {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				respondBadRequest(c, p.name+" must be a non-negative integer")
+				return q, false
+			}
+			*p.dst = n
+		}
+	}
+	times := []struct {
+		name string
+		dst  *time.Time
+	}{{"after", &q.After}, {"before", &q.Before}}
+	for _, p := range times {
+		if v := c.Query(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				respondBadRequest(c, p.name+" must be an RFC 3339 date-time")
+				return q, false
+			}
+			*p.dst = t
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			respondBadRequest(c, "limit must be a positive integer")
+			return q, false
+		}
+		q.Limit = n
+	}
+	return q, true
+}
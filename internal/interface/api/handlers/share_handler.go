@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	pathpkg "path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sharePathKey 上传时记录到访问日志的文件名
+const sharePathKey = "share_path"
+
+// ShareHandler 分享链接的管理 (登录用户) 与公开访问 (/s/:token，无需登录)
+type ShareHandler struct {
+	service *application.ShareService
+	files   *application.FileService
+}
+
+func NewShareHandler(s *application.ShareService, files *application.FileService) *ShareHandler {
+	return &ShareHandler{service: s, files: files}
+}
+
+type CreateShareRequest struct {
+	SourceKey    string     `json:"source_key" binding:"required"`
+	Path         string     `json:"path" binding:"required"`
+	Mode         string     `json:"mode"`       // read (默认) 或 upload
+	Password     string     `json:"password"`   // 为空表示不设密码
+	ExpiresAt    *time.Time `json:"expires_at"` // RFC 3339，为空表示永不过期
+	MaxDownloads int        `json:"max_downloads"`
+}
+
+// CreateHandler 创建分享链接
+// POST /api/v1/shares
+func (h *ShareHandler) CreateHandler(c *gin.Context) {
+	var req CreateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	share, err := h.service.Create(c.Request.Context(), currentUser(c), application.ShareOptions{
+		SourceKey:    req.SourceKey,
+		Path:         req.Path,
+		Mode:         req.Mode,
+		Password:     req.Password,
+		ExpiresAt:    req.ExpiresAt,
+		MaxDownloads: req.MaxDownloads,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"share": share,
+		},
+	})
+}
+
+// ListHandler 列出自己创建的分享，管理员 ?all=1 时列出全部
+// GET /api/v1/shares
+func (h *ShareHandler) ListHandler(c *gin.Context) {
+	all := c.Query("all") == "1" || c.Query("all") == "true"
+	shares, err := h.service.List(c.Request.Context(), currentUser(c), all)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"shares": shares,
+		},
+	})
+}
+
+// RevokeHandler 撤销分享，链接立即失效
+// DELETE /api/v1/shares/:id
+func (h *ShareHandler) RevokeHandler(c *gin.Context) {
+	id, ok := shareID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Revoke(c.Request.Context(), currentUser(c), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// AccessLogHandler 分享的访问日志
+// GET /api/v1/shares/:id/access
+func (h *ShareHandler) AccessLogHandler(c *gin.Context) {
+	id, ok := shareID(c)
+	if !ok {
+		return
+	}
+	logs, err := h.service.AccessLog(c.Request.Context(), currentUser(c), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"access": logs,
+		},
+	})
+}
+
+func shareID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondBadRequest(c, "invalid share id")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// --- 公开访问 ---
+
+// InfoHandler 分享的基本信息，设有密码时同样可以访问
+// GET /api/v1/s/:token
+func (h *ShareHandler) InfoHandler(c *gin.Context) {
+	h.withShare(c, model.ShareActionView, "", func(share *model.Share) {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"msg":  "success",
+			"data": gin.H{
+				"share": h.service.PublicInfo(share),
+			},
+		})
+	})
+}
+
+type UnlockShareRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UnlockHandler 校验分享密码，返回访问令牌
+// 之后的请求通过 Authorization: Bearer <token> 或 ?access_token= 携带
+// POST /api/v1/s/:token/unlock
+func (h *ShareHandler) UnlockHandler(c *gin.Context) {
+	h.withShare(c, model.ShareActionAuth, "", func(share *model.Share) {
+		var req UnlockShareRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondBadRequest(c, "Invalid request body")
+			return
+		}
+		token, expiresAt, err := h.service.Unlock(share, req.Password)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"msg":  "success",
+			"data": gin.H{
+				"access_token": token,
+				"expires_at":   expiresAt,
+			},
+		})
+	})
+}
+
+// FilesHandler 列出分享目录的内容，文件分享返回文件信息
+// GET /api/v1/s/:token/files/*path
+func (h *ShareHandler) FilesHandler(c *gin.Context) {
+	h.withShare(c, model.ShareActionView, model.ShareModeRead, func(share *model.Share) {
+		path, err := h.service.Resolve(share, c.Param("path"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		ctx := c.Request.Context()
+		info, err := h.files.Stat(ctx, share.SourceKey, path)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if !info.IsDir {
+			c.JSON(http.StatusOK, gin.H{
+				"code": 0,
+				"msg":  "success",
+				"data": gin.H{
+					"files": info,
+				},
+			})
+			return
+		}
+		files, err := h.files.ListFiles(ctx, share.SourceKey, path)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"msg":  "success",
+			"data": gin.H{
+				"files": files,
+			},
+		})
+	})
+}
+
+// DownloadHandler 下载分享的文件，响应送达文件最后一个字节时计入下载次数
+// GET 请求在发送前先预占一次下载 (次数用尽时返回 410)，没有送达结尾时退还，
+// 因此断点续传与分段下载只在取到结尾的那次请求计数，并发下载也不会超出上限
+// GET/HEAD /api/v1/s/:token/raw/*path
+func (h *ShareHandler) DownloadHandler(c *gin.Context) {
+	h.withShare(c, model.ShareActionDownload, model.ShareModeRead, func(share *model.Share) {
+		path, err := h.service.Resolve(share, c.Param("path"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		ctx := c.Request.Context()
+		info, err := h.files.Stat(ctx, share.SourceKey, path)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if info.IsDir {
+			respondBadRequest(c, "cannot download a directory")
+			return
+		}
+		// 多段 Range 的 multipart 响应无法判断是否取到了结尾，按完整下载处理
+		if strings.Contains(c.GetHeader("Range"), ",") {
+			c.Request.Header.Del("Range")
+		}
+		if c.Request.Method != http.MethodGet {
+			serveFile(c, h.files, share.SourceKey, path)
+			return
+		}
+		if err := h.service.AddDownload(ctx, share); err != nil {
+			respondError(c, err)
+			return
+		}
+		serveFile(c, h.files, share.SourceKey, path)
+		if !servedLastByte(c, info.Size) {
+			// 客户端可能已经断开，退还时不能使用请求的 ctx
+			if err := h.service.RefundDownload(context.WithoutCancel(ctx), share); err != nil {
+				fmt.Printf("[Share] Failed to refund download of share %d: %v\n", share.ID, err)
+			}
+		}
+	})
+}
+
+// servedLastByte 判断响应是否完整写出了包含文件最后一个字节的内容
+func servedLastByte(c *gin.Context, size int64) bool {
+	written := int64(max(c.Writer.Size(), 0))
+	switch c.Writer.Status() {
+	case http.StatusOK:
+		return written == size
+	case http.StatusPartialContent:
+		var first, last, total int64
+		if _, err := fmt.Sscanf(c.Writer.Header().Get("Content-Range"), "bytes %d-%d/%d", &first, &last, &total); err != nil {
+			return false
+		}
+		return last == total-1 && written == last-first+1
+	}
+	return false
+}
+
+// UploadHandler 向只传分享的目录上传文件 (multipart)，同名文件自动重命名，不会覆盖已有文件
+// POST /api/v1/s/:token/upload
+func (h *ShareHandler) UploadHandler(c *gin.Context) {
+	h.withShare(c, model.ShareActionUpload, model.ShareModeUpload, func(share *model.Share) {
+		reader, err := c.Request.MultipartReader()
+		if err != nil {
+			respondBadRequest(c, "invalid multipart body")
+			return
+		}
+		var results []application.UploadResult
+		var names []string
+		var firstErr error
+		succeeded := 0
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				respondBadRequest(c, "invalid multipart body: "+err.Error())
+				return
+			}
+			if part.FileName() == "" {
+				part.Close()
+				continue
+			}
+			name, ok := application.SanitizeFileName(part.FileName())
+			if !ok {
+				results = append(results, application.UploadResult{Name: part.FileName(), Status: "failed", Error: "invalid file name"})
+				part.Close()
+				continue
+			}
+			result, err := h.files.Upload(c.Request.Context(), share.SourceKey, pathpkg.Join(share.Path, name), part, -1, application.ConflictRename)
+			part.Close()
+			// 访问者只能看到分享内的相对路径
+			result.Path = h.service.RelPath(share, result.Path)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				result.Status = "failed"
+				result.Error = err.Error()
+			} else {
+				succeeded++
+				names = append(names, result.Name)
+			}
+			results = append(results, result)
+		}
+		c.Set(sharePathKey, strings.Join(names, ", "))
+
+		if len(results) == 0 {
+			respondBadRequest(c, "no file in request")
+			return
+		}
+		respondUploads(c, results, succeeded, firstErr)
+	})
+}
+
+// withShare 打开令牌对应的分享，mode 不为空时检查模式与访问令牌，之后以创建者的身份执行 fn
+// 分享存在时，无论成功与否都记录访问日志
+func (h *ShareHandler) withShare(c *gin.Context, action, mode string, fn func(share *model.Share)) {
+	ctx := c.Request.Context()
+	share, err := h.service.Open(ctx, c.Param("token"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer h.logAccess(c, share, action)
+
+	// mode 为空的是分享信息与输入密码两个接口，不需要访问令牌
+	if mode != "" {
+		if share.Mode != mode {
+			respondError(c, application.ErrShareMode)
+			return
+		}
+		if err := h.service.Authorize(share, shareAccessToken(c)); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	// 以创建者的身份经过 SecureDriver，创建者的权限变化立即生效
+	c.Request = c.Request.WithContext(context.WithValue(ctx, "username", share.Username))
+	fn(share)
+}
+
+func (h *ShareHandler) logAccess(c *gin.Context, share *model.Share, action string) {
+	path := c.GetString(sharePathKey)
+	if path == "" {
+		path = pathpkg.Clean("/" + c.Param("path"))
+	}
+	h.service.LogAccess(context.WithoutCancel(c.Request.Context()), &model.ShareAccess{
+		ShareID:   share.ID,
+		Action:    action,
+		Path:      path,
+		Status:    c.Writer.Status(),
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+}
+
+// shareAccessToken 优先读 Authorization 头，浏览器直接下载时使用 ?access_token=
+func shareAccessToken(c *gin.Context) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return c.Query("access_token")
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServedLastByte(t *testing.T) {
+	const size = 10
+	tests := []struct {
+		name         string
+		status       int
+		contentRange string
+		written      int
+		want         bool
+	}{
+		{"full body", http.StatusOK, "", 10, true},
+		{"interrupted", http.StatusOK, "", 4, false},
+		{"tail range", http.StatusPartialContent, "bytes 5-9/10", 5, true},
+		{"whole range", http.StatusPartialContent, "bytes 0-9/10", 10, true},
+		{"interrupted range", http.StatusPartialContent, "bytes 5-9/10", 3, false},
+		{"head range", http.StatusPartialContent, "bytes 0-0/10", 1, false},
+		{"middle range", http.StatusPartialContent, "bytes 2-6/10", 5, false},
+		{"missing content-range", http.StatusPartialContent, "", 10, false},
+		{"not modified", http.StatusNotModified, "", 0, false},
+		{"unsatisfiable", http.StatusRequestedRangeNotSatisfiable, "bytes */10", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			if tt.contentRange != "" {
+				c.Header("Content-Range", tt.contentRange)
+			}
+			c.Status(tt.status)
+			c.Writer.WriteHeaderNow()
+			if tt.written > 0 {
+				if _, err := c.Writer.WriteString(strings.Repeat("x", tt.written)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := servedLastByte(c, size); got != tt.want {
+				t.Fatalf("servedLastByte = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
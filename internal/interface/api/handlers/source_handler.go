@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/domain/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SourceHandler 存储源列表与管理
+type SourceHandler struct {
+	service *application.FileService
+}
+
+func NewSourceHandler(s *application.FileService) *SourceHandler {
+	return &SourceHandler{service: s}
+}
+
+// ListHandler 列出当前用户可见的存储源
+// GET /api/v1/sources
+func (h *SourceHandler) ListHandler(c *gin.Context) {
+	sources, err := h.service.GetAllSource(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"sources": sources,
+		},
+	})
+}
+
+// HealthHandler 返回所有存储源的健康状态
+// GET /api/v1/admin/sources/health?check=1  check=1 时立即执行一次检查
+func (h *SourceHandler) HealthHandler(c *gin.Context) {
+	check := c.Query("check") == "1" || c.Query("check") == "true"
+	health, err := h.service.SourceHealth(c.Request.Context(), check)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"sources": health,
+		},
+	})
+}
+
+// IndexHandler 在后台重建存储源的搜索索引
+// POST /api/v1/admin/sources/:source_key/index
+func (h *SourceHandler) IndexHandler(c *gin.Context) {
+	if err := h.service.RebuildSearchIndex(c.Request.Context(), c.Param("source_key")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"code": 0,
+		"msg":  "accepted",
+	})
+}
+
+// DriversHandler 列出可用的驱动类型及其配置项，供前端生成存储源表单
+// GET /api/v1/admin/drivers
+func (h *SourceHandler) DriversHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"drivers": h.service.DriverSchemas(),
+		},
+	})
+}
+
+// SourceRequest 创建或修改存储源；修改时为完整替换，省略的字段恢复默认值
+// 响应中的密码等敏感配置项显示为 ********，修改时原样提交表示保留原值
+type SourceRequest struct {
+	Key           string         `json:"key"`
+	Name          string         `json:"name"`
+	Type          string         `json:"type"`
+	Config        map[string]any `json:"config"`
+	Timeout       int            `json:"timeout"`
+	MaxUploadSize int64          `json:"max_upload_size"`
+	TrashDays     int            `json:"trash_days"`
+	VersionPaths  string         `json:"version_paths"`
+	VersionKeep   int            `json:"version_keep"`
+	VersionDays   int            `json:"version_days"`
+	DisableIndex  bool           `json:"disable_index"`
+}
+
+func (r *SourceRequest) model() *model.StorageSource {
+	return &model.StorageSource{
+		Key:           r.Key,
+		Name:          r.Name,
+		Type:          r.Type,
+		Config:        r.Config,
+		Timeout:       r.Timeout,
+		MaxUploadSize: r.MaxUploadSize,
+		TrashDays:     r.TrashDays,
+		VersionPaths:  r.VersionPaths,
+		VersionKeep:   r.VersionKeep,
+		VersionDays:   r.VersionDays,
+		DisableIndex:  r.DisableIndex,
+	}
+}
+
+// AdminListHandler 列出全部存储源及其配置
+// GET /api/v1/admin/sources
+func (h *SourceHandler) AdminListHandler(c *gin.Context) {
+	sources, err := h.service.ListSources(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"sources": sources,
+		},
+	})
+}
+
+// CreateHandler 创建存储源
+// POST /api/v1/admin/sources
+func (h *SourceHandler) CreateHandler(c *gin.Context) {
+	var req SourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	source, err := h.service.CreateSource(c.Request.Context(), req.model())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": source,
+	})
+}
+
+// UpdateHandler 修改存储源，缓存的驱动会被关闭并按新配置重建
+// PUT /api/v1/admin/sources/:source_key
+func (h *SourceHandler) UpdateHandler(c *gin.Context) {
+	var req SourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	source, err := h.service.UpdateSource(c.Request.Context(), c.Param("source_key"), req.model())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": source,
+	})
+}
+
+// DeleteHandler 删除存储源配置，存储后端中的文件不受影响
+// DELETE /api/v1/admin/sources/:source_key
+func (h *SourceHandler) DeleteHandler(c *gin.Context) {
+	if err := h.service.DeleteSource(c.Request.Context(), c.Param("source_key")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// TestHandler 用请求中的配置测试连接，不保存
+// POST /api/v1/admin/sources/test
+func (h *SourceHandler) TestHandler(c *gin.Context) {
+	var req SourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	elapsed, err := h.service.TestSource(c.Request.Context(), req.model())
+	respondTest(c, elapsed, err)
+}
+
+// TestSavedHandler 测试已保存的存储源，不影响正在使用的驱动
+// POST /api/v1/admin/sources/:source_key/test
+func (h *SourceHandler) TestSavedHandler(c *gin.Context) {
+	elapsed, err := h.service.TestSavedSource(c.Request.Context(), c.Param("source_key"))
+	respondTest(c, elapsed, err)
+}
+
+func respondTest(c *gin.Context, elapsed time.Duration, err error) {
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"elapsed_ms": elapsed.Milliseconds(),
+		},
+	})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ThumbnailHandler struct {
+	service *application.ThumbnailService
+}
+
+func NewThumbnailHandler(s *application.ThumbnailService) *ThumbnailHandler {
+	return &ThumbnailHandler{service: s}
+}
+
+// GetHandler 返回图片缩略图 (默认 JPEG，format=webp 时为保留透明度的 WebP)
+// GET /api/v1/thumb/:source_key/*path?size=256&format=jpeg
+// 尚未生成完成时返回 202 与 Retry-After，客户端稍后重试
+func (h *ThumbnailHandler) GetHandler(c *gin.Context) {
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil || size < 0 {
+		respondBadRequest(c, "size must be a positive integer")
+		return
+	}
+	thumb, err := h.service.Thumbnail(c.Request.Context(), c.Param("source_key"), c.Param("path"), size, c.Query("format"))
+	if errors.Is(err, application.ErrThumbPending) {
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusAccepted, gin.H{
+			"code": 0,
+			"msg":  "pending",
+		})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	f, err := os.Open(thumb.Path)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer f.Close()
+	header := c.Writer.Header()
+	header.Set("Content-Type", thumb.ContentType)
+	header.Set("ETag", thumb.ETag)
+	// ETag 随原图的修改时间与大小变化，每次重新验证，原图更新后立即看到新缩略图
+	header.Set("Cache-Control", "private, no-cache")
+	header.Set("X-Content-Type-Options", "nosniff")
+	// ServeContent 处理 If-None-Match 与 304
+	http.ServeContent(c.Writer, c.Request, "", thumb.ModTime, f)
+}
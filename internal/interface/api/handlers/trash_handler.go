@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler 回收站：列出、恢复与永久删除已删除的文件
+type TrashHandler struct {
+	service *application.FileService
+}
+
+func NewTrashHandler(s *application.FileService) *TrashHandler {
+	return &TrashHandler{service: s}
+}
+
+// ListHandler 列出自己删除的条目，?source_key= 按存储源过滤，管理员 ?all=1 时列出全部
+// GET /api/v1/trash
+func (h *TrashHandler) ListHandler(c *gin.Context) {
+	all := c.Query("all") == "1" || c.Query("all") == "true"
+	items, err := h.service.ListTrash(c.Request.Context(), currentUser(c), c.Query("source_key"), all)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"items": items,
+		},
+	})
+}
+
+type RestoreTrashRequest struct {
+	Conflict string `json:"conflict"` // 原路径已被占用时：fail (默认)、rename、overwrite
+}
+
+// RestoreHandler 恢复到原路径
+// POST /api/v1/trash/:id/restore
+func (h *TrashHandler) RestoreHandler(c *gin.Context) {
+	id, ok := trashItemID(c)
+	if !ok {
+		return
+	}
+	var req RestoreTrashRequest
+	// 请求体可省略
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	path, err := h.service.RestoreTrash(c.Request.Context(), currentUser(c), id, req.Conflict)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"path": path,
+		},
+	})
+}
+
+// PurgeHandler 永久删除一个条目
+// DELETE /api/v1/trash/:id
+func (h *TrashHandler) PurgeHandler(c *gin.Context) {
+	id, ok := trashItemID(c)
+	if !ok {
+		return
+	}
+	if err := h.service.PurgeTrash(c.Request.Context(), currentUser(c), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// EmptyHandler 清空自己删除的条目，?source_key= 只清空一个存储源，管理员 ?all=1 时清空全部
+// DELETE /api/v1/trash
+func (h *TrashHandler) EmptyHandler(c *gin.Context) {
+	all := c.Query("all") == "1" || c.Query("all") == "true"
+	purged, err := h.service.EmptyTrash(c.Request.Context(), currentUser(c), c.Query("source_key"), all)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"purged": purged,
+		},
+	})
+}
+
+func trashItemID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondBadRequest(c, "invalid trash item id")
+		return 0, false
+	}
+	return uint(id), true
+}
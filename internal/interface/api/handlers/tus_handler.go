@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+const tusVersion = "1.0.0"
+
+// tus 协议中校验失败使用的非标准状态码
+const statusChecksumMismatch = 460
+
+// TusHandler 实现 tus 1.0 断点续传协议
+// 支持扩展: creation, termination, checksum, expiration
+//
+//	OPTIONS /api/v1/tus/:source_key        能力发现
+//	OPTIONS /api/v1/tus/:source_key/:id    跨域预检
+//	POST    /api/v1/tus/:source_key        创建上传 (Upload-Length, Upload-Metadata: filename, dir, conflict)
+//	HEAD    /api/v1/tus/:source_key/:id    查询偏移量
+//	PATCH   /api/v1/tus/:source_key/:id    追加数据
+//	DELETE  /api/v1/tus/:source_key/:id    取消上传
+type TusHandler struct {
+	service *application.TusService
+}
+
+func NewTusHandler(s *application.TusService) *TusHandler {
+	return &TusHandler{service: s}
+}
+
+// tusHeaders 所有 tus 响应都需要的公共头
+func tusHeaders(c *gin.Context) {
+	c.Header("Tus-Resumable", tusVersion)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Access-Control-Expose-Headers", "Location, Upload-Offset, Upload-Length, Upload-Metadata, Upload-Expires, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Tus-Checksum-Algorithm")
+}
+
+// checkVersion 除 OPTIONS 外，请求必须声明 Tus-Resumable: 1.0.0
+func checkVersion(c *gin.Context) bool {
+	if c.GetHeader("Tus-Resumable") != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatus(http.StatusPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// OptionsHandler 能力发现，同时作为跨域预检的响应 (集合与单个上传的地址都会收到预检)
+// 预检请求不带 Authorization，因此不经过鉴权
+func (h *TusHandler) OptionsHandler(c *gin.Context) {
+	tusHeaders(c)
+	c.Header("Access-Control-Allow-Methods", "POST, HEAD, PATCH, DELETE, OPTIONS")
+	c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, Tus-Resumable, Upload-Length, Upload-Metadata, Upload-Offset, Upload-Checksum, X-Requested-With, X-HTTP-Method-Override")
+	c.Header("Access-Control-Max-Age", "86400")
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", "creation,termination,checksum,expiration")
+	c.Header("Tus-Checksum-Algorithm", strings.Join(application.TusChecksumAlgorithms, ","))
+	if maxSize, err := h.service.MaxSize(c.Request.Context(), c.Param("source_key")); err == nil && maxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TusHandler) CreateHandler(c *gin.Context) {
+	tusHeaders(c)
+	if !checkVersion(c) {
+		return
+	}
+	if c.GetHeader("Upload-Defer-Length") != "" {
+		c.String(http.StatusBadRequest, "Upload-Defer-Length is not supported")
+		return
+	}
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		c.String(http.StatusBadRequest, "invalid Upload-Length")
+		return
+	}
+	rawMeta := c.GetHeader("Upload-Metadata")
+	meta, err := application.ParseTusMetadata(rawMeta)
+	if err != nil {
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sourceKey := c.Param("source_key")
+	session, err := h.service.CreateUpload(c.Request.Context(), currentUser(c), sourceKey, length, meta, rawMeta)
+	if err != nil {
+		c.String(tusErrorStatus(err), err.Error())
+		return
+	}
+	c.Header("Location", "/api/v1/tus/"+sourceKey+"/"+session.ID)
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+func (h *TusHandler) HeadHandler(c *gin.Context) {
+	tusHeaders(c)
+	if !checkVersion(c) {
+		return
+	}
+	session, err := h.service.GetUpload(c.Request.Context(), currentUser(c), c.Param("source_key"), c.Param("id"))
+	if err != nil {
+		c.Status(tusErrorStatus(err))
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Length, 10))
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	if session.Metadata != "" {
+		c.Header("Upload-Metadata", session.Metadata)
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *TusHandler) PatchHandler(c *gin.Context) {
+	tusHeaders(c)
+	if !checkVersion(c) {
+		return
+	}
+	if c.ContentType() != "application/offset+octet-stream" {
+		c.String(http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.String(http.StatusBadRequest, "invalid Upload-Offset")
+		return
+	}
+
+	newOffset, err := h.service.WriteChunk(c.Request.Context(), currentUser(c), c.Param("source_key"), c.Param("id"), offset, c.Request.Body, c.GetHeader("Upload-Checksum"))
+	if err != nil {
+		c.String(tusErrorStatus(err), err.Error())
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TusHandler) DeleteHandler(c *gin.Context) {
+	tusHeaders(c)
+	if !checkVersion(c) {
+		return
+	}
+	if err := h.service.Terminate(c.Request.Context(), currentUser(c), c.Param("source_key"), c.Param("id")); err != nil {
+		c.Status(tusErrorStatus(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// tusErrorStatus 校验失败使用 tus 协议规定的 460，其余沿用统一的错误映射
+func tusErrorStatus(err error) int {
+	if errors.Is(err, application.ErrChecksumMismatch) {
+		return statusChecksumMismatch
+	}
+	return errorStatus(err)
+}
+
+// currentUser 获取认证中间件注入的用户名
+func currentUser(c *gin.Context) string {
+	username, _ := c.Request.Context().Value("username").(string)
+	return username
+}
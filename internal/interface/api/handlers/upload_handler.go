@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	pathpkg "path"
+	"strings"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadHandler 上传文件
+//
+//	PUT/POST /api/v1/files/:source_key/*path            请求体即文件内容，path 为目标文件
+//	PUT/POST /api/v1/files/:source_key/*path (multipart) 可包含多个文件，path 为目标目录
+//
+// Query Param: conflict=overwrite|fail|rename (默认 overwrite)
+func (h *FileHandler) UploadHandler(c *gin.Context) {
+	sourceKey := c.Param("source_key")
+	path := c.Param("path")
+	policy := c.Query("conflict")
+	if !application.ValidConflictPolicy(policy) {
+		respondBadRequest(c, "invalid conflict policy: "+policy)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		h.uploadMultipart(c, sourceKey, path, policy)
+		return
+	}
+
+	if path == "" || strings.HasSuffix(path, "/") {
+		respondBadRequest(c, "target file path is required")
+		return
+	}
+	result, err := h.service.Upload(c.Request.Context(), sourceKey, path, c.Request.Body, c.Request.ContentLength, policy)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"files": []application.UploadResult{result},
+		},
+	})
+}
+
+// uploadMultipart 逐个读取 multipart 中的文件并直接写入存储，不缓存到内存或临时文件
+func (h *FileHandler) uploadMultipart(c *gin.Context, sourceKey, dir, policy string) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		respondBadRequest(c, "invalid multipart body")
+		return
+	}
+
+	var results []application.UploadResult
+	var firstErr error
+	succeeded := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			respondBadRequest(c, "invalid multipart body: "+err.Error())
+			return
+		}
+		if part.FileName() == "" {
+			// 普通表单字段，忽略
+			part.Close()
+			continue
+		}
+
+		name, ok := sanitizeFileName(part.FileName())
+		if !ok {
+			results = append(results, application.UploadResult{Name: part.FileName(), Status: "failed", Error: "invalid file name"})
+			part.Close()
+			continue
+		}
+		result, err := h.service.Upload(c.Request.Context(), sourceKey, pathpkg.Join("/", dir, name), part, -1, policy)
+		part.Close()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			result.Status = "failed"
+			result.Error = err.Error()
+		} else {
+			succeeded++
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		respondBadRequest(c, "no file in request")
+		return
+	}
+	respondUploads(c, results, succeeded, firstErr)
+}
+
+// respondUploads 多文件上传的响应，files 为逐个文件的结果
+// 至少一个文件成功时返回 200；全部失败时按第一个错误返回错误码，请求中只有非法文件名时为 400
+func respondUploads(c *gin.Context, files any, succeeded int, firstErr error) {
+	if succeeded > 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"msg":  "success",
+			"data": gin.H{
+				"files": files,
+			},
+		})
+		return
+	}
+	e, msg := apiError{http.StatusBadRequest, CodeInvalidRequest}, "no file was uploaded"
+	if firstErr != nil {
+		e, msg = describeError(c, firstErr)
+	}
+	c.AbortWithStatusJSON(e.status, gin.H{
+		"code":  e.code,
+		"msg":   http.StatusText(e.status),
+		"error": msg,
+		"data": gin.H{
+			"files": files,
+		},
+	})
+}
+
+// sanitizeFileName 只保留文件名部分，防止客户端通过 "../" 或 Windows 路径写到目标目录之外
+func sanitizeFileName(name string) (string, bool) {
+	name = pathpkg.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" || strings.ContainsRune(name, 0) {
+		return "", false
+	}
+	return name, true
+}
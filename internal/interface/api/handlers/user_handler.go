@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserHandler 用户管理 (管理员) 与个人资料、密码修改
+type UserHandler struct {
+	service *application.AuthService
+}
+
+func NewUserHandler(s *application.AuthService) *UserHandler {
+	return &UserHandler{service: s}
+}
+
+type CreateUserRequest struct {
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+	Role        string `json:"role"` // admin 或 user (默认)
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+}
+
+// UpdateUserRequest 省略的字段保持不变
+type UpdateUserRequest struct {
+	Role        *string `json:"role"`
+	DisplayName *string `json:"display_name"`
+	Email       *string `json:"email"`
+	Password    *string `json:"password"` // 重置密码，不需要原密码
+}
+
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"display_name"`
+	Email       *string `json:"email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ListHandler 列出全部用户
+// GET /api/v1/admin/users
+func (h *UserHandler) ListHandler(c *gin.Context) {
+	users, err := h.service.ListUsers(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"users": users,
+		},
+	})
+}
+
+// CreateHandler 创建用户
+// POST /api/v1/admin/users
+func (h *UserHandler) CreateHandler(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	user, err := h.service.CreateUser(c.Request.Context(), application.UserInput{
+		Username:    req.Username,
+		Password:    req.Password,
+		Role:        req.Role,
+		DisplayName: req.DisplayName,
+		Email:       req.Email,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": user,
+	})
+}
+
+// UpdateHandler 修改用户的角色、资料或重置密码
+// PATCH /api/v1/admin/users/:username
+func (h *UserHandler) UpdateHandler(c *gin.Context) {
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	user, err := h.service.UpdateUser(c.Request.Context(), c.Param("username"), application.UserUpdate{
+		Role:        req.Role,
+		DisplayName: req.DisplayName,
+		Email:       req.Email,
+		Password:    req.Password,
+	})
+	respondUser(c, user, err)
+}
+
+// DeactivateHandler 停用用户，立即生效
+// POST /api/v1/admin/users/:username/deactivate
+func (h *UserHandler) DeactivateHandler(c *gin.Context) {
+	user, err := h.service.SetActive(c.Request.Context(), currentUser(c), c.Param("username"), false)
+	respondUser(c, user, err)
+}
+
+// ActivateHandler 重新启用用户
+// POST /api/v1/admin/users/:username/activate
+func (h *UserHandler) ActivateHandler(c *gin.Context) {
+	user, err := h.service.SetActive(c.Request.Context(), currentUser(c), c.Param("username"), true)
+	respondUser(c, user, err)
+}
+
+// DeleteHandler 删除用户及其权限规则
+// DELETE /api/v1/admin/users/:username
+func (h *UserHandler) DeleteHandler(c *gin.Context) {
+	if err := h.service.DeleteUser(c.Request.Context(), currentUser(c), c.Param("username")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+	})
+}
+
+// ProfileHandler 修改自己的显示名称与邮箱
+// PATCH /api/v1/users/me
+func (h *UserHandler) ProfileHandler(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	user, err := h.service.UpdateProfile(c.Request.Context(), currentUser(c), req.DisplayName, req.Email)
+	respondUser(c, user, err)
+}
+
+// PasswordHandler 修改自己的密码，需要提供当前密码
+// 已签发的 Token 全部失效，响应中返回新的 Token
+// POST /api/v1/users/me/password
+func (h *UserHandler) PasswordHandler(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondBadRequest(c, "Invalid request body")
+		return
+	}
+	token, err := h.service.ChangePassword(c.Request.Context(), currentUser(c), req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"token": token,
+		},
+	})
+}
+
+func respondUser(c *gin.Context, user any, err error) {
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": user,
+	})
+}
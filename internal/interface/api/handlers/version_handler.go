@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	pathpkg "path"
+	"strconv"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionHandler 文件历史版本：列出、下载、恢复与比较
+type VersionHandler struct {
+	service *application.VersionService
+}
+
+func NewVersionHandler(s *application.VersionService) *VersionHandler {
+	return &VersionHandler{service: s}
+}
+
+// ListHandler 列出文件的历史版本，最新的在前
+// GET /api/v1/versions/:source_key/*path
+func (h *VersionHandler) ListHandler(c *gin.Context) {
+	versions, err := h.service.List(c.Request.Context(), currentUser(c), c.Param("source_key"), c.Param("path"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": gin.H{
+			"versions": versions,
+		},
+	})
+}
+
+// DownloadHandler 下载一个历史版本 (?inline=1 浏览器预览)
+// GET /api/v1/file-versions/:id/raw
+func (h *VersionHandler) DownloadHandler(c *gin.Context) {
+	id, ok := versionID(c, "id")
+	if !ok {
+		return
+	}
+	version, stream, err := h.service.Open(c.Request.Context(), currentUser(c), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer stream.Close()
+	name := pathpkg.Base(version.Path)
+	serveStream(c, name, vfs.FileInfo{Name: name, Size: version.Size, ModTime: version.ModTime}, stream)
+}
+
+// RestoreHandler 用历史版本覆盖当前文件，被覆盖的内容会成为新的历史版本
+// POST /api/v1/file-versions/:id/restore
+func (h *VersionHandler) RestoreHandler(c *gin.Context) {
+	id, ok := versionID(c, "id")
+	if !ok {
+		return
+	}
+	result, err := h.service.Restore(c.Request.Context(), currentUser(c), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": result,
+	})
+}
+
+// DiffHandler 比较两个文本版本，?against= 为另一个版本 ID，省略时与当前文件比较
+// GET /api/v1/file-versions/:id/diff
+func (h *VersionHandler) DiffHandler(c *gin.Context) {
+	id, ok := versionID(c, "id")
+	if !ok {
+		return
+	}
+	var against uint
+	if c.Query("against") != "" {
+		if against, ok = versionID(c, "against"); !ok {
+			return
+		}
+	}
+	diff, err := h.service.Diff(c.Request.Context(), currentUser(c), id, against)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "success",
+		"data": diff,
+	})
+}
+
+// versionID 从路径参数或 query 参数中解析版本 ID
+func versionID(c *gin.Context, name string) (uint, bool) {
+	raw := c.Param(name)
+	if raw == "" {
+		raw = c.Query(name)
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		respondBadRequest(c, "invalid version id")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"sync"
@@ -13,16 +16,20 @@ import (
 	"golang.org/x/net/webdav"
 )
 
+// maxPropPatchBody PROPPATCH 请求体上限，属性更新不会很大
+const maxPropPatchBody = 1 << 20
+
 type WebDAVHandler struct {
 	fileService *application.FileService
 	authService *application.AuthService
+	metaService *application.MetadataService
 	// 用于存储不同 SourceID 的锁系统
 	// Key: sourceID (string), Value: webdav.LockSystem
 	lockSystems sync.Map
 }
 
-func NewWebDAVHandler(fs *application.FileService, as *application.AuthService) *WebDAVHandler {
-	return &WebDAVHandler{fileService: fs, authService: as}
+func NewWebDAVHandler(fs *application.FileService, as *application.AuthService, ms *application.MetadataService) *WebDAVHandler {
+	return &WebDAVHandler{fileService: fs, authService: as, metaService: ms}
 }
 
 // ServeHTTP 处理 WebDAV 请求
@@ -50,7 +57,13 @@ func (h *WebDAVHandler) Handler(c *gin.Context) {
 	driver, err := h.fileService.GetDriver(c.Request.Context(), sourceKey)
 	if err != nil {
 		fmt.Printf("[Debug] Driver not found: %v\n", err)
-		c.AbortWithStatus(http.StatusNotFound) // <--- 如果是这里报404，说明数据库没查到ID
+		c.AbortWithStatus(errorStatus(err)) // 404 说明数据库没查到该存储源，503 说明驱动无法初始化
+		return
+	}
+	// 自定义属性 (Dead Properties) 的存储
+	metadata, err := h.metaService.ForSource(c.Request.Context(), sourceKey)
+	if err != nil {
+		c.AbortWithStatus(errorStatus(err))
 		return
 	}
 
@@ -78,7 +91,18 @@ func (h *WebDAVHandler) Handler(c *gin.Context) {
 	prefix := "/webdav/" + sourceKey + "/"
 
 	handler := &webdav.Handler{
-		FileSystem: &webdav_adapter.DriverFileSystem{Driver: driver},
+		FileSystem: &webdav_adapter.DriverFileSystem{
+			Driver:   driver,
+			Metadata: metadata,
+			// DELETE 与 REST 接口一致，按存储源配置移入回收站
+			Remove: func(ctx context.Context, name string) error {
+				return h.fileService.Delete(ctx, sourceKey, name)
+			},
+			// PUT 覆盖已有文件时与上传接口一致，旧文件移入回收站
+			Replace: func(ctx context.Context, tmp, name string) error {
+				return h.fileService.Replace(ctx, sourceKey, tmp, name)
+			},
+		},
 		LockSystem: lockSystem,
 		Logger: func(r *http.Request, err error) {
 			// 只打印错误，或者打印所有请求以便调试
@@ -90,6 +114,72 @@ func (h *WebDAVHandler) Handler(c *gin.Context) {
 
 	handler.Prefix = prefix
 
-	// 转交处理
-	handler.ServeHTTP(c.Writer, c.Request)
+	// PROPPATCH 修改 DAV:getlastmodified 会被 webdav 库当作 live property 拒绝，先改写请求体
+	if c.Request.Method == "PROPPATCH" && c.Request.Body != nil {
+		// 多读一个字节判断是否超出上限，截断的 XML 不能交给 webdav 库
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPropPatchBody+1))
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxPropPatchBody {
+			c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+			return
+		}
+		body = webdav_adapter.RewriteLastModified(body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+	}
+
+	// 转交处理，文件系统错误由 davErrorWriter 按统一映射修正状态码
+	ctx, rec := webdav_adapter.WithErrorRecorder(c.Request.Context())
+	c.Request = c.Request.WithContext(ctx)
+	handler.ServeHTTP(&davErrorWriter{ResponseWriter: c.Writer, rec: rec}, c.Request)
+
+	// 同步客户端上传时通过 X-OC-Mtime 携带原始修改时间，上传成功后回写
+	if c.Request.Method == "PUT" && (c.Writer.Status() == http.StatusCreated || c.Writer.Status() == http.StatusNoContent) {
+		if mtime, ok := webdav_adapter.ParseOCMtime(c.GetHeader("X-OC-Mtime")); ok {
+			if err := driver.SetModTime(c.Request.Context(), c.Param("path"), mtime); err != nil {
+				fmt.Printf("[WebDAV] Failed to set mtime for %s: %v\n", c.Param("path"), err)
+			}
+		}
+	}
+}
+
+// davErrorWriter webdav 库对无法识别的错误一律返回 500 (GET 时则是 404)
+// 当请求中出现权限、只读、配额、不可用、超时等领域错误时，改为 errorStatus 映射的状态码
+type davErrorWriter struct {
+	gin.ResponseWriter
+	rec        *webdav_adapter.ErrorRecorder
+	overridden bool
+}
+
+func (w *davErrorWriter) WriteHeader(code int) {
+	if code >= http.StatusBadRequest {
+		if err := w.rec.Err(); err != nil {
+			switch status := errorStatus(err); status {
+			case http.StatusForbidden, http.StatusRequestEntityTooLarge, http.StatusInsufficientStorage,
+				http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+				if status != code {
+					w.overridden = true
+					w.ResponseWriter.WriteHeader(status)
+					w.ResponseWriter.Write([]byte(http.StatusText(status) + "\n"))
+					return
+				}
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write 状态码被修正后丢弃 webdav 库原本的错误文本
+func (w *davErrorWriter) Write(b []byte) (int, error) {
+	if w.overridden {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *davErrorWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
 }
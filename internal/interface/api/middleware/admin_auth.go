@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/interface/api/handlers"
+)
+
+// AdminOnly 仅允许管理员访问，必须放在 JWTAuth 之后使用
+func AdminOnly(authService *application.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, ok := c.Request.Context().Value("username").(string)
+		if !ok || username == "" {
+			handlers.Fail(c, http.StatusUnauthorized, handlers.CodeUnauthorized, "Unauthorized")
+			return
+		}
+		user, err := authService.GetUser(c.Request.Context(), username)
+		if err != nil || user.Role != "admin" {
+			handlers.Fail(c, http.StatusForbidden, handlers.CodePermissionDenied, "Admin privileges required")
+			return
+		}
+		c.Next()
+	}
+}
@@ -7,26 +7,26 @@ import (
 	"strings"
 
 	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/interface/api/handlers"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWTAuth() gin.HandlerFunc {
+// JWTAuth 校验 Bearer Token；用户已删除、停用或修改过密码时 Token 随之失效
+func JWTAuth(authService *application.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1. 获取 Authorization Header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			c.Abort()
+			handlers.Fail(c, http.StatusUnauthorized, handlers.CodeUnauthorized, "Authorization header required")
 			return
 		}
 
 		// 格式通常是 "Bearer <token>"
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format"})
-			c.Abort()
+			handlers.Fail(c, http.StatusUnauthorized, handlers.CodeUnauthorized, "Invalid authorization format")
 			return
 		}
 		tokenString := parts[1]
@@ -41,22 +41,35 @@ func JWTAuth() gin.HandlerFunc {
 
 		// 3. 验证 Claims
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
-			c.Abort()
+			handlers.Fail(c, http.StatusUnauthorized, handlers.CodeUnauthorized, "Invalid or expired token")
 			return
 		}
 
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+			handlers.Fail(c, http.StatusUnauthorized, handlers.CodeUnauthorized, "Invalid token claims")
+			return
+		}
+		// 分享链接的访问令牌同样由 JWTSecret 签发，但不带 username，不能用于登录接口
+		username, ok := claims["username"].(string)
+		if !ok || username == "" {
+			handlers.Fail(c, http.StatusUnauthorized, handlers.CodeUnauthorized, "Invalid token claims")
+			return
+		}
+		user, err := authService.GetUser(c.Request.Context(), username)
+		if err != nil || !user.IsActive {
+			handlers.Fail(c, http.StatusUnauthorized, handlers.CodeUnauthorized, "User not found or disabled")
+			return
+		}
+		if application.TokenRevoked(user, claims) {
+			handlers.Fail(c, http.StatusUnauthorized, handlers.CodeUnauthorized, "Token revoked by password change")
 			return
 		}
 
 		// 4. 将用户信息注入 Context，供后续 Handler 使用
 		// 注意：JSON数字解析后通常是float64，需要转型
 		ctx := c.Request.Context()
-		ctx = context.WithValue(ctx, "username", claims["username"])
+		ctx = context.WithValue(ctx, "username", username)
 		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
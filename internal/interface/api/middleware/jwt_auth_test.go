@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestAuth 创建管理员 admin 与普通用户 users，密码均为 password123
+// 用户缓存在进程内共享，不同测试使用不同的用户名
+func newTestAuth(t *testing.T, admin string, users ...string) *application.AuthService {
+	t.Helper()
+	db, err := persistence.InitDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := application.NewAuthService(persistence.NewUserRepository(db), persistence.NewPermissionRepository(db))
+	ctx := context.Background()
+	if err := auth.Register(ctx, admin, "password123", application.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range users {
+		if _, err := auth.CreateUser(ctx, application.UserInput{Username: name, Password: "password123"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return auth
+}
+
+func signToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(application.JWTSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJWTAuth(t *testing.T) {
+	auth := newTestAuth(t, "admin", "bob", "carol")
+	ctx := context.Background()
+	tokens := make(map[string]string)
+	for _, name := range []string{"admin", "bob", "carol"} {
+		token, err := auth.LoginJwt(ctx, name, "password123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[name] = token
+	}
+	// 登录后停用或删除，已签发的 Token 立即失效
+	if _, err := auth.SetActive(ctx, "admin", "bob", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.DeleteUser(ctx, "admin", "carol"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/me", JWTAuth(auth), func(c *gin.Context) {
+		c.String(http.StatusOK, "%v", c.Request.Context().Value("username"))
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{"active user", "Bearer " + tokens["admin"], http.StatusOK},
+		{"deactivated user", "Bearer " + tokens["bob"], http.StatusUnauthorized},
+		{"deleted user", "Bearer " + tokens["carol"], http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + tokens["admin"], http.StatusUnauthorized},
+		{"expired", "Bearer " + signToken(t, jwt.MapClaims{"username": "admin", "exp": time.Now().Add(-time.Minute).Unix()}), http.StatusUnauthorized},
+		// 分享访问令牌没有 username
+		{"share token", "Bearer " + signToken(t, jwt.MapClaims{"share": "abc", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusOK && w.Body.String() != "admin" {
+				t.Fatalf("username in context = %q, want admin", w.Body.String())
+			}
+		})
+	}
+}
+
+// 修改或重置密码后，之前签发的 Token 失效
+func TestJWTAuthRevokedByPasswordChange(t *testing.T) {
+	auth := newTestAuth(t, "grace", "heidi", "ivan")
+	ctx := context.Background()
+	login := func(name, password string) string {
+		t.Helper()
+		token, err := auth.LoginJwt(ctx, name, password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	heidiOld := login("heidi", "password123")
+	ivanOld := login("ivan", "password123")
+	heidiNew, err := auth.ChangePassword(ctx, "heidi", "password123", "new-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reset := "reset-password"
+	if _, err := auth.UpdateUser(ctx, "ivan", application.UserUpdate{Password: &reset}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.GET("/me", JWTAuth(auth), func(c *gin.Context) {
+		c.String(http.StatusOK, "%v", c.Request.Context().Value("username"))
+	})
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"before change", heidiOld, http.StatusUnauthorized},
+		{"returned by change", heidiNew, http.StatusOK},
+		{"login after change", login("heidi", "new-password"), http.StatusOK},
+		{"before admin reset", ivanOld, http.StatusUnauthorized},
+		{"login after reset", login("ivan", reset), http.StatusOK},
+		// 升级前签发的 Token 没有 ver，只对从未修改过密码的用户有效
+		{"without version", signToken(t, jwt.MapClaims{"username": "grace", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusOK},
+		{"without version after change", signToken(t, jwt.MapClaims{"username": "heidi", "exp": time.Now().Add(time.Hour).Unix()}), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestBasicAuthRejectsDisabled(t *testing.T) {
+	auth := newTestAuth(t, "root", "dan", "erin")
+	if _, err := auth.SetActive(context.Background(), "root", "dan", false); err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.Handle("PROPFIND", "/webdav", BasicAuth(auth), func(c *gin.Context) {
+		c.Status(http.StatusMultiStatus)
+	})
+
+	tests := []struct {
+		username string
+		status   int
+	}{
+		{"root", http.StatusMultiStatus},
+		{"dan", http.StatusUnauthorized},
+		{"erin", http.StatusMultiStatus},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("PROPFIND", "/webdav", nil)
+		req.SetBasicAuth(tt.username, "password123")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.username, w.Code, tt.status)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/wentf9/MyGoFileHub/config"
+	"github.com/wentf9/MyGoFileHub/internal/interface/api/handlers"
 )
 
 var localSubnets []net.Addr
@@ -21,8 +22,7 @@ func ClientCheck() gin.HandlerFunc {
 		clientIp := getClientIp(c)
 
 		if ok, err := isSameSubnet(clientIp); err != nil || !ok {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: unable to determine client IP"})
-			c.Abort()
+			handlers.Fail(c, http.StatusForbidden, handlers.CodePermissionDenied, "Forbidden: unable to determine client IP")
 			return
 		}
 		// 继续处理请求
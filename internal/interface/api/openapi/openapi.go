@@ -0,0 +1,148 @@
+// Package openapi 提供 /api/v1 的 OpenAPI 3 文档，并根据文档校验请求参数
+//
+// openapi.json 是接口契约的唯一来源：新增或修改路由时必须同步更新，
+// 缺少文档的路由会在启动时由 CheckRoutes 报出，不会悄悄跳过校验
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var specJSON []byte
+
+// spec 解析后的文档，启动时解析一次
+var spec = mustParse(specJSON)
+
+// basePath 与文档中 servers[0].url 一致
+const basePath = "/api/v1"
+
+type document struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]*Schema    `json:"schemas"`
+		Parameters map[string]*Parameter `json:"parameters"`
+	} `json:"components"`
+
+	// operations 按 "METHOD /path" 索引
+	operations map[string]*Operation
+}
+
+type Operation struct {
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+func mustParse(data []byte) *document {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		panic("openapi: invalid openapi.json: " + err.Error())
+	}
+	doc.operations = make(map[string]*Operation)
+	for path, item := range doc.Paths {
+		// 路径级参数对该路径下所有方法生效
+		var shared []*Parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				panic("openapi: invalid parameters of " + path + ": " + err.Error())
+			}
+		}
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op Operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				panic("openapi: invalid operation " + method + " " + path + ": " + err.Error())
+			}
+			params := append(append([]*Parameter{}, shared...), op.Parameters...)
+			for i, p := range params {
+				params[i] = doc.resolveParameter(p)
+			}
+			op.Parameters = params
+			doc.operations[strings.ToUpper(method)+" "+path] = &op
+		}
+	}
+	return &doc
+}
+
+func (d *document) resolveParameter(p *Parameter) *Parameter {
+	if p.Ref == "" {
+		return p
+	}
+	name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+	if resolved, ok := d.Components.Parameters[name]; ok {
+		return resolved
+	}
+	panic("openapi: unknown parameter " + p.Ref)
+}
+
+// resolveSchema 展开 $ref
+func (d *document) resolveSchema(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		next, ok := d.Components.Schemas[name]
+		if !ok {
+			panic("openapi: unknown schema " + s.Ref)
+		}
+		s = next
+	}
+	return s
+}
+
+// Handler 提供 GET /api/v1/openapi.json
+func Handler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", specJSON)
+}
+
+// operationFor 将 gin 的路由模板转换为文档路径，例如
+// /api/v1/files/:source_key/*path -> /files/{source_key}/{path}
+func operationFor(method, fullPath string) *Operation {
+	path := strings.TrimPrefix(fullPath, basePath)
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return spec.operations[method+" "+strings.Join(segments, "/")]
+}
+
+// CheckRoutes 检查 /api/v1 下的每个路由在文档中都有对应的 operation
+// 缺少文档的路由不会经过 Validator 校验，启动时发现比上线后发现好
+func CheckRoutes(routes gin.RoutesInfo) error {
+	var missing []string
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, basePath+"/") {
+			continue
+		}
+		if operationFor(r.Method, r.Path) == nil {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("openapi: routes missing from openapi.json:\n  %s", strings.Join(missing, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/interface/api/handlers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxJSONBody JSON 请求体上限 (批量操作可能包含上万条)
+const maxJSONBody = 8 << 20
+
+// Schema 校验用到的 JSON Schema 子集
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []any              `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *additional        `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinLength            *int               `json:"minLength"`
+	MinProperties        *int               `json:"minProperties"`
+	Minimum              *float64           `json:"minimum"`
+	Pattern              string             `json:"pattern"`
+	AllOf                []*Schema          `json:"allOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+}
+
+// additional additionalProperties 可以是布尔值或 Schema
+type additional struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// 正则只编译一次
+var patterns sync.Map // map[string]*regexp.Regexp
+
+// Validator 按文档校验 query 参数与 JSON 请求体，不合法时返回 400
+// 文档中没有的路由 (/api/v1 之外) 直接放行，/api/v1 下的路由由 CheckRoutes 保证都有文档；header 参数 (tus 协议) 由 Handler 自行按协议返回状态码
+func Validator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := operationFor(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+		if err := validateQuery(c, op); err != nil {
+			handlers.Fail(c, http.StatusBadRequest, handlers.CodeInvalidRequest, err.Error())
+			return
+		}
+		if err := validateBody(c, op); err != nil {
+			handlers.Fail(c, http.StatusBadRequest, handlers.CodeInvalidRequest, err.Error())
+			return
+		}
+		c.Next()
+	}
+}
+
+func validateQuery(c *gin.Context, op *Operation) error {
+	query := c.Request.URL.Query()
+	for _, p := range op.Parameters {
+		if p.In != "query" {
+			continue
+		}
+		values, present := query[p.Name]
+		if !present || len(values) == 0 {
+			if p.Required {
+				return fmt.Errorf("query parameter %q is required", p.Name)
+			}
+			continue
+		}
+		schema := spec.resolveSchema(p.Schema)
+		if schema.Type == "array" {
+			items := make([]any, len(values))
+			for i, v := range values {
+				items[i] = v
+			}
+			if err := validate(items, schema, p.Name); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := parseScalar(values[0], schema.Type)
+		if err != nil {
+			return fmt.Errorf("query parameter %q: %v", p.Name, err)
+		}
+		if err := validate(value, schema, p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseScalar 将 query 字符串转换为文档声明的类型
+func parseScalar(s, typ string) (any, error) {
+	switch typ {
+	case "boolean":
+		switch s {
+		case "1", "true":
+			return true, nil
+		case "0", "false", "":
+			return false, nil
+		}
+		return nil, fmt.Errorf("must be a boolean")
+	case "integer", "number":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return f, nil
+	}
+	return s, nil
+}
+
+// validateBody 只校验声明了 application/json 的请求体，上传等二进制请求体不读取
+func validateBody(c *gin.Context, op *Operation) error {
+	if op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+	var body []byte
+	if c.Request.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(c.Request.Body, maxJSONBody+1))
+		if err != nil {
+			return fmt.Errorf("failed to read request body")
+		}
+		if len(body) > maxJSONBody {
+			return fmt.Errorf("request body too large")
+		}
+		// 校验后还原请求体，Handler 照常绑定
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return fmt.Errorf("request body is required")
+		}
+		return nil
+	}
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return validate(value, media.Schema, "body")
+}
+
+// validate 校验一个 JSON 值，where 用于错误信息中定位字段
+func validate(value any, schema *Schema, where string) error {
+	schema = spec.resolveSchema(schema)
+	if schema == nil {
+		return nil
+	}
+	for _, sub := range schema.AllOf {
+		if err := validate(value, sub, where); err != nil {
+			return err
+		}
+	}
+	if len(schema.OneOf) > 0 {
+		matched := false
+		for _, sub := range schema.OneOf {
+			if validate(value, sub, where) == nil {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("%s does not match any allowed schema", where)
+		}
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", where)
+		}
+		return validateObject(obj, schema, where)
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", where)
+		}
+		if schema.MinItems != nil && len(arr) < *schema.MinItems {
+			return fmt.Errorf("%s must contain at least %d items", where, *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(arr) > *schema.MaxItems {
+			return fmt.Errorf("%s must contain at most %d items", where, *schema.MaxItems)
+		}
+		for i, item := range arr {
+			if err := validate(item, schema.Items, fmt.Sprintf("%s[%d]", where, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", where)
+		}
+		if err := validateString(s, schema, where); err != nil {
+			return err
+		}
+	case "integer", "number":
+		n, err := toFloat(value)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", where)
+		}
+		if schema.Type == "integer" && n != float64(int64(n)) {
+			return fmt.Errorf("%s must be an integer", where)
+		}
+		if schema.Minimum != nil && n < *schema.Minimum {
+			return fmt.Errorf("%s must be >= %v", where, *schema.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a boolean", where)
+		}
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		return fmt.Errorf("%s must be one of %v", where, schema.Enum)
+	}
+	return nil
+}
+
+func validateObject(obj map[string]any, schema *Schema, where string) error {
+	for _, name := range schema.Required {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s.%s is required", where, name)
+		}
+	}
+	if schema.MinProperties != nil && len(obj) < *schema.MinProperties {
+		return fmt.Errorf("%s must contain at least %d fields", where, *schema.MinProperties)
+	}
+	// 按字段名排序，保证错误信息稳定
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := where + "." + name
+		if prop, ok := schema.Properties[name]; ok {
+			if err := validate(obj[name], prop, field); err != nil {
+				return err
+			}
+			continue
+		}
+		if schema.AdditionalProperties == nil {
+			continue
+		}
+		if !schema.AdditionalProperties.Allowed {
+			return fmt.Errorf("%s is not allowed", field)
+		}
+		if err := validate(obj[name], schema.AdditionalProperties.Schema, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateString(s string, schema *Schema, where string) error {
+	if schema.MinLength != nil && len([]rune(s)) < *schema.MinLength {
+		if *schema.MinLength == 1 {
+			return fmt.Errorf("%s must not be empty", where)
+		}
+		return fmt.Errorf("%s must be at least %d characters", where, *schema.MinLength)
+	}
+	if schema.Format == "date-time" {
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("%s must be an RFC 3339 date-time", where)
+		}
+	}
+	if schema.Pattern != "" {
+		re, ok := patterns.Load(schema.Pattern)
+		if !ok {
+			re, _ = patterns.LoadOrStore(schema.Pattern, regexp.MustCompile(schema.Pattern))
+		}
+		if !re.(*regexp.Regexp).MatchString(s) {
+			return fmt.Errorf("%s has invalid format", where)
+		}
+	}
+	return nil
+}
+
+func toFloat(v any) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("not a number")
+}
+
+func inEnum(value any, enum []any) bool {
+	s := fmt.Sprint(value)
+	for _, e := range enum {
+		if fmt.Sprint(e) == s {
+			return true
+		}
+	}
+	return false
+}
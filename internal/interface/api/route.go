@@ -4,11 +4,12 @@ import (
 	"github.com/wentf9/MyGoFileHub/internal/application"
 	"github.com/wentf9/MyGoFileHub/internal/interface/api/handlers"
 	"github.com/wentf9/MyGoFileHub/internal/interface/api/middleware"
+	"github.com/wentf9/MyGoFileHub/internal/interface/api/openapi"
 
 	"github.com/gin-gonic/gin"
 )
 
-func InitRouter(fileService *application.FileService, authService *application.AuthService) *gin.Engine {
+func InitRouter(fileService *application.FileService, authService *application.AuthService, metaService *application.MetadataService, tusService *application.TusService, fullTextService *application.FullTextService, thumbService *application.ThumbnailService, mediaService *application.MediaService, shareService *application.ShareService, fileRequestService *application.FileRequestService, notifyService *application.NotificationService, versionService *application.VersionService) *gin.Engine {
 	r := gin.Default()
 	// ---------------------------------------------------------
 	// 关闭 Gin 的自动重定向
@@ -23,30 +24,142 @@ func InitRouter(fileService *application.FileService, authService *application.A
 	})
 
 	// 依赖注入 Handler
-	fileHandler := handlers.NewFileHandler(fileService)
+	fileHandler := handlers.NewFileHandler(fileService, mediaService)
 	authHandler := handlers.NewAuthHandler(authService)
-	webDAVHandler := handlers.NewWebDAVHandler(fileService, authService)
+	webDAVHandler := handlers.NewWebDAVHandler(fileService, authService, metaService)
+	metadataHandler := handlers.NewMetadataHandler(metaService)
+	sourceHandler := handlers.NewSourceHandler(fileService)
+	tusHandler := handlers.NewTusHandler(tusService)
+	fullTextHandler := handlers.NewFullTextHandler(fullTextService)
+	thumbHandler := handlers.NewThumbnailHandler(thumbService)
+	shareHandler := handlers.NewShareHandler(shareService, fileService)
+	fileRequestHandler := handlers.NewFileRequestHandler(fileRequestService)
+	notificationHandler := handlers.NewNotificationHandler(notifyService)
+	trashHandler := handlers.NewTrashHandler(fileService)
+	versionHandler := handlers.NewVersionHandler(versionService)
+	userHandler := handlers.NewUserHandler(authService)
 
-	file := r.Group("/")
-	file.Use(middleware.ClientCheck(), middleware.JWTAuth())
-	{
-		file.GET("/", fileHandler.GetHandler)
-		file.GET("/:source_key/*path", fileHandler.GetHandler)
-		file.DELETE("/:source_key/*path", fileHandler.DeleteHandler)
-	}
-
-	// API 版本控制
+	// API 版本控制，接口契约见 openapi/openapi.json
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.ClientCheck())
 	{
 		// 公开接口
-		v1.POST("/login", authHandler.Login).Use(middleware.ClientCheck())
+		// openapi.Validator 按文档校验 query 参数与 JSON 请求体，放在鉴权之后
+		v1.POST("/login", openapi.Validator(), authHandler.Login)
+		v1.GET("/openapi.json", openapi.Handler)
+		v1.GET("/highlight.css", handlers.HighlightCSSHandler)
+		// tus 能力发现与跨域预检不需要登录
+		v1.OPTIONS("/tus/:source_key", tusHandler.OptionsHandler)
+		v1.OPTIONS("/tus/:source_key/:id", tusHandler.OptionsHandler)
+		// 分享链接的公开访问，不经过 JWTAuth，由分享令牌 (及密码) 授权
+		shared := v1.Group("/s/:token")
+		shared.Use(openapi.Validator())
+		{
+			shared.GET("", shareHandler.InfoHandler)
+			shared.POST("/unlock", shareHandler.UnlockHandler)
+			shared.GET("/files/*path", shareHandler.FilesHandler)
+			shared.GET("/raw/*path", shareHandler.DownloadHandler)
+			shared.HEAD("/raw/*path", shareHandler.DownloadHandler)
+			shared.POST("/upload", shareHandler.UploadHandler)
+		}
+		// 文件收集请求的公开上传，上传者看不到目录内容
+		v1.GET("/r/:token", openapi.Validator(), fileRequestHandler.InfoHandler)
+		v1.POST("/r/:token", openapi.Validator(), fileRequestHandler.UploadHandler)
 		// 保护接口 (使用 JWTAuth 中间件)
-		// protected := v1.Group("/")
-		// protected.Use(middleware.ClientCheck(), middleware.JWTAuth())
-		// {
-		// 	files := protected.Group("/files")
-		// 	files.GET("/list", fileHandler.List)
-		// }
+		protected := v1.Group("/")
+		protected.Use(middleware.JWTAuth(authService), openapi.Validator())
+		{
+			// 当前用户与可见的存储源
+			protected.GET("/users/me", authHandler.MeHandler)
+			protected.PATCH("/users/me", userHandler.ProfileHandler)
+			protected.POST("/users/me/password", userHandler.PasswordHandler)
+			protected.GET("/sources", sourceHandler.ListHandler)
+			// 文件列表、上传、删除 (?media=1 附带媒体元数据)
+			protected.GET("/files/:source_key/*path", fileHandler.GetHandler)
+			protected.DELETE("/files/:source_key/*path", fileHandler.DeleteHandler)
+			protected.PUT("/files/:source_key/*path", fileHandler.UploadHandler)
+			protected.POST("/files/:source_key/*path", fileHandler.UploadHandler)
+			// 按文件名、大小、修改时间搜索 (NDJSON 流式返回)
+			protected.GET("/search", fileHandler.SearchHandler)
+			// 按文件内容搜索 (全文索引)
+			protected.GET("/search/content", fullTextHandler.SearchHandler)
+			// 文件自定义元数据
+			protected.GET("/meta/:source_key/*path", metadataHandler.GetHandler)
+			protected.PUT("/meta/:source_key/*path", metadataHandler.SetHandler)
+			protected.DELETE("/meta/:source_key/*path", metadataHandler.DeleteHandler)
+			// 文件属性 (修改时间、权限、大小)
+			protected.PATCH("/attr/:source_key/*path", fileHandler.AttrHandler)
+			// 下载文件内容 (?inline=1 浏览器预览)
+			protected.GET("/raw/:source_key/*path", fileHandler.DownloadHandler)
+			protected.HEAD("/raw/:source_key/*path", fileHandler.DownloadHandler)
+			// 文本、Markdown 与源码预览
+			protected.GET("/preview/:source_key/*path", fileHandler.PreviewHandler)
+			// 图片缩略图 (?size=256)
+			protected.GET("/thumb/:source_key/*path", thumbHandler.GetHandler)
+			// 打包下载目录或多个选中项 (zip / tar.gz)
+			protected.GET("/archive/:source_key", fileHandler.ArchiveHandler)
+			protected.POST("/archive/:source_key", fileHandler.ArchiveHandler)
+			// 目录管理
+			protected.POST("/fs/mkdir", fileHandler.MkdirHandler)
+			protected.POST("/fs/rename", fileHandler.RenameHandler)
+			protected.POST("/fs/move", fileHandler.MoveHandler)
+			protected.POST("/fs/copy", fileHandler.CopyHandler)
+			protected.POST("/fs/batch", fileHandler.BatchHandler)
+			protected.GET("/fs/jobs/:id", fileHandler.JobHandler)
+			protected.DELETE("/fs/jobs/:id", fileHandler.CancelJobHandler)
+			// 回收站
+			protected.GET("/trash", trashHandler.ListHandler)
+			protected.DELETE("/trash", trashHandler.EmptyHandler)
+			protected.POST("/trash/:id/restore", trashHandler.RestoreHandler)
+			protected.DELETE("/trash/:id", trashHandler.PurgeHandler)
+			// 文件历史版本
+			protected.GET("/versions/:source_key/*path", versionHandler.ListHandler)
+			protected.GET("/file-versions/:id/raw", versionHandler.DownloadHandler)
+			protected.POST("/file-versions/:id/restore", versionHandler.RestoreHandler)
+			protected.GET("/file-versions/:id/diff", versionHandler.DiffHandler)
+			// 分享链接管理
+			protected.POST("/shares", shareHandler.CreateHandler)
+			protected.GET("/shares", shareHandler.ListHandler)
+			protected.DELETE("/shares/:id", shareHandler.RevokeHandler)
+			protected.GET("/shares/:id/access", shareHandler.AccessLogHandler)
+			// 文件收集请求
+			protected.POST("/file-requests", fileRequestHandler.CreateHandler)
+			protected.GET("/file-requests", fileRequestHandler.ListHandler)
+			protected.DELETE("/file-requests/:id", fileRequestHandler.CloseHandler)
+			protected.GET("/file-requests/:id/uploads", fileRequestHandler.UploadsHandler)
+			// 站内通知
+			protected.GET("/notifications", notificationHandler.ListHandler)
+			protected.POST("/notifications/read", notificationHandler.MarkReadHandler)
+			// 断点续传 (tus 1.0)
+			protected.POST("/tus/:source_key", tusHandler.CreateHandler)
+			protected.HEAD("/tus/:source_key/:id", tusHandler.HeadHandler)
+			protected.PATCH("/tus/:source_key/:id", tusHandler.PatchHandler)
+			protected.DELETE("/tus/:source_key/:id", tusHandler.DeleteHandler)
+		}
+
+		// 管理接口 (仅管理员)
+		admin := v1.Group("/admin")
+		admin.Use(middleware.JWTAuth(authService), middleware.AdminOnly(authService), openapi.Validator())
+		{
+			// 用户管理
+			admin.GET("/users", userHandler.ListHandler)
+			admin.POST("/users", userHandler.CreateHandler)
+			admin.PATCH("/users/:username", userHandler.UpdateHandler)
+			admin.DELETE("/users/:username", userHandler.DeleteHandler)
+			admin.POST("/users/:username/deactivate", userHandler.DeactivateHandler)
+			admin.POST("/users/:username/activate", userHandler.ActivateHandler)
+			// 存储源管理
+			admin.GET("/drivers", sourceHandler.DriversHandler)
+			admin.GET("/sources", sourceHandler.AdminListHandler)
+			admin.POST("/sources", sourceHandler.CreateHandler)
+			admin.POST("/sources/test", sourceHandler.TestHandler)
+			admin.PUT("/sources/:source_key", sourceHandler.UpdateHandler)
+			admin.DELETE("/sources/:source_key", sourceHandler.DeleteHandler)
+			admin.POST("/sources/:source_key/test", sourceHandler.TestSavedHandler)
+			admin.GET("/sources/health", sourceHandler.HealthHandler)
+			admin.POST("/sources/:source_key/index", sourceHandler.IndexHandler)
+			admin.POST("/sources/:source_key/fulltext", fullTextHandler.ReindexHandler)
+		}
 	}
 
 	// -------------------------------------------------------------
@@ -70,5 +183,9 @@ func InitRouter(fileService *application.FileService, authService *application.A
 		}
 	}
 
+	// 每个 /api/v1 路由都必须在 openapi.json 中有文档，否则 Validator 会跳过它的校验
+	if err := openapi.CheckRoutes(r.Routes()); err != nil {
+		panic(err)
+	}
 	return r
 }
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wentf9/MyGoFileHub/internal/application"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+	"github.com/wentf9/MyGoFileHub/internal/interface/api/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// 只注册路由，不处理请求，Service 均可为 nil
+func TestRoutesDocumented(t *testing.T) {
+	r := InitRouter(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	if err := openapi.CheckRoutes(r.Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRoutesReportsMissing(t *testing.T) {
+	r := gin.New()
+	noop := func(*gin.Context) {}
+	r.GET("/api/v1/users/me", noop)
+	r.GET("/api/v1/undocumented/:id", noop)
+	r.GET("/webdav/:source_key", noop) // /api/v1 之外不检查
+	err := openapi.CheckRoutes(r.Routes())
+	if err == nil {
+		t.Fatal("expected an error for the undocumented route")
+	}
+	if want := "GET /api/v1/undocumented/:id"; !strings.Contains(err.Error(), want) {
+		t.Fatalf("error %q does not mention %q", err, want)
+	}
+	if strings.Contains(err.Error(), "/users/me") || strings.Contains(err.Error(), "/webdav") {
+		t.Fatalf("error %q reports routes that are documented or outside /api/v1", err)
+	}
+}
+
+// 浏览器对 PATCH / HEAD / DELETE 发出的预检不带 Authorization，必须在鉴权之前得到允许
+func TestTusPreflight(t *testing.T) {
+	dir := t.TempDir()
+	db, err := persistence.InitDB(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := persistence.NewUserRepository(db)
+	perms := persistence.NewPermissionRepository(db)
+	files := application.NewFileService(persistence.NewSourceRepository(db), persistence.NewSearchIndexRepository(db), persistence.NewTrashRepository(db), application.NewPermissionService(perms, users), nil)
+	tus, err := application.NewTusService(files, persistence.NewUploadSessionRepository(db), filepath.Join(dir, "staging"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := InitRouter(files, nil, nil, tus, nil, nil, nil, nil, nil, nil, nil)
+
+	for _, target := range []string{"/api/v1/tus/local", "/api/v1/tus/local/abc123"} {
+		req := httptest.NewRequest(http.MethodOptions, target, nil)
+		req.Header.Set("Origin", "http://example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+		req.Header.Set("Access-Control-Request-Headers", "authorization, tus-resumable, upload-offset, content-type")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("OPTIONS %s = %d, want 204: %s", target, w.Code, w.Body.String())
+		}
+		for _, method := range []string{"PATCH", "HEAD", "DELETE"} {
+			if !strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), method) {
+				t.Fatalf("OPTIONS %s does not allow %s", target, method)
+			}
+		}
+		for _, header := range []string{"Authorization", "Tus-Resumable", "Upload-Offset", "Content-Type"} {
+			if !strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), header) {
+				t.Fatalf("OPTIONS %s does not allow header %s", target, header)
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package webdav
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+)
+
+type errorRecorderKey struct{}
+
+// ErrorRecorder 记录一次 WebDAV 请求中文件系统返回的最后一个错误
+// webdav 库只区分 404/405/409/500 等少数状态，Handler 根据这里记录的领域错误修正响应状态码
+type ErrorRecorder struct {
+	mu  sync.Mutex
+	err error
+}
+
+// WithErrorRecorder 为请求附加一个 ErrorRecorder
+func WithErrorRecorder(ctx context.Context) (context.Context, *ErrorRecorder) {
+	rec := &ErrorRecorder{}
+	return context.WithValue(ctx, errorRecorderKey{}, rec), rec
+}
+
+func (r *ErrorRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// osError 记录领域错误，并转换为 webdav 库能识别的 *os.PathError
+// webdav 库使用 os.IsNotExist 等函数判断，它们不会展开自定义错误类型
+func osError(ctx context.Context, op, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if rec, ok := ctx.Value(errorRecorderKey{}).(*ErrorRecorder); ok {
+		rec.mu.Lock()
+		rec.err = err
+		rec.mu.Unlock()
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return &os.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	case errors.Is(err, fs.ErrPermission):
+		return &os.PathError{Op: op, Path: name, Err: fs.ErrPermission}
+	case errors.Is(err, fs.ErrExist) && !errors.Is(err, vfs.ErrNotEmpty):
+		return &os.PathError{Op: op, Path: name, Err: fs.ErrExist}
+	}
+	return err
+}
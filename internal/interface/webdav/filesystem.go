@@ -2,7 +2,10 @@ package webdav
 
 import (
 	"context"
+	"fmt"
 	"os"
+	pathpkg "path"
+	"time"
 
 	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
 
@@ -12,29 +15,95 @@ import (
 // DriverFileSystem 将我们的 StorageDriver 适配为 webdav.FileSystem
 type DriverFileSystem struct {
 	Driver vfs.StorageDriver
+	// Metadata 用于保存 PROPPATCH 写入的 Dead Properties，为 nil 时不支持自定义属性
+	Metadata vfs.MetadataDriver
+	// Remove 用于 DELETE (如移入回收站)，为 nil 时直接调用 Driver.Delete
+	Remove func(ctx context.Context, name string) error
+	// Replace 用 tmp 替换已存在的 name (如把旧文件移入回收站)，为 nil 时 PUT 直接截断原文件
+	Replace func(ctx context.Context, tmp, name string) error
 }
 
 func (fsys *DriverFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
-	return fsys.Driver.Mkdir(ctx, name, perm)
+	return osError(ctx, "mkdir", name, fsys.Driver.Mkdir(ctx, name, perm))
 }
 
 func (fsys *DriverFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
-	// 直接透传调用
-	return fsys.Driver.OpenFile(ctx, name, flag, perm)
+	if flag&os.O_TRUNC != 0 && fsys.Replace != nil {
+		if info, err := fsys.Driver.Stat(ctx, name); err == nil && !info.IsDir {
+			return fsys.openReplacement(ctx, name, flag, perm)
+		}
+	}
+	f, err := fsys.Driver.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, osError(ctx, "open", name, err)
+	}
+	// 包装一层以实现 webdav.DeadPropsHolder
+	return &propFile{File: f, ctx: ctx, name: name, driver: fsys.Driver, meta: fsys.Metadata}, nil
 }
 
 func (fsys *DriverFileSystem) RemoveAll(ctx context.Context, name string) error {
-	return fsys.Driver.Delete(ctx, name)
+	if fsys.Remove != nil {
+		return osError(ctx, "remove", name, fsys.Remove(ctx, name))
+	}
+	return osError(ctx, "remove", name, fsys.Driver.Delete(ctx, name))
 }
 
 func (fsys *DriverFileSystem) Rename(ctx context.Context, oldName, newName string) error {
-	return fsys.Driver.Rename(ctx, oldName, newName)
+	return osError(ctx, "rename", oldName, fsys.Driver.Rename(ctx, oldName, newName))
 }
 
 func (fsys *DriverFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
 	info, err := fsys.Driver.Stat(ctx, name)
 	if err != nil {
-		return nil, err
+		return nil, osError(ctx, "stat", name, err)
 	}
 	return vfs.ToOSFileInfo(info), nil
 }
+
+// openReplacement PUT 覆盖已有文件时先写入同目录下的临时文件，Close 时再替换原文件
+// 上传中断时原文件保持不变
+func (fsys *DriverFileSystem) openReplacement(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	tmp := pathpkg.Join(pathpkg.Dir(name), fmt.Sprintf(".%s.put-%d", pathpkg.Base(name), time.Now().UnixNano()))
+	f, err := fsys.Driver.OpenFile(ctx, tmp, flag|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return nil, osError(ctx, "open", name, err)
+	}
+	rf := &replacingFile{File: f, ctx: ctx, tmp: tmp, name: name, fsys: fsys}
+	return &propFile{File: rf, ctx: ctx, name: name, driver: fsys.Driver, meta: fsys.Metadata}, nil
+}
+
+// replacingFile 写入临时文件，Close 时用它替换原文件；写入失败或请求已取消时丢弃临时文件
+type replacingFile struct {
+	vfs.File
+	ctx      context.Context
+	tmp      string
+	name     string
+	fsys     *DriverFileSystem
+	writeErr error
+}
+
+func (f *replacingFile) Write(p []byte) (int, error) {
+	n, err := f.File.Write(p)
+	if err != nil && f.writeErr == nil {
+		f.writeErr = err
+	}
+	return n, err
+}
+
+func (f *replacingFile) Close() error {
+	err := f.File.Close()
+	if err == nil {
+		err = f.writeErr
+	}
+	if err == nil {
+		err = f.ctx.Err()
+	}
+	if err == nil {
+		err = f.fsys.Replace(f.ctx, f.tmp, f.name)
+	}
+	if err != nil {
+		f.fsys.Driver.Delete(context.WithoutCancel(f.ctx), f.tmp)
+		return osError(f.ctx, "close", f.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,273 @@
+package webdav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+
+	"golang.org/x/net/webdav"
+)
+
+// deadPropPrefix Dead Property 在元数据中的键前缀，完整格式为 "dav:{namespace}local"
+const deadPropPrefix = "dav:"
+
+var (
+	// Windows 资源管理器上传后通过 PROPPATCH 回写的修改时间
+	win32LastModified = xml.Name{Space: "urn:schemas-microsoft-com:", Local: "Win32LastModifiedTime"}
+	// DAV:getlastmodified 是 live property，webdav 库会直接拒绝修改
+	// 因此在进入 webdav 库之前将其替换为这个私有属性 (见 RewriteLastModified)
+	getLastModified = xml.Name{Space: "DAV:", Local: "getlastmodified"}
+	hubModTime      = xml.Name{Space: "urn:gofilehub:", Local: "mtime"}
+)
+
+// propFile 为 vfs.File 增加 Dead Properties 支持 (webdav.DeadPropsHolder)
+// webdav 库调用 DeadProps/Patch 时不传 ctx，因此在 OpenFile 时保存下来，用于权限检查
+type propFile struct {
+	vfs.File
+	ctx    context.Context
+	name   string
+	driver vfs.StorageDriver
+	meta   vfs.MetadataDriver // 为 nil 时不支持自定义属性
+}
+
+func (f *propFile) DeadProps() (map[xml.Name]webdav.Property, error) {
+	if f.meta == nil {
+		return nil, nil
+	}
+	meta, err := f.meta.GetMetadata(f.ctx, f.name)
+	if err != nil {
+		// 读不到自定义属性不应导致整个 PROPFIND 失败
+		return nil, nil
+	}
+	props := make(map[xml.Name]webdav.Property)
+	for key, value := range meta {
+		name, ok := decodePropKey(key)
+		if !ok {
+			continue
+		}
+		props[name] = webdav.Property{XMLName: name, InnerXML: []byte(value)}
+	}
+	return props, nil
+}
+
+// errInvalidPropValue 属性值格式不正确，对应 multistatus 中该属性的 409
+var errInvalidPropValue = errors.New("invalid property value")
+
+// propChange PROPPATCH 中的一个属性修改
+type propChange struct {
+	name   xml.Name // 响应中报告的属性名
+	key    string   // Dead Property 在元数据中的键，修改时间为空
+	value  string
+	remove bool
+	mtime  time.Time // key 为空时要设置的修改时间
+}
+
+// Patch 按 RFC 4918 全部成功或全部不生效：先检查所有属性，再依次应用，
+// 应用中途失败时撤销已应用的修改。失败的属性报告自身的状态 (无权修改为 403，取值无法解析为 409)，
+// 其余属性报告 424 Failed Dependency；其他错误使整个请求失败
+func (f *propFile) Patch(patches []webdav.Proppatch) ([]webdav.Propstat, error) {
+	var changes []propChange
+	failed := make(map[int]int) // changes 下标 -> 状态码
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			c := propChange{name: p.XMLName, remove: patch.Remove}
+			switch {
+			case !patch.Remove && (p.XMLName == win32LastModified || p.XMLName == hubModTime):
+				if p.XMLName == hubModTime {
+					// 响应中还原为客户端请求的属性名
+					c.name = getLastModified
+				}
+				mtime, err := parseModTime(string(p.InnerXML))
+				if err != nil {
+					failed[len(changes)] = http.StatusConflict
+				}
+				c.mtime = mtime
+			case f.meta == nil:
+				failed[len(changes)] = http.StatusForbidden
+			default:
+				c.key, c.value = encodePropKey(p.XMLName), string(p.InnerXML)
+			}
+			changes = append(changes, c)
+		}
+	}
+	if len(changes) == 0 {
+		return []webdav.Propstat{{Status: http.StatusOK}}, nil
+	}
+	if len(failed) > 0 {
+		return propStats(changes, failed), nil
+	}
+
+	undo, err := f.snapshotProps(changes)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range changes {
+		if err := f.applyProp(f.ctx, c); err != nil {
+			undo(changes[:i])
+			if errors.Is(err, os.ErrPermission) {
+				return propStats(changes, map[int]int{i: http.StatusForbidden}), nil
+			}
+			return nil, err
+		}
+	}
+	return propStats(changes, nil), nil
+}
+
+// snapshotProps 记录修改前的属性值，返回把 applied 中的修改恢复原状的函数
+func (f *propFile) snapshotProps(changes []propChange) (func(applied []propChange), error) {
+	var oldMeta map[string]string
+	var oldTime time.Time
+	for _, c := range changes {
+		var err error
+		switch {
+		case c.key != "" && oldMeta == nil:
+			oldMeta, err = f.meta.GetMetadata(f.ctx, f.name)
+			if oldMeta == nil {
+				oldMeta = map[string]string{}
+			}
+		case c.key == "" && oldTime.IsZero():
+			var info vfs.FileInfo
+			info, err = f.driver.Stat(f.ctx, f.name)
+			oldTime = info.ModTime
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return func(applied []propChange) {
+		// 请求可能已被取消，撤销不能半途而废
+		ctx := context.WithoutCancel(f.ctx)
+		restored := make(map[string]bool)
+		for _, c := range applied {
+			if restored[c.key] {
+				continue
+			}
+			restored[c.key] = true
+			old := propChange{key: c.key, mtime: oldTime}
+			if c.key != "" {
+				value, ok := oldMeta[c.key]
+				old.value, old.remove = value, !ok
+			}
+			if err := f.applyProp(ctx, old); err != nil {
+				fmt.Printf("[WebDAV] Failed to roll back property %s of %s: %v\n", c.name.Local, f.name, err)
+			}
+		}
+	}, nil
+}
+
+func (f *propFile) applyProp(ctx context.Context, c propChange) error {
+	switch {
+	case c.key == "":
+		return f.driver.SetModTime(ctx, f.name, c.mtime)
+	case c.remove:
+		return f.meta.DeleteMetadata(ctx, f.name, c.key)
+	default:
+		return f.meta.SetMetadata(ctx, f.name, c.key, c.value)
+	}
+}
+
+// propStats 按状态码分组生成 multistatus 内容，failed 之外的属性：
+// failed 为空时报告 200，否则报告 424 (因为其他属性失败而没有应用)
+func propStats(changes []propChange, failed map[int]int) []webdav.Propstat {
+	var pstats []webdav.Propstat
+	for i, c := range changes {
+		status, ok := failed[i]
+		switch {
+		case ok:
+		case len(failed) > 0:
+			status = http.StatusFailedDependency
+		default:
+			status = http.StatusOK
+		}
+		j := slices.IndexFunc(pstats, func(ps webdav.Propstat) bool { return ps.Status == status })
+		if j < 0 {
+			pstats = append(pstats, webdav.Propstat{Status: status})
+			j = len(pstats) - 1
+		}
+		pstats[j].Props = append(pstats[j].Props, webdav.Property{XMLName: c.name})
+	}
+	return pstats
+}
+
+// parseModTime 解析 RFC 1123 格式的时间 (Win32LastModifiedTime 与 getlastmodified 均使用该格式)
+func parseModTime(value string) (time.Time, error) {
+	mtime, err := http.ParseTime(strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q is not an HTTP date", errInvalidPropValue, value)
+	}
+	return mtime, nil
+}
+
+// RewriteLastModified 将 PROPPATCH 请求体中 <D:set> 里的 DAV:getlastmodified 替换为私有属性，
+// 使修改时间能够到达 propFile.Patch；请求体无法解析时原样返回
+func RewriteLastModified(body []byte) []byte {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	var out bytes.Buffer
+	var last int64
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name != getLastModified {
+			continue
+		}
+		var value string
+		if err := dec.DecodeElement(&value, &se); err != nil {
+			return body
+		}
+		// <D:remove> 中的属性没有值，交给 webdav 库按 live property 拒绝
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		out.Write(body[last:start])
+		out.WriteString(`<g:mtime xmlns:g="` + hubModTime.Space + `">`)
+		xml.EscapeText(&out, []byte(value))
+		out.WriteString(`</g:mtime>`)
+		last = dec.InputOffset()
+	}
+	if last == 0 {
+		return body
+	}
+	out.Write(body[last:])
+	return out.Bytes()
+}
+
+// ParseOCMtime 解析 ownCloud/Nextcloud 同步客户端 (以及 rclone) 在 PUT 时携带的 X-OC-Mtime 头 (Unix 秒)
+func ParseOCMtime(value string) (time.Time, bool) {
+	// 部分客户端会带小数部分，只取整数秒
+	sec, _, _ := strings.Cut(value, ".")
+	n, err := strconv.ParseInt(sec, 10, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(n, 0), true
+}
+
+func encodePropKey(name xml.Name) string {
+	return deadPropPrefix + "{" + name.Space + "}" + name.Local
+}
+
+func decodePropKey(key string) (xml.Name, bool) {
+	rest, ok := strings.CutPrefix(key, deadPropPrefix+"{")
+	if !ok {
+		return xml.Name{}, false
+	}
+	space, local, ok := strings.Cut(rest, "}")
+	if !ok || local == "" {
+		return xml.Name{}, false
+	}
+	return xml.Name{Space: space, Local: local}, true
+}
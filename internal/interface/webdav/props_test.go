@@ -0,0 +1,175 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wentf9/MyGoFileHub/internal/domain/vfs"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/local"
+
+	"golang.org/x/net/webdav"
+)
+
+// memMetadata 内存中的元数据，deny 中的键写入时返回 os.ErrPermission
+type memMetadata struct {
+	props map[string]string
+	deny  map[string]bool
+}
+
+func (m *memMetadata) GetMetadata(ctx context.Context, path string) (map[string]string, error) {
+	return maps.Clone(m.props), nil
+}
+
+func (m *memMetadata) SetMetadata(ctx context.Context, path string, key, value string) error {
+	if m.deny[key] {
+		return os.ErrPermission
+	}
+	m.props[key] = value
+	return nil
+}
+
+func (m *memMetadata) DeleteMetadata(ctx context.Context, path string, key string) error {
+	if m.deny[key] {
+		return os.ErrPermission
+	}
+	delete(m.props, key)
+	return nil
+}
+
+var (
+	propA = xml.Name{Space: "urn:test:", Local: "a"}
+	propB = xml.Name{Space: "urn:test:", Local: "b"}
+)
+
+func newPropTest(t *testing.T, meta vfs.MetadataDriver) (*propFile, vfs.StorageDriver) {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	driver := local.NewLocalDriver()
+	if err := driver.Init(context.Background(), map[string]any{"root_path": root}); err != nil {
+		t.Fatal(err)
+	}
+	return &propFile{ctx: context.Background(), name: "/a.txt", driver: driver, meta: meta}, driver
+}
+
+// statusOf 返回 multistatus 中属性的状态码
+func statusOf(pstats []webdav.Propstat, name xml.Name) int {
+	for _, ps := range pstats {
+		for _, p := range ps.Props {
+			if p.XMLName == name {
+				return ps.Status
+			}
+		}
+	}
+	return 0
+}
+
+func TestPatchAllOrNothing(t *testing.T) {
+	mtime := "Mon, 02 Jan 2006 15:04:05 GMT"
+	tests := []struct {
+		name    string
+		deny    map[string]bool
+		patches []webdav.Proppatch
+		want    map[xml.Name]int
+		props   map[string]string // 修改后的元数据
+		mtime   bool              // 修改时间是否被设置
+	}{
+		{
+			name: "all succeed",
+			patches: []webdav.Proppatch{
+				{Props: []webdav.Property{{XMLName: propA, InnerXML: []byte("new")}, {XMLName: win32LastModified, InnerXML: []byte(mtime)}}},
+				{Remove: true, Props: []webdav.Property{{XMLName: propB}}},
+			},
+			want:  map[xml.Name]int{propA: http.StatusOK, win32LastModified: http.StatusOK, propB: http.StatusOK},
+			props: map[string]string{encodePropKey(propA): "new"},
+			mtime: true,
+		},
+		{
+			// 取值无法解析时在应用前就失败，其他属性都不应用
+			name: "invalid value",
+			patches: []webdav.Proppatch{
+				{Props: []webdav.Property{{XMLName: propA, InnerXML: []byte("new")}, {XMLName: win32LastModified, InnerXML: []byte("yesterday")}}},
+			},
+			want:  map[xml.Name]int{propA: http.StatusFailedDependency, win32LastModified: http.StatusConflict},
+			props: map[string]string{encodePropKey(propA): "old", encodePropKey(propB): "old"},
+		},
+		{
+			// 应用中途被拒绝时撤销已经应用的修改
+			name: "denied after others applied",
+			deny: map[string]bool{encodePropKey(propB): true},
+			patches: []webdav.Proppatch{
+				{Props: []webdav.Property{{XMLName: propA, InnerXML: []byte("new")}, {XMLName: win32LastModified, InnerXML: []byte(mtime)}}},
+				{Remove: true, Props: []webdav.Property{{XMLName: propB}}},
+			},
+			want:  map[xml.Name]int{propA: http.StatusFailedDependency, win32LastModified: http.StatusFailedDependency, propB: http.StatusForbidden},
+			props: map[string]string{encodePropKey(propA): "old", encodePropKey(propB): "old"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta := &memMetadata{
+				props: map[string]string{encodePropKey(propA): "old", encodePropKey(propB): "old"},
+				deny:  tt.deny,
+			}
+			f, driver := newPropTest(t, meta)
+			before, err := driver.Stat(context.Background(), "/a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pstats, err := f.Patch(tt.patches)
+			if err != nil {
+				t.Fatalf("Patch: %v", err)
+			}
+			for name, status := range tt.want {
+				if got := statusOf(pstats, name); got != status {
+					t.Errorf("status of %s = %d, want %d", name.Local, got, status)
+				}
+			}
+			if !maps.Equal(meta.props, tt.props) {
+				t.Errorf("metadata = %v, want %v", meta.props, tt.props)
+			}
+			after, err := driver.Stat(context.Background(), "/a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := before.ModTime
+			if tt.mtime {
+				want, _ = http.ParseTime(mtime)
+			}
+			if !after.ModTime.Equal(want) {
+				t.Errorf("mtime = %v, want %v", after.ModTime, want)
+			}
+		})
+	}
+}
+
+// 没有元数据存储时 Dead Property 报告 403，修改时间也不应用
+func TestPatchWithoutMetadata(t *testing.T) {
+	f, driver := newPropTest(t, nil)
+	before, _ := driver.Stat(context.Background(), "/a.txt")
+	pstats, err := f.Patch([]webdav.Proppatch{{Props: []webdav.Property{
+		{XMLName: propA, InnerXML: []byte("new")},
+		{XMLName: win32LastModified, InnerXML: []byte(time.Now().UTC().Format(http.TimeFormat))},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := statusOf(pstats, propA); got != http.StatusForbidden {
+		t.Errorf("status of a = %d, want 403", got)
+	}
+	if got := statusOf(pstats, win32LastModified); got != http.StatusFailedDependency {
+		t.Errorf("status of mtime = %d, want 424", got)
+	}
+	if after, _ := driver.Stat(context.Background(), "/a.txt"); !after.ModTime.Equal(before.ModTime) {
+		t.Errorf("mtime changed to %v", after.ModTime)
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/wentf9/MyGoFileHub/internal/application"
 	"github.com/wentf9/MyGoFileHub/internal/domain/model"
 	_ "github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/local"
+	_ "github.com/wentf9/MyGoFileHub/internal/infrastructure/drivers/smb"
 	"github.com/wentf9/MyGoFileHub/internal/infrastructure/persistence"
+	"github.com/wentf9/MyGoFileHub/internal/infrastructure/secrets"
 	"github.com/wentf9/MyGoFileHub/internal/interface/api"
 )
 
@@ -47,11 +49,59 @@ func main() {
 	sourceRepo := persistence.NewSourceRepository(db)
 	userRepo := persistence.NewUserRepository(db)
 	permRepo := persistence.NewPermissionRepository(db)
+	metaRepo := persistence.NewMetadataRepository(db)
+	uploadRepo := persistence.NewUploadSessionRepository(db)
+	indexRepo := persistence.NewSearchIndexRepository(db)
+	fullTextRepo := persistence.NewFullTextRepository(db)
+	mediaRepo := persistence.NewMediaInfoRepository(db)
+	shareRepo := persistence.NewShareRepository(db)
+	fileRequestRepo := persistence.NewFileRequestRepository(db)
+	notifyRepo := persistence.NewNotificationRepository(db)
+	trashRepo := persistence.NewTrashRepository(db)
+	versionRepo := persistence.NewFileVersionRepository(db)
+
+	// 存储源密码等敏感配置的主密钥，更换主密钥见 cmd/rotate-key
+	masterKey, err := secrets.LoadKey(config.AppConfig.MasterKey, config.AppConfig.MasterKeyFile)
+	if err != nil {
+		log.Fatalf("Master key initialization failed: %v", err)
+	}
+	cipher, err := secrets.New(masterKey)
+	if err != nil {
+		log.Fatalf("Master key initialization failed: %v", err)
+	}
 
 	// 4. 初始化 Service (注入 Repo)
 	permService := application.NewPermissionService(permRepo, userRepo)
-	fileService := application.NewFileService(sourceRepo, permService)
-	authService := application.NewAuthService(userRepo)
+	fileService := application.NewFileService(sourceRepo, indexRepo, trashRepo, permService, cipher)
+
+	// 加密升级前以明文保存的密码
+	if n, err := fileService.SealSources(context.Background()); err != nil {
+		log.Printf("Failed to encrypt source secrets: %v", err)
+	} else if n > 0 {
+		fmt.Printf("Encrypted secrets of %d storage sources\n", n)
+	}
+	authService := application.NewAuthService(userRepo, permRepo)
+	metaService := application.NewMetadataService(fileService, metaRepo)
+	fullTextService := application.NewFullTextService(fileService, fullTextRepo)
+	mediaService := application.NewMediaService(fileService, mediaRepo)
+	shareService := application.NewShareService(fileService, shareRepo)
+	notifyService := application.NewNotificationService(notifyRepo)
+	fileRequestService := application.NewFileRequestService(fileService, fileRequestRepo, notifyService)
+	versionService := application.NewVersionService(fileService, versionRepo)
+	tusService, err := application.NewTusService(fileService, uploadRepo, config.AppConfig.DataDir+"/uploads")
+	if err != nil {
+		log.Fatalf("Upload service initialization failed: %v", err)
+	}
+	thumbService, err := application.NewThumbnailService(fileService, config.AppConfig.DataDir+"/thumbs")
+	if err != nil {
+		log.Fatalf("Thumbnail service initialization failed: %v", err)
+	}
+	// 删除用户时清理以用户名关联的记录，同名的新用户不会继承
+	authService.OnDelete(shareService.OnUserDeleted)
+	authService.OnDelete(fileRequestService.OnUserDeleted)
+	authService.OnDelete(notifyService.OnUserDeleted)
+	authService.OnDelete(tusService.OnUserDeleted)
+	authService.OnDelete(fileService.OnUserDeleted)
 
 	// --- Seeding: 创建默认管理员 ---
 	var userCount int64
@@ -68,7 +118,7 @@ func main() {
 	}
 
 	// 5. 初始化 Router
-	r := api.InitRouter(fileService, authService)
+	r := api.InitRouter(fileService, authService, metaService, tusService, fullTextService, thumbService, mediaService, shareService, fileRequestService, notifyService, versionService)
 
 	// 6. 启动
 	fmt.Printf("Server starting on :%s...", config.AppConfig.ServerPort)